@@ -0,0 +1,60 @@
+package binarymetadata
+
+import (
+	"errors"
+	"fmt"
+
+	"google3/privacy/net/boq/common/tokens/tokentypes"
+)
+
+// PopulationProvider estimates how many users are located in an area. Implementations must be
+// safe for concurrent use.
+type PopulationProvider interface {
+	// Population returns the estimated number of users in the area described by geo, which is
+	// as precise as the geo hint it was taken from: Region and City may be empty.
+	Population(geo tokentypes.GeoHint) (int64, error)
+}
+
+// AnonymitySetChecker rejects geo hints shared by too few users to keep them anonymous. It is safe
+// for concurrent use if its PopulationProvider is.
+type AnonymitySetChecker struct {
+	provider      PopulationProvider
+	minPopulation int64
+}
+
+// NewAnonymitySetChecker returns an AnonymitySetChecker that requires at least minPopulation users
+// behind every geo hint, as estimated by p.
+func NewAnonymitySetChecker(p PopulationProvider, minPopulation int64) (*AnonymitySetChecker, error) {
+	if p == nil {
+		return nil, errors.New("nil PopulationProvider")
+	}
+	if minPopulation < 1 {
+		return nil, fmt.Errorf("minPopulation %d is less than 1", minPopulation)
+	}
+	return &AnonymitySetChecker{provider: p, minPopulation: minPopulation}, nil
+}
+
+// Estimate returns the estimated number of users behind geo.
+func (c *AnonymitySetChecker) Estimate(geo *tokentypes.GeoHint) (int64, error) {
+	if geo == nil || geo.Country == "" {
+		return 0, fmt.Errorf("%w: geo hint has no country", ErrMissingField)
+	}
+	return c.provider.Population(*geo)
+}
+
+// Check returns an error wrapping ErrAnonymitySetTooSmall if fewer users than required share geo.
+func (c *AnonymitySetChecker) Check(geo *tokentypes.GeoHint) error {
+	n, err := c.Estimate(geo)
+	if err != nil {
+		return err
+	}
+	if n < c.minPopulation {
+		return fmt.Errorf("%w: about %d users share %s,%s,%s, want at least %d", ErrAnonymitySetTooSmall, n, geo.Country, geo.Region, geo.City, c.minPopulation)
+	}
+	return nil
+}
+
+// CheckStruct is like Check for the geo hint of bs.
+func (c *AnonymitySetChecker) CheckStruct(bs *BinaryStruct) error {
+	return c.Check(bs.GetGeoHint())
+}
@@ -0,0 +1,65 @@
+package binarymetadata
+
+import (
+	"errors"
+	"testing"
+
+	"google3/privacy/net/boq/common/tokens/tokentypes"
+)
+
+type fakePopulation map[tokentypes.GeoHint]int64
+
+func (p fakePopulation) Population(geo tokentypes.GeoHint) (int64, error) {
+	n, ok := p[geo]
+	if !ok {
+		return 0, errors.New("unknown area")
+	}
+	return n, nil
+}
+
+func TestAnonymitySetChecker(t *testing.T) {
+	pop := fakePopulation{
+		{Country: "US"}:                                      300000000,
+		{Country: "US", Region: "US-CA"}:                     39000000,
+		{Country: "US", Region: "US-CA", City: "SMALL TOWN"}: 120,
+	}
+	c, err := NewAnonymitySetChecker(pop, 1000)
+	if err != nil {
+		t.Fatalf("NewAnonymitySetChecker failed: %v", err)
+	}
+	tests := []struct {
+		name    string
+		geo     *tokentypes.GeoHint
+		wantErr error
+	}{
+		{name: "country", geo: &tokentypes.GeoHint{Country: "US"}},
+		{name: "region", geo: &tokentypes.GeoHint{Country: "US", Region: "US-CA"}},
+		{name: "small city", geo: &tokentypes.GeoHint{Country: "US", Region: "US-CA", City: "SMALL TOWN"}, wantErr: ErrAnonymitySetTooSmall},
+		{name: "no country", geo: &tokentypes.GeoHint{}, wantErr: ErrMissingField},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := c.Check(tc.geo); !errors.Is(err, tc.wantErr) {
+				t.Errorf("Check() returned error: %v, want error: %v", err, tc.wantErr)
+			}
+		})
+	}
+	if err := c.Check(&tokentypes.GeoHint{Country: "FR"}); err == nil {
+		t.Error("Check() with a failing provider succeeded, want error")
+	}
+
+	bs := New(&NewBinaryFields{Version: 2, Country: "US", Region: "US-CA", City: "SMALL TOWN"})
+	defer bs.Free()
+	if err := c.CheckStruct(bs); !errors.Is(err, ErrAnonymitySetTooSmall) {
+		t.Errorf("CheckStruct() returned error: %v, want error: %v", err, ErrAnonymitySetTooSmall)
+	}
+}
+
+func TestNewAnonymitySetCheckerRejectsBadConfig(t *testing.T) {
+	if _, err := NewAnonymitySetChecker(nil, 10); err == nil {
+		t.Error("NewAnonymitySetChecker(nil) succeeded, want error")
+	}
+	if _, err := NewAnonymitySetChecker(fakePopulation{}, 0); err == nil {
+		t.Error("NewAnonymitySetChecker(0) succeeded, want error")
+	}
+}
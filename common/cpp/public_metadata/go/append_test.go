@@ -0,0 +1,52 @@
+package binarymetadata
+
+import (
+	"bytes"
+	"testing"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+)
+
+func TestAppendSerialized(t *testing.T) {
+	bs := New(&NewBinaryFields{
+		Version:     1,
+		Country:     "US",
+		ServiceType: ServiceTypeChromeIPBlinding,
+		Expiration:  &tpb.Timestamp{Seconds: 900},
+	})
+	defer bs.Free()
+	for _, withExtra := range []bool{false, true} {
+		if withExtra {
+			if err := bs.SetExtension(0xF0FF, []byte("opaque")); err != nil {
+				t.Fatalf("SetExtension failed: %v", err)
+			}
+		}
+		want, err := Serialize(bs)
+		if err != nil {
+			t.Fatalf("Serialize failed: %v", err)
+		}
+		buf := make([]byte, 0, 256)
+		buf = append(buf, "prefix"...)
+		got, err := AppendSerialized(buf, bs)
+		if err != nil {
+			t.Fatalf("AppendSerialized failed: %v", err)
+		}
+		if !bytes.Equal(got, append([]byte("prefix"), want...)) {
+			t.Errorf("AppendSerialized() = %x, want prefix followed by %x", got, want)
+		}
+		if &got[0] != &buf[:1][0] {
+			t.Error("AppendSerialized() reallocated a buffer with enough capacity")
+		}
+	}
+
+	unset := New(&NewBinaryFields{Version: 1})
+	defer unset.Free()
+	buf := []byte("prefix")
+	got, err := AppendSerialized(buf, unset)
+	if err == nil {
+		t.Fatal("AppendSerialized() of incomplete metadata succeeded, want error")
+	}
+	if string(got) != "prefix" {
+		t.Errorf("AppendSerialized() on error = %q, want dst unchanged", got)
+	}
+}
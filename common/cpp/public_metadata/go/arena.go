@@ -0,0 +1,93 @@
+package binarymetadata
+
+import (
+	"fmt"
+	"sync"
+
+	wrap "google3/privacy/net/common/cpp/public_metadata/go/wrap_public_metadata"
+)
+
+// Arena owns the C++ memory of many BinaryStructs and deletes it all in a single call when
+// released, for request-scoped work creating dozens of structs that would otherwise pay for one
+// delete each. Structs may still be freed one by one, which then only marks them freed. An Arena is
+// safe for concurrent use.
+type Arena struct {
+	mu      sync.Mutex
+	arena   wrap.MetadataArena
+	structs []*BinaryStruct
+}
+
+// NewArena returns an empty Arena. Release it once its structs are no longer used.
+func NewArena() *Arena {
+	return &Arena{arena: wrap.NewMetadataArena()}
+}
+
+// New is like the package level New, but allocates the struct in a. It returns ErrFreed if a has
+// been released.
+func (a *Arena) New(fields *NewBinaryFields) (*BinaryStruct, error) {
+	bs, err := a.newStruct()
+	if err != nil {
+		auditBlob(AuditConstruct, "arena_new", fields.AuditTag, nil, err)
+		return nil, err
+	}
+	setFields(bs.metadata, fields)
+	bs.auditTag = fields.AuditTag
+	bs.audit(AuditConstruct, "arena_new", nil)
+	return bs, nil
+}
+
+// Deserialize is like the package level Deserialize, but allocates the result in a.
+func (a *Arena) Deserialize(in []byte) (*BinaryStruct, error) {
+	return DeserializeOptions{Arena: a}.Deserialize(in)
+}
+
+// Len returns the number of structs allocated in a since it was created, freed ones included.
+func (a *Arena) Len() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.structs)
+}
+
+// Release frees every struct allocated in a, which must not be used afterwards, and then a itself.
+// Calling it again has no effect.
+func (a *Arena) Release() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.arena == nil {
+		return
+	}
+	for _, bs := range a.structs {
+		bs.Free()
+	}
+	wrap.DeleteMetadataArena(a.arena)
+	liveMetadata.Add(-int64(len(a.structs)))
+	a.arena = nil
+	a.structs = nil
+}
+
+// newStruct returns an empty struct allocated in a, or on the C++ heap and tracked for leak
+// detection if a is nil.
+func (a *Arena) newStruct() (*BinaryStruct, error) {
+	if a == nil {
+		bs := &BinaryStruct{metadata: newMetadata()}
+		bs.trackAllocation()
+		return bs, nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.arena == nil {
+		return nil, fmt.Errorf("%w: arena has been released", ErrFreed)
+	}
+	liveMetadata.Add(1)
+	bs := &BinaryStruct{metadata: a.arena.New(), arena: a}
+	a.structs = append(a.structs, bs)
+	return bs, nil
+}
+
+// releaseMetadata deletes m, the C++ struct of bs, unless an Arena owns it. The caller holds the
+// lock.
+func (bs *BinaryStruct) releaseMetadata(m wrap.BinaryPublicMetadata) {
+	if bs.arena == nil {
+		deleteMetadata(m)
+	}
+}
@@ -0,0 +1,59 @@
+package binarymetadata
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+)
+
+func TestArena(t *testing.T) {
+	runtime.GC()
+	runtime.GC()
+	before := GetLiveStats()
+	fields := &NewBinaryFields{Version: 1, Country: "US", ServiceType: "chromeipblinding", Expiration: &tpb.Timestamp{Seconds: 900}}
+	in := serializeForTest(t, fields)
+
+	a := NewArena()
+	created, err := a.New(fields)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	parsed, err := a.Deserialize(in)
+	if err != nil {
+		t.Fatalf("Deserialize failed: %v", err)
+	}
+	if !Equal(created, parsed) {
+		t.Errorf("Deserialize() differs from New(): %v", Diff(created, parsed))
+	}
+	freed, err := a.New(fields)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	freed.Free()
+	// Unmarshaling moves created onto the C++ heap, which Release must free too.
+	if err := created.UnmarshalBinary(in); err != nil {
+		t.Fatalf("UnmarshalBinary failed: %v", err)
+	}
+	if _, err := a.Deserialize([]byte{0}); err == nil {
+		t.Error("Deserialize() succeeded, want error")
+	}
+	if got := a.Len(); got != 4 {
+		t.Errorf("Len() = %d, want 4", got)
+	}
+
+	a.Release()
+	a.Release()
+	for _, bs := range []*BinaryStruct{created, parsed, freed} {
+		if _, err := Serialize(bs); !errors.Is(err, ErrFreed) {
+			t.Errorf("Serialize() after Release returned error: %v, want error: %v", err, ErrFreed)
+		}
+	}
+	if got := GetLiveStats(); got != before {
+		t.Errorf("GetLiveStats() = %+v after Release, want %+v", got, before)
+	}
+	if _, err := a.New(fields); !errors.Is(err, ErrFreed) {
+		t.Errorf("New() after Release returned error: %v, want error: %v", err, ErrFreed)
+	}
+}
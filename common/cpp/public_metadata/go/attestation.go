@@ -0,0 +1,112 @@
+package binarymetadata
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// AttestationLevel is how strongly the device of a client was attested at issuance, as carried by
+// the attestation level extension.
+type AttestationLevel uint8
+
+// Attestation levels from weakest to strongest, equal to their wire values.
+const (
+	AttestationNone   AttestationLevel = 0x00
+	AttestationBasic  AttestationLevel = 0x01
+	AttestationStrong AttestationLevel = 0x02
+)
+
+func (l AttestationLevel) String() string {
+	return coarseName(ExtensionTypeAttestationLevel, uint8(l))
+}
+
+var (
+	attestationMu sync.RWMutex
+	// attestationLevels holds the levels added with RegisterAttestationLevels by service type.
+	attestationLevels = map[string][]AttestationLevel{}
+)
+
+// RegisterAttestationLevels restricts the attestation levels metadata of serviceType may carry to
+// levels. Service types without registered levels accept every level. The service type must be
+// known, and registering it twice is an error. It is meant to be called from init functions.
+func RegisterAttestationLevels(serviceType string, levels ...AttestationLevel) error {
+	if err := checkServiceType(serviceType); err != nil {
+		return err
+	}
+	if len(levels) == 0 {
+		return fmt.Errorf("%w: no attestation levels for %q", ErrInvalidAttestationLevel, serviceType)
+	}
+	for _, l := range levels {
+		if _, err := encodeCoarse(ExtensionTypeAttestationLevel, uint8(l)); err != nil {
+			return err
+		}
+	}
+	attestationMu.Lock()
+	defer attestationMu.Unlock()
+	if _, ok := attestationLevels[serviceType]; ok {
+		return fmt.Errorf("attestation levels of service type %q are already registered", serviceType)
+	}
+	levels = slices.Clone(levels)
+	slices.Sort(levels)
+	attestationLevels[serviceType] = slices.Compact(levels)
+	return nil
+}
+
+// AllowedAttestationLevels returns the attestation levels metadata of serviceType may carry, in
+// ascending order.
+func AllowedAttestationLevels(serviceType string) []AttestationLevel {
+	attestationMu.RLock()
+	defer attestationMu.RUnlock()
+	if levels, ok := attestationLevels[serviceType]; ok {
+		return slices.Clone(levels)
+	}
+	return []AttestationLevel{AttestationNone, AttestationBasic, AttestationStrong}
+}
+
+// checkAttestationLevel returns an error wrapping ErrInvalidAttestationLevel if metadata of
+// serviceType may not carry l.
+func checkAttestationLevel(serviceType string, l AttestationLevel) error {
+	if !slices.Contains(AllowedAttestationLevels(serviceType), l) {
+		return fmt.Errorf("%w: %v is not allowed for service type %q", ErrInvalidAttestationLevel, l, serviceType)
+	}
+	return nil
+}
+
+// GetAttestationLevel returns the attestation level and whether it is set to a valid value.
+func (bs *BinaryStruct) GetAttestationLevel() (AttestationLevel, bool) {
+	v, ok := bs.getCoarse(ExtensionTypeAttestationLevel)
+	return AttestationLevel(v), ok
+}
+
+// SetAttestationLevel sets the attestation level. It fails with ErrInvalidAttestationLevel for
+// values other than the AttestationLevel constants, for levels not allowed for the service type of
+// bs, and for versions without the AttestationLevel capability. Set the service type first.
+func (bs *BinaryStruct) SetAttestationLevel(l AttestationLevel) error {
+	err := bs.setAttestationLevel(l)
+	bs.audit(AuditMutate, "set_attestation_level", err)
+	return err
+}
+
+// setAttestationLevel checks l against the service type and sets it under a single write lock, so
+// that the service type cannot change in between.
+func (bs *BinaryStruct) setAttestationLevel(l AttestationLevel) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if err := bs.checkMutable(); err != nil {
+		return err
+	}
+	if err := checkAttestationLevel(bs.serviceType(), l); err != nil {
+		return err
+	}
+	e, err := encodeCoarse(ExtensionTypeAttestationLevel, uint8(l))
+	if err != nil {
+		return err
+	}
+	return bs.storeCoarse(e)
+}
+
+// ClearAttestationLevel removes the attestation level.
+func (bs *BinaryStruct) ClearAttestationLevel() {
+	bs.clearCoarse(ExtensionTypeAttestationLevel)
+}
@@ -0,0 +1,102 @@
+package binarymetadata
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"google3/third_party/golang/cmp/cmp"
+)
+
+// registerAttestationLevelsForTest is RegisterAttestationLevels, undone when t finishes.
+func registerAttestationLevelsForTest(t *testing.T, serviceType string, levels ...AttestationLevel) {
+	t.Helper()
+	if err := RegisterAttestationLevels(serviceType, levels...); err != nil {
+		t.Fatalf("RegisterAttestationLevels(%q) failed: %v", serviceType, err)
+	}
+	t.Cleanup(func() {
+		attestationMu.Lock()
+		defer attestationMu.Unlock()
+		delete(attestationLevels, serviceType)
+	})
+}
+
+func TestRegisterAttestationLevels(t *testing.T) {
+	registerAttestationLevelsForTest(t, ServiceTypeChromeIPBlinding, AttestationStrong, AttestationBasic, AttestationStrong)
+	if diff := cmp.Diff([]AttestationLevel{AttestationBasic, AttestationStrong}, AllowedAttestationLevels(ServiceTypeChromeIPBlinding)); diff != "" {
+		t.Errorf("AllowedAttestationLevels() mismatch (-want +got):\n%s", diff)
+	}
+	if got := AllowedAttestationLevels(ServiceTypeCronet); len(got) != 3 {
+		t.Errorf("AllowedAttestationLevels(unregistered) = %v, want every level", got)
+	}
+	for _, tc := range []struct {
+		name        string
+		serviceType string
+		levels      []AttestationLevel
+	}{
+		{name: "duplicate", serviceType: ServiceTypeChromeIPBlinding, levels: []AttestationLevel{AttestationNone}},
+		{name: "unknown service type", serviceType: "nosuchservice", levels: []AttestationLevel{AttestationNone}},
+		{name: "no levels", serviceType: ServiceTypeCronet},
+		{name: "invalid level", serviceType: ServiceTypeCronet, levels: []AttestationLevel{3}},
+	} {
+		if err := RegisterAttestationLevels(tc.serviceType, tc.levels...); err == nil {
+			t.Errorf("%s: RegisterAttestationLevels() succeeded, want error", tc.name)
+		}
+	}
+}
+
+func TestAttestationLevelRoundTrip(t *testing.T) {
+	for _, want := range []AttestationLevel{AttestationNone, AttestationBasic, AttestationStrong} {
+		bs := newTestStruct(t, 3)
+		if err := bs.SetAttestationLevel(want); err != nil {
+			t.Fatalf("SetAttestationLevel(%v) failed: %v", want, err)
+		}
+		out, err := Serialize(bs)
+		if err != nil {
+			t.Fatalf("Serialize failed: %v", err)
+		}
+		got, err := DeserializeOptions{Strict: true, Canonical: true}.Deserialize(out)
+		if err != nil {
+			t.Fatalf("Deserialize failed: %v", err)
+		}
+		defer got.Free()
+		if got.GetVersion() != 3 {
+			t.Errorf("GetVersion() after round trip = %d, want 3", got.GetVersion())
+		}
+		if l, ok := got.GetAttestationLevel(); !ok || l != want {
+			t.Errorf("GetAttestationLevel() = %v, %t, want %v, true", l, ok, want)
+		}
+	}
+}
+
+func TestAttestationLevelServiceTypeRules(t *testing.T) {
+	registerAttestationLevelsForTest(t, ServiceTypeChromeIPBlinding, AttestationStrong)
+	bs := newTestStruct(t, 3)
+	if err := bs.SetAttestationLevel(AttestationBasic); !errors.Is(err, ErrInvalidAttestationLevel) {
+		t.Errorf("SetAttestationLevel(BASIC) returned error: %v, want error: %v", err, ErrInvalidAttestationLevel)
+	}
+	if err := bs.SetAttestationLevel(AttestationStrong); err != nil {
+		t.Fatalf("SetAttestationLevel(STRONG) failed: %v", err)
+	}
+	if err := defaultValidator.ValidateStruct(bs, time.Unix(0, 0)); err != nil {
+		t.Errorf("ValidateStruct(STRONG) failed: %v", err)
+	}
+
+	// Metadata from an issuer that skipped the check is rejected on validation.
+	if err := bs.SetExtension(ExtensionTypeAttestationLevel, []byte{byte(AttestationNone)}); err != nil {
+		t.Fatalf("SetExtension failed: %v", err)
+	}
+	err := defaultValidator.ValidateStruct(bs, time.Unix(0, 0))
+	var fe *FieldError
+	if !errors.As(err, &fe) || fe.Field != "attestation_level" || !errors.Is(err, ErrInvalidAttestationLevel) {
+		t.Errorf("ValidateStruct(NONE) returned error: %v, want attestation_level error wrapping %v", err, ErrInvalidAttestationLevel)
+	}
+}
+
+func TestAttestationLevelRejectsOlderVersions(t *testing.T) {
+	bs := New(&NewBinaryFields{Version: 2, Country: "US", ServiceType: "chromeipblinding"})
+	defer bs.Free()
+	if err := bs.SetAttestationLevel(AttestationBasic); !errors.Is(err, ErrInvalidAttestationLevel) {
+		t.Errorf("SetAttestationLevel() returned error: %v, want error: %v", err, ErrInvalidAttestationLevel)
+	}
+}
@@ -0,0 +1,132 @@
+package binarymetadata
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"sync/atomic"
+)
+
+// AuditAction is the kind of event an AuditSink receives.
+type AuditAction string
+
+// Audited actions.
+const (
+	// AuditConstruct is recorded when a BinaryStruct is created by New, NewChecked, Acquire,
+	// Deserialize or Freeze.
+	AuditConstruct AuditAction = "construct"
+	// AuditMutate is recorded when a setter, Reset, Refill or an Unmarshal method is called.
+	AuditMutate AuditAction = "mutate"
+	// AuditValidate is recorded for every validation decision of a Validator and of
+	// ValidateMetadataCardinality.
+	AuditValidate AuditAction = "validate"
+)
+
+// AuditEvent describes one audited call.
+type AuditEvent struct {
+	Action AuditAction
+	// Operation names the call in lower snake case, e.g. "new", "set_service_type" or "validate".
+	Operation string
+	// Tag is the AuditTag of the fields, options or ValidationConfig involved in the call, which
+	// identifies the caller. Metadata derived from tagged metadata, e.g. by Migrate, keeps its tag.
+	Tag string
+	// Fingerprint is the KeyedFingerprint of the metadata after the call under the key of an
+	// AuditFingerprintKeyer sink. It is zero if the sink has no key or the metadata cannot be
+	// serialized.
+	Fingerprint [32]byte
+	// Metadata is the metadata after the call as String formats it without debug mode, that is with
+	// the geo hint truncated to the country, the expiration bucketed and the service tier withheld.
+	// It is empty for freed metadata and for ValidateMetadataCardinality.
+	Metadata string
+	// Err is the error the call returned. For AuditValidate events nil means the metadata was
+	// accepted.
+	Err error
+}
+
+// AuditSink receives an AuditEvent for every audited call. Implementations must be safe for
+// concurrent use and should return quickly, since they run inline with the call.
+type AuditSink interface {
+	Audit(e AuditEvent)
+}
+
+// AuditFingerprintKeyer is implemented by AuditSinks that want AuditEvent.Fingerprint set. The
+// key should be a secret of at least 32 random bytes, see KeyedFingerprint; an unkeyed hash would
+// reveal the fields redacted from AuditEvent.Metadata to anyone who hashes candidates.
+type AuditFingerprintKeyer interface {
+	AuditFingerprintKey() []byte
+}
+
+// auditHolder wraps an AuditSink so that it can be stored in an atomic.Pointer.
+type auditHolder struct {
+	s AuditSink
+	// key is the fingerprint key of s, or nil if it has none.
+	key []byte
+}
+
+// fingerprint returns the HMAC-SHA256 of in under the key of h.
+func (h *auditHolder) fingerprint(in []byte) [32]byte {
+	mac := hmac.New(sha256.New, h.key)
+	mac.Write(in)
+	var fp [32]byte
+	mac.Sum(fp[:0])
+	return fp
+}
+
+var registeredAudit atomic.Pointer[auditHolder]
+
+// SetAuditSink registers s to receive the audit events of this package, replacing any earlier
+// registration. It is meant to be called once during process start up; nil disables auditing,
+// which is the default. The fingerprint key of an AuditFingerprintKeyer is read once, here.
+func SetAuditSink(s AuditSink) {
+	if s == nil {
+		registeredAudit.Store(nil)
+		return
+	}
+	h := &auditHolder{s: s}
+	if k, ok := s.(AuditFingerprintKeyer); ok {
+		h.key = bytes.Clone(k.AuditFingerprintKey())
+	}
+	registeredAudit.Store(h)
+}
+
+// audit records op on bs, which must not be locked by the caller.
+func (bs *BinaryStruct) audit(action AuditAction, op string, err error) {
+	bs.auditTagged(action, op, "", err)
+}
+
+// auditTagged is audit with a tag that takes precedence over the one of bs if set.
+func (bs *BinaryStruct) auditTagged(action AuditAction, op, tag string, err error) {
+	h := registeredAudit.Load()
+	if h == nil {
+		return
+	}
+	e := AuditEvent{Action: action, Operation: op, Tag: tag, Err: err}
+	bs.mu.RLock()
+	if e.Tag == "" {
+		e.Tag = bs.auditTag
+	}
+	if bs.metadata != nil {
+		e.Metadata = bs.redactedString()
+	}
+	bs.mu.RUnlock()
+	if h.key != nil {
+		if out, err := appendSerialized(nil, bs); err == nil {
+			e.Fingerprint = h.fingerprint(out)
+		}
+	}
+	h.s.Audit(e)
+}
+
+// auditBlob records op on serialized metadata, fingerprinting in as is. A nil in, for calls that
+// failed before there was any metadata, leaves the fingerprint zero.
+func auditBlob(action AuditAction, op, tag string, in []byte, err error) {
+	h := registeredAudit.Load()
+	if h == nil {
+		return
+	}
+	e := AuditEvent{Action: action, Operation: op, Tag: tag, Err: err}
+	if in != nil && h.key != nil {
+		e.Fingerprint = h.fingerprint(in)
+	}
+	h.s.Audit(e)
+}
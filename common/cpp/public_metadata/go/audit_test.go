@@ -0,0 +1,156 @@
+package binarymetadata
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google3/third_party/golang/cmp/cmp"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	plpb "google3/privacy/net/common/proto/proxy_layer_go_proto"
+)
+
+type fakeAuditSink struct {
+	mu     sync.Mutex
+	events []AuditEvent
+}
+
+func (f *fakeAuditSink) Audit(e AuditEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, e)
+}
+
+func (f *fakeAuditSink) operations() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var ops []string
+	for _, e := range f.events {
+		ops = append(ops, string(e.Action)+" "+e.Operation+" "+e.Tag)
+	}
+	return ops
+}
+
+// keyedAuditSink is a fakeAuditSink with a fingerprint key.
+type keyedAuditSink struct {
+	fakeAuditSink
+	key []byte
+}
+
+func (k *keyedAuditSink) AuditFingerprintKey() []byte {
+	return k.key
+}
+
+func TestAudit(t *testing.T) {
+	sink := &keyedAuditSink{key: []byte("0123456789abcdef0123456789abcdef")}
+	SetAuditSink(sink)
+	defer SetAuditSink(nil)
+
+	bs := New(&NewBinaryFields{Version: 2, Country: "US", Region: "US-CA", City: "SUNNYVALE", ServiceType: "chromeipblinding", Expiration: &tpb.Timestamp{Seconds: 900}, ProxyLayer: plpb.ProxyLayer_PROXY_A, AuditTag: "issuer"})
+	defer bs.Free()
+	bs.SetServiceType("cronet")
+	if err := bs.SetProxyLayer(plpb.ProxyLayer_PROXY_LAYER_UNSPECIFIED); err == nil {
+		t.Fatal("SetProxyLayer(UNSPECIFIED) succeeded, want error")
+	}
+	bs.SetServiceType("chromeipblinding")
+	v, err := NewValidator(ValidationConfig{AuditTag: "redeemer"})
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+	if err := v.ValidateStruct(bs, time.Unix(0, 0)); err != nil {
+		t.Fatalf("ValidateStruct failed: %v", err)
+	}
+	if err := v.ValidateStruct(bs, time.Unix(3600, 0)); err == nil {
+		t.Fatal("ValidateStruct(after expiration) succeeded, want error")
+	}
+	out, err := Serialize(bs)
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	parsed, err := DeserializeOptions{AuditTag: "proxy"}.Deserialize(out)
+	if err != nil {
+		t.Fatalf("Deserialize failed: %v", err)
+	}
+	defer parsed.Free()
+
+	want := []string{
+		"construct new issuer",
+		"mutate set_service_type issuer",
+		"mutate set_proxy_layer issuer",
+		"mutate set_service_type issuer",
+		"validate validate redeemer",
+		"validate validate redeemer",
+		"construct deserialize proxy",
+	}
+	if diff := cmp.Diff(want, sink.operations()); diff != "" {
+		t.Errorf("audited operations mismatch (-want +got):\n%s", diff)
+	}
+
+	fp, err := bs.KeyedFingerprint(sink.key)
+	if err != nil {
+		t.Fatalf("KeyedFingerprint failed: %v", err)
+	}
+	for i, e := range sink.events {
+		if strings.Contains(e.Metadata, "SUNNYVALE") || strings.Contains(e.Metadata, "US-CA") {
+			t.Errorf("event %d: Metadata = %q, want the geo hint redacted", i, e.Metadata)
+		}
+		if i >= 3 && e.Fingerprint != fp {
+			t.Errorf("event %d: Fingerprint = %x, want %x", i, e.Fingerprint, fp)
+		}
+	}
+	if err := sink.events[5].Err; !errors.Is(err, ErrExpired) {
+		t.Errorf("rejected validation event has error: %v, want error: %v", err, ErrExpired)
+	}
+	if err := sink.events[2].Err; !errors.Is(err, ErrInvalidProxyLayer) {
+		t.Errorf("failed mutation event has error: %v, want error: %v", err, ErrInvalidProxyLayer)
+	}
+
+	// Freed metadata is still audited, without its contents.
+	parsed.Free()
+	parsed.SetServiceType("cronet")
+	if e := sink.events[len(sink.events)-1]; e.Metadata != "" || e.Fingerprint != [32]byte{} {
+		t.Errorf("event for freed metadata = %+v, want no metadata or fingerprint", e)
+	}
+
+	// So is a construction that failed before there was any metadata.
+	a := NewArena()
+	a.Release()
+	if _, err := a.New(&NewBinaryFields{Version: 1, Country: "US", ServiceType: "chromeipblinding"}); err == nil {
+		t.Fatal("New on a released arena succeeded, want error")
+	}
+	if e := sink.events[len(sink.events)-1]; e.Operation != "arena_new" || e.Fingerprint != [32]byte{} {
+		t.Errorf("event for a failed construction = %+v, want arena_new without a fingerprint", e)
+	}
+}
+
+func TestAuditUnkeyed(t *testing.T) {
+	sink := &fakeAuditSink{}
+	SetAuditSink(sink)
+	defer SetAuditSink(nil)
+	bs := New(&NewBinaryFields{Version: 1, Country: "US", ServiceType: "chromeipblinding", Expiration: &tpb.Timestamp{Seconds: 900}})
+	defer bs.Free()
+	bs.SetServiceType("cronet")
+	if len(sink.events) != 2 {
+		t.Fatalf("recorded %d events, want 2", len(sink.events))
+	}
+	for i, e := range sink.events {
+		if e.Fingerprint != [32]byte{} {
+			t.Errorf("event %d: Fingerprint = %x, want zero for a sink without a key", i, e.Fingerprint)
+		}
+	}
+}
+
+func TestAuditDisabled(t *testing.T) {
+	sink := &fakeAuditSink{}
+	SetAuditSink(sink)
+	SetAuditSink(nil)
+	bs := New(&NewBinaryFields{Version: 1, Country: "US", ServiceType: "chromeipblinding"})
+	defer bs.Free()
+	bs.SetServiceType("cronet")
+	if len(sink.events) != 0 {
+		t.Errorf("SetAuditSink(nil) left %d events recorded", len(sink.events))
+	}
+}
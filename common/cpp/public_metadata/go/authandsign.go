@@ -0,0 +1,114 @@
+package binarymetadata
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"google3/third_party/golang/protobuf/v2/proto/proto"
+
+	attpb "google3/privacy/net/attestation/proto/attestation_go_proto"
+	aspb "google3/privacy/net/common/proto/auth_and_sign_go_proto"
+	kspb "google3/privacy/net/common/proto/key_services_go_proto"
+)
+
+// AuthAndSignBuilder assembles the AuthAndSignRequest with which the Krypton client has blinded
+// tokens signed with a public metadata key, so that Go load tests exercise the same flow. The
+// first failure is kept and returned by Build; later setters are then no-ops.
+type AuthAndSignBuilder struct {
+	key      *PublicKey
+	metadata *BinaryStruct
+	req      *aspb.AuthAndSignRequest
+	err      error
+}
+
+// NewAuthAndSignBuilder returns a builder for tokens bound to metadata and signed with key,
+// typically the Key and Metadata of a PublicKeyResponse. metadata must stay unfreed until Build.
+func NewAuthAndSignBuilder(key *PublicKey, metadata *BinaryStruct) *AuthAndSignBuilder {
+	return &AuthAndSignBuilder{
+		key:      key,
+		metadata: metadata,
+		req: &aspb.AuthAndSignRequest{
+			KeyType: kspb.KeyType_AT_PUBLIC_METADATA_KEY_TYPE,
+			// The derived exponent replaces the RSA public exponent, as it does for the Krypton client.
+			DoNotUseRsaPublicExponent: true,
+		},
+	}
+}
+
+func (b *AuthAndSignBuilder) fail(field, value string, err error) *AuthAndSignBuilder {
+	if b.err == nil {
+		b.err = &FieldError{Field: field, Value: value, Err: err}
+	}
+	return b
+}
+
+// OAuthToken sets the bearer token the client authenticates with.
+func (b *AuthAndSignBuilder) OAuthToken(token string) *AuthAndSignBuilder {
+	if b.err != nil {
+		return b
+	}
+	if token == "" {
+		return b.fail("oauth_token", token, ErrMissingField)
+	}
+	b.req.OauthToken = token
+	return b
+}
+
+// BlindedTokens adds serialized blinded tokens, which are sent base64 encoded in order. The
+// signatures of the response are in the same order.
+func (b *AuthAndSignBuilder) BlindedTokens(tokens ...[]byte) *AuthAndSignBuilder {
+	if b.err != nil {
+		return b
+	}
+	for i, t := range tokens {
+		if len(t) == 0 {
+			return b.fail("blinded_token", fmt.Sprint(i), ErrMissingField)
+		}
+		b.req.BlindedToken = append(b.req.BlindedToken, base64.StdEncoding.EncodeToString(t))
+	}
+	return b
+}
+
+// Attestation sets the attestation data of the client.
+func (b *AuthAndSignBuilder) Attestation(a *attpb.AttestationData) *AuthAndSignBuilder {
+	b.req.Attestation = a
+	return b
+}
+
+// Build checks the metadata against the key and returns the request. The metadata must have a
+// supported version and an expiration within the validity window of the key, since tokens bound to
+// it would otherwise outlive the key that verifies them.
+func (b *AuthAndSignBuilder) Build() (*aspb.AuthAndSignRequest, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	switch {
+	case b.key == nil:
+		return nil, &FieldError{Field: "key", Err: ErrMissingField}
+	case b.metadata == nil:
+		return nil, &FieldError{Field: "metadata", Err: ErrMissingField}
+	case len(b.req.BlindedToken) == 0:
+		return nil, &FieldError{Field: "blinded_token", Err: ErrMissingField}
+	}
+	exts, err := Serialize(b.metadata)
+	if err != nil {
+		return nil, err
+	}
+	v := b.metadata.GetVersion()
+	if _, err := Capabilities(v); err != nil {
+		return nil, &FieldError{Field: "version", Value: fmt.Sprint(v), Err: err}
+	}
+	if b.metadata.GetExpiration() == nil {
+		return nil, &FieldError{Field: "expiration", Err: ErrMissingField}
+	}
+	exp := b.metadata.GetExpiration().AsTime()
+	if !exp.After(b.key.ValidFrom) || !b.key.ValidUntil.IsZero() && exp.After(b.key.ValidUntil) {
+		return nil, &FieldError{Field: "expiration", Value: exp.String(), Err: fmt.Errorf("%w: key %d is valid from %v until %v", ErrMetadataMismatch, b.key.Version, b.key.ValidFrom, b.key.ValidUntil)}
+	}
+	req := proto.Clone(b.req).(*aspb.AuthAndSignRequest)
+	req.ServiceType = b.metadata.GetServiceType()
+	req.KeyVersion = uint64(b.key.Version)
+	req.PublicMetadataExtensions = exts
+	req.ProxyLayer = b.metadata.GetProxyLayer()
+	return req, nil
+}
@@ -0,0 +1,93 @@
+package binarymetadata
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	aspb "google3/privacy/net/common/proto/auth_and_sign_go_proto"
+	gidpb "google3/privacy/net/common/proto/get_initial_data_go_proto"
+	kspb "google3/privacy/net/common/proto/key_services_go_proto"
+	plpb "google3/privacy/net/common/proto/proxy_layer_go_proto"
+)
+
+// publicKeyForTest returns the parsed response of publicKeyResponseForTest, whose metadata is freed
+// when t finishes.
+func publicKeyForTest(t *testing.T, exp time.Time, mutate func(*gidpb.GetInitialDataResponse)) *PublicKeyResponse {
+	t.Helper()
+	body, _ := publicKeyResponseForTest(t, exp, mutate)
+	resp, err := PublicKeyRequest{ServiceType: ServiceTypeChromeIPBlinding, Version: 2, ProxyLayer: plpb.ProxyLayer_PROXY_B}.ParsePublicKeyResponse(body)
+	if err != nil {
+		t.Fatalf("ParsePublicKeyResponse failed: %v", err)
+	}
+	t.Cleanup(resp.Metadata.Free)
+	return resp
+}
+
+func TestAuthAndSignBuilder(t *testing.T) {
+	exp := time.Unix(1800000000, 0).Truncate(15 * time.Minute)
+	resp := publicKeyForTest(t, exp, nil)
+	req, err := NewAuthAndSignBuilder(resp.Key, resp.Metadata).
+		OAuthToken("token").
+		BlindedTokens([]byte("blinded1"), []byte("blinded2")).
+		Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	want, err := Serialize(resp.Metadata)
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	if string(req.GetPublicMetadataExtensions()) != string(want) {
+		t.Errorf("public_metadata_extensions = %x, want %x", req.GetPublicMetadataExtensions(), want)
+	}
+	if got := req.GetBlindedToken(); len(got) != 2 || got[1] != base64.StdEncoding.EncodeToString([]byte("blinded2")) {
+		t.Errorf("blinded_token = %q, want the base64 of both tokens in order", got)
+	}
+	if req.GetOauthToken() != "token" || req.GetServiceType() != ServiceTypeChromeIPBlinding || req.GetKeyVersion() != 3 ||
+		req.GetKeyType() != kspb.KeyType_AT_PUBLIC_METADATA_KEY_TYPE || !req.GetDoNotUseRsaPublicExponent() || req.GetProxyLayer() != plpb.ProxyLayer_PROXY_B {
+		t.Errorf("Build() = %v, want it to carry the token, service type, key 3 and PROXY_B", req)
+	}
+}
+
+func TestAuthAndSignBuilderErrors(t *testing.T) {
+	exp := time.Unix(1800000000, 0).Truncate(15 * time.Minute)
+	resp := publicKeyForTest(t, exp, nil)
+	for _, tc := range []struct {
+		name  string
+		build func() (*aspb.AuthAndSignRequest, error)
+		want  error
+	}{
+		{name: "no_blinded_tokens", build: NewAuthAndSignBuilder(resp.Key, resp.Metadata).OAuthToken("token").Build, want: ErrMissingField},
+		{name: "empty_blinded_token", build: NewAuthAndSignBuilder(resp.Key, resp.Metadata).BlindedTokens(nil).Build, want: ErrMissingField},
+		{name: "empty_oauth_token", build: NewAuthAndSignBuilder(resp.Key, resp.Metadata).OAuthToken("").BlindedTokens([]byte("b")).Build, want: ErrMissingField},
+		{name: "expires_after_key", build: func() (*aspb.AuthAndSignRequest, error) {
+			short := publicKeyForTest(t, exp, func(r *gidpb.GetInitialDataResponse) {
+				r.AtPublicMetadataPublicKey.ExpirationTime = tpb.New(exp.Add(-time.Hour))
+			})
+			return NewAuthAndSignBuilder(short.Key, short.Metadata).BlindedTokens([]byte("b")).Build()
+		}, want: ErrMetadataMismatch},
+		{name: "expires_before_key", build: func() (*aspb.AuthAndSignRequest, error) {
+			late := publicKeyForTest(t, exp, func(r *gidpb.GetInitialDataResponse) {
+				r.AtPublicMetadataPublicKey.KeyValidityStartTime = tpb.New(exp)
+			})
+			return NewAuthAndSignBuilder(late.Key, late.Metadata).BlindedTokens([]byte("b")).Build()
+		}, want: ErrMetadataMismatch},
+		{name: "freed_metadata", build: func() (*aspb.AuthAndSignRequest, error) {
+			bs, err := resp.Metadata.Freeze()
+			if err != nil {
+				t.Fatalf("Freeze failed: %v", err)
+			}
+			bs.Free()
+			return NewAuthAndSignBuilder(resp.Key, bs).BlindedTokens([]byte("b")).Build()
+		}, want: ErrFreed},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := tc.build(); !errors.Is(err, tc.want) {
+				t.Errorf("Build() = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
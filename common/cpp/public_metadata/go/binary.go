@@ -0,0 +1,42 @@
+package binarymetadata
+
+// MarshalBinary implements encoding.BinaryMarshaler using Serialize.
+func (bs *BinaryStruct) MarshalBinary() ([]byte, error) {
+	return Serialize(bs)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler using Deserialize, replacing the contents
+// of bs. It may be called on a zero BinaryStruct, e.g. one allocated by encoding/gob, which then
+// owns C++ memory and must be freed with Free. bs is left unchanged on error.
+func (bs *BinaryStruct) UnmarshalBinary(data []byte) error {
+	parsed, err := Deserialize(data)
+	if err == nil {
+		err = bs.adopt(parsed)
+	}
+	bs.audit(AuditMutate, "unmarshal_binary", err)
+	return err
+}
+
+// adopt moves the contents of parsed, which must not be used afterwards, into bs and deletes the
+// C++ struct bs held before, unless an Arena owns it. It frees parsed instead and fails with ErrFrozen if bs is frozen.
+func (bs *BinaryStruct) adopt(parsed *BinaryStruct) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if bs.frozen {
+		parsed.Free()
+		return ErrFrozen
+	}
+	old, oldArena := bs.metadata, bs.arena
+	bs.metadata, parsed.metadata = parsed.metadata, nil
+	bs.arena, parsed.arena = parsed.arena, nil
+	bs.extra, parsed.extra = parsed.extra, nil
+	bs.newer, parsed.newer = parsed.newer, false
+	parsed.releaseAllocation()
+	if old != nil && oldArena == nil {
+		deleteMetadata(old)
+	} else {
+		// bs owns C++ memory of its own now, which must be freed.
+		bs.trackAllocation()
+	}
+	return nil
+}
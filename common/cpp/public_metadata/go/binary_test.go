@@ -0,0 +1,55 @@
+package binarymetadata
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/gob"
+	"testing"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	plpb "google3/privacy/net/common/proto/proxy_layer_go_proto"
+)
+
+var (
+	_ encoding.BinaryMarshaler   = (*BinaryStruct)(nil)
+	_ encoding.BinaryUnmarshaler = (*BinaryStruct)(nil)
+)
+
+func TestBinaryMarshalerGob(t *testing.T) {
+	type record struct {
+		ID       int
+		Metadata *BinaryStruct
+	}
+	bs := New(&NewBinaryFields{
+		Version:     2,
+		Country:     "US",
+		Region:      "US-CA",
+		ServiceType: "chromeipblinding",
+		Expiration:  &tpb.Timestamp{Seconds: 900},
+		ProxyLayer:  plpb.ProxyLayer_PROXY_B,
+	})
+	defer bs.Free()
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(record{ID: 7, Metadata: bs}); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	var got record
+	if err := gob.NewDecoder(&buf).Decode(&got); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	defer got.Metadata.Free()
+	if got.ID != 7 || !Equal(bs, got.Metadata) {
+		t.Errorf("gob round trip = %d, %v, want 7 and no diff", got.ID, Diff(bs, got.Metadata))
+	}
+}
+
+func TestUnmarshalBinaryError(t *testing.T) {
+	bs := New(&NewBinaryFields{Version: 1, Country: "US"})
+	defer bs.Free()
+	if err := bs.UnmarshalBinary([]byte("bad")); err == nil {
+		t.Fatal("UnmarshalBinary(bad) succeeded, want error")
+	}
+	if got := bs.GetGeoHint().Country; got != "US" {
+		t.Errorf("UnmarshalBinary(bad) changed country to %q", got)
+	}
+}
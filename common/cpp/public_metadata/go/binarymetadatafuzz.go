@@ -0,0 +1,92 @@
+// Package binarymetadatafuzz contains fuzzing helpers for binary metadata that downstream packages
+// can wire into their own go test fuzz targets, e.g.
+//
+//	func FuzzDeserialize(f *testing.F) {
+//		binarymetadatafuzz.AddSeeds(f)
+//		f.Fuzz(binarymetadatafuzz.FuzzDeserialize)
+//	}
+package binarymetadatafuzz
+
+import (
+	"testing"
+
+	"google3/privacy/net/common/cpp/public_metadata/go/binarymetadata"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	plpb "google3/privacy/net/common/proto/proxy_layer_go_proto"
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+)
+
+// seedFields are serialized by AddSeeds to give the fuzzer well-formed starting points for each
+// metadata version.
+var seedFields = []*binarymetadata.NewBinaryFields{
+	{
+		Version:     1,
+		Country:     "US",
+		ServiceType: "chromeipblinding",
+		Expiration:  &tpb.Timestamp{Seconds: 900},
+	},
+	{
+		Version:     2,
+		Country:     "US",
+		Region:      "US-CA",
+		City:        "SUNNYVALE",
+		ServiceType: "chromeipblinding",
+		Expiration:  &tpb.Timestamp{Seconds: 1701110700},
+		DebugMode:   pmpb.PublicMetadata_DEBUG_ALL,
+		ProxyLayer:  plpb.ProxyLayer_PROXY_B,
+	},
+}
+
+// AddSeeds adds serialized metadata covering every supported version to the corpus of f.
+func AddSeeds(f *testing.F) {
+	f.Helper()
+	for _, fields := range seedFields {
+		bs := binarymetadata.New(fields)
+		out, err := binarymetadata.Serialize(bs)
+		bs.Free()
+		if err != nil {
+			f.Fatalf("Serialize(%+v): %v", fields, err)
+		}
+		f.Add(out)
+	}
+}
+
+// Deserialize is like binarymetadata.Deserialize but frees the result when t finishes, so that fuzz
+// iterations do not leak C++ memory. It returns nil if in does not deserialize.
+func Deserialize(t testing.TB, in []byte) *binarymetadata.BinaryStruct {
+	t.Helper()
+	bs, err := binarymetadata.Deserialize(in)
+	if err != nil {
+		return nil
+	}
+	t.Cleanup(bs.Free)
+	return bs
+}
+
+// CheckRoundTrip fails t unless metadata deserialized from in, serialized again and deserialized
+// once more is equal to the first result. Input that does not deserialize is ignored.
+func CheckRoundTrip(t testing.TB, in []byte) {
+	t.Helper()
+	first := Deserialize(t, in)
+	if first == nil {
+		return
+	}
+	out, err := binarymetadata.Serialize(first)
+	if err != nil {
+		t.Fatalf("Serialize(Deserialize(%x)): %v", in, err)
+	}
+	second := Deserialize(t, out)
+	if second == nil {
+		t.Fatalf("Deserialize(%x) of reserialized %x failed", out, in)
+	}
+	if diff := binarymetadata.Diff(first, second); len(diff) != 0 {
+		t.Errorf("round trip of %x changed fields %v", in, diff)
+	}
+}
+
+// FuzzDeserialize is a fuzz body for use with testing.F.Fuzz. It checks that Deserialize does not
+// crash on arbitrary input and that accepted input survives CheckRoundTrip.
+func FuzzDeserialize(t *testing.T, in []byte) {
+	CheckRoundTrip(t, in)
+}
@@ -0,0 +1,10 @@
+package binarymetadatafuzz
+
+import "testing"
+
+func FuzzRoundTrip(f *testing.F) {
+	AddSeeds(f)
+	f.Add([]byte{})
+	f.Add([]byte{0x00, 0x00})
+	f.Fuzz(FuzzDeserialize)
+}
@@ -0,0 +1,120 @@
+// Package binarymetadatamobile is a facade over binarymetadata that gomobile can bind, so that
+// Android and iOS test harnesses build and inspect metadata with the same code as the servers.
+// Signatures only use types gomobile supports: enums are passed by name, expirations as unix
+// seconds and serialized metadata as bytes.
+package binarymetadatamobile
+
+import (
+	"fmt"
+	"time"
+
+	"google3/privacy/net/common/cpp/public_metadata/go/binarymetadata"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	plpb "google3/privacy/net/common/proto/proxy_layer_go_proto"
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+)
+
+// Metadata is public metadata as plain values.
+type Metadata struct {
+	Version     int32
+	ServiceType string
+	Country     string
+	Region      string
+	City        string
+	// ExpirationEpochSeconds is the expiration in seconds since the unix epoch, or zero if unset.
+	ExpirationEpochSeconds int64
+	// DebugMode is the name of a PublicMetadata.DebugMode value, e.g. "UNSPECIFIED_DEBUG_MODE".
+	// Empty means the default.
+	DebugMode string
+	// ProxyLayer is the name of a ProxyLayer value, e.g. "PROXY_A". Empty means the default.
+	ProxyLayer string
+}
+
+// NewMetadata returns empty version 2 metadata, since gomobile cannot bind struct literals.
+func NewMetadata() *Metadata {
+	return &Metadata{Version: 2}
+}
+
+func (m *Metadata) fields() (*binarymetadata.NewBinaryFields, error) {
+	f := &binarymetadata.NewBinaryFields{
+		Version:     m.Version,
+		ServiceType: m.ServiceType,
+		Country:     m.Country,
+		Region:      m.Region,
+		City:        m.City,
+	}
+	if m.ExpirationEpochSeconds != 0 {
+		f.Expiration = &tpb.Timestamp{Seconds: m.ExpirationEpochSeconds}
+	}
+	if m.DebugMode != "" {
+		v, ok := pmpb.PublicMetadata_DebugMode_value[m.DebugMode]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown debug mode %q", binarymetadata.ErrInvalidDebugMode, m.DebugMode)
+		}
+		f.DebugMode = pmpb.PublicMetadata_DebugMode(v)
+	}
+	if m.ProxyLayer != "" {
+		v, ok := plpb.ProxyLayer_value[m.ProxyLayer]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown proxy layer %q", binarymetadata.ErrInvalidProxyLayer, m.ProxyLayer)
+		}
+		f.ProxyLayer = plpb.ProxyLayer(v)
+	}
+	return f, nil
+}
+
+func fromStruct(bs *binarymetadata.BinaryStruct) *Metadata {
+	m := &Metadata{
+		Version:     bs.GetVersion(),
+		ServiceType: bs.GetServiceType(),
+		DebugMode:   bs.GetDebugMode().String(),
+		ProxyLayer:  bs.GetProxyLayer().String(),
+	}
+	if geo := bs.GetGeoHint(); geo != nil {
+		m.Country, m.Region, m.City = geo.Country, geo.Region, geo.City
+	}
+	if exp := bs.GetExpiration(); exp != nil {
+		m.ExpirationEpochSeconds = exp.GetSeconds()
+	}
+	return m
+}
+
+// Serialize checks m like binarymetadata.NewChecked and serializes it.
+func Serialize(m *Metadata) ([]byte, error) {
+	f, err := m.fields()
+	if err != nil {
+		return nil, err
+	}
+	bs, err := binarymetadata.NewChecked(f)
+	if err != nil {
+		return nil, err
+	}
+	defer bs.Free()
+	return binarymetadata.Serialize(bs)
+}
+
+// Deserialize deserializes in, strictly if strict is set.
+func Deserialize(in []byte, strict bool) (*Metadata, error) {
+	bs, err := binarymetadata.DeserializeOptions{Strict: strict}.Deserialize(in)
+	if err != nil {
+		return nil, err
+	}
+	defer bs.Free()
+	return fromStruct(bs), nil
+}
+
+// Validate checks in with binarymetadata.ValidateMetadataCardinality at nowEpochSeconds.
+func Validate(in []byte, nowEpochSeconds int64) error {
+	return binarymetadata.ValidateMetadataCardinality(in, time.Unix(nowEpochSeconds, 0))
+}
+
+// ErrorKind returns the message of the binarymetadata sentinel that err matches, e.g. "metadata has
+// expired", or "" if it matches none. Bound languages cannot compare errors with errors.Is.
+func ErrorKind(err error) string {
+	k := binarymetadata.ErrorKind(err)
+	if k == nil {
+		return ""
+	}
+	return k.Error()
+}
@@ -0,0 +1,86 @@
+package binarymetadatamobile
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"google3/privacy/net/common/cpp/public_metadata/go/binarymetadata"
+)
+
+func TestSerializeRoundTrip(t *testing.T) {
+	exp := time.Now().Add(time.Hour).Truncate(15 * time.Minute).Unix()
+	m := NewMetadata()
+	m.ServiceType = "chromeipblinding"
+	m.Country = "US"
+	m.Region = "US-CA"
+	m.City = "SUNNYVALE"
+	m.ExpirationEpochSeconds = exp
+	m.ProxyLayer = "PROXY_B"
+	in, err := Serialize(m)
+	if err != nil {
+		t.Fatalf("Serialize() failed: %v", err)
+	}
+	if err := Validate(in, time.Now().Unix()); err != nil {
+		t.Errorf("Validate() failed: %v", err)
+	}
+	got, err := Deserialize(in, true)
+	if err != nil {
+		t.Fatalf("Deserialize() failed: %v", err)
+	}
+	want := *m
+	want.DebugMode = "UNSPECIFIED_DEBUG_MODE"
+	if *got != want {
+		t.Errorf("Deserialize() = %+v, want %+v", *got, want)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		err  func() error
+		want error
+	}{
+		{
+			name: "unknown_debug_mode",
+			err: func() error {
+				m := NewMetadata()
+				m.DebugMode = "DEBUG_SOME"
+				_, err := Serialize(m)
+				return err
+			},
+			want: binarymetadata.ErrInvalidDebugMode,
+		},
+		{
+			name: "unknown_proxy_layer",
+			err: func() error {
+				m := NewMetadata()
+				m.ProxyLayer = "PROXY_Z"
+				_, err := Serialize(m)
+				return err
+			},
+			want: binarymetadata.ErrInvalidProxyLayer,
+		},
+		{
+			name: "malformed",
+			err: func() error {
+				_, err := Deserialize([]byte{0x00}, true)
+				return err
+			},
+			want: binarymetadata.ErrMalformed,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.err()
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("error = %v, want %v", err, tc.want)
+			}
+			if got := ErrorKind(err); got != tc.want.Error() {
+				t.Errorf("ErrorKind() = %q, want %q", got, tc.want.Error())
+			}
+		})
+	}
+	if got := ErrorKind(nil); got != "" {
+		t.Errorf("ErrorKind(nil) = %q, want \"\"", got)
+	}
+}
@@ -0,0 +1,191 @@
+// Package binarymetadatatest contains factories for binary metadata fixtures that pass the same
+// validation rules as production metadata.
+package binarymetadatatest
+
+import (
+	"encoding/base64"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"google3/privacy/net/common/cpp/public_metadata/go/binarymetadata"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	plpb "google3/privacy/net/common/proto/proxy_layer_go_proto"
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+)
+
+// Golden blobs serialized by the C++ library for US,US-NY,NEW YORK CITY, service type
+// chromeipblinding, debug mode off and an expiration of 1701110700 (2023-11-27T18:45:00Z).
+var (
+	// GoldenV1 is version 1 metadata.
+	GoldenV1 = mustDecode("ADoAAQAQAAAAAAAAA4QAAAAAZWTjrAACABgAFlVTLFVTLU5ZLE5FVyBZT1JLIENJVFnwAQABAfACAAEA")
+	// GoldenV2 is version 2 metadata with proxy layer A.
+	GoldenV2 = mustDecode("AD8AAQAQAAAAAAAAA4QAAAAAZWTjrAACABgAFlVTLFVTLU5ZLE5FVyBZT1JLIENJVFnwAQABAfACAAEA8AMAAQA=")
+)
+
+// GoldenExpiration is the expiration of the golden blobs.
+var GoldenExpiration = time.Unix(1701110700, 0)
+
+func mustDecode(s string) []byte {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// Option customizes the fields built by Fields.
+type Option func(*binarymetadata.NewBinaryFields)
+
+// WithVersion sets the metadata version.
+func WithVersion(v int32) Option {
+	return func(f *binarymetadata.NewBinaryFields) { f.Version = v }
+}
+
+// WithGeo sets the country, region and city.
+func WithGeo(country, region, city string) Option {
+	return func(f *binarymetadata.NewBinaryFields) {
+		f.Country = country
+		f.Region = region
+		f.City = city
+	}
+}
+
+// WithServiceType sets the service type.
+func WithServiceType(s string) Option {
+	return func(f *binarymetadata.NewBinaryFields) { f.ServiceType = s }
+}
+
+// WithExpiration sets the expiration to t, which is used as is and not rounded.
+func WithExpiration(t time.Time) Option {
+	return func(f *binarymetadata.NewBinaryFields) { f.Expiration = &tpb.Timestamp{Seconds: t.Unix()} }
+}
+
+// WithClock sets the expiration to the first expiration boundary at least an hour after the time
+// of c. It must follow any WithVersion option.
+func WithClock(c binarymetadata.Clock) Option {
+	return func(f *binarymetadata.NewBinaryFields) {
+		exp, err := binarymetadata.NextExpiration(c, time.Hour, f.Version)
+		if err != nil {
+			panic(err)
+		}
+		f.Expiration = &tpb.Timestamp{Seconds: exp.Unix()}
+	}
+}
+
+// WithDebugMode sets the debug mode.
+func WithDebugMode(m pmpb.PublicMetadata_DebugMode) Option {
+	return func(f *binarymetadata.NewBinaryFields) { f.DebugMode = m }
+}
+
+// WithProxyLayer sets the proxy layer.
+func WithProxyLayer(l plpb.ProxyLayer) Option {
+	return func(f *binarymetadata.NewBinaryFields) { f.ProxyLayer = l }
+}
+
+// Fields returns version 2, country level US metadata for chromeipblinding on proxy layer A that
+// expires on the first expiration boundary at least an hour from now, with opts applied.
+func Fields(opts ...Option) *binarymetadata.NewBinaryFields {
+	f := &binarymetadata.NewBinaryFields{
+		Version:     2,
+		Country:     "US",
+		ServiceType: "chromeipblinding",
+		ProxyLayer:  plpb.ProxyLayer_PROXY_A,
+	}
+	WithClock(binarymetadata.SystemClock)(f)
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// ValidMetadata returns metadata built from Fields(opts...) that is freed when t finishes.
+func ValidMetadata(t testing.TB, opts ...Option) *binarymetadata.BinaryStruct {
+	t.Helper()
+	bs, err := binarymetadata.NewChecked(Fields(opts...))
+	if err != nil {
+		t.Fatalf("NewChecked(): %v", err)
+	}
+	t.Cleanup(bs.Free)
+	return bs
+}
+
+// ExpiredMetadata returns otherwise valid metadata that expired an hour ago.
+func ExpiredMetadata(t testing.TB, opts ...Option) *binarymetadata.BinaryStruct {
+	t.Helper()
+	exp := time.Now().Add(-time.Hour).Truncate(15 * time.Minute)
+	return ValidMetadata(t, append([]Option{WithExpiration(exp)}, opts...)...)
+}
+
+// CityLevelMetadata returns valid metadata with its geo hint set down to the city.
+func CityLevelMetadata(t testing.TB, country, region, city string, opts ...Option) *binarymetadata.BinaryStruct {
+	t.Helper()
+	return ValidMetadata(t, append([]Option{WithGeo(country, region, city)}, opts...)...)
+}
+
+// Serialized returns the serialization of Fields(opts...).
+func Serialized(t testing.TB, opts ...Option) []byte {
+	t.Helper()
+	out, err := binarymetadata.Serialize(ValidMetadata(t, opts...))
+	if err != nil {
+		t.Fatalf("Serialize(): %v", err)
+	}
+	return out
+}
+
+// FakeClock is a binarymetadata.Clock that only moves when told to. It is safe for concurrent use.
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFakeClock returns a FakeClock stopped at now.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now returns the time c is stopped at.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Set stops c at t.
+func (c *FakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+// Advance moves c forward by d.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// CheckNoLeaks turns on binarymetadata leak detection until t finishes, and then fails t for every
+// BinaryStruct allocated in the meantime that the garbage collector reclaimed without a call to
+// Free, reporting where it was allocated. Detection is best effort: a struct still reachable when
+// t finishes, or collected later, is not reported.
+func CheckNoLeaks(t testing.TB) {
+	t.Helper()
+	before := len(binarymetadata.LeakedStacks())
+	was := binarymetadata.SetLeakDetection(true)
+	t.Cleanup(func() {
+		// Cleanups run on their own goroutine after a collection, so give them a few chances.
+		var leaks []string
+		for range 5 {
+			runtime.GC()
+			time.Sleep(time.Millisecond)
+			leaks = binarymetadata.LeakedStacks()[before:]
+		}
+		binarymetadata.SetLeakDetection(was)
+		for _, stack := range leaks {
+			t.Errorf("BinaryStruct collected without Free, allocated at:\n%s", stack)
+		}
+	})
+}
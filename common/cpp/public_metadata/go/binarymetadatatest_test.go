@@ -0,0 +1,95 @@
+package binarymetadatatest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"google3/privacy/net/common/cpp/public_metadata/go/binarymetadata"
+)
+
+func TestSerializedIsValid(t *testing.T) {
+	for _, v := range []int32{1, 2} {
+		in := Serialized(t, WithVersion(v))
+		if err := binarymetadata.ValidateMetadataCardinality(in, time.Now()); err != nil {
+			t.Errorf("ValidateMetadataCardinality(version %d) failed: %v", v, err)
+		}
+	}
+}
+
+func TestExpiredMetadata(t *testing.T) {
+	in, err := binarymetadata.Serialize(ExpiredMetadata(t))
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	if err := binarymetadata.ValidateMetadataCardinality(in, time.Now()); !errors.Is(err, binarymetadata.ErrExpired) {
+		t.Errorf("ValidateMetadataCardinality() returned error: %v, want error: %v", err, binarymetadata.ErrExpired)
+	}
+}
+
+func TestCityLevelMetadata(t *testing.T) {
+	bs := CityLevelMetadata(t, "US", "US-CA", "SUNNYVALE")
+	if geo := bs.GetGeoHint(); geo.Country != "US" || geo.Region != "US-CA" || geo.City != "SUNNYVALE" {
+		t.Errorf("GetGeoHint() = %+v, want US,US-CA,SUNNYVALE", geo)
+	}
+}
+
+func TestGolden(t *testing.T) {
+	for name, in := range map[string][]byte{"v1": GoldenV1, "v2": GoldenV2} {
+		bs, err := binarymetadata.Deserialize(in)
+		if err != nil {
+			t.Fatalf("Deserialize(%s) failed: %v", name, err)
+		}
+		if got := bs.GetExpiration().AsTime(); !got.Equal(GoldenExpiration) {
+			t.Errorf("%s expiration = %v, want %v", name, got, GoldenExpiration)
+		}
+		if err := binarymetadata.ValidateMetadataCardinality(in, GoldenExpiration.Add(-time.Hour)); err != nil {
+			t.Errorf("ValidateMetadataCardinality(%s) failed: %v", name, err)
+		}
+		bs.Free()
+	}
+}
+
+func TestFakeClockBucketEdge(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1800000000, 0).Truncate(15 * time.Minute).Add(-time.Hour))
+	bs := ValidMetadata(t, WithClock(clock))
+	exp := bs.GetExpiration().AsTime()
+	if want := clock.Now().Add(time.Hour); !exp.Equal(want) {
+		t.Fatalf("expiration = %v, want the boundary %v", exp, want)
+	}
+	v, err := binarymetadata.NewValidator(binarymetadata.ValidationConfig{Clock: clock})
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+	in, err := binarymetadata.Serialize(bs)
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	clock.Set(exp.Add(-time.Second))
+	if err := v.Validate(in, v.Now()); err != nil {
+		t.Errorf("Validate a second before the expiration failed: %v", err)
+	}
+	if bs.IsExpiredAt(clock) {
+		t.Error("IsExpiredAt a second before the expiration = true, want false")
+	}
+	clock.Advance(time.Second)
+	if err := v.Validate(in, v.Now()); err != nil {
+		t.Errorf("Validate at the expiration failed: %v", err)
+	}
+	if !bs.IsExpiredAt(clock) {
+		t.Error("IsExpiredAt the expiration = false, want true")
+	}
+	clock.Advance(time.Second)
+	if err := v.Validate(in, v.Now()); !errors.Is(err, binarymetadata.ErrExpired) {
+		t.Errorf("Validate a second after the expiration = %v, want ErrExpired", err)
+	}
+}
+
+func TestCheckNoLeaks(t *testing.T) {
+	CheckNoLeaks(t)
+	bs, err := binarymetadata.NewChecked(Fields())
+	if err != nil {
+		t.Fatalf("NewChecked failed: %v", err)
+	}
+	bs.Free()
+}
@@ -0,0 +1,40 @@
+package binarymetadata
+
+import (
+	"fmt"
+	"time"
+)
+
+// BucketError is the error of the "expiration_bucket" rule of a Validator for an expiration that
+// is not on a bucket boundary. It wraps ErrExpirationNotRounded.
+type BucketError struct {
+	// Bucket is the distance between boundaries.
+	Bucket time.Duration
+	// Offset is how far after multiples of Bucket since the Unix epoch the boundaries lie.
+	Offset time.Duration
+	// Delta is how far the expiration is from the nearest boundary, negative if it is before it.
+	Delta time.Duration
+}
+
+func (e *BucketError) Error() string {
+	if e.Offset == 0 {
+		return fmt.Sprintf("%v: %v off a multiple of %v", ErrExpirationNotRounded, e.Delta, e.Bucket)
+	}
+	return fmt.Sprintf("%v: %v off a multiple of %v offset by %v", ErrExpirationNotRounded, e.Delta, e.Bucket, e.Offset)
+}
+
+func (e *BucketError) Unwrap() error {
+	return ErrExpirationNotRounded
+}
+
+// bucketDelta returns how far exp is from the nearest boundary offset after a multiple of bucket,
+// rounding ties down. bucket must be positive and offset smaller than it.
+func bucketDelta(exp time.Time, bucket, offset time.Duration) time.Duration {
+	b := int64(bucket / time.Millisecond)
+	ms := exp.Unix()*1000 + int64(exp.Nanosecond())/int64(time.Millisecond) - int64(offset/time.Millisecond)
+	d := time.Duration((ms%b+b)%b)*time.Millisecond + time.Duration(exp.Nanosecond())%time.Millisecond
+	if d > bucket/2 {
+		d -= bucket
+	}
+	return d
+}
@@ -0,0 +1,209 @@
+package binarymetadata
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	plpb "google3/privacy/net/common/proto/proxy_layer_go_proto"
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+)
+
+// expirationGranularity is the boundary expirations must be rounded to, matching the
+// timestamp_precision that Serialize writes.
+const expirationGranularity = 15 * time.Minute
+
+// FieldError reports a metadata field that failed validation.
+type FieldError struct {
+	// Field is the name of the offending field, e.g. "country".
+	Field string
+	// Value is the rejected value, formatted for display.
+	Value string
+	// Err describes the rule that was violated.
+	Err error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("invalid %s %q: %v", e.Field, e.Value, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Builder incrementally constructs a BinaryStruct, validating each field as it is set. The first
+// validation failure is kept and returned by Build; later setters are then no-ops.
+type Builder struct {
+	fields              NewBinaryFields
+	err                 error
+	allowUnknownService bool
+}
+
+// NewBuilder returns a Builder for metadata version 2, which carries the proxy layer but none of
+// the extensions of MaxKnownVersion. Version selects another version.
+func NewBuilder() *Builder {
+	return &Builder{fields: NewBinaryFields{Version: 2}}
+}
+
+func (b *Builder) fail(field, value string, err error) *Builder {
+	if b.err == nil {
+		b.err = &FieldError{Field: field, Value: value, Err: err}
+	}
+	return b
+}
+
+// Version sets the metadata version.
+func (b *Builder) Version(v int32) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if v < 1 || v > maxVersion {
+		return b.fail("version", fmt.Sprint(v), ErrUnknownVersion)
+	}
+	b.fields.Version = v
+	return b
+}
+
+// AllowUnknownServiceTypes makes later calls to ServiceType accept names that are neither built
+// in nor registered with RegisterServiceType.
+func (b *Builder) AllowUnknownServiceTypes() *Builder {
+	b.allowUnknownService = true
+	return b
+}
+
+// ServiceType sets the service type, which must be known unless AllowUnknownServiceTypes was
+// called.
+func (b *Builder) ServiceType(s string) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if s == "" {
+		return b.fail("service_type", s, ErrMissingField)
+	}
+	if !b.allowUnknownService {
+		if err := checkServiceType(s); err != nil {
+			return b.fail("service_type", s, err)
+		}
+	}
+	b.fields.ServiceType = s
+	return b
+}
+
+// Country sets the ISO 3166-1 country code. Alpha-3 codes are converted to alpha-2 and the code is
+// stored upper-cased.
+func (b *Builder) Country(c string) *Builder {
+	if b.err != nil {
+		return b
+	}
+	country, err := NormalizeCountry(c)
+	if err != nil {
+		return b.fail("country", c, err)
+	}
+	b.fields.Country = country
+	return b
+}
+
+// Region sets the ISO 3166-2 region code, e.g. "US-CA". It is stored upper-cased.
+func (b *Builder) Region(r string) *Builder {
+	if b.err != nil {
+		return b
+	}
+	country, sub, ok := strings.Cut(r, "-")
+	if !ok || !IsValidCountry(strings.ToUpper(country)) || len(sub) == 0 || len(sub) > 3 || !isAlnum(sub) {
+		return b.fail("region", r, fmt.Errorf("%w: region must be an ISO 3166-2 code", ErrInvalidGeoHint))
+	}
+	b.fields.Region = strings.ToUpper(r)
+	return b
+}
+
+// City sets the city name. It is stored upper-cased.
+func (b *Builder) City(c string) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if c == "" || strings.Contains(c, ",") {
+		return b.fail("city", c, fmt.Errorf("%w: city must be non-empty and must not contain ','", ErrInvalidGeoHint))
+	}
+	b.fields.City = strings.ToUpper(c)
+	return b
+}
+
+// Expiration sets the expiration time, which must fall on a 15 minute boundary.
+func (b *Builder) Expiration(t time.Time) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if t.IsZero() || t.Before(time.Unix(0, 0)) {
+		return b.fail("expiration", t.String(), fmt.Errorf("%w: must be after the unix epoch", ErrInvalidExpiration))
+	}
+	if !t.Truncate(expirationGranularity).Equal(t) {
+		return b.fail("expiration", t.String(), fmt.Errorf("%w: must be a multiple of %v", ErrExpirationNotRounded, expirationGranularity))
+	}
+	b.fields.Expiration = tpb.New(t)
+	return b
+}
+
+// DebugMode sets the debug mode.
+func (b *Builder) DebugMode(m pmpb.PublicMetadata_DebugMode) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if _, ok := pmpb.PublicMetadata_DebugMode_name[int32(m)]; !ok {
+		return b.fail("debug_mode", m.String(), ErrInvalidDebugMode)
+	}
+	b.fields.DebugMode = m
+	return b
+}
+
+// DebugModeCapability passes c on to the registered DebugModeAuthorizer when Build creates
+// DEBUG_ALL metadata.
+func (b *Builder) DebugModeCapability(c *DebugModeCapability) *Builder {
+	b.fields.DebugModeCapability = c
+	return b
+}
+
+// ProxyLayer sets the proxy layer the token is restricted to.
+func (b *Builder) ProxyLayer(l plpb.ProxyLayer) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if _, err := ProxyLayerToWire(l); err != nil {
+		return b.fail("proxy_layer", l.String(), err)
+	}
+	b.fields.ProxyLayer = l
+	return b
+}
+
+// Build checks the fields against each other and returns the new BinaryStruct. The caller must
+// Free it.
+func (b *Builder) Build() (*BinaryStruct, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	f := &b.fields
+	switch {
+	case f.ServiceType == "":
+		return nil, &FieldError{Field: "service_type", Err: ErrMissingField}
+	case f.Expiration == nil:
+		return nil, &FieldError{Field: "expiration", Err: ErrMissingField}
+	case f.Country == "":
+		return nil, &FieldError{Field: "country", Err: ErrMissingField}
+	case f.Region != "" && !strings.HasPrefix(f.Region, f.Country+"-"):
+		return nil, &FieldError{Field: "region", Value: f.Region, Err: fmt.Errorf("%w: region is not in country %s", ErrInvalidGeoHint, f.Country)}
+	case f.City != "" && f.Region == "":
+		return nil, &FieldError{Field: "city", Value: f.City, Err: fmt.Errorf("%w: city requires a region", ErrInvalidGeoHint)}
+	case f.ProxyLayer != plpb.ProxyLayer_PROXY_LAYER_UNSPECIFIED && f.Version < 2:
+		return nil, &FieldError{Field: "proxy_layer", Value: f.ProxyLayer.String(), Err: fmt.Errorf("%w: proxy layer requires version 2 or newer", ErrInvalidProxyLayer)}
+	}
+	return NewChecked(f)
+}
+
+func isAlnum(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
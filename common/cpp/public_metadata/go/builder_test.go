@@ -0,0 +1,113 @@
+package binarymetadata
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	plpb "google3/privacy/net/common/proto/proxy_layer_go_proto"
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+)
+
+func TestBuilder(t *testing.T) {
+	exp := time.Unix(3600, 0)
+	valid := func() *Builder {
+		return NewBuilder().ServiceType("chromeipblinding").Expiration(exp).Country("us")
+	}
+	tests := []struct {
+		name      string
+		b         *Builder
+		wantField string
+	}{
+		{
+			name: "country only",
+			b:    valid(),
+		},
+		{
+			name: "city level",
+			b:    valid().Region("us-ca").City("Sunnyvale").DebugMode(pmpb.PublicMetadata_DEBUG_ALL).ProxyLayer(plpb.ProxyLayer_PROXY_B),
+		},
+		{
+			name: "alpha-3 country",
+			b:    valid().Country("USA"),
+		},
+		{
+			name:      "bad country",
+			b:         valid().Country("XX"),
+			wantField: "country",
+		},
+		{
+			name:      "bad region",
+			b:         valid().Region("California"),
+			wantField: "region",
+		},
+		{
+			name:      "region not in country",
+			b:         valid().Region("CA-ON"),
+			wantField: "region",
+		},
+		{
+			name:      "city without region",
+			b:         valid().City("SUNNYVALE"),
+			wantField: "city",
+		},
+		{
+			name:      "city with comma",
+			b:         valid().Region("US-CA").City("SUNNYVALE,CA"),
+			wantField: "city",
+		},
+		{
+			name:      "unrounded expiration",
+			b:         valid().Expiration(exp.Add(time.Minute)),
+			wantField: "expiration",
+		},
+		{
+			name:      "unknown service type",
+			b:         valid().ServiceType("other"),
+			wantField: "service_type",
+		},
+		{
+			name:      "missing expiration",
+			b:         NewBuilder().ServiceType("chromeipblinding").Country("US"),
+			wantField: "expiration",
+		},
+		{
+			name:      "unspecified proxy layer",
+			b:         valid().ProxyLayer(plpb.ProxyLayer_PROXY_LAYER_UNSPECIFIED),
+			wantField: "proxy_layer",
+		},
+		{
+			name:      "proxy layer on v1",
+			b:         valid().Version(1).ProxyLayer(plpb.ProxyLayer_PROXY_A),
+			wantField: "proxy_layer",
+		},
+		{
+			name:      "first error wins",
+			b:         valid().Country("U").Region("nope"),
+			wantField: "country",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bs, err := tc.b.Build()
+			if tc.wantField == "" {
+				if err != nil {
+					t.Fatalf("Build() failed: %v", err)
+				}
+				defer bs.Free()
+				if _, err := Serialize(bs); err != nil {
+					t.Errorf("Serialize() failed: %v", err)
+				}
+				return
+			}
+			var fe *FieldError
+			if !errors.As(err, &fe) {
+				t.Fatalf("Build() returned error %v, want a *FieldError", err)
+			}
+			if fe.Field != tc.wantField {
+				t.Errorf("Build() rejected field %q, want %q", fe.Field, tc.wantField)
+			}
+		})
+	}
+}
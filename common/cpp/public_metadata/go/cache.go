@@ -0,0 +1,138 @@
+package binarymetadata
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+	"time"
+)
+
+// Cache memoizes Deserialize for identical blobs, e.g. when an issuer reuses one metadata value
+// for a whole key epoch. It holds at most a fixed number of entries, evicting the least recently
+// used, and optionally expires entries after a TTL. A Cache is safe for concurrent use.
+//
+// Cached structs are shared between callers, so they are frozen: their setters fail with ErrFrozen,
+// see Freeze. They must not be freed either. Each Deserialize returns a release function instead;
+// an evicted struct is freed once every caller holding it has released it.
+type Cache struct {
+	mu         sync.Mutex
+	maxEntries int
+	ttl        time.Duration
+	now        func() time.Time
+	lru        *list.List
+	entries    map[[32]byte]*list.Element
+}
+
+type cacheEntry struct {
+	key     [32]byte
+	bs      *BinaryStruct
+	added   time.Time
+	refs    int
+	evicted bool
+}
+
+// NewCache returns a Cache holding at most maxEntries structs, each for at most ttl. A ttl of
+// zero keeps entries until they are evicted for space.
+func NewCache(maxEntries int, ttl time.Duration) *Cache {
+	if maxEntries < 1 {
+		maxEntries = 1
+	}
+	return &Cache{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		now:        time.Now,
+		lru:        list.New(),
+		entries:    map[[32]byte]*list.Element{},
+	}
+}
+
+// Deserialize returns the deserialized metadata for in, from the cache when possible, and a
+// function that must be called exactly once when the caller is done with the struct. Errors are
+// not cached.
+func (c *Cache) Deserialize(in []byte) (*BinaryStruct, func(), error) {
+	key := sha256.Sum256(in)
+	c.mu.Lock()
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*cacheEntry)
+		if c.ttl == 0 || c.now().Sub(e.added) < c.ttl {
+			c.lru.MoveToFront(el)
+			e.refs++
+			c.mu.Unlock()
+			return e.bs, c.releaser(e), nil
+		}
+		c.evict(el)
+	}
+	c.mu.Unlock()
+
+	parsed, err := Deserialize(in)
+	if err != nil {
+		return nil, nil, err
+	}
+	bs, err := parsed.Freeze()
+	parsed.Free()
+	if err != nil {
+		return nil, nil, err
+	}
+	e := &cacheEntry{key: key, bs: bs, refs: 1}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e.added = c.now()
+	if el, ok := c.entries[key]; ok {
+		// Another caller raced us; replace its entry, which stays alive until released.
+		c.evict(el)
+	}
+	c.entries[key] = c.lru.PushFront(e)
+	for c.lru.Len() > c.maxEntries {
+		c.evict(c.lru.Back())
+	}
+	return bs, c.releaser(e), nil
+}
+
+// releaser returns the release function for one reference to e.
+func (c *Cache) releaser(e *cacheEntry) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			e.refs--
+			if e.evicted && e.refs == 0 {
+				e.bs.Free()
+			}
+		})
+	}
+}
+
+// evict removes el from c, freeing its struct unless a caller still holds it. c.mu must be held.
+func (c *Cache) evict(el *list.Element) {
+	e := el.Value.(*cacheEntry)
+	c.lru.Remove(el)
+	delete(c.entries, e.key)
+	e.evicted = true
+	if e.refs == 0 {
+		e.bs.Free()
+	}
+}
+
+// SetClock makes c measure the TTL of entries with clk instead of SystemClock.
+func (c *Cache) SetClock(clk Clock) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = clk.Now
+}
+
+// Len returns the number of cached entries.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Purge evicts every entry.
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.lru.Len() > 0 {
+		c.evict(c.lru.Back())
+	}
+}
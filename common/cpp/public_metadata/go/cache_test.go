@@ -0,0 +1,133 @@
+package binarymetadata
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"google3/privacy/net/boq/common/tokens/tokentypes"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+)
+
+func cacheTestBlob(t *testing.T, country string) []byte {
+	t.Helper()
+	return serializeForTest(t, &NewBinaryFields{
+		Version:     1,
+		Country:     country,
+		ServiceType: "chromeipblinding",
+		Expiration:  &tpb.Timestamp{Seconds: 900},
+	})
+}
+
+func TestCacheHitAndEviction(t *testing.T) {
+	c := NewCache(2, 0)
+	us, ca, mx := cacheTestBlob(t, "US"), cacheTestBlob(t, "CA"), cacheTestBlob(t, "MX")
+
+	first, releaseFirst, err := c.Deserialize(us)
+	if err != nil {
+		t.Fatalf("Deserialize failed: %v", err)
+	}
+	second, releaseSecond, err := c.Deserialize(us)
+	if err != nil {
+		t.Fatalf("Deserialize failed: %v", err)
+	}
+	if first != second {
+		t.Error("Deserialize of the same blob returned different structs")
+	}
+	releaseSecond()
+
+	for _, in := range [][]byte{ca, mx} {
+		_, release, err := c.Deserialize(in)
+		if err != nil {
+			t.Fatalf("Deserialize failed: %v", err)
+		}
+		release()
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", c.Len())
+	}
+	// US was evicted but is still held, so it must not have been freed.
+	if got := first.GetGeoHint().Country; got != "US" {
+		t.Errorf("evicted held struct has country %q, want US", got)
+	}
+	releaseFirst()
+	if first.metadata != nil {
+		t.Error("evicted struct was not freed after its last release")
+	}
+	c.Purge()
+	if c.Len() != 0 {
+		t.Errorf("Len() after Purge() = %d, want 0", c.Len())
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	c := NewCache(4, time.Minute)
+	now := time.Unix(1000, 0)
+	c.now = func() time.Time { return now }
+	in := cacheTestBlob(t, "US")
+	first, release, err := c.Deserialize(in)
+	if err != nil {
+		t.Fatalf("Deserialize failed: %v", err)
+	}
+	release()
+	now = now.Add(2 * time.Minute)
+	second, release, err := c.Deserialize(in)
+	if err != nil {
+		t.Fatalf("Deserialize failed: %v", err)
+	}
+	defer release()
+	if first == second {
+		t.Error("Deserialize returned an expired entry")
+	}
+}
+
+func TestCacheConcurrent(t *testing.T) {
+	c := NewCache(2, 0)
+	blobs := [][]byte{cacheTestBlob(t, "US"), cacheTestBlob(t, "CA"), cacheTestBlob(t, "MX")}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				bs, release, err := c.Deserialize(blobs[(i+j)%len(blobs)])
+				if err != nil {
+					t.Errorf("Deserialize failed: %v", err)
+					return
+				}
+				_ = bs.GetGeoHint()
+				release()
+			}
+		}(i)
+	}
+	wg.Wait()
+	c.Purge()
+}
+
+func TestCacheReturnsFrozen(t *testing.T) {
+	c := NewCache(1, 0)
+	in := cacheTestBlob(t, "US")
+	bs, release, err := c.Deserialize(in)
+	if err != nil {
+		t.Fatalf("Deserialize failed: %v", err)
+	}
+	defer release()
+	if !bs.IsFrozen() {
+		t.Error("Deserialize returned a struct that is not frozen")
+	}
+	if err := bs.SetDebugMode(pmpb.PublicMetadata_DEBUG_ALL); !errors.Is(err, ErrFrozen) {
+		t.Errorf("SetDebugMode() on a cached struct returned error: %v, want error: %v", err, ErrFrozen)
+	}
+	bs.SetGeoHint(&tokentypes.GeoHint{Country: "CA"})
+	again, releaseAgain, err := c.Deserialize(in)
+	if err != nil {
+		t.Fatalf("Deserialize failed: %v", err)
+	}
+	defer releaseAgain()
+	if got := again.GetGeoHint().Country; got != "US" {
+		t.Errorf("cached struct has country %q, want US", got)
+	}
+}
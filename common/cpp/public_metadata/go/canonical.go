@@ -0,0 +1,116 @@
+package binarymetadata
+
+import "bytes"
+
+// IsCanonical reports whether in is public metadata in the canonical encoding, the one Serialize
+// produces: a well-formed extensions list with no trailing bytes, extension types in strictly
+// increasing order, and every known extension holding a valid value in its only encoding, with an
+// upper-case geo hint and no version extension for a version the proxy layer extension already
+// implies. Values of unknown extension types are opaque and not checked.
+//
+// Two canonical blobs are equal if and only if they encode the same metadata, so canonical blobs
+// can be compared and hashed byte for byte.
+func IsCanonical(in []byte) bool {
+	exts, err := DecodeExtensions(in)
+	if err != nil {
+		return false
+	}
+	if checkExtensionOrder(exts) != nil {
+		return false
+	}
+	hasProxyLayer := false
+	for _, e := range exts {
+		if !isCanonicalValue(e) {
+			return false
+		}
+		hasProxyLayer = hasProxyLayer || e.Type == ExtensionTypeProxyLayer
+	}
+	for _, e := range exts {
+		// Serialize leaves out the version extension unless the version exceeds the implied one.
+		if e.Type == ExtensionTypeVersion && int32(e.Value[0]) <= impliedVersion(hasProxyLayer) {
+			return false
+		}
+	}
+	return true
+}
+
+// isCanonicalValue reports whether a known extension decodes and encodes back to the same bytes.
+func isCanonicalValue(e Extension) bool {
+	var (
+		out Extension
+		err error
+	)
+	switch e.Type {
+	case ExtensionTypeExpirationTimestamp:
+		var exp ExpirationExtension
+		if exp, err = ExpirationExtensionFromExtension(e); err == nil {
+			out, err = exp.AsExtension()
+		}
+	case ExtensionTypeGeoHint:
+		var geo GeoHintExtension
+		if geo, err = GeoHintExtensionFromExtension(e); err == nil {
+			if s := string(e.Value); s != asciiToUpper(s) {
+				return false
+			}
+			out, err = geo.AsExtension()
+		}
+	case ExtensionTypeServiceType:
+		var st ServiceTypeExtension
+		if st, err = ServiceTypeExtensionFromExtension(e); err == nil {
+			out, err = st.AsExtension()
+		}
+	case ExtensionTypeDebugMode:
+		var dm DebugModeExtension
+		if dm, err = DebugModeExtensionFromExtension(e); err == nil {
+			out, err = dm.AsExtension()
+		}
+	case ExtensionTypeProxyLayer:
+		var pl ProxyLayerExtension
+		if pl, err = ProxyLayerExtensionFromExtension(e); err == nil {
+			out, err = pl.AsExtension()
+		}
+	case ExtensionTypeExpirationMillis:
+		var m ExpirationMillisExtension
+		if m, err = ExpirationMillisExtensionFromExtension(e); err == nil {
+			out, err = m.AsExtension()
+		}
+	case ExtensionTypeNetworkType:
+		var nt NetworkTypeExtension
+		if nt, err = NetworkTypeExtensionFromExtension(e); err == nil {
+			out, err = nt.AsExtension()
+		}
+	case ExtensionTypeClientPlatform:
+		var p ClientPlatformExtension
+		if p, err = ClientPlatformExtensionFromExtension(e); err == nil {
+			out, err = p.AsExtension()
+		}
+	case ExtensionTypeServiceTier:
+		var st ServiceTierExtension
+		if st, err = ServiceTierExtensionFromExtension(e); err == nil {
+			out, err = st.AsExtension()
+		}
+	case ExtensionTypeAttestationLevel:
+		var a AttestationLevelExtension
+		if a, err = AttestationLevelExtensionFromExtension(e); err == nil {
+			out, err = a.AsExtension()
+		}
+	case ExtensionTypeKeyEpoch:
+		var k KeyEpochExtension
+		if k, err = KeyEpochExtensionFromExtension(e); err == nil {
+			out, err = k.AsExtension()
+		}
+	case ExtensionTypeNonce:
+		var n NonceExtension
+		if n, err = NonceExtensionFromExtension(e); err == nil {
+			out, err = n.AsExtension()
+		}
+	case ExtensionTypeVersion:
+		var v VersionExtension
+		if v, err = VersionExtensionFromExtension(e); err == nil {
+			out, err = v.AsExtension()
+		}
+	default:
+		return true
+	}
+	return err == nil && bytes.Equal(out.Value, e.Value)
+}
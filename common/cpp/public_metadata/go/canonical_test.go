@@ -0,0 +1,110 @@
+package binarymetadata
+
+import (
+	"errors"
+	"testing"
+)
+
+func canonicalTestExtensions(t *testing.T, geo GeoHintExtension) []Extension {
+	t.Helper()
+	var exts []Extension
+	for _, f := range []func() (Extension, error){
+		ExpirationExtension{TimestampPrecision: 900, Timestamp: 3600}.AsExtension,
+		geo.AsExtension,
+		ServiceTypeExtension{ServiceTypeID: ServiceTypeIDChromeIPBlinding}.AsExtension,
+		DebugModeExtension{}.AsExtension,
+	} {
+		e, err := f()
+		if err != nil {
+			t.Fatal(err)
+		}
+		exts = append(exts, e)
+	}
+	return exts
+}
+
+func encodeForTest(t *testing.T, exts ...Extension) []byte {
+	t.Helper()
+	out, err := EncodeExtensions(exts)
+	if err != nil {
+		t.Fatalf("EncodeExtensions failed: %v", err)
+	}
+	return out
+}
+
+func TestSerializeIsCanonical(t *testing.T) {
+	bs := newExtensionAccessStruct()
+	defer bs.Free()
+	for _, typeID := range []uint16{0xF0FF, 0x00AA, 0xF0AA} {
+		if err := bs.SetExtension(typeID, []byte{0x01}); err != nil {
+			t.Fatalf("SetExtension(%#04x) failed: %v", typeID, err)
+		}
+	}
+	out, err := Serialize(bs)
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	if !IsCanonical(out) {
+		t.Errorf("IsCanonical(Serialize()) = false, want true for %x", out)
+	}
+	bs2, err := DeserializeOptions{Canonical: true}.Deserialize(out)
+	if err != nil {
+		t.Fatalf("Deserialize(canonical) failed: %v", err)
+	}
+	defer bs2.Free()
+	again, err := Serialize(bs2)
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	if string(again) != string(out) {
+		t.Errorf("Serialize(Deserialize(out)) = %x, want %x", again, out)
+	}
+}
+
+func TestIsCanonical(t *testing.T) {
+	exts := canonicalTestExtensions(t, GeoHintExtension{CountryCode: "US", Region: "US-CA", City: "SUNNYVALE"})
+	lower := canonicalTestExtensions(t, GeoHintExtension{CountryCode: "us", Region: "us-ca", City: "sunnyvale"})
+	proxyLayer := Extension{Type: ExtensionTypeProxyLayer, Value: []byte{0x00}}
+	version := func(v uint8) Extension {
+		return Extension{Type: ExtensionTypeVersion, Value: []byte{v}}
+	}
+	tests := []struct {
+		name string
+		in   []byte
+		want bool
+	}{
+		{name: "canonical", in: encodeForTest(t, exts...), want: true},
+		{name: "version 2 without proxy layer", in: encodeForTest(t, append(exts, version(2))...), want: true},
+		{name: "version 3 with proxy layer", in: encodeForTest(t, append(exts, proxyLayer, version(3))...), want: true},
+		{name: "implied version 1", in: encodeForTest(t, append(exts, version(1))...)},
+		{name: "implied version 2", in: encodeForTest(t, append(exts, proxyLayer, version(2))...)},
+		{name: "version 1 with proxy layer", in: encodeForTest(t, append(exts, proxyLayer, version(1))...)},
+		{name: "unknown extension in order", in: encodeForTest(t, exts[0], exts[1], Extension{Type: 0x00AA, Value: []byte("x")}, exts[2], exts[3]), want: true},
+		{name: "out of order", in: encodeForTest(t, exts[1], exts[0], exts[2], exts[3])},
+		{name: "repeated type", in: encodeForTest(t, exts[0], exts[1], exts[1], exts[2], exts[3])},
+		{name: "lower case geo hint", in: encodeForTest(t, lower...)},
+		{name: "geo hint length prefix", in: encodeForTest(t, exts[0], Extension{Type: ExtensionTypeGeoHint, Value: []byte("\x00\x09US,,")}, exts[2], exts[3])},
+		{name: "wide debug mode", in: encodeForTest(t, exts[0], exts[1], exts[2], Extension{Type: ExtensionTypeDebugMode, Value: []byte{0x00, 0x00}})},
+		{name: "trailing bytes", in: append(encodeForTest(t, exts...), 0x00)},
+		{name: "truncated", in: encodeForTest(t, exts...)[:10]},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := IsCanonical(tc.in); got != tc.want {
+				t.Errorf("IsCanonical(%x) = %v, want %v", tc.in, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDeserializeCanonical(t *testing.T) {
+	in := encodeForTest(t, canonicalTestExtensions(t, GeoHintExtension{CountryCode: "us", Region: "us-ca"})...)
+	bs, err := Deserialize(in)
+	if err != nil {
+		t.Fatalf("Deserialize(lower case geo hint) failed: %v", err)
+	}
+	bs.Free()
+	if _, err := (DeserializeOptions{Canonical: true}).Deserialize(in); !errors.Is(err, ErrMalformed) {
+		t.Errorf("Deserialize(lower case geo hint) returned error: %v, want error: %v", err, ErrMalformed)
+	}
+}
@@ -0,0 +1,225 @@
+package binarymetadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// The CBOR encoding is a single map using the same keys and value representation as the JSON
+// encoding, with unset optionals omitted. Encoding follows the RFC 8949 section 4.2.1 core
+// deterministic encoding requirements: definite lengths, shortest-form integers and map keys
+// sorted by their encoded bytes.
+
+const (
+	cborUint   = 0
+	cborNegInt = 1
+	cborText   = 3
+	cborMap    = 5
+)
+
+// SerializeCBOR encodes bs as a deterministic CBOR map. Like the JSON encoding, it rejects metadata
+// carrying extensions the C++ struct does not model with ErrUnrepresentable.
+func SerializeCBOR(bs *BinaryStruct) ([]byte, error) {
+	j, err := bs.toJSON()
+	if err != nil {
+		return nil, err
+	}
+	return encodeCBOR(j), nil
+}
+
+func encodeCBOR(j *jsonMetadata) []byte {
+	type entry struct {
+		key, value []byte
+	}
+	var entries []entry
+	add := func(key string, value []byte) {
+		entries = append(entries, entry{appendCBORText(nil, key), value})
+	}
+	add("version", appendCBORInt(nil, int64(j.Version)))
+	for _, o := range []struct {
+		key   string
+		value *string
+	}{
+		{"service_type", j.ServiceType},
+		{"country", j.Country},
+		{"region", j.Region},
+		{"city", j.City},
+	} {
+		if o.value != nil {
+			add(o.key, appendCBORText(nil, *o.value))
+		}
+	}
+	if j.ExpirationEpochSeconds != nil {
+		add("expiration_epoch_seconds", appendCBORInt(nil, *j.ExpirationEpochSeconds))
+	}
+	add("debug_mode", appendCBORText(nil, j.DebugMode))
+	add("proxy_layer", appendCBORText(nil, j.ProxyLayer))
+
+	sort.Slice(entries, func(i, k int) bool { return bytes.Compare(entries[i].key, entries[k].key) < 0 })
+	out := appendCBORHead(nil, cborMap, uint64(len(entries)))
+	for _, e := range entries {
+		out = append(out, e.key...)
+		out = append(out, e.value...)
+	}
+	return out
+}
+
+// DeserializeCBOR decodes a CBOR map produced by SerializeCBOR. Input that is not in deterministic
+// form, or that has unknown keys or trailing bytes, is rejected with ErrMalformed.
+func DeserializeCBOR(in []byte) (*BinaryStruct, error) {
+	d := cborDecoder{buf: in}
+	major, n, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	if major != cborMap {
+		return nil, fmt.Errorf("%w: CBOR major type %d, want map", ErrMalformed, major)
+	}
+	var j jsonMetadata
+	for i := uint64(0); i < n; i++ {
+		key, err := d.text()
+		if err != nil {
+			return nil, err
+		}
+		switch key {
+		case "version":
+			v, err := d.int()
+			if err != nil {
+				return nil, err
+			}
+			j.Version = int32(v)
+		case "service_type", "country", "region", "city":
+			v, err := d.text()
+			if err != nil {
+				return nil, err
+			}
+			switch key {
+			case "service_type":
+				j.ServiceType = &v
+			case "country":
+				j.Country = &v
+			case "region":
+				j.Region = &v
+			case "city":
+				j.City = &v
+			}
+		case "expiration_epoch_seconds":
+			v, err := d.int()
+			if err != nil {
+				return nil, err
+			}
+			j.ExpirationEpochSeconds = &v
+		case "debug_mode":
+			if j.DebugMode, err = d.text(); err != nil {
+				return nil, err
+			}
+		case "proxy_layer":
+			if j.ProxyLayer, err = d.text(); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("%w: unknown CBOR key %q", ErrMalformed, key)
+		}
+	}
+	if len(d.buf) != 0 {
+		return nil, fmt.Errorf("%w: %d trailing bytes after CBOR map", ErrMalformed, len(d.buf))
+	}
+	if !bytes.Equal(encodeCBOR(&j), in) {
+		return nil, fmt.Errorf("%w: CBOR input is not in deterministic form", ErrMalformed)
+	}
+	fields, err := j.toFields()
+	if err != nil {
+		return nil, err
+	}
+	return New(fields), nil
+}
+
+func appendCBORHead(b []byte, major byte, n uint64) []byte {
+	m := major << 5
+	switch {
+	case n < 24:
+		return append(b, m|byte(n))
+	case n <= 0xff:
+		return append(b, m|24, byte(n))
+	case n <= 0xffff:
+		return binary.BigEndian.AppendUint16(append(b, m|25), uint16(n))
+	case n <= 0xffffffff:
+		return binary.BigEndian.AppendUint32(append(b, m|26), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, m|27), n)
+	}
+}
+
+func appendCBORInt(b []byte, v int64) []byte {
+	if v < 0 {
+		return appendCBORHead(b, cborNegInt, uint64(-1-v))
+	}
+	return appendCBORHead(b, cborUint, uint64(v))
+}
+
+func appendCBORText(b []byte, s string) []byte {
+	return append(appendCBORHead(b, cborText, uint64(len(s))), s...)
+}
+
+type cborDecoder struct {
+	buf []byte
+}
+
+func (d *cborDecoder) head() (byte, uint64, error) {
+	if len(d.buf) == 0 {
+		return 0, 0, fmt.Errorf("%w: truncated CBOR", ErrMalformed)
+	}
+	major, info := d.buf[0]>>5, d.buf[0]&0x1f
+	d.buf = d.buf[1:]
+	if info < 24 {
+		return major, uint64(info), nil
+	}
+	size := map[byte]int{24: 1, 25: 2, 26: 4, 27: 8}[info]
+	if size == 0 {
+		return 0, 0, fmt.Errorf("%w: unsupported CBOR additional info %d", ErrMalformed, info)
+	}
+	if len(d.buf) < size {
+		return 0, 0, fmt.Errorf("%w: truncated CBOR", ErrMalformed)
+	}
+	var n uint64
+	for _, c := range d.buf[:size] {
+		n = n<<8 | uint64(c)
+	}
+	d.buf = d.buf[size:]
+	return major, n, nil
+}
+
+func (d *cborDecoder) text() (string, error) {
+	major, n, err := d.head()
+	if err != nil {
+		return "", err
+	}
+	if major != cborText {
+		return "", fmt.Errorf("%w: CBOR major type %d, want text", ErrMalformed, major)
+	}
+	if uint64(len(d.buf)) < n {
+		return "", fmt.Errorf("%w: truncated CBOR", ErrMalformed)
+	}
+	s := string(d.buf[:n])
+	d.buf = d.buf[n:]
+	return s, nil
+}
+
+func (d *cborDecoder) int() (int64, error) {
+	major, n, err := d.head()
+	if err != nil {
+		return 0, err
+	}
+	if n > 1<<63-1 {
+		return 0, fmt.Errorf("%w: CBOR integer out of range", ErrMalformed)
+	}
+	switch major {
+	case cborUint:
+		return int64(n), nil
+	case cborNegInt:
+		return -1 - int64(n), nil
+	}
+	return 0, fmt.Errorf("%w: CBOR major type %d, want integer", ErrMalformed, major)
+}
@@ -0,0 +1,61 @@
+package binarymetadata
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+)
+
+func TestCBORRoundTrip(t *testing.T) {
+	bs := New(&NewBinaryFields{
+		Version:     1,
+		Country:     "US",
+		ServiceType: "chromeipblinding",
+		Expiration:  &tpb.Timestamp{Seconds: 3600},
+		DebugMode:   pmpb.PublicMetadata_DEBUG_ALL,
+	})
+	defer bs.Free()
+	encoded, err := SerializeCBOR(bs)
+	if err != nil {
+		t.Fatalf("SerializeCBOR failed: %v", err)
+	}
+	again, err := SerializeCBOR(bs)
+	if err != nil || hex.EncodeToString(again) != hex.EncodeToString(encoded) {
+		t.Errorf("SerializeCBOR is not deterministic: %x vs %x (err %v)", encoded, again, err)
+	}
+	decoded, err := DeserializeCBOR(encoded)
+	if err != nil {
+		t.Fatalf("DeserializeCBOR(%x) failed: %v", encoded, err)
+	}
+	defer decoded.Free()
+	if diff := Diff(bs, decoded); diff != nil {
+		t.Errorf("CBOR round trip changed fields %v", diff)
+	}
+}
+
+func TestDeserializeCBORRejectsNonCanonical(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+	}{
+		// {"version": 1} with the integer in a non-shortest form.
+		{name: "long integer", in: "a16776657273696f6e1801"},
+		{name: "not a map", in: "01"},
+		{name: "unknown key", in: "a163666f6f01"},
+		{name: "trailing bytes", in: "a0ff"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			in, err := hex.DecodeString(tc.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := DeserializeCBOR(in); !errors.Is(err, ErrMalformed) {
+				t.Errorf("DeserializeCBOR(%s) returned error: %v, want error: %v", tc.in, err, ErrMalformed)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package binarymetadata
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TokenChallenge is a Privacy Pass token challenge, RFC 9577 section 2.1.
+type TokenChallenge struct {
+	TokenType TokenType
+	// IssuerName names the issuer; it must be 1 to 65535 bytes long.
+	IssuerName string
+	// RedemptionContext is either empty or 32 bytes long.
+	RedemptionContext []byte
+	// OriginInfo lists the origins the token is redeemable at. Empty means any origin.
+	OriginInfo []string
+}
+
+// MarshalBinary encodes c in the TLS presentation language encoding of RFC 9577.
+func (c *TokenChallenge) MarshalBinary() ([]byte, error) {
+	if len(c.IssuerName) == 0 || len(c.IssuerName) > 0xffff {
+		return nil, fmt.Errorf("%w: issuer name of %d bytes", ErrMalformed, len(c.IssuerName))
+	}
+	if n := len(c.RedemptionContext); n != 0 && n != 32 {
+		return nil, fmt.Errorf("%w: redemption context of %d bytes, want 0 or 32", ErrMalformed, n)
+	}
+	for _, o := range c.OriginInfo {
+		if o == "" || strings.Contains(o, ",") {
+			return nil, fmt.Errorf("%w: origin %q", ErrMalformed, o)
+		}
+	}
+	origins := strings.Join(c.OriginInfo, ",")
+	if len(origins) > 0xffff {
+		return nil, fmt.Errorf("%w: origin info of %d bytes", ErrMalformed, len(origins))
+	}
+	out := binary.BigEndian.AppendUint16(nil, uint16(c.TokenType))
+	out = binary.BigEndian.AppendUint16(out, uint16(len(c.IssuerName)))
+	out = append(out, c.IssuerName...)
+	out = append(out, byte(len(c.RedemptionContext)))
+	out = append(out, c.RedemptionContext...)
+	out = binary.BigEndian.AppendUint16(out, uint16(len(origins)))
+	return append(out, origins...), nil
+}
+
+// ParseTokenChallenge decodes a TokenChallenge encoded by MarshalBinary. It returns an error
+// wrapping ErrMalformed if in is truncated, has trailing bytes or breaks a length rule.
+func ParseTokenChallenge(in []byte) (*TokenChallenge, error) {
+	r := challengeReader{in: in}
+	c := &TokenChallenge{TokenType: TokenType(r.uint16())}
+	c.IssuerName = string(r.bytes(int(r.uint16())))
+	c.RedemptionContext = r.bytes(int(r.uint8()))
+	origins := string(r.bytes(int(r.uint16())))
+	if r.err != nil {
+		return nil, r.err
+	}
+	if len(r.in) != 0 {
+		return nil, fmt.Errorf("%w: %d trailing bytes after token challenge", ErrMalformed, len(r.in))
+	}
+	if origins != "" {
+		c.OriginInfo = strings.Split(origins, ",")
+	}
+	if _, err := c.MarshalBinary(); err != nil {
+		return nil, err
+	}
+	if len(c.RedemptionContext) == 0 {
+		c.RedemptionContext = nil
+	}
+	return c, nil
+}
+
+// challengeReader reads big-endian fields off in, recording the first short read in err.
+type challengeReader struct {
+	in  []byte
+	err error
+}
+
+func (r *challengeReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.in) < n {
+		r.err = fmt.Errorf("%w: token challenge is truncated", ErrMalformed)
+		return nil
+	}
+	b := r.in[:n:n]
+	r.in = r.in[n:]
+	return b
+}
+
+func (r *challengeReader) uint8() uint8 {
+	if b := r.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *challengeReader) uint16() uint16 {
+	if b := r.bytes(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+// challengeScheme is the HTTP authentication scheme of RFC 9577.
+const challengeScheme = "PrivateToken"
+
+// Challenge is a PrivateToken WWW-Authenticate challenge carrying public metadata in its
+// extensions parameter, as the public metadata draft extends RFC 9577.
+type Challenge struct {
+	TokenChallenge TokenChallenge
+	// TokenKey is the issuer public key the token is to be signed with.
+	TokenKey []byte
+	// Extensions is the serialized public metadata.
+	Extensions []byte
+}
+
+// NewChallenge returns a challenge for tokens of tc.TokenType carrying bs. It fails if the token
+// type cannot carry the metadata version of bs.
+func NewChallenge(tc TokenChallenge, tokenKey []byte, bs *BinaryStruct) (*Challenge, error) {
+	if err := CheckTokenType(tc.TokenType, bs.GetVersion()); err != nil {
+		return nil, err
+	}
+	exts, err := Serialize(bs)
+	if err != nil {
+		return nil, err
+	}
+	return &Challenge{TokenChallenge: tc, TokenKey: tokenKey, Extensions: exts}, nil
+}
+
+// Header returns c as a WWW-Authenticate header value, with each parameter in unpadded base64url.
+func (c *Challenge) Header() (string, error) {
+	tc, err := c.TokenChallenge.MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+	params := []string{"challenge=" + quoteBase64(tc)}
+	if len(c.TokenKey) > 0 {
+		params = append(params, "token-key="+quoteBase64(c.TokenKey))
+	}
+	params = append(params, "extensions="+quoteBase64(c.Extensions))
+	return challengeScheme + " " + strings.Join(params, ", "), nil
+}
+
+func quoteBase64(b []byte) string {
+	return `"` + base64.RawURLEncoding.EncodeToString(b) + `"`
+}
+
+// ParseChallenge parses a PrivateToken WWW-Authenticate header value holding a single challenge.
+// Parameter values may be quoted and padded; unknown parameters are ignored. It fails with
+// ErrMissingField if the challenge or extensions parameter is absent.
+func ParseChallenge(value string) (*Challenge, error) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(value), " ")
+	if !strings.EqualFold(scheme, challengeScheme) {
+		return nil, fmt.Errorf("%w: authentication scheme %q, want %s", ErrMalformed, scheme, challengeScheme)
+	}
+	params := make(map[string][]byte)
+	for _, p := range strings.Split(rest, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(p), "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: challenge parameter %q", ErrMalformed, p)
+		}
+		k = strings.ToLower(strings.TrimSpace(k))
+		v = strings.TrimRight(strings.Trim(strings.TrimSpace(v), `"`), "=")
+		b, err := base64.RawURLEncoding.DecodeString(v)
+		if err != nil {
+			if k != "challenge" && k != "token-key" && k != "extensions" {
+				continue
+			}
+			return nil, fmt.Errorf("%w: %s is not base64url: %v", ErrMalformed, k, err)
+		}
+		params[k] = b
+	}
+	for _, k := range []string{"challenge", "extensions"} {
+		if _, ok := params[k]; !ok {
+			return nil, fmt.Errorf("%w: no %s parameter", ErrMissingField, k)
+		}
+	}
+	tc, err := ParseTokenChallenge(params["challenge"])
+	if err != nil {
+		return nil, err
+	}
+	return &Challenge{TokenChallenge: *tc, TokenKey: params["token-key"], Extensions: params["extensions"]}, nil
+}
+
+// Metadata deserializes the extensions of c and checks them with v at t and against the token
+// type of the challenge, so that a client does not request tokens for metadata it would not
+// accept. The caller must Free the result.
+func (c *Challenge) Metadata(v *Validator, t time.Time) (*BinaryStruct, error) {
+	bs, err := Deserialize(c.Extensions)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckTokenType(c.TokenChallenge.TokenType, bs.GetVersion()); err != nil {
+		bs.Free()
+		return nil, err
+	}
+	if err := v.ValidateStruct(bs, t); err != nil {
+		bs.Free()
+		return nil, err
+	}
+	return bs, nil
+}
@@ -0,0 +1,128 @@
+package binarymetadata
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"google3/third_party/golang/cmp/cmp"
+)
+
+func TestTokenChallengeRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		tc   TokenChallenge
+	}{
+		{name: "minimal", tc: TokenChallenge{TokenType: TokenTypePublicMetadata, IssuerName: "issuer.example"}},
+		{name: "full", tc: TokenChallenge{
+			TokenType:         TokenTypePublicMetadata,
+			IssuerName:        "issuer.example",
+			RedemptionContext: bytes.Repeat([]byte{0xab}, 32),
+			OriginInfo:        []string{"a.example", "b.example"},
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			in, err := tc.tc.MarshalBinary()
+			if err != nil {
+				t.Fatalf("MarshalBinary failed: %v", err)
+			}
+			got, err := ParseTokenChallenge(in)
+			if err != nil {
+				t.Fatalf("ParseTokenChallenge failed: %v", err)
+			}
+			if diff := cmp.Diff(&tc.tc, got); diff != "" {
+				t.Errorf("ParseTokenChallenge returned diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTokenChallengeEncoding(t *testing.T) {
+	tc := TokenChallenge{TokenType: TokenTypePublicMetadata, IssuerName: "i", OriginInfo: []string{"o"}}
+	got, err := tc.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+	want := []byte{0xda, 0x7a, 0x00, 0x01, 'i', 0x00, 0x00, 0x01, 'o'}
+	if !bytes.Equal(got, want) {
+		t.Errorf("MarshalBinary = %x, want %x", got, want)
+	}
+}
+
+func TestTokenChallengeErrors(t *testing.T) {
+	for _, in := range [][]byte{
+		{},
+		{0xda, 0x7a, 0x00, 0x00, 0x00, 0x00, 0x00},
+		{0xda, 0x7a, 0x00, 0x01, 'i', 0x01, 0x00, 0x00, 0x00},
+		{0xda, 0x7a, 0x00, 0x01, 'i', 0x00, 0x00, 0x02, 'o'},
+		{0xda, 0x7a, 0x00, 0x01, 'i', 0x00, 0x00, 0x00, 0x00},
+		{0xda, 0x7a, 0x00, 0x01, 'i', 0x00, 0x00, 0x02, 'o', ','},
+	} {
+		if _, err := ParseTokenChallenge(in); !errors.Is(err, ErrMalformed) {
+			t.Errorf("ParseTokenChallenge(%x) = %v, want ErrMalformed", in, err)
+		}
+	}
+}
+
+func TestChallengeHeader(t *testing.T) {
+	bs := newHeaderTestStruct(t, time.Now().Add(time.Hour))
+	c, err := NewChallenge(TokenChallenge{TokenType: TokenTypePublicMetadata, IssuerName: "issuer.example"}, []byte("key"), bs)
+	if err != nil {
+		t.Fatalf("NewChallenge failed: %v", err)
+	}
+	h, err := c.Header()
+	if err != nil {
+		t.Fatalf("Header failed: %v", err)
+	}
+	got, err := ParseChallenge(h)
+	if err != nil {
+		t.Fatalf("ParseChallenge(%q) failed: %v", h, err)
+	}
+	if diff := cmp.Diff(c, got); diff != "" {
+		t.Errorf("ParseChallenge returned diff (-want +got):\n%s", diff)
+	}
+
+	v, err := NewValidator(ValidationConfig{})
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+	md, err := got.Metadata(v, time.Now())
+	if err != nil {
+		t.Fatalf("Metadata failed: %v", err)
+	}
+	defer md.Free()
+	if !Equal(bs, md) {
+		t.Errorf("Metadata = %v, want %v", md, bs)
+	}
+	if _, err := got.Metadata(v, time.Now().Add(2*time.Hour)); !errors.Is(err, ErrExpired) {
+		t.Errorf("Metadata after expiration = %v, want ErrExpired", err)
+	}
+	got.TokenChallenge.TokenType = TokenTypeBlindRSA
+	if _, err := got.Metadata(v, time.Now()); !errors.Is(err, ErrUnsupportedTokenType) {
+		t.Errorf("Metadata for a blind RSA challenge = %v, want ErrUnsupportedTokenType", err)
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  error
+	}{
+		{name: "padded and unquoted", value: "privatetoken challenge=2noAAWkAAAA=, extensions=AAA=, max-age=10"},
+		{name: "wrong scheme", value: `Basic realm="x"`, want: ErrMalformed},
+		{name: "no extensions", value: `PrivateToken challenge="2noAAWkAAAA"`, want: ErrMissingField},
+		{name: "no challenge", value: `PrivateToken extensions="AAA"`, want: ErrMissingField},
+		{name: "bad base64", value: `PrivateToken challenge="2noAAWkAAAA", extensions="A+A"`, want: ErrMalformed},
+		{name: "bad challenge", value: `PrivateToken challenge="2noA", extensions="AAA"`, want: ErrMalformed},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := ParseChallenge(tc.value); !errors.Is(err, tc.want) {
+				t.Errorf("ParseChallenge(%q) = %v, want %v", tc.value, err, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package binarymetadata
+
+// ClientPlatform is the coarse class of platform a client runs on, as carried by the client
+// platform extension. Proxies can rate limit per class without learning the operating system or
+// its build.
+type ClientPlatform uint8
+
+// Client platforms, equal to their wire values.
+const (
+	ClientPlatformOther   ClientPlatform = 0x00
+	ClientPlatformMobile  ClientPlatform = 0x01
+	ClientPlatformDesktop ClientPlatform = 0x02
+)
+
+func (p ClientPlatform) String() string {
+	return coarseName(ExtensionTypeClientPlatform, uint8(p))
+}
+
+// GetClientPlatform returns the client platform and whether it is set to a valid value.
+func (bs *BinaryStruct) GetClientPlatform() (ClientPlatform, bool) {
+	v, ok := bs.getCoarse(ExtensionTypeClientPlatform)
+	return ClientPlatform(v), ok
+}
+
+// SetClientPlatform sets the client platform. It fails with ErrInvalidClientPlatform for values
+// other than the ClientPlatform constants and for versions without the ClientPlatform capability.
+func (bs *BinaryStruct) SetClientPlatform(p ClientPlatform) error {
+	return bs.setCoarse(ExtensionTypeClientPlatform, uint8(p))
+}
+
+// ClearClientPlatform removes the client platform.
+func (bs *BinaryStruct) ClearClientPlatform() {
+	bs.clearCoarse(ExtensionTypeClientPlatform)
+}
@@ -0,0 +1,70 @@
+package binarymetadata
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestClientPlatformRoundTrip(t *testing.T) {
+	for _, want := range []ClientPlatform{ClientPlatformOther, ClientPlatformMobile, ClientPlatformDesktop} {
+		bs := newTestStruct(t, 3)
+		if err := bs.SetClientPlatform(want); err != nil {
+			t.Fatalf("SetClientPlatform(%v) failed: %v", want, err)
+		}
+		out, err := Serialize(bs)
+		if err != nil {
+			t.Fatalf("Serialize failed: %v", err)
+		}
+		got, err := DeserializeOptions{Strict: true, Canonical: true}.Deserialize(out)
+		if err != nil {
+			t.Fatalf("Deserialize failed: %v", err)
+		}
+		defer got.Free()
+		if got.GetVersion() != 3 {
+			t.Errorf("GetVersion() after round trip = %d, want 3", got.GetVersion())
+		}
+		if p, ok := got.GetClientPlatform(); !ok || p != want {
+			t.Errorf("GetClientPlatform() = %v, %t, want %v, true", p, ok, want)
+		}
+	}
+}
+
+func TestClientPlatformRejectsFinerValues(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		enable  bool
+		value   []byte
+		wantErr error
+	}{
+		{name: "unsupported version", value: []byte{byte(ClientPlatformMobile)}, wantErr: ErrInvalidClientPlatform},
+		{name: "os build", enable: true, value: []byte{0x03}, wantErr: ErrInvalidClientPlatform},
+		{name: "versioned", enable: true, value: []byte{byte(ClientPlatformMobile), 0x11}, wantErr: ErrMalformed},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			version := int32(2)
+			if tc.enable {
+				version = 3
+			}
+			bs := newTestStruct(t, version)
+			if err := bs.SetExtension(ExtensionTypeClientPlatform, tc.value); err != nil {
+				t.Fatalf("SetExtension failed: %v", err)
+			}
+			out, err := Serialize(bs)
+			if err != nil {
+				t.Fatalf("Serialize failed: %v", err)
+			}
+			if _, err := (DeserializeOptions{Strict: true}).Deserialize(out); !errors.Is(err, tc.wantErr) {
+				t.Errorf("Deserialize() returned error: %v, want error: %v", err, tc.wantErr)
+			}
+			err = defaultValidator.Validate(out, time.Unix(0, 0))
+			var fe *FieldError
+			if !errors.As(err, &fe) || fe.Field != "client_platform" || !errors.Is(err, tc.wantErr) {
+				t.Errorf("Validate() returned error: %v, want client_platform error wrapping %v", err, tc.wantErr)
+			}
+		})
+	}
+	if err := (&BinaryStruct{}).SetClientPlatform(ClientPlatform(4)); !errors.Is(err, ErrInvalidClientPlatform) {
+		t.Errorf("SetClientPlatform(4) returned error: %v, want error: %v", err, ErrInvalidClientPlatform)
+	}
+}
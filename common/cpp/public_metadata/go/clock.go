@@ -0,0 +1,28 @@
+package binarymetadata
+
+import "time"
+
+// Clock tells the time to the parts of this package that would otherwise call time.Now: the
+// Validator, the Cache and the HTTP middleware. Tests substitute a fake to land exactly on an
+// expiration boundary instead of sleeping; binarymetadatatest.FakeClock is one.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// SystemClock is the Clock backed by time.Now.
+var SystemClock Clock = systemClock{}
+
+// NextExpiration returns the first expiration boundary of version at least lifetime after the
+// time of c.
+func NextExpiration(c Clock, lifetime time.Duration, version int32) (time.Time, error) {
+	return RoundExpiration(c.Now().Add(lifetime), version)
+}
+
+// IsExpiredAt is like IsExpired at the time of c.
+func (bs *BinaryStruct) IsExpiredAt(c Clock) bool {
+	return bs.IsExpired(c.Now())
+}
@@ -0,0 +1,55 @@
+package binarymetadata
+
+import (
+	"testing"
+	"time"
+)
+
+type fixedClock struct{ t time.Time }
+
+func (c *fixedClock) Now() time.Time { return c.t }
+
+func TestNextExpiration(t *testing.T) {
+	edge := time.Unix(1800000000, 0).Truncate(15 * time.Minute)
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{name: "on the boundary", now: edge.Add(-time.Hour), want: edge},
+		{name: "just past the boundary", now: edge.Add(-time.Hour + time.Second), want: edge.Add(15 * time.Minute)},
+		{name: "just before the boundary", now: edge.Add(-time.Hour - time.Second), want: edge},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NextExpiration(&fixedClock{tc.now}, time.Hour, 1)
+			if err != nil {
+				t.Fatalf("NextExpiration failed: %v", err)
+			}
+			if !got.Equal(tc.want) {
+				t.Errorf("NextExpiration = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCacheSetClock(t *testing.T) {
+	clock := &fixedClock{time.Unix(1000, 0)}
+	c := NewCache(4, time.Minute)
+	c.SetClock(clock)
+	in := cacheTestBlob(t, "US")
+	first, release, err := c.Deserialize(in)
+	if err != nil {
+		t.Fatalf("Deserialize failed: %v", err)
+	}
+	release()
+	clock.t = clock.t.Add(2 * time.Minute)
+	second, release, err := c.Deserialize(in)
+	if err != nil {
+		t.Fatalf("Deserialize failed: %v", err)
+	}
+	defer release()
+	if first == second {
+		t.Error("Deserialize returned an entry older than the TTL of the clock")
+	}
+}
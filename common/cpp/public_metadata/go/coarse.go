@@ -0,0 +1,181 @@
+package binarymetadata
+
+import (
+	"fmt"
+	"slices"
+)
+
+// coarseExtension describes an optional extension carrying one of a few coarse values in a single
+// byte. None is modeled by the C++ struct, so they travel among the extensions added with
+// SetExtension. Keeping the values few and coarse keeps them from narrowing the anonymity set; a
+// new extension of this kind only needs an entry in coarseExtensions.
+type coarseExtension struct {
+	// field names the extension in findings and errors, e.g. "network_type".
+	field string
+	// names holds the display name of each wire value. Values past its end are invalid.
+	names []string
+	// allowed reports whether a version carries the extension.
+	allowed func(VersionCapabilities) bool
+	// err is the sentinel for invalid values and versions that do not carry the extension.
+	err error
+}
+
+// coarseExtensions maps the type IDs of the coarse extensions onto their descriptions.
+var coarseExtensions = map[uint16]coarseExtension{
+	ExtensionTypeNetworkType: {
+		field:   "network_type",
+		names:   []string{"UNKNOWN", "WIFI", "CELLULAR"},
+		allowed: func(c VersionCapabilities) bool { return c.NetworkType },
+		err:     ErrInvalidNetworkType,
+	},
+	ExtensionTypeClientPlatform: {
+		field:   "client_platform",
+		names:   []string{"OTHER", "MOBILE", "DESKTOP"},
+		allowed: func(c VersionCapabilities) bool { return c.ClientPlatform },
+		err:     ErrInvalidClientPlatform,
+	},
+	ExtensionTypeServiceTier: {
+		field:   "service_tier",
+		names:   []string{"FREE", "PAID"},
+		allowed: func(c VersionCapabilities) bool { return c.ServiceTier },
+		err:     ErrInvalidServiceTier,
+	},
+	ExtensionTypeAttestationLevel: {
+		field:   "attestation_level",
+		names:   []string{"NONE", "BASIC", "STRONG"},
+		allowed: func(c VersionCapabilities) bool { return c.AttestationLevel },
+		err:     ErrInvalidAttestationLevel,
+	},
+}
+
+// coarseTypes returns the type IDs of coarseExtensions in ascending order.
+func coarseTypes() []uint16 {
+	types := make([]uint16, 0, len(coarseExtensions))
+	for t := range coarseExtensions {
+		types = append(types, t)
+	}
+	slices.Sort(types)
+	return types
+}
+
+// coarseName returns the display name of wire value v of extension typeID.
+func coarseName(typeID uint16, v uint8) string {
+	if names := coarseExtensions[typeID].names; int(v) < len(names) {
+		return names[v]
+	}
+	return fmt.Sprintf("%s(%d)", coarseExtensions[typeID].field, v)
+}
+
+// decodeCoarse decodes a coarse extension, rejecting values without a name.
+func decodeCoarse(e Extension) (uint8, error) {
+	d, ok := coarseExtensions[e.Type]
+	if !ok {
+		return 0, fmt.Errorf("%w: extension type %#04x is not a coarse extension", ErrMalformed, e.Type)
+	}
+	if len(e.Value) != 1 {
+		return 0, fmt.Errorf("%w: %s extension is %d bytes, want 1", ErrMalformed, d.field, len(e.Value))
+	}
+	if int(e.Value[0]) >= len(d.names) {
+		return 0, fmt.Errorf("%w: %d", d.err, e.Value[0])
+	}
+	return e.Value[0], nil
+}
+
+// encodeCoarse encodes wire value v of extension typeID, rejecting values without a name.
+func encodeCoarse(typeID uint16, v uint8) (Extension, error) {
+	e := Extension{Type: typeID, Value: []byte{v}}
+	if _, err := decodeCoarse(e); err != nil {
+		return Extension{}, err
+	}
+	return e, nil
+}
+
+// getCoarse returns the wire value of extension typeID and whether it is set to a valid value.
+func (bs *BinaryStruct) getCoarse(typeID uint16) (uint8, bool) {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+	return bs.coarse(typeID)
+}
+
+// coarse is getCoarse for callers that hold the lock.
+func (bs *BinaryStruct) coarse(typeID uint16) (uint8, bool) {
+	e, ok := bs.findExtra(typeID)
+	if !ok {
+		return 0, false
+	}
+	v, err := decodeCoarse(e)
+	return v, err == nil
+}
+
+// setCoarse sets extension typeID to wire value v, if the version of bs carries it.
+func (bs *BinaryStruct) setCoarse(typeID uint16, v uint8) error {
+	err := bs.putCoarse(typeID, v)
+	bs.audit(AuditMutate, "set_"+coarseExtensions[typeID].field, err)
+	return err
+}
+
+func (bs *BinaryStruct) putCoarse(typeID uint16, v uint8) error {
+	e, err := encodeCoarse(typeID, v)
+	if err != nil {
+		return err
+	}
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if err := bs.checkMutable(); err != nil {
+		return err
+	}
+	return bs.storeCoarse(e)
+}
+
+// storeCoarse is putCoarse of the encoded extension e for callers that hold the write lock and
+// have checked that bs is mutable.
+func (bs *BinaryStruct) storeCoarse(e Extension) error {
+	c, err := Capabilities(int32(bs.md().GetVersion()))
+	if err != nil {
+		return err
+	}
+	if d := coarseExtensions[e.Type]; !d.allowed(c) {
+		return fmt.Errorf("%w: version %d does not support the %s extension", d.err, c.Version, d.field)
+	}
+	bs.putExtra(e)
+	return nil
+}
+
+// clearCoarse removes extension typeID.
+func (bs *BinaryStruct) clearCoarse(typeID uint16) {
+	defer bs.audit(AuditMutate, "clear_"+coarseExtensions[typeID].field, nil)
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if !bs.frozen {
+		bs.removeExtra(typeID)
+	}
+}
+
+// coarseFindings reports the coarse extensions of bs that are malformed or not carried by the
+// version c. The caller holds the lock.
+func (bs *BinaryStruct) coarseFindings(c VersionCapabilities) []Finding {
+	var fs []Finding
+	for _, t := range coarseTypes() {
+		e, ok := bs.findExtra(t)
+		if !ok {
+			continue
+		}
+		d := coarseExtensions[t]
+		value := fmt.Sprintf("%x", e.Value)
+		if _, err := decodeCoarse(e); err != nil {
+			fs = append(fs, Finding{Field: d.field, Rule: "coarse_value", Value: value, Severity: SeverityError, Err: err})
+		} else if !d.allowed(c) {
+			err := fmt.Errorf("%w: version %d does not support the %s extension", d.err, c.Version, d.field)
+			fs = append(fs, Finding{Field: d.field, Rule: "supported_by_version", Value: value, Severity: SeverityError, Err: err})
+		}
+	}
+	return fs
+}
+
+// checkCoarse returns the first of coarseFindings as a *FieldError. The caller holds the lock.
+func (bs *BinaryStruct) checkCoarse(c VersionCapabilities) error {
+	if fs := bs.coarseFindings(c); len(fs) > 0 {
+		return fs[0].fieldError()
+	}
+	return nil
+}
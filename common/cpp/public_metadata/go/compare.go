@@ -0,0 +1,141 @@
+package binarymetadata
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// FieldDelta is a field that differs between two structs, with its value in each formatted for
+// display.
+type FieldDelta struct {
+	// Field is the name of the field, e.g. "expiration".
+	Field string
+	// Old is the value in the first struct passed to Diff.
+	Old string
+	// New is the value in the second struct passed to Diff.
+	New string
+}
+
+func (d FieldDelta) String() string {
+	return fmt.Sprintf("%s: %q -> %q", d.Field, d.Old, d.New)
+}
+
+// Equal reports whether a and b carry the same metadata. See Diff for how fields are compared.
+func Equal(a, b *BinaryStruct) bool {
+	return len(Diff(a, b)) == 0
+}
+
+// Diff returns the fields that differ between a and b, in a stable order. Fields are compared
+// through the getters, so the comparison is semantic rather than byte-wise: geo fields are
+// compared case-insensitively as Serialize upper-cases them, and the version only matters where it
+// changes the meaning of a field, as it does for the proxy layer. Expirations are compared with
+// their millisecond part and formatted as RFC 3339, and enums by name; a missing expiration is
+// empty. The extensions not modeled by the C++ struct are compared by type after the other fields:
+// the coarse ones by value name, the key epoch in decimal, and the nonce and unknown types, named
+// "extension_" and their hex type ID, as hex. A missing extension is empty.
+func Diff(a, b *BinaryStruct) []FieldDelta {
+	var diff []FieldDelta
+	add := func(field, old, new string) {
+		diff = append(diff, FieldDelta{Field: field, Old: old, New: new})
+	}
+	if sa, sb := a.GetServiceType(), b.GetServiceType(); sa != sb {
+		add("service_type", sa, sb)
+	}
+	if !equalExpiration(a, b) {
+		add("expiration", formatExpiration(a), formatExpiration(b))
+	}
+	if da, db := a.GetDebugMode(), b.GetDebugMode(); da != db {
+		add("debug_mode", da.String(), db.String())
+	}
+	geoA, geoB := a.GetGeoHint(), b.GetGeoHint()
+	if !strings.EqualFold(geoA.Country, geoB.Country) {
+		add("country", geoA.Country, geoB.Country)
+	}
+	if !strings.EqualFold(geoA.Region, geoB.Region) {
+		add("region", geoA.Region, geoB.Region)
+	}
+	if !strings.EqualFold(geoA.City, geoB.City) {
+		add("city", geoA.City, geoB.City)
+	}
+	if pa, pb := a.GetProxyLayer(), b.GetProxyLayer(); pa != pb {
+		add("proxy_layer", pa.String(), pb.String())
+	}
+	extraA, extraB := a.extras(), b.extras()
+	for len(extraA) > 0 || len(extraB) > 0 {
+		var ea, eb *Extension
+		switch {
+		case len(extraB) == 0 || len(extraA) > 0 && extraA[0].Type < extraB[0].Type:
+			ea, extraA = &extraA[0], extraA[1:]
+		case len(extraA) == 0 || extraB[0].Type < extraA[0].Type:
+			eb, extraB = &extraB[0], extraB[1:]
+		default:
+			ea, eb, extraA, extraB = &extraA[0], &extraB[0], extraA[1:], extraB[1:]
+		}
+		if ea != nil && eb != nil && bytes.Equal(ea.Value, eb.Value) {
+			continue
+		}
+		e := ea
+		if e == nil {
+			e = eb
+		}
+		if e.Type == ExtensionTypeExpirationMillis {
+			continue // Compared with the expiration.
+		}
+		add(extraField(e.Type), formatExtra(ea), formatExtra(eb))
+	}
+	return diff
+}
+
+// extras returns the extensions of bs not modeled by the C++ struct, sorted by type.
+func (bs *BinaryStruct) extras() []Extension {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+	return slices.Clone(bs.extra)
+}
+
+// extraField names an extension of bs.extra in a FieldDelta.
+func extraField(typeID uint16) string {
+	if d, ok := coarseExtensions[typeID]; ok {
+		return d.field
+	}
+	switch typeID {
+	case ExtensionTypeKeyEpoch:
+		return "key_epoch"
+	case ExtensionTypeNonce:
+		return "nonce"
+	}
+	return fmt.Sprintf("extension_%04x", typeID)
+}
+
+// formatExtra formats the value of e for a FieldDelta, or returns "" for nil.
+func formatExtra(e *Extension) string {
+	if e == nil {
+		return ""
+	}
+	if d, ok := coarseExtensions[e.Type]; ok {
+		if v, err := decodeCoarse(*e); err == nil && int(v) < len(d.names) {
+			return d.names[v]
+		}
+	}
+	if e.Type == ExtensionTypeKeyEpoch {
+		if k, err := KeyEpochExtensionFromExtension(*e); err == nil {
+			return fmt.Sprint(k.Epoch)
+		}
+	}
+	return fmt.Sprintf("%x", e.Value)
+}
+
+func equalExpiration(a, b *BinaryStruct) bool {
+	return a.GetExpirationTime().Equal(b.GetExpirationTime())
+}
+
+func formatExpiration(bs *BinaryStruct) string {
+	exp := bs.GetExpirationTime()
+	if exp.IsZero() {
+		return ""
+	}
+	return exp.UTC().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,140 @@
+package binarymetadata
+
+import (
+	"testing"
+	"time"
+
+	"google3/third_party/golang/cmp/cmp"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	plpb "google3/privacy/net/common/proto/proxy_layer_go_proto"
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+)
+
+func TestDiff(t *testing.T) {
+	base := NewBinaryFields{
+		Version:     2,
+		Country:     "US",
+		Region:      "US-CA",
+		City:        "SUNNYVALE",
+		ServiceType: "chromeipblinding",
+		Expiration:  &tpb.Timestamp{Seconds: 3600},
+		DebugMode:   pmpb.PublicMetadata_DEBUG_ALL,
+		ProxyLayer:  plpb.ProxyLayer_PROXY_A,
+	}
+	tests := []struct {
+		name   string
+		modify func(f *NewBinaryFields)
+		want   []FieldDelta
+	}{
+		{
+			name:   "equal",
+			modify: func(f *NewBinaryFields) {},
+		},
+		{
+			name:   "geo case differs",
+			modify: func(f *NewBinaryFields) { f.City = "Sunnyvale" },
+		},
+		{
+			name:   "expiration",
+			modify: func(f *NewBinaryFields) { f.Expiration = &tpb.Timestamp{Seconds: 4500} },
+			want:   []FieldDelta{{Field: "expiration", Old: "1970-01-01T01:00:00Z", New: "1970-01-01T01:15:00Z"}},
+		},
+		{
+			name: "geo",
+			modify: func(f *NewBinaryFields) {
+				f.Region = "US-NY"
+				f.City = "NEW YORK CITY"
+			},
+			want: []FieldDelta{
+				{Field: "region", Old: "US-CA", New: "US-NY"},
+				{Field: "city", Old: "SUNNYVALE", New: "NEW YORK CITY"},
+			},
+		},
+		{
+			name:   "version drops proxy layer",
+			modify: func(f *NewBinaryFields) { f.Version = 1 },
+			want:   []FieldDelta{{Field: "proxy_layer", Old: "PROXY_A", New: "PROXY_LAYER_UNSPECIFIED"}},
+		},
+		{
+			name:   "debug mode",
+			modify: func(f *NewBinaryFields) { f.DebugMode = pmpb.PublicMetadata_UNSPECIFIED_DEBUG_MODE },
+			want:   []FieldDelta{{Field: "debug_mode", Old: "DEBUG_ALL", New: "UNSPECIFIED_DEBUG_MODE"}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			a := New(&base)
+			defer a.Free()
+			fields := base
+			tc.modify(&fields)
+			b := New(&fields)
+			defer b.Free()
+			if diff := cmp.Diff(tc.want, Diff(a, b)); diff != "" {
+				t.Errorf("Diff() returned unexpected diff (-want +got):\n%s", diff)
+			}
+			if got, want := Equal(a, b), len(tc.want) == 0; got != want {
+				t.Errorf("Equal() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestDiffExtensions(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(bs *BinaryStruct) error
+		want   []FieldDelta
+	}{
+		{
+			name:   "service tier",
+			modify: func(bs *BinaryStruct) error { return bs.SetServiceTier(ServiceTierPaid) },
+			want:   []FieldDelta{{Field: "service_tier", Old: "", New: "PAID"}},
+		},
+		{
+			name:   "network type",
+			modify: func(bs *BinaryStruct) error { return bs.SetNetworkType(NetworkTypeWiFi) },
+			want:   []FieldDelta{{Field: "network_type", Old: "", New: "WIFI"}},
+		},
+		{
+			name:   "key epoch",
+			modify: func(bs *BinaryStruct) error { return bs.SetKeyEpoch(7) },
+			want:   []FieldDelta{{Field: "key_epoch", Old: "3", New: "7"}},
+		},
+		{
+			name:   "nonce",
+			modify: func(bs *BinaryStruct) error { return bs.SetNonce([NonceSize]byte{1}) },
+			want:   []FieldDelta{{Field: "nonce", Old: "", New: "01000000000000000000000000000000"}},
+		},
+		{
+			name:   "millisecond expiration",
+			modify: func(bs *BinaryStruct) error { return bs.SetExpirationTime(time.Unix(900, 250*int64(time.Millisecond))) },
+			want:   []FieldDelta{{Field: "expiration", Old: "1970-01-01T00:15:00Z", New: "1970-01-01T00:15:00.25Z"}},
+		},
+		{
+			name:   "unknown extension",
+			modify: func(bs *BinaryStruct) error { return bs.SetExtension(0x00AA, []byte{0xab}) },
+			want:   []FieldDelta{{Field: "extension_00aa", Old: "", New: "ab"}},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			a, b := newTestStruct(t, 3), newTestStruct(t, 3)
+			for _, bs := range []*BinaryStruct{a, b} {
+				if err := bs.SetKeyEpoch(3); err != nil {
+					t.Fatalf("SetKeyEpoch failed: %v", err)
+				}
+			}
+			if err := tc.modify(b); err != nil {
+				t.Fatalf("modify failed: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, Diff(a, b)); diff != "" {
+				t.Errorf("Diff() returned unexpected diff (-want +got):\n%s", diff)
+			}
+			if Equal(a, b) {
+				t.Error("Equal() = true, want false")
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+// Package conformance checks the binary metadata encoder and decoder against local regression
+// vectors of the extensions list, so that a change to the wire format is noticed. The vectors were
+// written by hand from the encoding the C++ library produces; they are not the published vectors of
+// the Privacy Pass public metadata drafts, and agreeing with them does not prove conformance to a
+// draft. They are in hex, one extension per space separated group.
+package conformance
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"google3/privacy/net/common/cpp/public_metadata/go/binarymetadata"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	plpb "google3/privacy/net/common/proto/proxy_layer_go_proto"
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+)
+
+// ExtensionsVector is an encoded extensions list. Extensions is nil for input that must be
+// rejected.
+type ExtensionsVector struct {
+	Name       string
+	Encoded    []byte
+	Extensions []binarymetadata.Extension
+}
+
+// MetadataVector is an encoded extensions list carrying public metadata.
+type MetadataVector struct {
+	Name    string
+	Fields  *binarymetadata.NewBinaryFields
+	Encoded []byte
+}
+
+// ExtensionsVectors are the regression vectors of the Extensions structure.
+var ExtensionsVectors = []ExtensionsVector{
+	{Name: "empty_list", Encoded: mustDecode("0000"), Extensions: []binarymetadata.Extension{}},
+	{
+		Name:       "single_empty_value",
+		Encoded:    mustDecode("0004 00010000"),
+		Extensions: []binarymetadata.Extension{{Type: 0x0001, Value: []byte{}}},
+	},
+	{
+		Name:    "two_extensions",
+		Encoded: mustDecode("000b 00010002abcd 00020001ff"),
+		Extensions: []binarymetadata.Extension{
+			{Type: 0x0001, Value: []byte{0xab, 0xcd}},
+			{Type: 0x0002, Value: []byte{0xff}},
+		},
+	},
+	{Name: "short_length", Encoded: mustDecode("00")},
+	{Name: "length_mismatch", Encoded: mustDecode("0005 00010000")},
+	{Name: "truncated_header", Encoded: mustDecode("0002 f001")},
+	{Name: "truncated_value", Encoded: mustDecode("0006 00010003abcd")},
+}
+
+// MetadataVectors are the regression vectors of the public metadata extensions, expiring on
+// 2023-11-27T18:45:00Z.
+var MetadataVectors = []MetadataVector{
+	{
+		Name: "v1_city",
+		Fields: &binarymetadata.NewBinaryFields{
+			Version:     1,
+			Country:     "US",
+			Region:      "US-NY",
+			City:        "NEW YORK CITY",
+			ServiceType: "chromeipblinding",
+			Expiration:  &tpb.Timestamp{Seconds: 1701110700},
+			DebugMode:   pmpb.PublicMetadata_UNSPECIFIED_DEBUG_MODE,
+		},
+		Encoded: mustDecode("003a 000100100000000000000384000000006564e3ac" +
+			" 0002001800165553 2c55532d4e592c4e455720594f524b2043495459 f001000101 f002000100"),
+	},
+	{
+		Name: "v2_city_proxy_a",
+		Fields: &binarymetadata.NewBinaryFields{
+			Version:     2,
+			Country:     "US",
+			Region:      "US-NY",
+			City:        "NEW YORK CITY",
+			ServiceType: "chromeipblinding",
+			Expiration:  &tpb.Timestamp{Seconds: 1701110700},
+			DebugMode:   pmpb.PublicMetadata_UNSPECIFIED_DEBUG_MODE,
+			ProxyLayer:  plpb.ProxyLayer_PROXY_A,
+		},
+		Encoded: mustDecode("003f 000100100000000000000384000000006564e3ac" +
+			" 0002001800165553 2c55532d4e592c4e455720594f524b2043495459 f001000101 f002000100 f003000100"),
+	},
+}
+
+// mustDecode decodes hex, ignoring the spaces that group bytes by extension.
+func mustDecode(s string) []byte {
+	b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// Run checks every vector and returns the failures joined, or nil if the package agrees with them.
+func Run() error {
+	var errs []error
+	for _, v := range ExtensionsVectors {
+		if err := v.check(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", v.Name, err))
+		}
+	}
+	for _, v := range MetadataVectors {
+		if err := v.check(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", v.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (v ExtensionsVector) check() error {
+	got, err := binarymetadata.DecodeExtensions(v.Encoded)
+	if v.Extensions == nil {
+		if err == nil {
+			return fmt.Errorf("DecodeExtensions accepted %x, want error", v.Encoded)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("DecodeExtensions: %w", err)
+	}
+	if !slices.EqualFunc(got, v.Extensions, equalExtensions) {
+		return fmt.Errorf("DecodeExtensions = %v, want %v", got, v.Extensions)
+	}
+	out, err := binarymetadata.EncodeExtensions(v.Extensions)
+	if err != nil {
+		return fmt.Errorf("EncodeExtensions: %w", err)
+	}
+	if !bytes.Equal(out, v.Encoded) {
+		return fmt.Errorf("EncodeExtensions = %x, want %x", out, v.Encoded)
+	}
+	return nil
+}
+
+func (v MetadataVector) check() error {
+	want, err := binarymetadata.NewChecked(v.Fields)
+	if err != nil {
+		return fmt.Errorf("NewChecked: %w", err)
+	}
+	defer want.Free()
+	out, err := binarymetadata.Serialize(want)
+	if err != nil {
+		return fmt.Errorf("Serialize: %w", err)
+	}
+	if !bytes.Equal(out, v.Encoded) {
+		return fmt.Errorf("Serialize = %x, want %x", out, v.Encoded)
+	}
+	got, err := binarymetadata.DeserializeOptions{Strict: true, Canonical: true}.Deserialize(v.Encoded)
+	if err != nil {
+		return fmt.Errorf("Deserialize: %w", err)
+	}
+	defer got.Free()
+	if diff := binarymetadata.Diff(want, got); len(diff) > 0 {
+		return fmt.Errorf("Deserialize differs from the fields: %v", diff)
+	}
+	return nil
+}
+
+func equalExtensions(a, b binarymetadata.Extension) bool {
+	return a.Type == b.Type && bytes.Equal(a.Value, b.Value)
+}
@@ -0,0 +1,25 @@
+package conformance
+
+import (
+	"testing"
+
+	"google3/privacy/net/common/cpp/public_metadata/go/binarymetadata"
+)
+
+func TestRun(t *testing.T) {
+	if err := Run(); err != nil {
+		t.Errorf("Run() failed:\n%v", err)
+	}
+}
+
+func TestRunReportsFailures(t *testing.T) {
+	old := ExtensionsVectors
+	t.Cleanup(func() { ExtensionsVectors = old })
+	ExtensionsVectors = []ExtensionsVector{
+		{Name: "wrong_value", Encoded: mustDecode("0005 f0010001 ab"), Extensions: []binarymetadata.Extension{{Type: 0xF001, Value: []byte{0xcd}}}},
+		{Name: "accepted_malformed", Encoded: mustDecode("0000")},
+	}
+	if err := Run(); err == nil {
+		t.Error("Run() succeeded with failing vectors, want error")
+	}
+}
@@ -0,0 +1,45 @@
+package binarymetadata
+
+import (
+	"context"
+	"time"
+)
+
+// The Context variants below check ctx before crossing into C++ so that work for a request that
+// has already been cancelled or has passed its deadline is skipped. The C++ call itself cannot be
+// interrupted once started. When a Tracer is registered with SetTracer, each call that reaches C++
+// is wrapped in a span.
+
+// SerializeContext is like Serialize but fails with ctx.Err() if ctx is already done.
+func SerializeContext(ctx context.Context, bs *BinaryStruct) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	span := startSpan(ctx, OperationSerialize)
+	out, err := Serialize(bs)
+	endSpan(span, bs, out, err)
+	return out, err
+}
+
+// DeserializeContext is like Deserialize but fails with ctx.Err() if ctx is already done.
+func DeserializeContext(ctx context.Context, in []byte) (*BinaryStruct, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	span := startSpan(ctx, OperationDeserialize)
+	bs, err := Deserialize(in)
+	endSpan(span, bs, in, err)
+	return bs, err
+}
+
+// ValidateMetadataCardinalityContext is like ValidateMetadataCardinality but fails with ctx.Err()
+// if ctx is already done.
+func ValidateMetadataCardinalityContext(ctx context.Context, in []byte, t time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	span := startSpan(ctx, OperationValidateMetadataCardinality)
+	err := ValidateMetadataCardinality(in, t)
+	endSpan(span, nil, in, err)
+	return err
+}
@@ -0,0 +1,45 @@
+package binarymetadata
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+)
+
+func TestContextVariants(t *testing.T) {
+	bs := New(&NewBinaryFields{
+		Version:     1,
+		Country:     "US",
+		ServiceType: "chromeipblinding",
+		Expiration:  tpb.New(time.Now().Add(time.Hour).Truncate(expirationGranularity)),
+	})
+	defer bs.Free()
+	ctx := context.Background()
+	serialized, err := SerializeContext(ctx, bs)
+	if err != nil {
+		t.Fatalf("SerializeContext failed: %v", err)
+	}
+	deserialized, err := DeserializeContext(ctx, serialized)
+	if err != nil {
+		t.Fatalf("DeserializeContext failed: %v", err)
+	}
+	deserialized.Free()
+	if err := ValidateMetadataCardinalityContext(ctx, serialized, time.Now()); err != nil {
+		t.Errorf("ValidateMetadataCardinalityContext failed: %v", err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := SerializeContext(cancelled, bs); !errors.Is(err, context.Canceled) {
+		t.Errorf("SerializeContext() returned error: %v, want error: %v", err, context.Canceled)
+	}
+	if _, err := DeserializeContext(cancelled, serialized); !errors.Is(err, context.Canceled) {
+		t.Errorf("DeserializeContext() returned error: %v, want error: %v", err, context.Canceled)
+	}
+	if err := ValidateMetadataCardinalityContext(cancelled, serialized, time.Now()); !errors.Is(err, context.Canceled) {
+		t.Errorf("ValidateMetadataCardinalityContext() returned error: %v, want error: %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,54 @@
+package binarymetadata
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizeCountry(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr error
+	}{
+		{in: "US", want: "US"},
+		{in: " gb ", want: "GB"},
+		{in: "DEU", want: "DE"},
+		{in: "usa", want: "US"},
+		{in: "XX", wantErr: ErrInvalidCountry},
+		{in: "UK", wantErr: ErrInvalidCountry},
+		{in: "", wantErr: ErrInvalidCountry},
+	}
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := NormalizeCountry(tc.in)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("NormalizeCountry(%q) returned error: %v, want error: %v", tc.in, err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("NormalizeCountry(%q) = %q, want %q", tc.in, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidateCountry(t *testing.T) {
+	tests := []struct {
+		country string
+		wantErr error
+	}{
+		{country: "US"},
+		{country: "us", wantErr: ErrInvalidCountry},
+		{country: "ZZ", wantErr: ErrInvalidCountry},
+		{country: "", wantErr: ErrMissingField},
+	}
+	for _, tc := range tests {
+		t.Run(tc.country, func(t *testing.T) {
+			bs := New(&NewBinaryFields{Country: tc.country})
+			defer bs.Free()
+			if err := bs.ValidateCountry(); !errors.Is(err, tc.wantErr) {
+				t.Errorf("ValidateCountry() returned error: %v, want error: %v", err, tc.wantErr)
+			}
+		})
+	}
+}
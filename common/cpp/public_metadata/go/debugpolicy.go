@@ -0,0 +1,80 @@
+package binarymetadata
+
+import (
+	"fmt"
+	"slices"
+	"sync/atomic"
+
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+)
+
+// DebugModeAuthorizer decides whether metadata may be created with the DEBUG_ALL debug mode.
+// Implementations must be safe for concurrent use.
+type DebugModeAuthorizer interface {
+	// AuthorizeDebugMode returns nil if f may carry DEBUG_ALL. It is only called for fields with
+	// that debug mode.
+	AuthorizeDebugMode(f *NewBinaryFields) error
+}
+
+// DebugModeCapability is an explicit grant to create DEBUG_ALL metadata, passed in
+// NewBinaryFields.DebugModeCapability. It can only be obtained from GrantDebugMode, so that every
+// place granting it is easy to find.
+type DebugModeCapability struct {
+	reason string
+}
+
+// GrantDebugMode returns a capability to create DEBUG_ALL metadata. reason explains the grant and
+// should name the tool or test that needs it.
+func GrantDebugMode(reason string) *DebugModeCapability {
+	return &DebugModeCapability{reason: reason}
+}
+
+// Reason returns the reason passed to GrantDebugMode.
+func (c *DebugModeCapability) Reason() string {
+	return c.reason
+}
+
+// DebugModeAllowlist is a DebugModeAuthorizer accepting DEBUG_ALL for the listed service types
+// and for fields carrying a DebugModeCapability.
+type DebugModeAllowlist struct {
+	ServiceTypes []string
+}
+
+// AuthorizeDebugMode implements DebugModeAuthorizer.
+func (a DebugModeAllowlist) AuthorizeDebugMode(f *NewBinaryFields) error {
+	if f.DebugModeCapability != nil || slices.Contains(a.ServiceTypes, f.ServiceType) {
+		return nil
+	}
+	return fmt.Errorf("%w: service type %q may not use DEBUG_ALL", ErrInvalidDebugMode, f.ServiceType)
+}
+
+// debugModeAuthorizerHolder wraps a DebugModeAuthorizer so that it can be stored in an
+// atomic.Pointer.
+type debugModeAuthorizerHolder struct {
+	a DebugModeAuthorizer
+}
+
+var registeredDebugModeAuthorizer atomic.Pointer[debugModeAuthorizerHolder]
+
+// SetDebugModeAuthorizer registers a to restrict DEBUG_ALL in NewChecked, Builder.Build and
+// BinaryStruct.SetDebugMode, replacing any earlier registration. New does not consult it. It is
+// meant to be called once during process start up; nil lifts the restriction.
+func SetDebugModeAuthorizer(a DebugModeAuthorizer) {
+	if a == nil {
+		registeredDebugModeAuthorizer.Store(nil)
+		return
+	}
+	registeredDebugModeAuthorizer.Store(&debugModeAuthorizerHolder{a: a})
+}
+
+// authorizeDebugMode checks f against the registered DebugModeAuthorizer.
+func authorizeDebugMode(f *NewBinaryFields) error {
+	if f.DebugMode != pmpb.PublicMetadata_DEBUG_ALL {
+		return nil
+	}
+	h := registeredDebugModeAuthorizer.Load()
+	if h == nil {
+		return nil
+	}
+	return h.a.AuthorizeDebugMode(f)
+}
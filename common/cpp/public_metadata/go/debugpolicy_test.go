@@ -0,0 +1,71 @@
+package binarymetadata
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+)
+
+func TestDebugModeAllowlist(t *testing.T) {
+	SetDebugModeAuthorizer(DebugModeAllowlist{ServiceTypes: []string{ServiceTypeChromeIPBlinding}})
+	t.Cleanup(func() { SetDebugModeAuthorizer(nil) })
+	debug := func(service string) *NewBinaryFields {
+		return &NewBinaryFields{Version: 2, Country: "US", ServiceType: service, Expiration: &tpb.Timestamp{Seconds: 900}, DebugMode: pmpb.PublicMetadata_DEBUG_ALL}
+	}
+
+	bs, err := NewChecked(debug(ServiceTypeChromeIPBlinding))
+	if err != nil {
+		t.Errorf("NewChecked(allowlisted) failed: %v", err)
+	} else {
+		bs.Free()
+	}
+	if _, err := NewChecked(debug(ServiceTypeCronet)); !errors.Is(err, ErrInvalidDebugMode) {
+		t.Errorf("NewChecked(not allowlisted) returned error: %v, want error: %v", err, ErrInvalidDebugMode)
+	}
+	f := debug(ServiceTypeCronet)
+	f.DebugModeCapability = GrantDebugMode("test")
+	if bs, err := NewChecked(f); err != nil {
+		t.Errorf("NewChecked(capability) failed: %v", err)
+	} else {
+		bs.Free()
+	}
+	if _, err := NewBuilder().ServiceType(ServiceTypeCronet).Country("US").Expiration(time.Unix(900, 0)).DebugMode(pmpb.PublicMetadata_DEBUG_ALL).Build(); !errors.Is(err, ErrInvalidDebugMode) {
+		t.Errorf("Build(not allowlisted) returned error: %v, want error: %v", err, ErrInvalidDebugMode)
+	}
+
+	prod := New(&NewBinaryFields{Version: 2, Country: "US", ServiceType: ServiceTypeCronet})
+	defer prod.Free()
+	if err := prod.SetDebugMode(pmpb.PublicMetadata_DEBUG_ALL); !errors.Is(err, ErrInvalidDebugMode) {
+		t.Errorf("SetDebugMode(DEBUG_ALL) returned error: %v, want error: %v", err, ErrInvalidDebugMode)
+	}
+	if got := prod.GetDebugMode(); got != pmpb.PublicMetadata_UNSPECIFIED_DEBUG_MODE {
+		t.Errorf("GetDebugMode() after rejected SetDebugMode = %v, want %v", got, pmpb.PublicMetadata_UNSPECIFIED_DEBUG_MODE)
+	}
+	if err := prod.SetDebugMode(pmpb.PublicMetadata_UNSPECIFIED_DEBUG_MODE); err != nil {
+		t.Errorf("SetDebugMode(UNSPECIFIED) failed: %v", err)
+	}
+}
+
+func TestValidatorDebugModeUntil(t *testing.T) {
+	cutoff := time.Unix(1000, 0)
+	v, err := NewValidator(ValidationConfig{DebugModeUntil: cutoff})
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+	in := serializeForTest(t, &NewBinaryFields{
+		Version:     1,
+		Country:     "US",
+		ServiceType: ServiceTypeChromeIPBlinding,
+		Expiration:  &tpb.Timestamp{Seconds: 1800},
+		DebugMode:   pmpb.PublicMetadata_DEBUG_ALL,
+	})
+	if err := v.Validate(in, cutoff); err != nil {
+		t.Errorf("Validate() at the cutoff returned error: %v", err)
+	}
+	if err := v.Validate(in, cutoff.Add(time.Second)); !errors.Is(err, ErrInvalidDebugMode) {
+		t.Errorf("Validate() after the cutoff returned error: %v, want error: %v", err, ErrInvalidDebugMode)
+	}
+}
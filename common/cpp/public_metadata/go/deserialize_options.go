@@ -0,0 +1,211 @@
+package binarymetadata
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// DeserializeOptions configures Deserialize.
+type DeserializeOptions struct {
+	// Strict rejects input that the default, lenient mode accepts: extensions of unknown types,
+	// extensions out of ascending type order or repeated, enum values without a mapping, versions
+	// this package does not support, and bytes after the extensions list. Redemption servers should
+	// deserialize strictly; debugging tools can keep the lenient default to inspect as much of a
+	// blob as possible.
+	Strict bool
+	// AllowNewerVersions makes lenient mode accept a well-formed extensions list that the C++
+	// library rejects, on the assumption that it was produced by a newer version. The first
+	// extension of each type that decodes sets its field, or is kept as is for types without one;
+	// repeated extensions and known ones that do not decode are dropped, so that Serialize emits a
+	// list the C++ library can read back. The result reports FromNewerVersion. It has no effect in
+	// strict mode.
+	AllowNewerVersions bool
+	// Canonical rejects input that is not in the canonical encoding; see IsCanonical. It applies in
+	// both modes and is checked after the size limits.
+	Canonical bool
+	// MaxInputSize caps the length of the input, which is rejected with ErrTooLarge before it is
+	// copied into C++ memory. Zero or values above MaxSerializedSize mean MaxSerializedSize, which
+	// applies in any case.
+	MaxInputSize int
+	// MaxExtensions caps the number of extensions in the input. Zero means no limit.
+	MaxExtensions int
+	// MaxExtensionLength caps the length of the value of each extension in the input. Zero means no
+	// limit.
+	MaxExtensionLength int
+	// WireFormat is the draft revision in is encoded in. The size limits are checked on in, which is
+	// then converted into WireFormatCurrent before the other options, including the size limits
+	// again, apply.
+	WireFormat WireFormat
+	// Arena, if set, allocates the result, which is then freed by Arena.Release at the latest.
+	Arena *Arena
+	// AuditTag is the NewBinaryFields.AuditTag of the result.
+	AuditTag string
+}
+
+// Deserialize is like the package level Deserialize, which equals DeserializeOptions{}.Deserialize.
+func (o DeserializeOptions) Deserialize(in []byte) (*BinaryStruct, error) {
+	start := time.Now()
+	bs, err := o.deserialize(in)
+	record(OperationDeserialize, start, err)
+	if err != nil {
+		auditBlob(AuditConstruct, "deserialize", o.AuditTag, in, err)
+		return nil, err
+	}
+	bs.auditTag = o.AuditTag
+	bs.audit(AuditConstruct, "deserialize", nil)
+	return bs, nil
+}
+
+func (o DeserializeOptions) deserialize(in []byte) (*BinaryStruct, error) {
+	bs, err := o.deserializeFields(in)
+	if err != nil {
+		return nil, err
+	}
+	if err := bs.checkTextFields(); err != nil {
+		bs.Free()
+		return nil, err
+	}
+	return bs, nil
+}
+
+func (o DeserializeOptions) deserializeFields(in []byte) (*BinaryStruct, error) {
+	if err := o.checkBounds(in); err != nil {
+		return nil, err
+	}
+	if o.WireFormat != WireFormatCurrent {
+		var err error
+		if in, err = decodeWireFormat(in, o.WireFormat); err != nil {
+			return nil, err
+		}
+		if err := o.checkBounds(in); err != nil {
+			return nil, err
+		}
+	}
+	if o.Canonical && !IsCanonical(in) {
+		return nil, fmt.Errorf("%w: not in the canonical encoding", ErrMalformed)
+	}
+	if !o.Strict {
+		bs, err := deserialize(in, o.Arena)
+		if err != nil && o.AllowNewerVersions {
+			if newer, ok := deserializeNewer(in, o.Arena); ok {
+				return newer, nil
+			}
+		}
+		return bs, err
+	}
+	if err := checkStrict(in); err != nil {
+		return nil, err
+	}
+	bs, err := deserialize(in, o.Arena)
+	if err != nil {
+		return nil, err
+	}
+	c, err := Capabilities(int32(bs.md().GetVersion()))
+	if err == nil {
+		err = bs.checkExpirationMillis(c)
+	}
+	if err == nil {
+		err = bs.checkCoarse(c)
+	}
+	if err == nil {
+		err = bs.checkKeyEpoch(c)
+	}
+	if err == nil {
+		err = bs.checkNonce(c)
+	}
+	if err != nil {
+		bs.Free()
+		return nil, err
+	}
+	return bs, nil
+}
+
+// checkStrict decodes every extension of in with its typed decoder, so that values the C++
+// library would map to a default are reported instead. Extensions must be in ascending type order
+// without repeats.
+func checkStrict(in []byte) error {
+	exts, err := DecodeExtensions(in)
+	if err != nil {
+		return err
+	}
+	if err := checkExtensionOrder(exts); err != nil {
+		return err
+	}
+	for _, e := range exts {
+		switch e.Type {
+		case ExtensionTypeExpirationTimestamp:
+			_, err = ExpirationExtensionFromExtension(e)
+		case ExtensionTypeGeoHint:
+			_, err = GeoHintExtensionFromExtension(e)
+		case ExtensionTypeServiceType:
+			_, err = ServiceTypeExtensionFromExtension(e)
+		case ExtensionTypeDebugMode:
+			_, err = DebugModeExtensionFromExtension(e)
+		case ExtensionTypeProxyLayer:
+			_, err = ProxyLayerExtensionFromExtension(e)
+		case ExtensionTypeExpirationMillis:
+			_, err = ExpirationMillisExtensionFromExtension(e)
+		case ExtensionTypeNetworkType:
+			_, err = NetworkTypeExtensionFromExtension(e)
+		case ExtensionTypeClientPlatform:
+			_, err = ClientPlatformExtensionFromExtension(e)
+		case ExtensionTypeServiceTier:
+			_, err = ServiceTierExtensionFromExtension(e)
+		case ExtensionTypeAttestationLevel:
+			_, err = AttestationLevelExtensionFromExtension(e)
+		case ExtensionTypeKeyEpoch:
+			_, err = KeyEpochExtensionFromExtension(e)
+		case ExtensionTypeNonce:
+			_, err = NonceExtensionFromExtension(e)
+		case ExtensionTypeVersion:
+			_, err = VersionExtensionFromExtension(e)
+		default:
+			err = fmt.Errorf("%w: unknown extension type %#04x", ErrMalformed, e.Type)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deserializeNewer decodes in field by field for AllowNewerVersions. It reports false if in is not
+// a well-formed extensions list.
+func deserializeNewer(in []byte, a *Arena) (*BinaryStruct, bool) {
+	exts, err := DecodeExtensions(in)
+	if err != nil {
+		return nil, false
+	}
+	bs, err := a.newStruct()
+	if err != nil {
+		return nil, false
+	}
+	bs.newer = true
+	bs.metadata.SetVersion(uint(MaxKnownVersion()))
+	seen := map[uint16]bool{}
+	for _, e := range exts {
+		if seen[e.Type] {
+			continue
+		}
+		if isKnownExtensionType(e.Type) {
+			if bs.setExtension(e.Type, e.Value) != nil {
+				continue
+			}
+		} else {
+			bs.extra = append(bs.extra, Extension{Type: e.Type, Value: bytes.Clone(e.Value)})
+		}
+		seen[e.Type] = true
+	}
+	slices.SortStableFunc(bs.extra, compareExtensionTypes)
+	return bs, true
+}
+
+// FromNewerVersion reports whether bs was deserialized with AllowNewerVersions from metadata of a
+// version newer than MaxKnownVersion. Its version is then reported as MaxKnownVersion.
+func (bs *BinaryStruct) FromNewerVersion() bool {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+	return bs.newer
+}
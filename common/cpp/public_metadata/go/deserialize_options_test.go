@@ -0,0 +1,191 @@
+package binarymetadata
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	plpb "google3/privacy/net/common/proto/proxy_layer_go_proto"
+)
+
+func TestDeserializeOptionsStrict(t *testing.T) {
+	bs := New(&NewBinaryFields{
+		Version:     2,
+		Country:     "US",
+		ServiceType: "chromeipblinding",
+		Expiration:  &tpb.Timestamp{Seconds: 900},
+		ProxyLayer:  plpb.ProxyLayer_PROXY_A,
+	})
+	valid, err := Serialize(bs)
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	if err := bs.SetExtension(0xF0FF, []byte{0x01}); err != nil {
+		t.Fatalf("SetExtension failed: %v", err)
+	}
+	withUnknown, err := Serialize(bs)
+	bs.Free()
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		in   []byte
+		// wantErr is the strict mode error. Lenient mode accepts all of these inputs.
+		wantErr error
+	}{
+		{name: "valid", in: valid},
+		{name: "unknown extension", in: withUnknown, wantErr: ErrMalformed},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			lenient, err := Deserialize(tc.in)
+			if err != nil {
+				t.Fatalf("lenient Deserialize failed: %v", err)
+			}
+			lenient.Free()
+			strict, err := DeserializeOptions{Strict: true}.Deserialize(tc.in)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("strict Deserialize returned error: %v, want error: %v", err, tc.wantErr)
+			}
+			if err == nil {
+				strict.Free()
+			}
+		})
+	}
+}
+
+func TestDeserializeOptionsStrictTrailingBytes(t *testing.T) {
+	bs := New(&NewBinaryFields{Version: 1, Country: "US", ServiceType: "chromeipblinding", Expiration: &tpb.Timestamp{Seconds: 900}})
+	defer bs.Free()
+	valid, err := Serialize(bs)
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	in := append(bytes.Clone(valid), 0x00)
+	if _, err := (DeserializeOptions{Strict: true}).Deserialize(in); !errors.Is(err, ErrMalformed) {
+		t.Errorf("strict Deserialize(trailing bytes) returned error: %v, want error: %v", err, ErrMalformed)
+	}
+}
+
+func TestDeserializeOptionsAllowNewerVersions(t *testing.T) {
+	bs := New(&NewBinaryFields{
+		Version:     2,
+		Country:     "US",
+		ServiceType: "chromeipblinding",
+		Expiration:  &tpb.Timestamp{Seconds: 900},
+		ProxyLayer:  plpb.ProxyLayer_PROXY_A,
+	})
+	valid, err := Serialize(bs)
+	bs.Free()
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	exts, err := DecodeExtensions(valid)
+	if err != nil {
+		t.Fatalf("DecodeExtensions failed: %v", err)
+	}
+	extraGeo, err := GeoHintExtension{CountryCode: "CA", Region: "CA-ON"}.AsExtension()
+	if err != nil {
+		t.Fatal(err)
+	}
+	newer, err := EncodeExtensions(append(exts[:len(exts):len(exts)], extraGeo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxyC := append([]Extension(nil), exts...)
+	proxyC[4] = Extension{Type: ExtensionTypeProxyLayer, Value: []byte{0x02}}
+	newerProxy, err := EncodeExtensions(proxyC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name        string
+		in          []byte
+		wantDropped Extension
+	}{
+		{name: "repeated extension", in: newer, wantDropped: extraGeo},
+		{name: "unknown proxy layer", in: newerProxy, wantDropped: proxyC[4]},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Deserialize(tc.in); err == nil {
+				t.Fatal("Deserialize succeeded, want error without AllowNewerVersions")
+			}
+			got, err := DeserializeOptions{AllowNewerVersions: true}.Deserialize(tc.in)
+			if err != nil {
+				t.Fatalf("Deserialize failed: %v", err)
+			}
+			defer got.Free()
+			if !got.FromNewerVersion() || got.GetVersion() != MaxKnownVersion() {
+				t.Errorf("FromNewerVersion() = %v, GetVersion() = %d, want true, %d", got.FromNewerVersion(), got.GetVersion(), MaxKnownVersion())
+			}
+			if got.GetGeoHint().Country != "US" || got.GetExpiration().GetSeconds() != 900 {
+				t.Errorf("Deserialize() = %s, want the known fields of the first extensions", got.DebugString())
+			}
+			out, err := Serialize(got)
+			if err != nil {
+				t.Fatalf("Serialize failed: %v", err)
+			}
+			outExts, err := DecodeExtensions(out)
+			if err != nil {
+				t.Fatalf("DecodeExtensions failed: %v", err)
+			}
+			if slices.ContainsFunc(outExts, func(e Extension) bool {
+				return e.Type == tc.wantDropped.Type && bytes.Equal(e.Value, tc.wantDropped.Value)
+			}) {
+				t.Errorf("serialized extensions = %+v, want %+v dropped", outExts, tc.wantDropped)
+			}
+			if !IsCanonical(out) {
+				t.Errorf("IsCanonical(Serialize()) = false, want true for %x", out)
+			}
+			again, err := Deserialize(out)
+			if err != nil {
+				t.Fatalf("Deserialize(Serialize()) failed: %v", err)
+			}
+			again.Free()
+		})
+	}
+
+	if _, err := (DeserializeOptions{AllowNewerVersions: true}).Deserialize([]byte{0x00}); err == nil {
+		t.Error("Deserialize(truncated) succeeded, want error")
+	}
+}
+
+func TestDeserializeOptionsStrictExtensionOrder(t *testing.T) {
+	bs := New(&NewBinaryFields{Version: 2, Country: "US", ServiceType: "chromeipblinding", Expiration: &tpb.Timestamp{Seconds: 900}, ProxyLayer: plpb.ProxyLayer_PROXY_A})
+	defer bs.Free()
+	if err := bs.SetExtension(0xF0FF, []byte{0x01}); err != nil {
+		t.Fatalf("SetExtension failed: %v", err)
+	}
+	valid, err := Serialize(bs)
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	exts, err := DecodeExtensions(valid)
+	if err != nil {
+		t.Fatalf("DecodeExtensions failed: %v", err)
+	}
+	if !slices.IsSortedFunc(exts, compareExtensionTypes) {
+		t.Errorf("Serialize() wrote extension types out of order: %v", exts)
+	}
+
+	swapped := slices.Clone(exts)
+	swapped[0], swapped[1] = swapped[1], swapped[0]
+	repeated := append(slices.Clone(exts[:2]), exts[1:]...)
+	for name, crafted := range map[string][]Extension{"swapped": swapped, "repeated": repeated} {
+		in, err := EncodeExtensions(crafted)
+		if err != nil {
+			t.Fatalf("EncodeExtensions failed: %v", err)
+		}
+		if _, err := (DeserializeOptions{Strict: true}).Deserialize(in); !errors.Is(err, ErrMalformed) {
+			t.Errorf("strict Deserialize(%s) returned error: %v, want error: %v", name, err, ErrMalformed)
+		}
+		if IsCanonical(in) {
+			t.Errorf("IsCanonical(%s) = true, want false", name)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package binarymetadata
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+)
+
+// Stages at which the C++ and the pure Go codepaths can diverge.
+const (
+	StageDeserialize = "deserialize"
+	StageSerialize   = "serialize"
+	StageValidate    = "validate"
+)
+
+// Divergence is a difference between the results of the C++ wrapper and of the pure Go codepath
+// for the same input.
+type Divergence struct {
+	// Stage is the operation whose results differ, one of the Stage constants.
+	Stage string
+	// CPP and Go describe the result of each codepath: the error it returned, or what it produced.
+	CPP, Go string
+}
+
+func (d Divergence) String() string {
+	return fmt.Sprintf("%s: C++ %s, Go %s", d.Stage, d.CPP, d.Go)
+}
+
+// CheckConsistency runs in through Deserialize and DeserializeGo and reports every divergence
+// between them: whether in is accepted, the fields it decodes to, and, for input both accept, the
+// bytes those fields serialize to and, if v is not nil, the verdict of v at t. It returns nil if
+// the codepaths agree.
+//
+// Errors only diverge when one codepath fails and the other does not; their messages are not
+// compared. CheckConsistency is meant for canaries and tests that build confidence in either
+// codepath, not for request handling.
+func CheckConsistency(in []byte, v *Validator, t time.Time) []Divergence {
+	bs, cppErr := Deserialize(in)
+	if bs != nil {
+		defer bs.Free()
+	}
+	fields, goErr := DeserializeGo(in)
+	if cppErr != nil || goErr != nil {
+		if (cppErr == nil) != (goErr == nil) {
+			return []Divergence{{Stage: StageDeserialize, CPP: describeResult("", cppErr), Go: describeResult("", goErr)}}
+		}
+		return nil
+	}
+	bs.mu.RLock()
+	cppFields := bs.fields()
+	bs.mu.RUnlock()
+	cppJSON, _ := cppFields.MarshalJSON()
+	goJSON, _ := fields.MarshalJSON()
+	if !bytes.Equal(cppJSON, goJSON) {
+		return []Divergence{{Stage: StageDeserialize, CPP: string(cppJSON), Go: string(goJSON)}}
+	}
+	ds := CheckFieldsConsistency(fields)
+	if v != nil {
+		goBS := New(fields)
+		defer goBS.Free()
+		cppErr, goErr := v.ValidateStruct(bs, t), v.ValidateStruct(goBS, t)
+		if (cppErr == nil) != (goErr == nil) {
+			ds = append(ds, Divergence{Stage: StageValidate, CPP: describeResult("valid", cppErr), Go: describeResult("valid", goErr)})
+		}
+	}
+	return ds
+}
+
+// CheckFieldsConsistency runs fields through Serialize(New(fields)) and SerializeGo and reports a
+// divergence if one fails and the other does not, or if they produce different bytes.
+func CheckFieldsConsistency(fields *NewBinaryFields) []Divergence {
+	bs := New(fields)
+	defer bs.Free()
+	cppOut, cppErr := Serialize(bs)
+	goOut, goErr := SerializeGo(fields)
+	if (cppErr == nil) != (goErr == nil) || !bytes.Equal(cppOut, goOut) {
+		return []Divergence{{Stage: StageSerialize, CPP: describeResult(fmt.Sprintf("%x", cppOut), cppErr), Go: describeResult(fmt.Sprintf("%x", goOut), goErr)}}
+	}
+	return nil
+}
+
+// describeResult returns ok, or the error if there is one.
+func describeResult(ok string, err error) string {
+	if err != nil {
+		return fmt.Sprintf("error %q", err)
+	}
+	if ok == "" {
+		return "ok"
+	}
+	return ok
+}
@@ -0,0 +1,47 @@
+package binarymetadata
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckConsistency(t *testing.T) {
+	exts := canonicalTestExtensions(t, GeoHintExtension{CountryCode: "US", Region: "US-CA", City: "SUNNYVALE"})
+	proxyB, err := ProxyLayerExtension{Layer: ProxyLayerWireB}.AsExtension()
+	if err != nil {
+		t.Fatal(err)
+	}
+	precision, err := ExpirationExtension{TimestampPrecision: 60, Timestamp: 3600}.AsExtension()
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs := map[string][]byte{
+		"v1":                encodeForTest(t, exts...),
+		"v2":                encodeForTest(t, append(exts[:4:4], proxyB)...),
+		"unknown extension": encodeForTest(t, append(exts[:4:4], Extension{Type: 0xF0FF, Value: []byte{0x01}})...),
+		"lower case geo":    encodeForTest(t, canonicalTestExtensions(t, GeoHintExtension{CountryCode: "us"})...),
+		"too few":           encodeForTest(t, exts[:3]...),
+		"out of order":      encodeForTest(t, exts[1], exts[0], exts[2], exts[3]),
+		"precision":         encodeForTest(t, precision, exts[1], exts[2], exts[3]),
+		"debug mode":        encodeForTest(t, exts[0], exts[1], exts[2], Extension{Type: ExtensionTypeDebugMode, Value: []byte{0x02}}),
+		"truncated":         {0x00, 0x05, 0x00},
+		"empty":             nil,
+	}
+	v, err := NewValidator(ValidationConfig{})
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+	for name, in := range inputs {
+		if ds := CheckConsistency(in, v, time.Unix(0, 0)); len(ds) != 0 {
+			t.Errorf("CheckConsistency(%s) = %v, want no divergences", name, ds)
+		}
+	}
+}
+
+func TestCheckFieldsConsistency(t *testing.T) {
+	for _, fields := range pureGoTestFields() {
+		if ds := CheckFieldsConsistency(fields); len(ds) != 0 {
+			t.Errorf("CheckFieldsConsistency(%+v) = %v, want no divergences", fields, ds)
+		}
+	}
+}
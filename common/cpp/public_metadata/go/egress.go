@@ -0,0 +1,48 @@
+package binarymetadata
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	bepb "google3/privacy/net/common/proto/beryllium_go_proto"
+	kspb "google3/privacy/net/common/proto/key_services_go_proto"
+)
+
+// UnblindedToken is a token signed through AuthAndSign and unblinded by the client.
+type UnblindedToken struct {
+	// Message is the plaintext message of the token.
+	Message []byte
+	// MessageMask is the mask that keys with AT_MESSAGE_MASK_CONCAT prepend to Message before it
+	// is signed. It is empty for keys without a mask.
+	MessageMask []byte
+	// Signature is the unblinded signature.
+	Signature []byte
+}
+
+// NewAddEgressRequest returns the AddEgressRequest with which the client spends tok on the
+// dataplane front end, carrying metadata, the serialized metadata tok was issued over, and ppn,
+// the dataplane parameters of the session. It first checks that tok is signed with key over
+// metadata that has not expired, as the front end will, so that a load generator sending
+// mismatched pairs fails here with ErrInvalidSignature instead of on the server.
+func NewAddEgressRequest(key *PublicKey, tok UnblindedToken, metadata []byte, ppn *bepb.PpnDataplaneRequest) (*bepb.AddEgressRequest, error) {
+	if key == nil {
+		return nil, &FieldError{Field: "key", Err: ErrMissingField}
+	}
+	if len(tok.Message) == 0 {
+		return nil, &FieldError{Field: "unblinded_token", Err: ErrMissingField}
+	}
+	message := append(append([]byte(nil), tok.MessageMask...), tok.Message...)
+	v := &RedemptionVerifier{PublicKey: key.RSA}
+	if err := v.Verify(RedeemedToken{Message: message, Signature: tok.Signature}, metadata); err != nil {
+		return nil, fmt.Errorf("token is not bound to its metadata: %w", err)
+	}
+	return &bepb.AddEgressRequest{
+		UnblindedToken:          string(tok.Message),
+		UnblindedTokenSignature: base64.StdEncoding.EncodeToString(tok.Signature),
+		Ppn:                     ppn,
+		SigningKeyVersion:       uint64(key.Version),
+		KeyType:                 kspb.KeyType_AT_PUBLIC_METADATA_KEY_TYPE,
+		MessageMask:             tok.MessageMask,
+		BinaryPublicMetadata:    metadata,
+	}, nil
+}
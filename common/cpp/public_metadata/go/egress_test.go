@@ -0,0 +1,66 @@
+package binarymetadata
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	bepb "google3/privacy/net/common/proto/beryllium_go_proto"
+)
+
+func TestNewAddEgressRequest(t *testing.T) {
+	expiration := time.Now().Add(time.Hour).Truncate(15 * time.Minute)
+	bs := newHeaderTestStruct(t, expiration)
+	metadata, err := Serialize(bs)
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	mask := []byte("0123456789abcdef0123456789abcdef")
+	message := []byte("blind:0123456789abcdef0123456789")
+	var key *rsa.PrivateKey
+	var sig []byte
+	for ok := false; !ok; {
+		if key, err = rsa.GenerateKey(rand.Reader, 1024); err != nil {
+			t.Fatalf("GenerateKey failed: %v", err)
+		}
+		sig, ok = signForTest(t, key, append(append([]byte(nil), mask...), message...), metadata)
+	}
+	pk := &PublicKey{Version: 4, RSA: &key.PublicKey}
+	tok := UnblindedToken{Message: message, MessageMask: mask, Signature: sig}
+	ppn := &bepb.PpnDataplaneRequest{ControlPlaneSockAddr: "192.0.2.1:443"}
+
+	req, err := NewAddEgressRequest(pk, tok, metadata, ppn)
+	if err != nil {
+		t.Fatalf("NewAddEgressRequest failed: %v", err)
+	}
+	if req.GetUnblindedToken() != string(message) || req.GetUnblindedTokenSignature() != base64.StdEncoding.EncodeToString(sig) ||
+		string(req.GetBinaryPublicMetadata()) != string(metadata) || req.GetSigningKeyVersion() != 4 || req.GetPpn() != ppn {
+		t.Errorf("NewAddEgressRequest() = %v, want the token, its signature and metadata, key 4 and ppn", req)
+	}
+
+	other, err := Serialize(newHeaderTestStruct(t, expiration.Add(15*time.Minute)))
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	unmasked := tok
+	unmasked.MessageMask = nil
+	for _, tc := range []struct {
+		name     string
+		tok      UnblindedToken
+		metadata []byte
+		want     error
+	}{
+		{name: "other_metadata", tok: tok, metadata: other, want: ErrInvalidSignature},
+		{name: "missing_mask", tok: unmasked, metadata: metadata, want: ErrInvalidSignature},
+		{name: "no_message", tok: UnblindedToken{Signature: sig}, metadata: metadata, want: ErrMissingField},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewAddEgressRequest(pk, tc.tok, tc.metadata, ppn); !errors.Is(err, tc.want) {
+				t.Errorf("NewAddEgressRequest() = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package binarymetadata
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Encoding is a text encoding for serialized metadata, for carrying it in HTTP headers and JSON.
+type Encoding int
+
+// Encodings supported by EncodeToString and DecodeString.
+const (
+	// EncodingBase64 is padded standard base64, RFC 4648 section 4.
+	EncodingBase64 Encoding = iota
+	// EncodingBase64URL is unpadded URL-safe base64, RFC 4648 section 5.
+	EncodingBase64URL
+	// EncodingHex is lower-case hex.
+	EncodingHex
+)
+
+// encodingPrefixes holds the prefix EncodeToString frames each encoding with.
+var encodingPrefixes = map[Encoding]string{
+	EncodingBase64:    "b64:",
+	EncodingBase64URL: "b64url:",
+	EncodingHex:       "hex:",
+}
+
+func (e Encoding) String() string {
+	switch e {
+	case EncodingBase64:
+		return "BASE64"
+	case EncodingBase64URL:
+		return "BASE64URL"
+	case EncodingHex:
+		return "HEX"
+	}
+	return fmt.Sprintf("Encoding(%d)", int(e))
+}
+
+// EncodeToString encodes blob, usually the output of Serialize, with enc and prefixes it with the
+// name of the encoding, e.g. "b64url:AAwAAQ...", so that DecodeString does not need to be told
+// which encoding was used. It panics if enc is not one of the Encoding constants.
+func EncodeToString(blob []byte, enc Encoding) string {
+	prefix, ok := encodingPrefixes[enc]
+	if !ok {
+		panic(fmt.Sprintf("binarymetadata: unknown %v", enc))
+	}
+	var body string
+	switch enc {
+	case EncodingBase64:
+		body = base64.StdEncoding.EncodeToString(blob)
+	case EncodingBase64URL:
+		body = base64.RawURLEncoding.EncodeToString(blob)
+	case EncodingHex:
+		body = hex.EncodeToString(blob)
+	}
+	return prefix + body
+}
+
+// DecodeString reverses EncodeToString and returns the blob along with the encoding it was
+// recorded with. It returns an error wrapping ErrMalformed if s has no known prefix or the rest of
+// s is not valid in that encoding; the blob itself is not parsed.
+func DecodeString(s string) ([]byte, Encoding, error) {
+	for enc, prefix := range encodingPrefixes {
+		body, ok := strings.CutPrefix(s, prefix)
+		if !ok {
+			continue
+		}
+		var blob []byte
+		var err error
+		switch enc {
+		case EncodingBase64:
+			blob, err = base64.StdEncoding.DecodeString(body)
+		case EncodingBase64URL:
+			blob, err = base64.RawURLEncoding.DecodeString(body)
+		case EncodingHex:
+			blob, err = hex.DecodeString(body)
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: invalid %v: %v", ErrMalformed, enc, err)
+		}
+		return blob, enc, nil
+	}
+	return nil, 0, fmt.Errorf("%w: no encoding prefix", ErrMalformed)
+}
@@ -0,0 +1,58 @@
+package binarymetadata
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestEncodeToString(t *testing.T) {
+	blob := []byte{0x00, 0x0c, 0xfb, 0xff, 0x3e}
+	tests := []struct {
+		enc  Encoding
+		want string
+	}{
+		{enc: EncodingBase64, want: "b64:AAz7/z4="},
+		{enc: EncodingBase64URL, want: "b64url:AAz7_z4"},
+		{enc: EncodingHex, want: "hex:000cfbff3e"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.enc.String(), func(t *testing.T) {
+			got := EncodeToString(blob, tc.enc)
+			if got != tc.want {
+				t.Errorf("EncodeToString = %q, want %q", got, tc.want)
+			}
+			out, enc, err := DecodeString(got)
+			if err != nil {
+				t.Fatalf("DecodeString(%q) failed: %v", got, err)
+			}
+			if enc != tc.enc || !bytes.Equal(out, blob) {
+				t.Errorf("DecodeString(%q) = %x, %v, want %x, %v", got, out, enc, blob, tc.enc)
+			}
+		})
+	}
+}
+
+func TestDecodeStringErrors(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"AAz7/z4=",
+		"b64:AAz7_z4",
+		"b64url:AAz7/z4=",
+		"hex:000cfbff3",
+		"HEX:000cfbff3e",
+	} {
+		if _, _, err := DecodeString(s); !errors.Is(err, ErrMalformed) {
+			t.Errorf("DecodeString(%q) = %v, want ErrMalformed", s, err)
+		}
+	}
+}
+
+func TestEncodeToStringUnknownEncoding(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("EncodeToString with an unknown encoding did not panic")
+		}
+	}()
+	EncodeToString(nil, Encoding(99))
+}
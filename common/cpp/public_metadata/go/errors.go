@@ -0,0 +1,172 @@
+package binarymetadata
+
+import (
+	"errors"
+	"strings"
+
+	"google3/util/task/go/status"
+)
+
+// Sentinel errors describing why metadata was rejected. Errors returned by this package match
+// these with errors.Is; errors that originate in the C++ library additionally keep matching their
+// canonical status code, e.g. status.ErrInvalidArgument.
+var (
+	// ErrMalformed is returned when the input is not a well-formed extensions list.
+	ErrMalformed = errors.New("malformed public metadata")
+	// ErrUnknownVersion is returned for a metadata version this package does not support.
+	ErrUnknownVersion = errors.New("unknown public metadata version")
+	// ErrMissingField is returned when a required field such as the expiration is unset.
+	ErrMissingField = errors.New("missing required public metadata field")
+	// ErrExtensionCount is returned when a blob carries more or fewer extensions than its version
+	// allows.
+	ErrExtensionCount = errors.New("wrong number of extensions")
+	// ErrTooLarge is returned for input exceeding the size limits of DeserializeOptions.
+	ErrTooLarge = errors.New("public metadata too large")
+	// ErrInvalidExpiration is returned for an expiration with an unsupported precision or range.
+	ErrInvalidExpiration = errors.New("invalid expiration")
+	// ErrExpirationNotRounded is returned for an expiration that is not on a 15 minute boundary.
+	ErrExpirationNotRounded = errors.New("expiration is not rounded")
+	// ErrExpired is returned when the expiration is before the validation time.
+	ErrExpired = errors.New("metadata has expired")
+	// ErrExpirationTooFar is returned when the expiration is too far after the validation time.
+	ErrExpirationTooFar = errors.New("expiration is too far in the future")
+	// ErrInvalidCountry is returned for a country that is not an ISO 3166-1 alpha-2 code.
+	ErrInvalidCountry = errors.New("invalid country")
+	// ErrInvalidGeoHint is returned for a malformed region or city.
+	ErrInvalidGeoHint = errors.New("invalid geo hint")
+	// ErrUnsupportedServiceType is returned for a service type with no wire encoding.
+	ErrUnsupportedServiceType = errors.New("unsupported service type")
+	// ErrInvalidDebugMode is returned for an out of range debug mode.
+	ErrInvalidDebugMode = errors.New("invalid debug mode")
+	// ErrInvalidProxyLayer is returned for an out of range or unmapped proxy layer.
+	ErrInvalidProxyLayer = errors.New("invalid proxy layer")
+	// ErrInvalidNetworkType is returned for invalid network types.
+	ErrInvalidNetworkType = errors.New("invalid network type")
+	// ErrInvalidClientPlatform is returned for invalid client platforms.
+	ErrInvalidClientPlatform = errors.New("invalid client platform")
+	// ErrInvalidServiceTier is returned for invalid service tiers.
+	ErrInvalidServiceTier = errors.New("invalid service tier")
+	// ErrInvalidAttestationLevel is returned for invalid attestation levels and levels not allowed
+	// for the service type.
+	ErrInvalidAttestationLevel = errors.New("invalid attestation level")
+	// ErrInvalidKeyEpoch is returned for key epochs on versions that cannot carry one.
+	ErrInvalidKeyEpoch = errors.New("invalid key epoch")
+	// ErrInvalidNonce is returned for nonces on versions that cannot carry one.
+	ErrInvalidNonce = errors.New("invalid nonce")
+	// ErrReplayed is returned when a ReplayCache has already seen a redeemed token and its
+	// metadata.
+	ErrReplayed = errors.New("token and metadata were already redeemed")
+	// ErrUnknownWireFormat is returned for a WireFormat that is not registered.
+	ErrUnknownWireFormat = errors.New("unknown wire format")
+	// ErrPolicyViolation is returned for metadata breaking a rule of a Policy.
+	ErrPolicyViolation = errors.New("public metadata policy violation")
+	// ErrFrozen is returned when mutating metadata returned by Freeze.
+	ErrFrozen = errors.New("metadata is frozen")
+	// ErrUnsupportedTokenType is returned for a token type that cannot carry the metadata.
+	ErrUnsupportedTokenType = errors.New("unsupported token type")
+	// ErrAnonymitySetTooSmall is returned when too few users share a geo hint.
+	ErrAnonymitySetTooSmall = errors.New("anonymity set is too small")
+	// ErrNoMatchingExit is returned when no available exit matches a geo hint preference.
+	ErrNoMatchingExit = errors.New("no matching exit")
+	// ErrFreed is returned when a BinaryStruct is used after Free.
+	ErrFreed = errors.New("binary struct has been freed")
+	// ErrInvalidSignature is returned when a token signature does not verify.
+	ErrInvalidSignature = errors.New("invalid token signature")
+	// ErrMetadataMismatch is returned when redeemed metadata differs from what was expected.
+	ErrMetadataMismatch = errors.New("metadata mismatch")
+	// ErrUnrepresentable is returned by the JSON and CBOR encodings for metadata carrying extensions
+	// they have no field for.
+	ErrUnrepresentable = errors.New("metadata cannot be represented in the encoding")
+)
+
+// Error wraps an error reported by the C++ library together with the sentinel that classifies it.
+type Error struct {
+	// Kind is one of the Err* sentinels above, or nil if the failure could not be classified.
+	Kind error
+	// Err is the underlying status error.
+	Err error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() []error {
+	if e.Kind == nil {
+		return []error{e.Err}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// statusMessageKinds maps fragments of the messages of C++ InvalidArgument statuses onto
+// sentinels. The first match wins, so more specific fragments come first. TestStatusMessageKinds
+// pins the kind of every message the C++ library returns.
+var statusMessageKinds = []struct {
+	fragment string
+	kind     error
+}{
+	{"missing", ErrMissingField},
+	{"number of extensions", ErrExtensionCount},
+	{"not supported by the version", ErrExtensionCount},
+	{"invalid version extension", ErrMalformed},
+	{"precision", ErrInvalidExpiration},
+	{"round", ErrExpirationNotRounded},
+	{"multiple", ErrExpirationNotRounded},
+	{"expired", ErrExpired},
+	{"in the past", ErrExpired},
+	{"future", ErrExpirationTooFar},
+	{"country", ErrInvalidCountry},
+	{"region", ErrInvalidGeoHint},
+	{"city", ErrInvalidGeoHint},
+	{"geo", ErrInvalidGeoHint},
+	{"service type", ErrUnsupportedServiceType},
+	{"debug", ErrInvalidDebugMode},
+	{"proxy", ErrInvalidProxyLayer},
+	{"version", ErrUnknownVersion},
+	{"extension", ErrMalformed},
+	{"decode", ErrMalformed},
+}
+
+// sentinels lists the Err* sentinels in the order ErrorKind tries them.
+var sentinels = []error{
+	ErrMalformed, ErrUnknownVersion, ErrMissingField, ErrExtensionCount, ErrTooLarge,
+	ErrInvalidExpiration, ErrExpirationNotRounded, ErrExpired, ErrExpirationTooFar, ErrInvalidCountry,
+	ErrInvalidGeoHint, ErrUnsupportedServiceType, ErrInvalidDebugMode, ErrInvalidProxyLayer,
+	ErrInvalidNetworkType, ErrInvalidClientPlatform, ErrInvalidServiceTier, ErrInvalidAttestationLevel,
+	ErrUnsupportedTokenType, ErrAnonymitySetTooSmall, ErrNoMatchingExit, ErrFreed, ErrFrozen,
+	ErrInvalidSignature, ErrMetadataMismatch, ErrPolicyViolation, ErrInvalidKeyEpoch,
+	ErrUnknownWireFormat, ErrInvalidNonce, ErrReplayed, ErrUnrepresentable,
+}
+
+// ErrorKind returns the Err* sentinel that err matches, or nil if it matches none.
+func ErrorKind(err error) error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	for _, k := range sentinels {
+		if errors.Is(err, k) {
+			return k
+		}
+	}
+	return nil
+}
+
+// classifyStatus returns the error of s with a sentinel attached. Only InvalidArgument statuses
+// describe the metadata, so other codes such as Internal are left unclassified.
+func classifyStatus(s *status.Status) error {
+	err := s.Err()
+	if err == nil {
+		return nil
+	}
+	if s.Code() != status.InvalidArgument {
+		return &Error{Err: err}
+	}
+	msg := strings.ToLower(s.Message())
+	for _, k := range statusMessageKinds {
+		if strings.Contains(msg, k.fragment) {
+			return &Error{Kind: k.kind, Err: err}
+		}
+	}
+	return &Error{Err: err}
+}
@@ -0,0 +1,142 @@
+package binarymetadata
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"google3/third_party/golang/protobuf/v2/proto/proto"
+	"google3/util/task/go/status"
+	stpb "google3/util/task/status_go_proto"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+)
+
+func statusForTest(code status.Code, msg string) *status.Status {
+	return status.FromProto(&stpb.StatusProto{Code: proto.Int32(int32(code)), CanonicalCode: proto.Int32(int32(code)), Message: proto.String(msg)})
+}
+
+// TestStatusMessageKinds pins the kind of every status message of public_metadata.cc, so that a
+// reworded message is noticed, and of messages of the anonymous tokens library the package relies
+// on.
+func TestStatusMessageKinds(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want error
+	}{
+		{msg: "Invalid version extension", want: ErrMalformed},
+		{msg: "Extension not supported by the version", want: ErrExtensionCount},
+		{msg: "missing expiration", want: ErrMissingField},
+		{msg: "missing country in geo information", want: ErrMissingField},
+		{msg: "missing service type", want: ErrMissingField},
+		{msg: "unsupported service type", want: ErrUnsupportedServiceType},
+		{msg: "invalid proxy layer", want: ErrInvalidProxyLayer},
+		{msg: "version does not fit the extension", want: ErrUnknownVersion},
+		{msg: "Wrong number of extensions", want: ErrExtensionCount},
+		{msg: "Invalid timestamp_precision", want: ErrInvalidExpiration},
+		{msg: "Unsupported service type", want: ErrUnsupportedServiceType},
+		{msg: "Unsupported version", want: ErrUnknownVersion},
+		{msg: "ExpirationTimestamp.timestamp is not rounded", want: ErrExpirationNotRounded},
+		{msg: "GeoHint.country_code is not uppercase", want: ErrInvalidCountry},
+	}
+	for _, tc := range tests {
+		t.Run(tc.msg, func(t *testing.T) {
+			err := classifyStatus(statusForTest(status.InvalidArgument, tc.msg))
+			if got := ErrorKind(err); got != tc.want {
+				t.Errorf("classifyStatus(%q) has kind %v, want %v", tc.msg, got, tc.want)
+			}
+			if !errors.Is(err, status.ErrInvalidArgument) {
+				t.Errorf("classifyStatus(%q) = %v, want it to match %v", tc.msg, err, status.ErrInvalidArgument)
+			}
+		})
+	}
+}
+
+func TestClassifyStatusOtherCodes(t *testing.T) {
+	if err := classifyStatus(statusForTest(status.OK, "")); err != nil {
+		t.Errorf("classifyStatus(OK) = %v, want nil", err)
+	}
+	err := classifyStatus(statusForTest(status.Internal, "missing expiration"))
+	if got := ErrorKind(err); got != nil {
+		t.Errorf("classifyStatus(Internal) has kind %v, want none", got)
+	}
+	if !errors.Is(err, status.ErrInternal) {
+		t.Errorf("classifyStatus(Internal) = %v, want it to match %v", err, status.ErrInternal)
+	}
+}
+
+// fakeWrappedStatus is a wrappedStatus whose serialized StatusProto is only read if it carries
+// payloads.
+type fakeWrappedStatus struct {
+	t          *testing.T
+	code       int
+	msg        string
+	hasPayload bool
+}
+
+func (s fakeWrappedStatus) GetStatus() []byte {
+	if !s.hasPayload {
+		s.t.Error("GetStatus() called for a status without payloads")
+	}
+	b, err := proto.Marshal(&stpb.StatusProto{Code: proto.Int32(int32(s.code)), CanonicalCode: proto.Int32(int32(s.code)), Message: proto.String(s.msg)})
+	if err != nil {
+		s.t.Fatalf("proto.Marshal failed: %v", err)
+	}
+	return b
+}
+
+func (s fakeWrappedStatus) GetStatus_code() int         { return s.code }
+func (s fakeWrappedStatus) GetStatus_message() string   { return s.msg }
+func (s fakeWrappedStatus) GetStatus_has_payload() bool { return s.hasPayload }
+
+func TestWrappedStatusToErr(t *testing.T) {
+	if err := wrappedStatusToErr(fakeWrappedStatus{t: t}); err != nil {
+		t.Errorf("wrappedStatusToErr(OK) = %v, want nil", err)
+	}
+	for _, hasPayload := range []bool{false, true} {
+		err := wrappedStatusToErr(fakeWrappedStatus{t: t, code: int(status.InvalidArgument), msg: "Unsupported service type", hasPayload: hasPayload})
+		if !errors.Is(err, ErrUnsupportedServiceType) || !errors.Is(err, status.ErrInvalidArgument) {
+			t.Errorf("wrappedStatusToErr(payload %t) = %v, want %v and %v", hasPayload, err, ErrUnsupportedServiceType, status.ErrInvalidArgument)
+		}
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	bs := New(&NewBinaryFields{Version: 1, Country: "US", ServiceType: "cronet", Expiration: &tpb.Timestamp{Seconds: 900}})
+	defer bs.Free()
+	_, err := Serialize(bs)
+	if !errors.Is(err, ErrUnsupportedServiceType) || !errors.Is(err, status.ErrInvalidArgument) {
+		t.Errorf("Serialize() with an unknown service type returned error: %v, want %v and %v", err, ErrUnsupportedServiceType, status.ErrInvalidArgument)
+	}
+	v4 := New(&NewBinaryFields{Version: 4, Country: "US", ServiceType: "chromeipblinding", Expiration: &tpb.Timestamp{Seconds: 900}})
+	defer v4.Free()
+	_, err = Serialize(v4)
+	if !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("Serialize() with version 4 returned error: %v, want %v", err, ErrUnknownVersion)
+	}
+	var e *Error
+	if err := ValidateMetadataCardinality([]byte{0}, time.Now()); !errors.As(err, &e) {
+		t.Errorf("ValidateMetadataCardinality() returned error: %v, want an *Error", err)
+	}
+}
+
+func TestErrorKind(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "nil", err: nil, want: nil},
+		{name: "wrapped sentinel", err: fmt.Errorf("%w: 7", ErrUnknownVersion), want: ErrUnknownVersion},
+		{name: "classified status", err: &Error{Kind: ErrExpired, Err: errors.New("expired")}, want: ErrExpired},
+		{name: "unclassified", err: errors.New("boom"), want: nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ErrorKind(tc.err); got != tc.want {
+				t.Errorf("ErrorKind(%v) = %v, want %v", tc.err, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package binarymetadata
+
+import (
+	"fmt"
+
+	"google3/privacy/net/boq/common/tokens/tokentypes"
+)
+
+// SelectExit returns the geo hint to request given the client's preferences, most preferred
+// first, and the geo hints of the available exits. Each preference is tried at its own
+// granularity and then, before moving on to the next preference, with the city and then the
+// region dropped. The result is the preference at the first granularity that some exit matches,
+// in canonical form, so that e.g. a preferred city without an exit falls back to requesting any
+// exit in its region. It returns an error wrapping ErrNoMatchingExit if nothing matches.
+func SelectExit(preferences, available []tokentypes.GeoHint) (*tokentypes.GeoHint, error) {
+	exits := make([]tokentypes.GeoHint, len(available))
+	for i, e := range available {
+		exits[i] = CanonicalizeGeoHint(e)
+	}
+	for _, p := range preferences {
+		p = CanonicalizeGeoHint(p)
+		if p.Country == "" {
+			continue
+		}
+		for _, want := range fallbacks(p) {
+			for _, e := range exits {
+				if coveredBy(want, e) {
+					return &want, nil
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: none of %d exits match %d preferences", ErrNoMatchingExit, len(available), len(preferences))
+}
+
+// fallbacks returns geo at its own granularity followed by its coarser forms.
+func fallbacks(geo tokentypes.GeoHint) []tokentypes.GeoHint {
+	out := []tokentypes.GeoHint{geo}
+	if geo.City != "" {
+		out = append(out, tokentypes.GeoHint{Country: geo.Country, Region: geo.Region})
+	}
+	if geo.Region != "" {
+		out = append(out, tokentypes.GeoHint{Country: geo.Country})
+	}
+	return out
+}
+
+// coveredBy reports whether exit serves requests for want, i.e. agrees with every part want sets.
+func coveredBy(want, exit tokentypes.GeoHint) bool {
+	return want.Country == exit.Country &&
+		(want.Region == "" || want.Region == exit.Region) &&
+		(want.City == "" || want.City == exit.City)
+}
@@ -0,0 +1,67 @@
+package binarymetadata
+
+import (
+	"errors"
+	"testing"
+
+	"google3/privacy/net/boq/common/tokens/tokentypes"
+)
+
+func TestSelectExit(t *testing.T) {
+	available := []tokentypes.GeoHint{
+		{Country: "US", Region: "US-CA", City: "LOS ANGELES"},
+		{Country: "US", Region: "US-NY", City: "NEW YORK CITY"},
+		{Country: "GB", Region: "GB-ENG", City: "LONDON"},
+	}
+	tests := []struct {
+		name        string
+		preferences []tokentypes.GeoHint
+		want        tokentypes.GeoHint
+		wantErr     error
+	}{
+		{
+			name:        "exact city",
+			preferences: []tokentypes.GeoHint{{Country: "us", Region: "us-ny", City: "New York City"}},
+			want:        tokentypes.GeoHint{Country: "US", Region: "US-NY", City: "NEW YORK CITY"},
+		},
+		{
+			name:        "city falls back to region",
+			preferences: []tokentypes.GeoHint{{Country: "US", Region: "US-CA", City: "SUNNYVALE"}},
+			want:        tokentypes.GeoHint{Country: "US", Region: "US-CA"},
+		},
+		{
+			name:        "region falls back to country",
+			preferences: []tokentypes.GeoHint{{Country: "US", Region: "US-TX", City: "AUSTIN"}},
+			want:        tokentypes.GeoHint{Country: "US"},
+		},
+		{
+			name: "falls back within a preference before the next one",
+			preferences: []tokentypes.GeoHint{
+				{Country: "US", Region: "US-CA", City: "SUNNYVALE"},
+				{Country: "GB", Region: "GB-ENG", City: "LONDON"},
+			},
+			want: tokentypes.GeoHint{Country: "US", Region: "US-CA"},
+		},
+		{
+			name:        "next preference",
+			preferences: []tokentypes.GeoHint{{Country: "FR"}, {Country: "GB"}},
+			want:        tokentypes.GeoHint{Country: "GB"},
+		},
+		{
+			name:        "no match",
+			preferences: []tokentypes.GeoHint{{Country: "FR", Region: "FR-IDF", City: "PARIS"}, {}},
+			wantErr:     ErrNoMatchingExit,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := SelectExit(tc.preferences, available)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("SelectExit() returned error: %v, want error: %v", err, tc.wantErr)
+			}
+			if err == nil && *got != tc.want {
+				t.Errorf("SelectExit() = %+v, want %+v", *got, tc.want)
+			}
+		})
+	}
+}
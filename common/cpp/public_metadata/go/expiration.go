@@ -0,0 +1,112 @@
+package binarymetadata
+
+import (
+	"fmt"
+	"time"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+)
+
+// MaxExpirationEpochSeconds is the latest expiration accepted, 9999-12-31T23:59:59Z, the end of the
+// range of google.protobuf.Timestamp. Later values are far beyond any token lifetime and, past
+// 2^63, no longer survive the conversion between the uint64 of the wire and the int64 of Go.
+const MaxExpirationEpochSeconds = 253402300799
+
+// checkExpirationSeconds rejects expirations before the unix epoch or after
+// MaxExpirationEpochSeconds. Wire values are passed as int64, so that those which wrapped from a
+// negative epoch are reported as such.
+func checkExpirationSeconds(s int64) error {
+	switch {
+	case s < 0:
+		return fmt.Errorf("%w: epoch %d is before the unix epoch", ErrInvalidExpiration, s)
+	case s > MaxExpirationEpochSeconds:
+		return fmt.Errorf("%w: epoch %d is after %d", ErrInvalidExpiration, s, int64(MaxExpirationEpochSeconds))
+	}
+	return nil
+}
+
+// ExpirationGranularity returns the boundary that expirations must be rounded to for version.
+func ExpirationGranularity(version int32) (time.Duration, error) {
+	c, err := Capabilities(version)
+	if err != nil {
+		return 0, err
+	}
+	return c.ExpirationGranularity, nil
+}
+
+// RoundExpiration rounds t up to the next expiration boundary required by version. Times already
+// on a boundary are returned unchanged, less any sub-second component.
+func RoundExpiration(t time.Time, version int32) (time.Time, error) {
+	g, err := ExpirationGranularity(version)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if t.IsZero() || t.Before(time.Unix(0, 0)) {
+		return time.Time{}, fmt.Errorf("%w: %v is before the unix epoch", ErrInvalidExpiration, t)
+	}
+	rounded := t.Truncate(g)
+	if rounded.Before(t) {
+		rounded = rounded.Add(g)
+	}
+	return rounded, nil
+}
+
+// IsExpired reports whether the metadata has expired at now. Metadata without an expiration is
+// treated as expired so that callers fail closed.
+func (bs *BinaryStruct) IsExpired(now time.Time) bool {
+	return bs.TimeToLive(now) == 0
+}
+
+// TimeToLive returns how long the metadata remains valid after now, or zero if it has expired or
+// has no expiration.
+func (bs *BinaryStruct) TimeToLive(now time.Time) time.Duration {
+	exp := bs.GetExpirationTime()
+	if exp.IsZero() {
+		return 0
+	}
+	if ttl := exp.Sub(now); ttl > 0 {
+		return ttl
+	}
+	return 0
+}
+
+// ExpirationTimestamp converts t into an expiration timestamp. Unlike tpb.New it fails for the
+// zero time, for times before the unix epoch or after MaxExpirationEpochSeconds and for times with
+// sub-second precision, which the wire format would silently drop.
+func ExpirationTimestamp(t time.Time) (*tpb.Timestamp, error) {
+	switch {
+	case t.IsZero():
+		return nil, fmt.Errorf("%w: expiration time is zero", ErrMissingField)
+	case t.Before(time.Unix(0, 0)):
+		return nil, fmt.Errorf("%w: %v is before the unix epoch", ErrInvalidExpiration, t)
+	case t.After(time.Unix(MaxExpirationEpochSeconds, 0)):
+		return nil, fmt.Errorf("%w: %v is after %v", ErrInvalidExpiration, t, time.Unix(MaxExpirationEpochSeconds, 0).UTC())
+	case t.Nanosecond() != 0:
+		return nil, fmt.Errorf("%w: %v has sub-second precision", ErrInvalidExpiration, t)
+	}
+	return &tpb.Timestamp{Seconds: t.Unix()}, nil
+}
+
+// expirationSeconds returns the expiration in epoch seconds, taken from Expiration if set and
+// ExpirationTime otherwise.
+func (f *NewBinaryFields) expirationSeconds() int64 {
+	if f.Expiration == nil && !f.ExpirationTime.IsZero() {
+		return f.ExpirationTime.Unix()
+	}
+	return f.Expiration.GetSeconds()
+}
+
+// checkExpirationTime reports the errors NewChecked returns for Expiration and ExpirationTime.
+func (f *NewBinaryFields) checkExpirationTime() error {
+	if f.Expiration == nil && f.ExpirationTime.IsZero() {
+		return &FieldError{Field: "expiration", Err: ErrMissingField}
+	}
+	if f.ExpirationTime.IsZero() {
+		return checkExpirationSeconds(f.Expiration.GetSeconds())
+	}
+	if f.Expiration != nil {
+		return fmt.Errorf("%w: both Expiration and ExpirationTime are set", ErrInvalidExpiration)
+	}
+	_, err := ExpirationTimestamp(f.ExpirationTime)
+	return err
+}
@@ -0,0 +1,113 @@
+package binarymetadata
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	wrap "google3/privacy/net/common/cpp/public_metadata/go/wrap_public_metadata"
+)
+
+// GetExpirationTime returns the expiration including its millisecond part, or the zero time if
+// no expiration is set.
+func (bs *BinaryStruct) GetExpirationTime() time.Time {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+	t, _ := bs.expirationTime()
+	return t
+}
+
+// expirationTime is GetExpirationTime for callers that hold the lock. It also reports whether the
+// expiration is set.
+func (bs *BinaryStruct) expirationTime() (time.Time, bool) {
+	exp := bs.expiration()
+	if exp == nil {
+		return time.Time{}, false
+	}
+	return time.Unix(exp.GetSeconds(), int64(bs.expirationMillis())*int64(time.Millisecond)), true
+}
+
+// SetExpirationTime sets the expiration to t. A millisecond part is carried in its own extension
+// and is only allowed for versions whose capabilities include ExpirationMillis; precision finer
+// than a millisecond is always rejected.
+func (bs *BinaryStruct) SetExpirationTime(t time.Time) error {
+	err := bs.setExpirationTime(t)
+	bs.audit(AuditMutate, "set_expiration_time", err)
+	return err
+}
+
+func (bs *BinaryStruct) setExpirationTime(t time.Time) error {
+	if err := checkExpirationSeconds(t.Unix()); err != nil {
+		return err
+	}
+	if t.Nanosecond()%int(time.Millisecond) != 0 {
+		return fmt.Errorf("%w: %v has sub-millisecond precision", ErrInvalidExpiration, t)
+	}
+	millis := uint16(t.Nanosecond() / int(time.Millisecond))
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if err := bs.checkMutable(); err != nil {
+		return err
+	}
+	c, err := Capabilities(int32(bs.md().GetVersion()))
+	if err != nil {
+		return err
+	}
+	if millis != 0 && !c.ExpirationMillis {
+		return fmt.Errorf("%w: version %d does not support millisecond expiration", ErrInvalidExpiration, c.Version)
+	}
+	bs.metadata.SetExpiration_epoch_seconds(wrap.NewUint64Optional(uint64(t.Unix())))
+	bs.removeExtra(ExtensionTypeExpirationMillis)
+	if millis != 0 {
+		e, err := ExpirationMillisExtension{Millis: millis}.AsExtension()
+		if err != nil {
+			return err
+		}
+		bs.putExtra(e)
+	}
+	return nil
+}
+
+// expirationMillis returns the millisecond part of the expiration, or zero if there is none.
+func (bs *BinaryStruct) expirationMillis() uint16 {
+	for _, e := range bs.extra {
+		if e.Type == ExtensionTypeExpirationMillis {
+			if m, err := ExpirationMillisExtensionFromExtension(e); err == nil {
+				return m.Millis
+			}
+		}
+	}
+	return 0
+}
+
+// checkExpirationMillis rejects a millisecond part on versions that cannot carry one.
+func (bs *BinaryStruct) checkExpirationMillis(c VersionCapabilities) error {
+	if c.ExpirationMillis {
+		return nil
+	}
+	for _, e := range bs.extra {
+		if e.Type == ExtensionTypeExpirationMillis {
+			return fmt.Errorf("%w: version %d does not support millisecond expiration", ErrInvalidExpiration, c.Version)
+		}
+	}
+	return nil
+}
+
+// findExtra returns the extension of type typeID added with SetExtension, if any.
+func (bs *BinaryStruct) findExtra(typeID uint16) (Extension, bool) {
+	i, found := slices.BinarySearchFunc(bs.extra, Extension{Type: typeID}, compareExtensionTypes)
+	if !found {
+		return Extension{}, false
+	}
+	return bs.extra[i], true
+}
+
+// removeExtra drops the extension of type typeID added with SetExtension, if any.
+func (bs *BinaryStruct) removeExtra(typeID uint16) {
+	for i, e := range bs.extra {
+		if e.Type == typeID {
+			bs.extra = append(bs.extra[:i], bs.extra[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package binarymetadata
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+)
+
+func TestSetExpirationTimeRejectsMillisOnOlderVersions(t *testing.T) {
+	for _, version := range []int32{1, 2} {
+		bs := New(&NewBinaryFields{Version: version, Country: "US", ServiceType: "chromeipblinding"})
+		defer bs.Free()
+		err := bs.SetExpirationTime(time.Unix(900, 250*int64(time.Millisecond)))
+		if !errors.Is(err, ErrInvalidExpiration) {
+			t.Errorf("version %d: SetExpirationTime() returned error: %v, want error: %v", version, err, ErrInvalidExpiration)
+		}
+		if err := bs.SetExpirationTime(time.Unix(900, 0)); err != nil {
+			t.Errorf("version %d: SetExpirationTime(whole seconds) failed: %v", version, err)
+		}
+		if got, want := bs.GetExpirationTime(), time.Unix(900, 0); !got.Equal(want) {
+			t.Errorf("version %d: GetExpirationTime() = %v, want %v", version, got, want)
+		}
+	}
+}
+
+func TestExpirationMillisRoundTrip(t *testing.T) {
+	bs := New(&NewBinaryFields{Version: 3, Country: "US", ServiceType: "chromeipblinding"})
+	defer bs.Free()
+	want := time.Unix(900, 250*int64(time.Millisecond))
+	if err := bs.SetExpirationTime(want); err != nil {
+		t.Fatalf("SetExpirationTime failed: %v", err)
+	}
+	if err := bs.SetExpirationTime(want.Add(time.Microsecond)); !errors.Is(err, ErrInvalidExpiration) {
+		t.Errorf("SetExpirationTime(microseconds) returned error: %v, want error: %v", err, ErrInvalidExpiration)
+	}
+	out, err := Serialize(bs)
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	got, err := DeserializeOptions{Strict: true}.Deserialize(out)
+	if err != nil {
+		t.Fatalf("Deserialize failed: %v", err)
+	}
+	defer got.Free()
+	if got.GetVersion() != 3 {
+		t.Errorf("GetVersion() after round trip = %d, want 3", got.GetVersion())
+	}
+	if !got.GetExpirationTime().Equal(want) {
+		t.Errorf("GetExpirationTime() = %v, want %v", got.GetExpirationTime(), want)
+	}
+	if exp := got.GetExpiration(); exp.GetSeconds() != 900 {
+		t.Errorf("GetExpiration() = %v, want 900 seconds", exp)
+	}
+}
+
+func TestDeserializeRejectsExpirationMillisOnOlderVersions(t *testing.T) {
+	bs := New(&NewBinaryFields{Version: 2, Country: "US", ServiceType: "chromeipblinding", Expiration: tpb.New(time.Unix(900, 0))})
+	defer bs.Free()
+	if err := bs.SetExtension(ExtensionTypeExpirationMillis, []byte{0x01, 0xf4}); err != nil {
+		t.Fatalf("SetExtension failed: %v", err)
+	}
+	out, err := Serialize(bs)
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	if _, err := (DeserializeOptions{Strict: true}).Deserialize(out); !errors.Is(err, ErrInvalidExpiration) {
+		t.Errorf("Deserialize() returned error: %v, want error: %v", err, ErrInvalidExpiration)
+	}
+	err = defaultValidator.Validate(out, time.Unix(0, 0))
+	var fe *FieldError
+	if !errors.As(err, &fe) || fe.Field != "expiration" || !errors.Is(err, ErrInvalidExpiration) {
+		t.Errorf("Validate() returned error: %v, want expiration error wrapping %v", err, ErrInvalidExpiration)
+	}
+}
+
+func TestExpirationMillisExtension(t *testing.T) {
+	e, err := ExpirationMillisExtension{Millis: 999}.AsExtension()
+	if err != nil {
+		t.Fatalf("AsExtension failed: %v", err)
+	}
+	got, err := ExpirationMillisExtensionFromExtension(e)
+	if err != nil || got.Millis != 999 {
+		t.Errorf("ExpirationMillisExtensionFromExtension() = %v, %v, want 999", got, err)
+	}
+	if _, err := (ExpirationMillisExtension{Millis: 1000}).AsExtension(); !errors.Is(err, ErrInvalidExpiration) {
+		t.Errorf("AsExtension(1000) returned error: %v, want error: %v", err, ErrInvalidExpiration)
+	}
+}
+
+func TestValidatorExpirationMillis(t *testing.T) {
+	bs := newTestStruct(t, 3)
+	if err := bs.SetExpirationTime(time.Unix(900, 500*int64(time.Millisecond))); err != nil {
+		t.Fatalf("SetExpirationTime failed: %v", err)
+	}
+	rules := func(v *Validator, at time.Time) []string {
+		var got []string
+		for _, f := range v.findings(bs, at) {
+			if f.Field == "expiration" {
+				got = append(got, f.Rule)
+			}
+		}
+		return got
+	}
+	coarse, err := NewValidator(ValidationConfig{})
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+	fine, err := NewValidator(ValidationConfig{ExpirationBucket: time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+	for _, tc := range []struct {
+		name string
+		v    *Validator
+		at   time.Time
+		want []string
+	}{
+		{name: "off the version granularity", v: coarse, at: time.Unix(900, 200*int64(time.Millisecond)), want: []string{"expiration_bucket"}},
+		{name: "before the millisecond part", v: fine, at: time.Unix(900, 200*int64(time.Millisecond))},
+		{name: "after the millisecond part", v: fine, at: time.Unix(900, 700*int64(time.Millisecond)), want: []string{"not_expired"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := rules(tc.v, tc.at); !slices.Equal(got, tc.want) {
+				t.Errorf("expiration findings = %v, want %v", got, tc.want)
+			}
+		})
+	}
+	if got, want := bs.TimeToLive(time.Unix(900, 200*int64(time.Millisecond))), 300*time.Millisecond; got != want {
+		t.Errorf("TimeToLive() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,198 @@
+package binarymetadata
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	wrap "google3/privacy/net/common/cpp/public_metadata/go/wrap_public_metadata"
+)
+
+func TestRoundExpiration(t *testing.T) {
+	base := time.Unix(1700000100, 0) // 2023-11-14T22:15:00Z, on a 15 minute boundary.
+	tests := []struct {
+		name    string
+		in      time.Time
+		version int32
+		want    time.Time
+		wantErr error
+	}{
+		{
+			name:    "on boundary",
+			in:      base,
+			version: 1,
+			want:    base,
+		},
+		{
+			name:    "rounds up",
+			in:      base.Add(time.Second),
+			version: 2,
+			want:    base.Add(15 * time.Minute),
+		},
+		{
+			name:    "sub second rounds up",
+			in:      base.Add(time.Millisecond),
+			version: 2,
+			want:    base.Add(15 * time.Minute),
+		},
+		{
+			name:    "unknown version",
+			in:      base,
+			version: 7,
+			wantErr: ErrUnknownVersion,
+		},
+		{
+			name:    "zero time",
+			version: 1,
+			wantErr: ErrInvalidExpiration,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := RoundExpiration(tc.in, tc.version)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("RoundExpiration() returned error: %v, want error: %v", err, tc.wantErr)
+			}
+			if !got.Equal(tc.want) {
+				t.Errorf("RoundExpiration() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTimeToLive(t *testing.T) {
+	exp := time.Unix(1700000100, 0)
+	bs := New(&NewBinaryFields{Version: 1, Expiration: tpb.New(exp)})
+	defer bs.Free()
+	unset := New(&NewBinaryFields{Version: 1})
+	defer unset.Free()
+	unset.metadata.SetExpiration_epoch_seconds(wrap.NewUint64Optional())
+	tests := []struct {
+		name        string
+		bs          *BinaryStruct
+		now         time.Time
+		wantTTL     time.Duration
+		wantExpired bool
+	}{
+		{
+			name:    "before expiration",
+			bs:      bs,
+			now:     exp.Add(-time.Minute),
+			wantTTL: time.Minute,
+		},
+		{
+			name:        "at expiration",
+			bs:          bs,
+			now:         exp,
+			wantExpired: true,
+		},
+		{
+			name:        "after expiration",
+			bs:          bs,
+			now:         exp.Add(time.Hour),
+			wantExpired: true,
+		},
+		{
+			name:        "no expiration",
+			bs:          unset,
+			now:         exp,
+			wantExpired: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.bs.TimeToLive(tc.now); got != tc.wantTTL {
+				t.Errorf("TimeToLive() = %v, want %v", got, tc.wantTTL)
+			}
+			if got := tc.bs.IsExpired(tc.now); got != tc.wantExpired {
+				t.Errorf("IsExpired() = %v, want %v", got, tc.wantExpired)
+			}
+		})
+	}
+}
+
+func TestExpirationTimestamp(t *testing.T) {
+	tests := []struct {
+		name    string
+		t       time.Time
+		wantErr error
+	}{
+		{name: "whole seconds", t: time.Unix(1800, 0)},
+		{name: "zero", t: time.Time{}, wantErr: ErrMissingField},
+		{name: "before epoch", t: time.Unix(-60, 0), wantErr: ErrInvalidExpiration},
+		{name: "latest", t: time.Unix(MaxExpirationEpochSeconds, 0)},
+		{name: "after latest", t: time.Unix(MaxExpirationEpochSeconds+1, 0), wantErr: ErrInvalidExpiration},
+		{name: "sub-second", t: time.Unix(1800, 5), wantErr: ErrInvalidExpiration},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ts, err := ExpirationTimestamp(tc.t)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("ExpirationTimestamp(%v) returned error: %v, want error: %v", tc.t, err, tc.wantErr)
+			}
+			if err == nil && ts.GetSeconds() != tc.t.Unix() {
+				t.Errorf("ExpirationTimestamp(%v) = %v", tc.t, ts)
+			}
+		})
+	}
+}
+
+func TestNewCheckedExpirationTime(t *testing.T) {
+	bs, err := NewChecked(&NewBinaryFields{Version: 1, ExpirationTime: time.Unix(1800, 0)})
+	if err != nil {
+		t.Fatalf("NewChecked failed: %v", err)
+	}
+	defer bs.Free()
+	if got := bs.GetExpiration().GetSeconds(); got != 1800 {
+		t.Errorf("GetExpiration() = %ds, want 1800s", got)
+	}
+	if _, err := NewChecked(&NewBinaryFields{Version: 1, ExpirationTime: time.Unix(1800, 1)}); !errors.Is(err, ErrInvalidExpiration) {
+		t.Errorf("NewChecked(sub-second) returned error: %v, want error: %v", err, ErrInvalidExpiration)
+	}
+	both := &NewBinaryFields{Version: 1, Expiration: &tpb.Timestamp{Seconds: 900}, ExpirationTime: time.Unix(1800, 0)}
+	if _, err := NewChecked(both); !errors.Is(err, ErrInvalidExpiration) {
+		t.Errorf("NewChecked(both set) returned error: %v, want error: %v", err, ErrInvalidExpiration)
+	}
+	if _, err := NewChecked(&NewBinaryFields{Version: 1, Country: "US", ServiceType: "chromeipblinding"}); !errors.Is(err, ErrMissingField) {
+		t.Errorf("NewChecked(neither set) returned error: %v, want error: %v", err, ErrMissingField)
+	}
+}
+
+func TestExpirationRange(t *testing.T) {
+	for _, seconds := range []int64{-1, MaxExpirationEpochSeconds + 1, math.MaxInt64} {
+		fields := &NewBinaryFields{Version: 1, Country: "US", ServiceType: "chromeipblinding", Expiration: &tpb.Timestamp{Seconds: seconds}}
+		if _, err := NewChecked(fields); !errors.Is(err, ErrInvalidExpiration) {
+			t.Errorf("NewChecked(%d) returned error: %v, want error: %v", seconds, err, ErrInvalidExpiration)
+		}
+		bs := New(fields)
+		if _, err := Serialize(bs); !errors.Is(err, ErrInvalidExpiration) {
+			t.Errorf("Serialize(%d) returned error: %v, want error: %v", seconds, err, ErrInvalidExpiration)
+		}
+		if _, err := SerializedSize(bs); !errors.Is(err, ErrInvalidExpiration) {
+			t.Errorf("SerializedSize(%d) returned error: %v, want error: %v", seconds, err, ErrInvalidExpiration)
+		}
+		bs.Free()
+		if _, err := SerializeGo(fields); !errors.Is(err, ErrInvalidExpiration) {
+			t.Errorf("SerializeGo(%d) returned error: %v, want error: %v", seconds, err, ErrInvalidExpiration)
+		}
+	}
+
+	for _, wire := range []uint64{MaxExpirationEpochSeconds + 900, math.MaxUint64 - 899} {
+		exts := canonicalTestExtensions(t, GeoHintExtension{CountryCode: "US"})
+		exts[0], _ = ExpirationExtension{TimestampPrecision: ExpirationTimestampPrecision, Timestamp: wire}.AsExtension()
+		in := encodeForTest(t, exts...)
+		if _, err := Deserialize(in); !errors.Is(err, ErrInvalidExpiration) {
+			t.Errorf("Deserialize(%d) returned error: %v, want error: %v", wire, err, ErrInvalidExpiration)
+		}
+		if _, err := DeserializeGo(in); !errors.Is(err, ErrInvalidExpiration) {
+			t.Errorf("DeserializeGo(%d) returned error: %v, want error: %v", wire, err, ErrInvalidExpiration)
+		}
+		if v, err := NewView(in); err != nil || v.GetExpiration() != nil {
+			t.Errorf("NewView(%d).GetExpiration() = %v, %v, want nil", wire, v.GetExpiration(), err)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package binarymetadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"time"
+
+	wrap "google3/privacy/net/common/cpp/public_metadata/go/wrap_public_metadata"
+)
+
+// isKnownExtensionType reports whether typeID is modeled as a field of the C++ struct.
+func isKnownExtensionType(typeID uint16) bool {
+	switch typeID {
+	case ExtensionTypeExpirationTimestamp, ExtensionTypeGeoHint, ExtensionTypeServiceType,
+		ExtensionTypeDebugMode, ExtensionTypeProxyLayer, ExtensionTypeVersion:
+		return true
+	}
+	return false
+}
+
+// GetExtension returns the wire value of the extension with type typeID and whether it is
+// present. Known extension types are encoded from the corresponding field; any other type is
+// looked up among the extensions added with SetExtension. The returned slice is a copy.
+func (bs *BinaryStruct) GetExtension(typeID uint16) ([]byte, bool) {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+	if bs.metadata == nil {
+		return nil, false
+	}
+	if isKnownExtensionType(typeID) {
+		e, ok := bs.knownExtension(typeID)
+		return e.Value, ok
+	}
+	for _, e := range bs.extra {
+		if e.Type == typeID {
+			return bytes.Clone(e.Value), true
+		}
+	}
+	return nil, false
+}
+
+func (bs *BinaryStruct) knownExtension(typeID uint16) (Extension, bool) {
+	var (
+		e   Extension
+		err error
+	)
+	switch typeID {
+	case ExtensionTypeExpirationTimestamp:
+		epoch := bs.md().GetExpiration_epoch_seconds()
+		if epoch == nil || !epoch.HasValue() {
+			return Extension{}, false
+		}
+		e, err = ExpirationExtension{
+			TimestampPrecision: uint64(expirationGranularity / time.Second),
+			Timestamp:          uint64(epoch.Value()),
+		}.AsExtension()
+	case ExtensionTypeGeoHint:
+		geo := bs.geoHint()
+		if geo.Country == "" {
+			return Extension{}, false
+		}
+		e, err = GeoHintExtension{CountryCode: geo.Country, Region: geo.Region, City: geo.City}.AsExtension()
+	case ExtensionTypeServiceType:
+		var st ServiceTypeExtension
+		if st, err = ServiceTypeExtensionFromName(bs.serviceType()); err == nil {
+			e, err = st.AsExtension()
+		}
+	case ExtensionTypeDebugMode:
+		e, err = DebugModeExtension{Mode: uint8(bs.md().GetDebug_mode())}.AsExtension()
+	case ExtensionTypeProxyLayer:
+		w := bs.md().GetProxy_layer()
+		if bs.md().GetVersion() < 2 || w == nil || !w.HasValue() {
+			return Extension{}, false
+		}
+		e, err = ProxyLayerExtension{Layer: uint8(w.Value())}.AsExtension()
+	case ExtensionTypeVersion:
+		v := bs.md().GetVersion()
+		if v <= uint(impliedVersion(v >= 2 && isSet(bs.md().GetProxy_layer()))) || v > 0xff {
+			return Extension{}, false
+		}
+		e, err = VersionExtension{Version: uint8(v)}.AsExtension()
+	}
+	return e, err == nil
+}
+
+// impliedVersion returns the version of an extensions list without a version extension, which
+// like the C++ library is inferred from the presence of the proxy layer extension.
+func impliedVersion(hasProxyLayer bool) int32 {
+	if hasProxyLayer {
+		return 2
+	}
+	return 1
+}
+
+// SetExtension sets the extension with type typeID to value. Known extension types are decoded
+// into the corresponding field and must hold a valid value for it. Any other type is stored as is
+// and serialized in type order with the known extensions, replacing an earlier value of the same
+// type.
+func (bs *BinaryStruct) SetExtension(typeID uint16, value []byte) error {
+	bs.mu.Lock()
+	err := bs.setExtension(typeID, value)
+	bs.mu.Unlock()
+	bs.audit(AuditMutate, "set_extension", err)
+	return err
+}
+
+func (bs *BinaryStruct) setExtension(typeID uint16, value []byte) error {
+	if err := bs.checkMutable(); err != nil {
+		return err
+	}
+	e := Extension{Type: typeID, Value: value}
+	switch typeID {
+	case ExtensionTypeExpirationTimestamp:
+		exp, err := ExpirationExtensionFromExtension(e)
+		if err != nil {
+			return err
+		}
+		if err := checkExpirationSeconds(int64(exp.Timestamp)); err != nil {
+			return err
+		}
+		bs.metadata.SetExpiration_epoch_seconds(wrap.NewUint64Optional(exp.Timestamp))
+	case ExtensionTypeGeoHint:
+		geo, err := GeoHintExtensionFromExtension(e)
+		if err != nil {
+			return err
+		}
+		bs.metadata.SetCountry(wrap.NewStringOptional(geo.CountryCode))
+		bs.metadata.SetRegion(wrap.NewStringOptional(geo.Region))
+		bs.metadata.SetCity(wrap.NewStringOptional(geo.City))
+	case ExtensionTypeServiceType:
+		st, err := ServiceTypeExtensionFromExtension(e)
+		if err != nil {
+			return err
+		}
+		bs.metadata.SetService_type(wrap.NewStringOptional(st.ServiceType))
+	case ExtensionTypeDebugMode:
+		dm, err := DebugModeExtensionFromExtension(e)
+		if err != nil {
+			return err
+		}
+		bs.metadata.SetDebug_mode(uint(dm.Mode))
+	case ExtensionTypeProxyLayer:
+		pl, err := ProxyLayerExtensionFromExtension(e)
+		if err != nil {
+			return err
+		}
+		bs.metadata.SetProxy_layer(wrap.NewUint32Optional(uint(pl.Layer)))
+	case ExtensionTypeVersion:
+		v, err := VersionExtensionFromExtension(e)
+		if err != nil {
+			return err
+		}
+		if _, err := Capabilities(int32(v.Version)); err != nil {
+			return err
+		}
+		bs.metadata.SetVersion(uint(v.Version))
+	default:
+		if len(value) > 0xffff {
+			return fmt.Errorf("%w: extension %#04x value of %d bytes is too long", ErrMalformed, typeID, len(value))
+		}
+		e.Value = bytes.Clone(value)
+		bs.putExtra(e)
+	}
+	return nil
+}
+
+// putExtra adds e to the extensions not modeled by the C++ struct, replacing an earlier one of the
+// same type and keeping them sorted by type.
+func (bs *BinaryStruct) putExtra(e Extension) {
+	i, found := slices.BinarySearchFunc(bs.extra, e, compareExtensionTypes)
+	if found {
+		bs.extra[i] = e
+		return
+	}
+	bs.extra = slices.Insert(bs.extra, i, e)
+}
+
+// appendExtra appends known, the extensions list serialized by the C++ library, to dst with the
+// extensions added with SetExtension merged in so that every extension is in type order.
+func (bs *BinaryStruct) appendExtra(dst []byte, known string) ([]byte, error) {
+	if len(bs.extra) == 0 {
+		return append(dst, known...), nil
+	}
+	if len(known) < 2 {
+		return nil, fmt.Errorf("%w: extensions list is %d bytes, want at least 2", ErrMalformed, len(known))
+	}
+	size := len(known) - 2
+	for _, e := range bs.extra {
+		size += 4 + len(e.Value)
+	}
+	if size > 0xffff {
+		return nil, fmt.Errorf("%w: extensions list of %d bytes is too long", ErrMalformed, size)
+	}
+	dst = slices.Grow(dst, 2+size)
+	dst = binary.BigEndian.AppendUint16(dst, uint16(size))
+	body, extra := known[2:], bs.extra
+	for len(body) >= 4 {
+		t := uint16(body[0])<<8 | uint16(body[1])
+		n := 4 + (int(body[2])<<8 | int(body[3]))
+		if n > len(body) {
+			break
+		}
+		for len(extra) > 0 && extra[0].Type < t {
+			dst = appendExtension(dst, extra[0])
+			extra = extra[1:]
+		}
+		dst = append(dst, body[:n]...)
+		body = body[n:]
+	}
+	if len(body) != 0 {
+		return nil, fmt.Errorf("%w: truncated extension in the serialized list", ErrMalformed)
+	}
+	for _, e := range extra {
+		dst = appendExtension(dst, e)
+	}
+	return dst, nil
+}
+
+// splitUnknownExtensions returns in without the extensions of unknown types, and copies of those
+// extensions. Input that does not decode is returned unchanged so that the C++ library reports
+// the error.
+func splitUnknownExtensions(in []byte) ([]byte, []Extension) {
+	exts, err := DecodeExtensions(in)
+	if err != nil {
+		return in, nil
+	}
+	var known, extra []Extension
+	for _, e := range exts {
+		if isKnownExtensionType(e.Type) {
+			known = append(known, e)
+			continue
+		}
+		extra = append(extra, Extension{Type: e.Type, Value: bytes.Clone(e.Value)})
+	}
+	if len(extra) == 0 {
+		return in, nil
+	}
+	slices.SortStableFunc(extra, compareExtensionTypes)
+	out, err := EncodeExtensions(known)
+	if err != nil {
+		return in, nil
+	}
+	return out, extra
+}
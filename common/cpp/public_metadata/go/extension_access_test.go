@@ -0,0 +1,139 @@
+package binarymetadata
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	plpb "google3/privacy/net/common/proto/proxy_layer_go_proto"
+)
+
+func newExtensionAccessStruct() *BinaryStruct {
+	return New(&NewBinaryFields{
+		Version:     2,
+		Country:     "US",
+		Region:      "US-CA",
+		City:        "SUNNYVALE",
+		ServiceType: "chromeipblinding",
+		Expiration:  &tpb.Timestamp{Seconds: 3600},
+		ProxyLayer:  plpb.ProxyLayer_PROXY_B,
+	})
+}
+
+func TestGetExtensionKnownMatchesSerialize(t *testing.T) {
+	bs := newExtensionAccessStruct()
+	defer bs.Free()
+	out, err := Serialize(bs)
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	exts, err := DecodeExtensions(out)
+	if err != nil {
+		t.Fatalf("DecodeExtensions failed: %v", err)
+	}
+	for _, e := range exts {
+		got, ok := bs.GetExtension(e.Type)
+		if !ok {
+			t.Errorf("GetExtension(%#04x) not present", e.Type)
+			continue
+		}
+		if !bytes.Equal(got, e.Value) {
+			t.Errorf("GetExtension(%#04x) = %x, want %x", e.Type, got, e.Value)
+		}
+	}
+}
+
+func TestGetExtensionUnset(t *testing.T) {
+	bs := New(&NewBinaryFields{Version: 1})
+	defer bs.Free()
+	for _, typeID := range []uint16{ExtensionTypeGeoHint, ExtensionTypeServiceType, ExtensionTypeProxyLayer, 0xF0FF} {
+		if v, ok := bs.GetExtension(typeID); ok {
+			t.Errorf("GetExtension(%#04x) = %x, want not present", typeID, v)
+		}
+	}
+}
+
+func TestSetExtensionKnown(t *testing.T) {
+	bs := newExtensionAccessStruct()
+	defer bs.Free()
+	geo, err := GeoHintExtension{CountryCode: "CA", Region: "CA-ON", City: "TORONTO"}.AsExtension()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bs.SetExtension(geo.Type, geo.Value); err != nil {
+		t.Fatalf("SetExtension(geo hint) failed: %v", err)
+	}
+	if got := bs.GetGeoHint(); got.Country != "CA" || got.Region != "CA-ON" || got.City != "TORONTO" {
+		t.Errorf("GetGeoHint() = %+v, want CA,CA-ON,TORONTO", got)
+	}
+	if err := bs.SetExtension(ExtensionTypeDebugMode, []byte{0x02}); !errors.Is(err, ErrInvalidDebugMode) {
+		t.Errorf("SetExtension(debug mode 2) returned error: %v, want error: %v", err, ErrInvalidDebugMode)
+	}
+	if err := bs.SetExtension(ExtensionTypeExpirationTimestamp, []byte{0x01}); !errors.Is(err, ErrMalformed) {
+		t.Errorf("SetExtension(short expiration) returned error: %v, want error: %v", err, ErrMalformed)
+	}
+}
+
+func TestSetExtensionUnknown(t *testing.T) {
+	bs := newExtensionAccessStruct()
+	defer bs.Free()
+	value := []byte{0xca, 0xfe}
+	if err := bs.SetExtension(0xF0FF, value); err != nil {
+		t.Fatalf("SetExtension failed: %v", err)
+	}
+	value[0] = 0x00
+	if err := bs.SetExtension(0xF0FF, []byte{0xbe, 0xef}); err != nil {
+		t.Fatalf("SetExtension failed: %v", err)
+	}
+	if got, ok := bs.GetExtension(0xF0FF); !ok || !bytes.Equal(got, []byte{0xbe, 0xef}) {
+		t.Errorf("GetExtension(0xF0FF) = %x, %v, want beef, true", got, ok)
+	}
+	out, err := Serialize(bs)
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	exts, err := DecodeExtensions(out)
+	if err != nil {
+		t.Fatalf("DecodeExtensions failed: %v", err)
+	}
+	if len(exts) != 6 {
+		t.Fatalf("Serialize produced %d extensions, want 6", len(exts))
+	}
+	if last := exts[5]; last.Type != 0xF0FF || !bytes.Equal(last.Value, []byte{0xbe, 0xef}) {
+		t.Errorf("last serialized extension = %#04x:%x, want 0xf0ff:beef", last.Type, last.Value)
+	}
+}
+
+func TestDeserializePreservesUnknownExtensions(t *testing.T) {
+	bs := newExtensionAccessStruct()
+	defer bs.Free()
+	if err := bs.SetExtension(0xF0FF, []byte{0xbe, 0xef}); err != nil {
+		t.Fatalf("SetExtension failed: %v", err)
+	}
+	if err := bs.SetExtension(0x00AA, nil); err != nil {
+		t.Fatalf("SetExtension failed: %v", err)
+	}
+	want, err := Serialize(bs)
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	parsed, err := Deserialize(want)
+	if err != nil {
+		t.Fatalf("Deserialize failed: %v", err)
+	}
+	defer parsed.Free()
+	if got, ok := parsed.GetExtension(0xF0FF); !ok || !bytes.Equal(got, []byte{0xbe, 0xef}) {
+		t.Errorf("GetExtension(0xF0FF) = %x, %v, want beef, true", got, ok)
+	}
+	if !Equal(bs, parsed) {
+		t.Errorf("Deserialize(Serialize()) known fields differ: %v", Diff(bs, parsed))
+	}
+	got, err := Serialize(parsed)
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Serialize(Deserialize(%x)) = %x", want, got)
+	}
+}
@@ -0,0 +1,512 @@
+package binarymetadata
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Extension type IDs of the known extensions, as registered by the Privacy Pass extensions draft
+// and the anonymous tokens library. Other types are carried as is; see BinaryStruct.SetExtension.
+const (
+	ExtensionTypeExpirationTimestamp uint16 = 0x0001
+	ExtensionTypeGeoHint             uint16 = 0x0002
+	ExtensionTypeServiceType         uint16 = 0xF001
+	ExtensionTypeDebugMode           uint16 = 0xF002
+	ExtensionTypeProxyLayer          uint16 = 0xF003
+	// ExtensionTypeExpirationMillis carries the sub-second part of the expiration for versions
+	// with millisecond precision. It is not modeled by the C++ struct.
+	ExtensionTypeExpirationMillis uint16 = 0xF004
+	// ExtensionTypeNetworkType carries the coarse NetworkType of the client for versions with the
+	// NetworkType capability. It is not modeled by the C++ struct.
+	ExtensionTypeNetworkType uint16 = 0xF005
+	// ExtensionTypeClientPlatform carries the coarse ClientPlatform of the client for versions with
+	// the ClientPlatform capability. It is not modeled by the C++ struct.
+	ExtensionTypeClientPlatform uint16 = 0xF006
+	// ExtensionTypeServiceTier carries the ServiceTier of the client for versions with the
+	// ServiceTier capability. It is not modeled by the C++ struct.
+	ExtensionTypeServiceTier uint16 = 0xF007
+	// ExtensionTypeAttestationLevel carries the AttestationLevel of the client for versions with the
+	// AttestationLevel capability. It is not modeled by the C++ struct.
+	ExtensionTypeAttestationLevel uint16 = 0xF008
+	// ExtensionTypeKeyEpoch carries the epoch of the signing key for versions with the KeyEpoch
+	// capability. It is not modeled by the C++ struct.
+	ExtensionTypeKeyEpoch uint16 = 0xF009
+	// ExtensionTypeNonce carries a random per-blob nonce for versions with the Nonce capability. It
+	// is not modeled by the C++ struct.
+	ExtensionTypeNonce uint16 = 0xF00A
+	// ExtensionTypeVersion carries the version of metadata whose version is not implied by the
+	// presence of the proxy layer extension, e.g. version 2 without a proxy layer. It is modeled by
+	// the version of the C++ struct.
+	ExtensionTypeVersion uint16 = 0xF00B
+)
+
+// Value ranges of the known extensions.
+const (
+	// ExpirationTimestampPrecision is the timestamp precision, in seconds, that the C++ library
+	// writes and requires.
+	ExpirationTimestampPrecision = 900
+	// MaxExpirationMillis is the largest millisecond part of an expiration.
+	MaxExpirationMillis = 999
+	// MaxGeoHintLength is the length of the longest "COUNTRY,REGION,CITY" string a geo hint can
+	// carry after its own uint16 length prefix.
+	MaxGeoHintLength = 0xffff - 2
+	// NonceSize is the length of the value of the nonce extension.
+	NonceSize = 16
+)
+
+// Extension is a single type/length/value entry of a Privacy Pass extensions list.
+type Extension struct {
+	Type  uint16
+	Value []byte
+}
+
+// EncodeExtensions encodes exts in the Privacy Pass extensions wire format: a big-endian uint16
+// total length followed by each extension as a uint16 type, a uint16 length and the value.
+func EncodeExtensions(exts []Extension) ([]byte, error) {
+	size := 0
+	for _, e := range exts {
+		if len(e.Value) > 0xffff {
+			return nil, fmt.Errorf("%w: extension %#04x value of %d bytes is too long", ErrMalformed, e.Type, len(e.Value))
+		}
+		size += 4 + len(e.Value)
+	}
+	if size > 0xffff {
+		return nil, fmt.Errorf("%w: extensions list of %d bytes is too long", ErrMalformed, size)
+	}
+	out := make([]byte, 0, 2+size)
+	out = binary.BigEndian.AppendUint16(out, uint16(size))
+	for _, e := range exts {
+		out = appendExtension(out, e)
+	}
+	return out, nil
+}
+
+// appendExtension appends the type, length and value of e to dst.
+func appendExtension(dst []byte, e Extension) []byte {
+	dst = binary.BigEndian.AppendUint16(dst, e.Type)
+	dst = binary.BigEndian.AppendUint16(dst, uint16(len(e.Value)))
+	return append(dst, e.Value...)
+}
+
+// compareExtensionTypes orders extensions by type, the order of the canonical encoding.
+func compareExtensionTypes(a, b Extension) int {
+	return cmp.Compare(a.Type, b.Type)
+}
+
+// DecodeExtensions decodes a Privacy Pass extensions list. The returned values alias in.
+func DecodeExtensions(in []byte) ([]Extension, error) {
+	if len(in) < 2 {
+		return nil, fmt.Errorf("%w: extensions list is %d bytes, want at least 2", ErrMalformed, len(in))
+	}
+	size := int(binary.BigEndian.Uint16(in))
+	body := in[2:]
+	if len(body) != size {
+		return nil, fmt.Errorf("%w: extensions list declares %d bytes but has %d", ErrMalformed, size, len(body))
+	}
+	var exts []Extension
+	for len(body) > 0 {
+		if len(body) < 4 {
+			return nil, fmt.Errorf("%w: truncated extension header", ErrMalformed)
+		}
+		t := binary.BigEndian.Uint16(body)
+		n := int(binary.BigEndian.Uint16(body[2:]))
+		body = body[4:]
+		if len(body) < n {
+			return nil, fmt.Errorf("%w: extension %#04x declares %d bytes but has %d", ErrMalformed, t, n, len(body))
+		}
+		exts = append(exts, Extension{Type: t, Value: body[:n:n]})
+		body = body[n:]
+	}
+	return exts, nil
+}
+
+// checkExtensionOrder reports an error wrapping ErrMalformed unless exts are in strictly ascending
+// type order, as the extensions list of a token request requires. A repeated type is out of order.
+func checkExtensionOrder(exts []Extension) error {
+	for i := 1; i < len(exts); i++ {
+		switch prev, t := exts[i-1].Type, exts[i].Type; {
+		case prev == t:
+			return fmt.Errorf("%w: duplicate extension type %#04x", ErrMalformed, t)
+		case prev > t:
+			return fmt.Errorf("%w: extension type %#04x after %#04x", ErrMalformed, t, prev)
+		}
+	}
+	return nil
+}
+
+func checkExtensionType(e Extension, want uint16) error {
+	if e.Type != want {
+		return fmt.Errorf("%w: extension type %#04x, want %#04x", ErrMalformed, e.Type, want)
+	}
+	return nil
+}
+
+// ExpirationExtension is the expiration timestamp extension.
+type ExpirationExtension struct {
+	// TimestampPrecision is the granularity of Timestamp in seconds.
+	TimestampPrecision uint64
+	// Timestamp is the expiration in seconds since the unix epoch.
+	Timestamp uint64
+}
+
+// AsExtension encodes e.
+func (e ExpirationExtension) AsExtension() (Extension, error) {
+	v := binary.BigEndian.AppendUint64(make([]byte, 0, 16), e.TimestampPrecision)
+	v = binary.BigEndian.AppendUint64(v, e.Timestamp)
+	return Extension{Type: ExtensionTypeExpirationTimestamp, Value: v}, nil
+}
+
+// ExpirationExtensionFromExtension decodes an expiration timestamp extension.
+func ExpirationExtensionFromExtension(e Extension) (ExpirationExtension, error) {
+	if err := checkExtensionType(e, ExtensionTypeExpirationTimestamp); err != nil {
+		return ExpirationExtension{}, err
+	}
+	if len(e.Value) != 16 {
+		return ExpirationExtension{}, fmt.Errorf("%w: expiration extension is %d bytes, want 16", ErrMalformed, len(e.Value))
+	}
+	return ExpirationExtension{
+		TimestampPrecision: binary.BigEndian.Uint64(e.Value),
+		Timestamp:          binary.BigEndian.Uint64(e.Value[8:]),
+	}, nil
+}
+
+// ExpirationMillisExtension is the millisecond part of the expiration, sent after the expiration
+// timestamp extension by versions with millisecond precision.
+type ExpirationMillisExtension struct {
+	// Millis is the number of milliseconds after Timestamp of the expiration timestamp extension.
+	Millis uint16
+}
+
+// AsExtension encodes e. Millis must be at most MaxExpirationMillis.
+func (e ExpirationMillisExtension) AsExtension() (Extension, error) {
+	if e.Millis > MaxExpirationMillis {
+		return Extension{}, fmt.Errorf("%w: %d milliseconds, want at most %d", ErrInvalidExpiration, e.Millis, MaxExpirationMillis)
+	}
+	return Extension{Type: ExtensionTypeExpirationMillis, Value: binary.BigEndian.AppendUint16(nil, e.Millis)}, nil
+}
+
+// ExpirationMillisExtensionFromExtension decodes an expiration milliseconds extension.
+func ExpirationMillisExtensionFromExtension(e Extension) (ExpirationMillisExtension, error) {
+	if err := checkExtensionType(e, ExtensionTypeExpirationMillis); err != nil {
+		return ExpirationMillisExtension{}, err
+	}
+	if len(e.Value) != 2 {
+		return ExpirationMillisExtension{}, fmt.Errorf("%w: expiration milliseconds extension is %d bytes, want 2", ErrMalformed, len(e.Value))
+	}
+	m := ExpirationMillisExtension{Millis: binary.BigEndian.Uint16(e.Value)}
+	if m.Millis > MaxExpirationMillis {
+		return ExpirationMillisExtension{}, fmt.Errorf("%w: %d milliseconds, want at most %d", ErrInvalidExpiration, m.Millis, MaxExpirationMillis)
+	}
+	return m, nil
+}
+
+// GeoHintExtension is the geo hint extension, carried on the wire as a uint16 length-prefixed
+// "COUNTRY,REGION,CITY" string.
+type GeoHintExtension struct {
+	CountryCode string
+	Region      string
+	City        string
+}
+
+// AsExtension encodes e. Commas are not allowed in any of the parts.
+func (e GeoHintExtension) AsExtension() (Extension, error) {
+	for _, p := range []string{e.CountryCode, e.Region, e.City} {
+		if strings.Contains(p, ",") {
+			return Extension{}, fmt.Errorf("%w: %q contains ','", ErrInvalidGeoHint, p)
+		}
+	}
+	s := e.CountryCode + "," + e.Region + "," + e.City
+	if len(s) > MaxGeoHintLength {
+		return Extension{}, fmt.Errorf("%w: geo hint of %d bytes is too long", ErrInvalidGeoHint, len(s))
+	}
+	v := binary.BigEndian.AppendUint16(make([]byte, 0, 2+len(s)), uint16(len(s)))
+	return Extension{Type: ExtensionTypeGeoHint, Value: append(v, s...)}, nil
+}
+
+// GeoHintExtensionFromExtension decodes a geo hint extension.
+func GeoHintExtensionFromExtension(e Extension) (GeoHintExtension, error) {
+	if err := checkExtensionType(e, ExtensionTypeGeoHint); err != nil {
+		return GeoHintExtension{}, err
+	}
+	if len(e.Value) < 2 || int(binary.BigEndian.Uint16(e.Value)) != len(e.Value)-2 {
+		return GeoHintExtension{}, fmt.Errorf("%w: geo hint length prefix does not match its value", ErrMalformed)
+	}
+	parts := strings.Split(string(e.Value[2:]), ",")
+	if len(parts) != 3 {
+		return GeoHintExtension{}, fmt.Errorf("%w: geo hint %q does not have 3 parts", ErrInvalidGeoHint, e.Value[2:])
+	}
+	return GeoHintExtension{CountryCode: parts[0], Region: parts[1], City: parts[2]}, nil
+}
+
+// Service type wire IDs.
+const (
+	ServiceTypeIDChromeIPBlinding uint8 = 0x01
+)
+
+// serviceTypeIDs maps service type names onto their wire IDs.
+var serviceTypeIDs = map[string]uint8{
+	ServiceTypeChromeIPBlinding: ServiceTypeIDChromeIPBlinding,
+}
+
+// ServiceTypeExtension is the service type extension, carried on the wire as a one byte ID.
+type ServiceTypeExtension struct {
+	ServiceTypeID uint8
+	// ServiceType is the name for ServiceTypeID, e.g. "chromeipblinding".
+	ServiceType string
+}
+
+// ServiceTypeExtensionFromName returns the extension for the named service type.
+func ServiceTypeExtensionFromName(name string) (ServiceTypeExtension, error) {
+	id, ok := serviceTypeIDs[name]
+	if !ok {
+		return ServiceTypeExtension{}, fmt.Errorf("%w: %q", ErrUnsupportedServiceType, name)
+	}
+	return ServiceTypeExtension{ServiceTypeID: id, ServiceType: name}, nil
+}
+
+// AsExtension encodes e using ServiceTypeID.
+func (e ServiceTypeExtension) AsExtension() (Extension, error) {
+	return Extension{Type: ExtensionTypeServiceType, Value: []byte{e.ServiceTypeID}}, nil
+}
+
+// ServiceTypeExtensionFromExtension decodes a service type extension. Unknown IDs are rejected
+// with ErrUnsupportedServiceType.
+func ServiceTypeExtensionFromExtension(e Extension) (ServiceTypeExtension, error) {
+	if err := checkExtensionType(e, ExtensionTypeServiceType); err != nil {
+		return ServiceTypeExtension{}, err
+	}
+	if len(e.Value) != 1 {
+		return ServiceTypeExtension{}, fmt.Errorf("%w: service type extension is %d bytes, want 1", ErrMalformed, len(e.Value))
+	}
+	for name, id := range serviceTypeIDs {
+		if id == e.Value[0] {
+			return ServiceTypeExtension{ServiceTypeID: id, ServiceType: name}, nil
+		}
+	}
+	return ServiceTypeExtension{}, fmt.Errorf("%w: service type ID %#02x", ErrUnsupportedServiceType, e.Value[0])
+}
+
+// Debug mode wire values. Values above DebugModeWireDebug are invalid.
+const (
+	DebugModeWireProd  uint8 = 0x00
+	DebugModeWireDebug uint8 = 0x01
+)
+
+// DebugModeExtension is the debug mode extension.
+type DebugModeExtension struct {
+	Mode uint8
+}
+
+// AsExtension encodes e.
+func (e DebugModeExtension) AsExtension() (Extension, error) {
+	if e.Mode > DebugModeWireDebug {
+		return Extension{}, fmt.Errorf("%w: %d", ErrInvalidDebugMode, e.Mode)
+	}
+	return Extension{Type: ExtensionTypeDebugMode, Value: []byte{e.Mode}}, nil
+}
+
+// DebugModeExtensionFromExtension decodes a debug mode extension.
+func DebugModeExtensionFromExtension(e Extension) (DebugModeExtension, error) {
+	if err := checkExtensionType(e, ExtensionTypeDebugMode); err != nil {
+		return DebugModeExtension{}, err
+	}
+	if len(e.Value) != 1 {
+		return DebugModeExtension{}, fmt.Errorf("%w: debug mode extension is %d bytes, want 1", ErrMalformed, len(e.Value))
+	}
+	if e.Value[0] > DebugModeWireDebug {
+		return DebugModeExtension{}, fmt.Errorf("%w: %d", ErrInvalidDebugMode, e.Value[0])
+	}
+	return DebugModeExtension{Mode: e.Value[0]}, nil
+}
+
+// ProxyLayerExtension is the proxy layer extension. Layer holds the wire value; see
+// ProxyLayerFromWire.
+type ProxyLayerExtension struct {
+	Layer uint8
+}
+
+// AsExtension encodes e.
+func (e ProxyLayerExtension) AsExtension() (Extension, error) {
+	if _, err := ProxyLayerFromWire(uint(e.Layer)); err != nil {
+		return Extension{}, err
+	}
+	return Extension{Type: ExtensionTypeProxyLayer, Value: []byte{e.Layer}}, nil
+}
+
+// ProxyLayerExtensionFromExtension decodes a proxy layer extension.
+func ProxyLayerExtensionFromExtension(e Extension) (ProxyLayerExtension, error) {
+	if err := checkExtensionType(e, ExtensionTypeProxyLayer); err != nil {
+		return ProxyLayerExtension{}, err
+	}
+	if len(e.Value) != 1 {
+		return ProxyLayerExtension{}, fmt.Errorf("%w: proxy layer extension is %d bytes, want 1", ErrMalformed, len(e.Value))
+	}
+	if _, err := ProxyLayerFromWire(uint(e.Value[0])); err != nil {
+		return ProxyLayerExtension{}, err
+	}
+	return ProxyLayerExtension{Layer: e.Value[0]}, nil
+}
+
+// NetworkTypeExtension is the network type extension.
+type NetworkTypeExtension struct {
+	Type NetworkType
+}
+
+// AsExtension encodes e.
+func (e NetworkTypeExtension) AsExtension() (Extension, error) {
+	return encodeCoarse(ExtensionTypeNetworkType, uint8(e.Type))
+}
+
+// NetworkTypeExtensionFromExtension decodes a network type extension.
+func NetworkTypeExtensionFromExtension(e Extension) (NetworkTypeExtension, error) {
+	if err := checkExtensionType(e, ExtensionTypeNetworkType); err != nil {
+		return NetworkTypeExtension{}, err
+	}
+	v, err := decodeCoarse(e)
+	if err != nil {
+		return NetworkTypeExtension{}, err
+	}
+	return NetworkTypeExtension{Type: NetworkType(v)}, nil
+}
+
+// ClientPlatformExtension is the client platform extension.
+type ClientPlatformExtension struct {
+	Platform ClientPlatform
+}
+
+// AsExtension encodes e.
+func (e ClientPlatformExtension) AsExtension() (Extension, error) {
+	return encodeCoarse(ExtensionTypeClientPlatform, uint8(e.Platform))
+}
+
+// ClientPlatformExtensionFromExtension decodes a client platform extension.
+func ClientPlatformExtensionFromExtension(e Extension) (ClientPlatformExtension, error) {
+	if err := checkExtensionType(e, ExtensionTypeClientPlatform); err != nil {
+		return ClientPlatformExtension{}, err
+	}
+	v, err := decodeCoarse(e)
+	if err != nil {
+		return ClientPlatformExtension{}, err
+	}
+	return ClientPlatformExtension{Platform: ClientPlatform(v)}, nil
+}
+
+// ServiceTierExtension is the service tier extension.
+type ServiceTierExtension struct {
+	Tier ServiceTier
+}
+
+// AsExtension encodes e.
+func (e ServiceTierExtension) AsExtension() (Extension, error) {
+	return encodeCoarse(ExtensionTypeServiceTier, uint8(e.Tier))
+}
+
+// ServiceTierExtensionFromExtension decodes a service tier extension.
+func ServiceTierExtensionFromExtension(e Extension) (ServiceTierExtension, error) {
+	if err := checkExtensionType(e, ExtensionTypeServiceTier); err != nil {
+		return ServiceTierExtension{}, err
+	}
+	v, err := decodeCoarse(e)
+	if err != nil {
+		return ServiceTierExtension{}, err
+	}
+	return ServiceTierExtension{Tier: ServiceTier(v)}, nil
+}
+
+// AttestationLevelExtension is the attestation level extension.
+type AttestationLevelExtension struct {
+	Level AttestationLevel
+}
+
+// AsExtension encodes e.
+func (e AttestationLevelExtension) AsExtension() (Extension, error) {
+	return encodeCoarse(ExtensionTypeAttestationLevel, uint8(e.Level))
+}
+
+// AttestationLevelExtensionFromExtension decodes an attestation level extension.
+func AttestationLevelExtensionFromExtension(e Extension) (AttestationLevelExtension, error) {
+	if err := checkExtensionType(e, ExtensionTypeAttestationLevel); err != nil {
+		return AttestationLevelExtension{}, err
+	}
+	v, err := decodeCoarse(e)
+	if err != nil {
+		return AttestationLevelExtension{}, err
+	}
+	return AttestationLevelExtension{Level: AttestationLevel(v)}, nil
+}
+
+// KeyEpochExtension is the key epoch extension, a uint32 identifying the signing key the metadata
+// was minted under.
+type KeyEpochExtension struct {
+	Epoch uint32
+}
+
+// AsExtension encodes e. Every epoch is encodable, so it never fails.
+func (e KeyEpochExtension) AsExtension() (Extension, error) {
+	return Extension{Type: ExtensionTypeKeyEpoch, Value: binary.BigEndian.AppendUint32(nil, e.Epoch)}, nil
+}
+
+// KeyEpochExtensionFromExtension decodes a key epoch extension.
+func KeyEpochExtensionFromExtension(e Extension) (KeyEpochExtension, error) {
+	if err := checkExtensionType(e, ExtensionTypeKeyEpoch); err != nil {
+		return KeyEpochExtension{}, err
+	}
+	if len(e.Value) != 4 {
+		return KeyEpochExtension{}, fmt.Errorf("%w: key epoch extension is %d bytes, want 4", ErrMalformed, len(e.Value))
+	}
+	return KeyEpochExtension{Epoch: binary.BigEndian.Uint32(e.Value)}, nil
+}
+
+// NonceExtension is the nonce extension, NonceSize random bytes that make every blob unique, so
+// that redemption servers can tell a replayed blob from a new one with the same fields.
+type NonceExtension struct {
+	Nonce [NonceSize]byte
+}
+
+// AsExtension encodes e. It never fails.
+func (e NonceExtension) AsExtension() (Extension, error) {
+	return Extension{Type: ExtensionTypeNonce, Value: bytes.Clone(e.Nonce[:])}, nil
+}
+
+// NonceExtensionFromExtension decodes a nonce extension.
+func NonceExtensionFromExtension(e Extension) (NonceExtension, error) {
+	if err := checkExtensionType(e, ExtensionTypeNonce); err != nil {
+		return NonceExtension{}, err
+	}
+	if len(e.Value) != NonceSize {
+		return NonceExtension{}, fmt.Errorf("%w: nonce extension is %d bytes, want %d", ErrMalformed, len(e.Value), NonceSize)
+	}
+	var n NonceExtension
+	copy(n.Nonce[:], e.Value)
+	return n, nil
+}
+
+// VersionExtension is the version extension.
+type VersionExtension struct {
+	Version uint8
+}
+
+// AsExtension encodes e.
+func (e VersionExtension) AsExtension() (Extension, error) {
+	if e.Version == 0 {
+		return Extension{}, fmt.Errorf("%w: %d", ErrUnknownVersion, e.Version)
+	}
+	return Extension{Type: ExtensionTypeVersion, Value: []byte{e.Version}}, nil
+}
+
+// VersionExtensionFromExtension decodes a version extension.
+func VersionExtensionFromExtension(e Extension) (VersionExtension, error) {
+	if err := checkExtensionType(e, ExtensionTypeVersion); err != nil {
+		return VersionExtension{}, err
+	}
+	if len(e.Value) != 1 {
+		return VersionExtension{}, fmt.Errorf("%w: version extension is %d bytes, want 1", ErrMalformed, len(e.Value))
+	}
+	if e.Value[0] == 0 {
+		return VersionExtension{}, fmt.Errorf("%w: %d", ErrUnknownVersion, e.Value[0])
+	}
+	return VersionExtension{Version: e.Value[0]}, nil
+}
@@ -0,0 +1,149 @@
+package binarymetadata
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"google3/third_party/golang/cmp/cmp"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	plpb "google3/privacy/net/common/proto/proxy_layer_go_proto"
+)
+
+// exampleExtensions is the example from publicmetadatacli: version 2 metadata for
+// US,US-NY,NEW YORK CITY expiring at 1701110700.
+const exampleExtensions = "AD8AAQAQAAAAAAAAA4QAAAAAZWTjrAACABgAFlVTLFVTLU5ZLE5FVyBZT1JLIENJVFnwAQABAfACAAEA8AMAAQA="
+
+func TestDecodeExtensionsExample(t *testing.T) {
+	in, err := base64.StdEncoding.DecodeString(exampleExtensions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exts, err := DecodeExtensions(in)
+	if err != nil {
+		t.Fatalf("DecodeExtensions failed: %v", err)
+	}
+	if len(exts) != 5 {
+		t.Fatalf("DecodeExtensions returned %d extensions, want 5", len(exts))
+	}
+	exp, err := ExpirationExtensionFromExtension(exts[0])
+	if err != nil {
+		t.Fatalf("ExpirationExtensionFromExtension failed: %v", err)
+	}
+	if diff := cmp.Diff(ExpirationExtension{TimestampPrecision: 900, Timestamp: 1701110700}, exp); diff != "" {
+		t.Errorf("expiration returned unexpected diff (-want +got):\n%s", diff)
+	}
+	geo, err := GeoHintExtensionFromExtension(exts[1])
+	if err != nil {
+		t.Fatalf("GeoHintExtensionFromExtension failed: %v", err)
+	}
+	if diff := cmp.Diff(GeoHintExtension{CountryCode: "US", Region: "US-NY", City: "NEW YORK CITY"}, geo); diff != "" {
+		t.Errorf("geo hint returned unexpected diff (-want +got):\n%s", diff)
+	}
+	st, err := ServiceTypeExtensionFromExtension(exts[2])
+	if err != nil || st.ServiceType != "chromeipblinding" {
+		t.Errorf("ServiceTypeExtensionFromExtension() = %v, %v, want chromeipblinding", st, err)
+	}
+	if dm, err := DebugModeExtensionFromExtension(exts[3]); err != nil || dm.Mode != DebugModeWireProd {
+		t.Errorf("DebugModeExtensionFromExtension() = %v, %v, want prod", dm, err)
+	}
+	if pl, err := ProxyLayerExtensionFromExtension(exts[4]); err != nil || pl.Layer != 0 {
+		t.Errorf("ProxyLayerExtensionFromExtension() = %v, %v, want layer 0", pl, err)
+	}
+
+	reencoded, err := EncodeExtensions(exts)
+	if err != nil {
+		t.Fatalf("EncodeExtensions failed: %v", err)
+	}
+	if !bytes.Equal(reencoded, in) {
+		t.Errorf("EncodeExtensions() = %x, want %x", reencoded, in)
+	}
+}
+
+func TestEncodeExtensionsMatchesSerialize(t *testing.T) {
+	bs := New(&NewBinaryFields{
+		Version:     2,
+		Country:     "US",
+		Region:      "US-CA",
+		City:        "SUNNYVALE",
+		ServiceType: "chromeipblinding",
+		Expiration:  &tpb.Timestamp{Seconds: 3600},
+		ProxyLayer:  plpb.ProxyLayer_PROXY_B,
+	})
+	defer bs.Free()
+	want, err := Serialize(bs)
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	service, err := ServiceTypeExtensionFromName("chromeipblinding")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exts []Extension
+	for _, e := range []interface{ AsExtension() (Extension, error) }{
+		ExpirationExtension{TimestampPrecision: 900, Timestamp: 3600},
+		GeoHintExtension{CountryCode: "US", Region: "US-CA", City: "SUNNYVALE"},
+		service,
+		DebugModeExtension{Mode: DebugModeWireProd},
+		ProxyLayerExtension{Layer: 1},
+	} {
+		ext, err := e.AsExtension()
+		if err != nil {
+			t.Fatalf("AsExtension() failed: %v", err)
+		}
+		exts = append(exts, ext)
+	}
+	got, err := EncodeExtensions(exts)
+	if err != nil {
+		t.Fatalf("EncodeExtensions failed: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("EncodeExtensions() = %x, want %x", got, want)
+	}
+}
+
+func TestDecodeExtensionsMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+	}{
+		{name: "empty", in: nil},
+		{name: "length mismatch", in: []byte{0x00, 0x05, 0x00, 0x01}},
+		{name: "truncated header", in: []byte{0x00, 0x02, 0x00, 0x01}},
+		{name: "truncated value", in: []byte{0x00, 0x05, 0x00, 0x01, 0x00, 0x02, 0xaa}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := DecodeExtensions(tc.in); !errors.Is(err, ErrMalformed) {
+				t.Errorf("DecodeExtensions(%x) returned error: %v, want error: %v", tc.in, err, ErrMalformed)
+			}
+		})
+	}
+}
+
+func TestExtensionValueRanges(t *testing.T) {
+	if _, err := (ExpirationMillisExtension{Millis: MaxExpirationMillis}).AsExtension(); err != nil {
+		t.Errorf("AsExtension(MaxExpirationMillis) failed: %v", err)
+	}
+	if _, err := (ExpirationMillisExtension{Millis: MaxExpirationMillis + 1}).AsExtension(); !errors.Is(err, ErrInvalidExpiration) {
+		t.Errorf("AsExtension(MaxExpirationMillis + 1) returned error: %v, want error: %v", err, ErrInvalidExpiration)
+	}
+	city := string(bytes.Repeat([]byte{'A'}, MaxGeoHintLength-len("US,,")))
+	if _, err := (GeoHintExtension{CountryCode: "US", City: city}).AsExtension(); err != nil {
+		t.Errorf("AsExtension(MaxGeoHintLength) failed: %v", err)
+	}
+	if _, err := (GeoHintExtension{CountryCode: "US", City: city + "A"}).AsExtension(); !errors.Is(err, ErrInvalidGeoHint) {
+		t.Errorf("AsExtension(MaxGeoHintLength + 1) returned error: %v, want error: %v", err, ErrInvalidGeoHint)
+	}
+	if _, err := (DebugModeExtension{Mode: DebugModeWireDebug + 1}).AsExtension(); !errors.Is(err, ErrInvalidDebugMode) {
+		t.Errorf("AsExtension(DebugModeWireDebug + 1) returned error: %v, want error: %v", err, ErrInvalidDebugMode)
+	}
+	if _, err := (ProxyLayerExtension{Layer: ProxyLayerWireB + 1}).AsExtension(); !errors.Is(err, ErrInvalidProxyLayer) {
+		t.Errorf("AsExtension(ProxyLayerWireB + 1) returned error: %v, want error: %v", err, ErrInvalidProxyLayer)
+	}
+	if _, err := (VersionExtension{Version: 0}).AsExtension(); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("AsExtension(version 0) returned error: %v, want error: %v", err, ErrUnknownVersion)
+	}
+}
@@ -0,0 +1,37 @@
+package binarymetadata
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+)
+
+// Fingerprint returns the SHA-256 hash of the serialized metadata. Serialize is deterministic, so
+// equal metadata always has the same fingerprint, which makes it suitable as a cache key or dedup
+// key. Unlike FingerprintPublicMetadata, which hashes the PublicMetadata proto, it covers every
+// field of the binary format, including extensions preserved from Deserialize.
+//
+// The hash is unsalted and the fields have few possible values, so anyone holding a fingerprint
+// can recover the geo hint and the other fields by hashing candidates. Use KeyedFingerprint where
+// fingerprints are seen by parties that must not learn the fields, e.g. in logs.
+func (bs *BinaryStruct) Fingerprint() ([32]byte, error) {
+	out, err := Serialize(bs)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return sha256.Sum256(out), nil
+}
+
+// KeyedFingerprint is like Fingerprint, but returns the HMAC-SHA256 of the serialized metadata
+// under key. Only holders of key can tell which metadata a keyed fingerprint belongs to, so it
+// hides the fields as long as key is secret and at least 32 random bytes.
+func (bs *BinaryStruct) KeyedFingerprint(key []byte) ([32]byte, error) {
+	out, err := Serialize(bs)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(out)
+	var fp [32]byte
+	mac.Sum(fp[:0])
+	return fp, nil
+}
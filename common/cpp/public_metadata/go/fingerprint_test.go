@@ -0,0 +1,55 @@
+package binarymetadata
+
+import (
+	"testing"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+)
+
+func TestFingerprint(t *testing.T) {
+	fields := &NewBinaryFields{
+		Version:     1,
+		Country:     "US",
+		ServiceType: "chromeipblinding",
+		Expiration:  &tpb.Timestamp{Seconds: 900},
+	}
+	a, b := New(fields), New(fields)
+	defer a.Free()
+	defer b.Free()
+	fa, err := a.Fingerprint()
+	if err != nil {
+		t.Fatalf("Fingerprint failed: %v", err)
+	}
+	if fb, err := b.Fingerprint(); err != nil || fb != fa {
+		t.Errorf("Fingerprint() of equal metadata = %x, %v, want %x", fb, err, fa)
+	}
+	b.SetExpiration(&tpb.Timestamp{Seconds: 1800})
+	if fb, err := b.Fingerprint(); err != nil || fb == fa {
+		t.Errorf("Fingerprint() after SetExpiration = %x, %v, want a different fingerprint", fb, err)
+	}
+}
+
+func TestKeyedFingerprint(t *testing.T) {
+	bs := New(&NewBinaryFields{Version: 1, Country: "US", ServiceType: "chromeipblinding", Expiration: &tpb.Timestamp{Seconds: 900}})
+	defer bs.Free()
+	key, other := make([]byte, 32), make([]byte, 32)
+	other[0] = 1
+	fp, err := bs.Fingerprint()
+	if err != nil {
+		t.Fatalf("Fingerprint failed: %v", err)
+	}
+	k1, err := bs.KeyedFingerprint(key)
+	if err != nil {
+		t.Fatalf("KeyedFingerprint failed: %v", err)
+	}
+	if again, err := bs.KeyedFingerprint(key); err != nil || again != k1 {
+		t.Errorf("KeyedFingerprint() with the same key = %x, %v, want %x", again, err, k1)
+	}
+	k2, err := bs.KeyedFingerprint(other)
+	if err != nil {
+		t.Fatalf("KeyedFingerprint failed: %v", err)
+	}
+	if k1 == fp || k2 == k1 {
+		t.Errorf("KeyedFingerprint() = %x and %x for two keys, want both to differ from each other and from Fingerprint() = %x", k1, k2, fp)
+	}
+}
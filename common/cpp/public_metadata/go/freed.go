@@ -0,0 +1,33 @@
+package binarymetadata
+
+import (
+	"fmt"
+	"sync"
+
+	wrap "google3/privacy/net/common/cpp/public_metadata/go/wrap_public_metadata"
+)
+
+// freedMetadata is read in place of the C++ struct of a freed BinaryStruct, so that getters return
+// zero values instead of dereferencing a deleted struct. It is never written to nor deleted.
+var freedMetadata = sync.OnceValue(wrap.NewBinaryPublicMetadata)
+
+// md returns the wrapped C++ struct for reading, or freedMetadata if bs has been freed. The caller
+// holds the lock. Writers must check bs.metadata themselves.
+func (bs *BinaryStruct) md() wrap.BinaryPublicMetadata {
+	if bs.metadata == nil {
+		return freedMetadata()
+	}
+	return bs.metadata
+}
+
+// checkFreed returns ErrFreed if bs has been freed, with the stack of the Free call if it was
+// recorded. The caller holds the lock.
+func (bs *BinaryStruct) checkFreed() error {
+	switch {
+	case bs.metadata != nil:
+		return nil
+	case bs.freedAt != nil:
+		return fmt.Errorf("%w; Free was called at:\n%s", ErrFreed, bs.freedAt)
+	}
+	return ErrFreed
+}
@@ -0,0 +1,10 @@
+//go:build binarymetadata_debug
+
+package binarymetadata
+
+// recordFreeStacks makes Free record its call stack, so that a later use of the struct reports
+// where it was freed.
+const recordFreeStacks = true
+
+// detectLeaks is the default of SetLeakDetection.
+const detectLeaks = true
@@ -0,0 +1,21 @@
+//go:build binarymetadata_debug
+
+package binarymetadata
+
+import (
+	"strings"
+	"testing"
+)
+
+func freeForTest(bs *BinaryStruct) {
+	bs.Free()
+}
+
+func TestUseAfterFreeReportsFreeStack(t *testing.T) {
+	bs := newExtensionAccessStruct()
+	freeForTest(bs)
+	_, err := Serialize(bs)
+	if err == nil || !strings.Contains(err.Error(), "freeForTest") {
+		t.Errorf("Serialize after Free returned error: %v, want it to include the stack of the Free call", err)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !binarymetadata_debug
+
+package binarymetadata
+
+// recordFreeStacks makes Free record its call stack. Build with the binarymetadata_debug tag to
+// enable it.
+const recordFreeStacks = false
+
+// detectLeaks is the default of SetLeakDetection. Build with the binarymetadata_debug tag to
+// enable it.
+const detectLeaks = false
@@ -0,0 +1,61 @@
+package binarymetadata
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"google3/privacy/net/boq/common/tokens/tokentypes"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	plpb "google3/privacy/net/common/proto/proxy_layer_go_proto"
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+)
+
+func TestUseAfterFree(t *testing.T) {
+	bs := newExtensionAccessStruct()
+	bs.Free()
+	bs.Free()
+
+	if v, exp, geo := bs.GetVersion(), bs.GetExpiration(), bs.GetGeoHint(); v != 0 || exp != nil || *geo != (tokentypes.GeoHint{}) {
+		t.Errorf("getters after Free = %d, %v, %+v, want zero values", v, exp, geo)
+	}
+	if bs.HasCountry() || bs.GetServiceType() != "" || bs.GetProxyLayer() != plpb.ProxyLayer_PROXY_LAYER_UNSPECIFIED {
+		t.Error("getters after Free report set fields, want none")
+	}
+	if _, ok := bs.GetExtension(ExtensionTypeDebugMode); ok {
+		t.Error("GetExtension after Free reports a present extension")
+	}
+	_ = bs.String()
+	bs.SetServiceType("chromeipblinding")
+	bs.SetExpiration(&tpb.Timestamp{Seconds: 900})
+	bs.SetGeoHint(&tokentypes.GeoHint{Country: "US"})
+	bs.Reset()
+	if bs.HasServiceType() {
+		t.Error("SetServiceType after Free set the service type")
+	}
+
+	v, err := NewValidator(ValidationConfig{})
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+	for name, f := range map[string]func() error{
+		"Serialize":         func() error { _, err := Serialize(bs); return err },
+		"SerializedSize":    func() error { _, err := SerializedSize(bs); return err },
+		"MarshalJSON":       func() error { _, err := bs.MarshalJSON(); return err },
+		"SerializeCBOR":     func() error { _, err := SerializeCBOR(bs); return err },
+		"Migrate":           func() error { _, err := Migrate(bs, 1); return err },
+		"SetExtension":      func() error { return bs.SetExtension(0xF0FF, nil) },
+		"SetExpirationTime": func() error { return bs.SetExpirationTime(time.Unix(900, 0)) },
+		"SetProxyLayer":     func() error { return bs.SetProxyLayer(plpb.ProxyLayer_PROXY_A) },
+		"SetDebugMode":      func() error { return bs.SetDebugMode(pmpb.PublicMetadata_DEBUG_ALL) },
+		"LookupProxyLayer":  func() error { _, err := bs.LookupProxyLayer(); return err },
+		"ValidateCountry":   bs.ValidateCountry,
+		"TokenType":         func() error { _, err := bs.TokenType(); return err },
+		"ValidateStruct":    func() error { return v.ValidateStruct(bs, time.Unix(0, 0)) },
+	} {
+		if err := f(); !errors.Is(err, ErrFreed) {
+			t.Errorf("%s after Free returned error: %v, want error: %v", name, err, ErrFreed)
+		}
+	}
+}
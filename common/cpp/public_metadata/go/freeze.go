@@ -0,0 +1,81 @@
+package binarymetadata
+
+import (
+	"bytes"
+
+	wrap "google3/privacy/net/common/cpp/public_metadata/go/wrap_public_metadata"
+)
+
+// Freeze returns a read-only copy of bs that can be handed to concurrent pipelines once its
+// Fingerprint has been computed or it has been signed. Setters of the copy that return an error
+// fail with ErrFrozen and the others do nothing, as do Reset, Refill and the Unmarshal methods.
+// Later changes to bs do not affect the copy, which must be freed with Free like any BinaryStruct.
+func (bs *BinaryStruct) Freeze() (*BinaryStruct, error) {
+	frozen, err := bs.freeze()
+	if err != nil {
+		return nil, err
+	}
+	frozen.audit(AuditConstruct, "freeze", nil)
+	return frozen, nil
+}
+
+func (bs *BinaryStruct) freeze() (*BinaryStruct, error) {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+	if err := bs.checkFreed(); err != nil {
+		return nil, err
+	}
+	frozen := &BinaryStruct{metadata: cloneMetadata(bs.metadata), newer: bs.newer, frozen: true, auditTag: bs.auditTag}
+	frozen.trackAllocation()
+	for _, e := range bs.extra {
+		frozen.extra = append(frozen.extra, Extension{Type: e.Type, Value: bytes.Clone(e.Value)})
+	}
+	return frozen, nil
+}
+
+// IsFrozen reports whether bs was returned by Freeze.
+func (bs *BinaryStruct) IsFrozen() bool {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+	return bs.frozen
+}
+
+// checkMutable is checkFreed for mutations, which also fail with ErrFrozen on frozen structs.
+func (bs *BinaryStruct) checkMutable() error {
+	if err := bs.checkFreed(); err != nil {
+		return err
+	}
+	if bs.frozen {
+		return ErrFrozen
+	}
+	return nil
+}
+
+// cloneMetadata copies every field of src into a new C++ struct, keeping unset optionals unset.
+func cloneMetadata(src wrap.BinaryPublicMetadata) wrap.BinaryPublicMetadata {
+	dst := newMetadata()
+	dst.SetVersion(src.GetVersion())
+	dst.SetService_type(cloneStringOptional(src.GetService_type()))
+	dst.SetCountry(cloneStringOptional(src.GetCountry()))
+	dst.SetRegion(cloneStringOptional(src.GetRegion()))
+	dst.SetCity(cloneStringOptional(src.GetCity()))
+	if exp := src.GetExpiration_epoch_seconds(); isSet(exp) {
+		dst.SetExpiration_epoch_seconds(wrap.NewUint64Optional(exp.Value()))
+	} else {
+		dst.SetExpiration_epoch_seconds(wrap.NewUint64Optional())
+	}
+	dst.SetDebug_mode(src.GetDebug_mode())
+	if l := src.GetProxy_layer(); isSet(l) {
+		dst.SetProxy_layer(wrap.NewUint32Optional(l.Value()))
+	} else {
+		dst.SetProxy_layer(wrap.NewUint32Optional())
+	}
+	return dst
+}
+
+func cloneStringOptional(o wrap.StringOptional) wrap.StringOptional {
+	if isSet(o) {
+		return wrap.NewStringOptional(o.Value())
+	}
+	return wrap.NewStringOptional()
+}
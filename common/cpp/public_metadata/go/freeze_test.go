@@ -0,0 +1,70 @@
+package binarymetadata
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"google3/privacy/net/boq/common/tokens/tokentypes"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	plpb "google3/privacy/net/common/proto/proxy_layer_go_proto"
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+)
+
+func TestFreeze(t *testing.T) {
+	bs := New(&NewBinaryFields{Version: 2, Country: "US", Region: "US-CA", ServiceType: "chromeipblinding", Expiration: &tpb.Timestamp{Seconds: 3600}, ProxyLayer: plpb.ProxyLayer_PROXY_B})
+	defer bs.Free()
+	if err := bs.SetExtension(0x7001, []byte{1}); err != nil {
+		t.Fatalf("SetExtension failed: %v", err)
+	}
+	want, err := Serialize(bs)
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	frozen, err := bs.Freeze()
+	if err != nil {
+		t.Fatalf("Freeze failed: %v", err)
+	}
+	defer frozen.Free()
+	if !frozen.IsFrozen() || bs.IsFrozen() {
+		t.Errorf("IsFrozen() = %t for the copy and %t for the original, want true and false", frozen.IsFrozen(), bs.IsFrozen())
+	}
+
+	// Changes to the original do not reach the copy.
+	bs.SetServiceType("cronet")
+	frozen.SetServiceType("cronet")
+	frozen.SetExpiration(nil)
+	frozen.SetGeoHint(&tokentypes.GeoHint{Country: "CA"})
+	frozen.Reset()
+	for name, err := range map[string]error{
+		"SetProxyLayer":       frozen.SetProxyLayer(plpb.ProxyLayer_PROXY_A),
+		"SetDebugMode":        frozen.SetDebugMode(pmpb.PublicMetadata_UNSPECIFIED_DEBUG_MODE),
+		"SetExpirationTime":   frozen.SetExpirationTime(time.Unix(7200, 0)),
+		"SetExtension":        frozen.SetExtension(0x7001, []byte{2}),
+		"Refill":              frozen.Refill(&NewBinaryFields{Version: 1}),
+		"UnmarshalBinary":     frozen.UnmarshalBinary(want),
+		"UnmarshalJSON":       frozen.UnmarshalJSON([]byte(`{"version":1}`)),
+		"SetAttestationLevel": frozen.SetAttestationLevel(AttestationNone),
+	} {
+		if !errors.Is(err, ErrFrozen) {
+			t.Errorf("%s() returned error: %v, want error: %v", name, err, ErrFrozen)
+		}
+	}
+	got, err := Serialize(frozen)
+	if err != nil {
+		t.Fatalf("Serialize(frozen) failed: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Serialize(frozen) = %x, want %x", got, want)
+	}
+}
+
+func TestFreezeFreed(t *testing.T) {
+	bs := New(&NewBinaryFields{Version: 1, Country: "US", ServiceType: "chromeipblinding"})
+	bs.Free()
+	if _, err := bs.Freeze(); !errors.Is(err, ErrFreed) {
+		t.Errorf("Freeze() after Free returned error: %v, want error: %v", err, ErrFreed)
+	}
+}
@@ -0,0 +1,66 @@
+package binarymetadata
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"google3/privacy/net/boq/common/tokens/tokentypes"
+	"google3/third_party/golang/protobuf/v2/proto/proto"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+)
+
+// GeoHintFromLocation is the inverse of GetExitLocation: city_geo_id holds the region, optionally
+// followed by a comma and the city. A leading part equal to the country, as in "US,US-CA", is
+// dropped. Parts are upper-cased like Serialize does. A nil location yields an empty geo hint.
+func GeoHintFromLocation(loc *pmpb.PublicMetadata_Location) (*tokentypes.GeoHint, error) {
+	country := strings.ToUpper(loc.GetCountry())
+	if country == "" {
+		if loc.GetCityGeoId() != "" {
+			return nil, fmt.Errorf("%w: city_geo_id %q without a country", ErrInvalidGeoHint, loc.GetCityGeoId())
+		}
+		return &tokentypes.GeoHint{}, nil
+	}
+	if !IsValidCountry(country) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidCountry, loc.GetCountry())
+	}
+	geo := &tokentypes.GeoHint{Country: country}
+	if loc.GetCityGeoId() == "" {
+		return geo, nil
+	}
+	parts := strings.Split(strings.ToUpper(loc.GetCityGeoId()), ",")
+	if len(parts) > 1 && parts[0] == country {
+		parts = parts[1:]
+	}
+	if len(parts) > 2 || slices.Contains(parts, "") {
+		return nil, fmt.Errorf("%w: city_geo_id %q is not REGION or REGION,CITY", ErrInvalidGeoHint, loc.GetCityGeoId())
+	}
+	geo.Region = parts[0]
+	if len(parts) == 2 {
+		geo.City = parts[1]
+	}
+	return geo, nil
+}
+
+// NewBinaryFieldsFromProto returns the fields for md at version, with the exit location mapped by
+// GeoHintFromLocation. The expiration is copied.
+func NewBinaryFieldsFromProto(md *pmpb.PublicMetadata, version int32) (*NewBinaryFields, error) {
+	geo, err := GeoHintFromLocation(md.GetExitLocation())
+	if err != nil {
+		return nil, err
+	}
+	f := &NewBinaryFields{
+		Version:     version,
+		ServiceType: md.GetServiceType(),
+		DebugMode:   md.GetDebugMode(),
+		Country:     geo.Country,
+		Region:      geo.Region,
+		City:        geo.City,
+	}
+	if md.GetExpiration() != nil {
+		f.Expiration = proto.Clone(md.GetExpiration()).(*tpb.Timestamp)
+	}
+	return f, nil
+}
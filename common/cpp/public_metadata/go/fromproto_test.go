@@ -0,0 +1,66 @@
+package binarymetadata
+
+import (
+	"errors"
+	"testing"
+
+	"google3/privacy/net/boq/common/tokens/tokentypes"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+)
+
+func TestGeoHintFromLocation(t *testing.T) {
+	tests := []struct {
+		name    string
+		loc     *pmpb.PublicMetadata_Location
+		want    tokentypes.GeoHint
+		wantErr error
+	}{
+		{name: "nil", loc: nil},
+		{name: "country", loc: &pmpb.PublicMetadata_Location{Country: "us"}, want: tokentypes.GeoHint{Country: "US"}},
+		{name: "region", loc: &pmpb.PublicMetadata_Location{Country: "US", CityGeoId: "us-ca"}, want: tokentypes.GeoHint{Country: "US", Region: "US-CA"}},
+		{name: "city", loc: &pmpb.PublicMetadata_Location{Country: "US", CityGeoId: "US-CA,Sunnyvale"}, want: tokentypes.GeoHint{Country: "US", Region: "US-CA", City: "SUNNYVALE"}},
+		{name: "country prefix", loc: &pmpb.PublicMetadata_Location{Country: "US", CityGeoId: "US,US-CA"}, want: tokentypes.GeoHint{Country: "US", Region: "US-CA"}},
+		{name: "too many parts", loc: &pmpb.PublicMetadata_Location{Country: "US", CityGeoId: "US-CA,SUNNYVALE,EXTRA"}, wantErr: ErrInvalidGeoHint},
+		{name: "empty part", loc: &pmpb.PublicMetadata_Location{Country: "US", CityGeoId: "US-CA,"}, wantErr: ErrInvalidGeoHint},
+		{name: "no country", loc: &pmpb.PublicMetadata_Location{CityGeoId: "US-CA"}, wantErr: ErrInvalidGeoHint},
+		{name: "bad country", loc: &pmpb.PublicMetadata_Location{Country: "XX"}, wantErr: ErrInvalidCountry},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := GeoHintFromLocation(tc.loc)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("GeoHintFromLocation() returned error: %v, want error: %v", err, tc.wantErr)
+			}
+			if err == nil && *got != tc.want {
+				t.Errorf("GeoHintFromLocation() = %+v, want %+v", *got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewBinaryFieldsFromProto(t *testing.T) {
+	md := &pmpb.PublicMetadata{
+		ExitLocation: &pmpb.PublicMetadata_Location{Country: "US", CityGeoId: "US-CA,SUNNYVALE"},
+		ServiceType:  ServiceTypeChromeIPBlinding,
+		Expiration:   &tpb.Timestamp{Seconds: 900},
+		DebugMode:    pmpb.PublicMetadata_DEBUG_ALL,
+	}
+	f, err := NewBinaryFieldsFromProto(md, 2)
+	if err != nil {
+		t.Fatalf("NewBinaryFieldsFromProto failed: %v", err)
+	}
+	bs := New(f)
+	defer bs.Free()
+	if got := bs.GetExitLocation(); got.GetCountry() != "US" || got.GetCityGeoId() != "US-CA,SUNNYVALE" {
+		t.Errorf("GetExitLocation() = %v, want the original location", got)
+	}
+	if bs.GetServiceType() != md.GetServiceType() || bs.GetDebugMode() != md.GetDebugMode() || bs.GetExpiration().GetSeconds() != 900 || bs.GetVersion() != 2 {
+		t.Errorf("New(NewBinaryFieldsFromProto()) = %s, want the fields of %v", bs.DebugString(), md)
+	}
+	f.Expiration.Seconds = 1800
+	if md.GetExpiration().GetSeconds() != 900 {
+		t.Error("NewBinaryFieldsFromProto() aliased the expiration of the proto")
+	}
+}
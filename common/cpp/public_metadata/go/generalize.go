@@ -0,0 +1,34 @@
+package binarymetadata
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Generalize returns a copy of bs with its geo hint coarsened to at most level: GeoRegion drops
+// the city and GeoCountry drops the region and city. Every other field and the extensions of bs
+// are carried over, so the result can be logged or exported where city level hints must not be.
+// Hints already at or above level are copied unchanged. bs is left unchanged and the caller must
+// Free the result.
+func Generalize(bs *BinaryStruct, level GeoGranularity) (*BinaryStruct, error) {
+	if level < GeoCountry || level > GeoCity {
+		return nil, fmt.Errorf("invalid GeoGranularity %d", level)
+	}
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+	if err := bs.checkFreed(); err != nil {
+		return nil, err
+	}
+	f := bs.fields()
+	if level < GeoCity {
+		f.City = ""
+	}
+	if level < GeoRegion {
+		f.Region = ""
+	}
+	out := New(f)
+	for _, e := range bs.extra {
+		out.extra = append(out.extra, Extension{Type: e.Type, Value: bytes.Clone(e.Value)})
+	}
+	return out, nil
+}
@@ -0,0 +1,50 @@
+package binarymetadata
+
+import (
+	"testing"
+
+	"google3/third_party/golang/cmp/cmp"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	plpb "google3/privacy/net/common/proto/proxy_layer_go_proto"
+)
+
+func TestGeneralize(t *testing.T) {
+	bs := New(&NewBinaryFields{
+		Version:     2,
+		Country:     "US",
+		Region:      "US-CA",
+		City:        "SUNNYVALE",
+		ServiceType: ServiceTypeChromeIPBlinding,
+		Expiration:  &tpb.Timestamp{Seconds: 3600},
+		ProxyLayer:  plpb.ProxyLayer_PROXY_B,
+	})
+	defer bs.Free()
+	if err := bs.SetExtension(0xF0AA, []byte("opaque")); err != nil {
+		t.Fatalf("SetExtension failed: %v", err)
+	}
+	tests := []struct {
+		level GeoGranularity
+		want  []FieldDelta
+	}{
+		{level: GeoCity},
+		{level: GeoRegion, want: []FieldDelta{{Field: "city", Old: "SUNNYVALE"}}},
+		{level: GeoCountry, want: []FieldDelta{{Field: "region", Old: "US-CA"}, {Field: "city", Old: "SUNNYVALE"}}},
+	}
+	for _, tc := range tests {
+		out, err := Generalize(bs, tc.level)
+		if err != nil {
+			t.Fatalf("Generalize(%d) failed: %v", tc.level, err)
+		}
+		defer out.Free()
+		if diff := cmp.Diff(tc.want, Diff(bs, out)); diff != "" {
+			t.Errorf("Generalize(%d) returned unexpected diff (-want +got):\n%s", tc.level, diff)
+		}
+		if v, ok := out.GetExtension(0xF0AA); !ok || string(v) != "opaque" {
+			t.Errorf("Generalize(%d).GetExtension(0xF0AA) = %q, %v, want opaque, true", tc.level, v, ok)
+		}
+	}
+	if _, err := Generalize(bs, 0); err == nil {
+		t.Error("Generalize(0) succeeded, want error")
+	}
+}
@@ -0,0 +1,77 @@
+package binarymetadata
+
+import "strings"
+
+// GeoCatalog knows which regions and cities exist, so that validation can reject geo hints that
+// are well-formed but meaningless, e.g. the city PARIS in the region US-CA. Implementations must
+// be safe for concurrent use. Arguments are upper-case, as they are on the wire.
+type GeoCatalog interface {
+	// IsValidRegion reports whether region is an ISO 3166-2 subdivision of country.
+	IsValidRegion(country, region string) bool
+	// IsValidCity reports whether city lies in region of country.
+	IsValidCity(country, region, city string) bool
+}
+
+// regionsByCountry lists the subdivisions the default catalog knows for each country. Regions of
+// other countries only have to be in "CC-SUB" form.
+var regionsByCountry = map[string]map[string]bool{
+	"US": setOf("US-",
+		"AK AL AR AZ CA CO CT DC DE FL GA HI IA ID IL IN KS KY LA MA MD ME MI MN MO MS MT NC ND NE NH "+
+			"NJ NM NV NY OH OK OR PA RI SC SD TN TX UT VA VT WA WI WV WY AS GU MP PR UM VI"),
+	"CA": setOf("CA-", "AB BC MB NB NL NS NT NU ON PE QC SK YT"),
+}
+
+// citiesByRegion lists the cities the default catalog knows for a region. Any well-formed city is
+// accepted in regions without a list.
+var citiesByRegion = map[string]map[string]bool{
+	"US-CA": setOf("", strings.Join([]string{
+		"ANAHEIM", "BAKERSFIELD", "BERKELEY", "CUPERTINO", "FREMONT", "FRESNO", "IRVINE", "LONG_BEACH",
+		"LOS_ANGELES", "MOUNTAIN_VIEW", "OAKLAND", "PALO_ALTO", "RIVERSIDE", "SACRAMENTO", "SAN_DIEGO",
+		"SAN_FRANCISCO", "SAN_JOSE", "SANTA_ANA", "SANTA_CLARA", "STOCKTON", "SUNNYVALE",
+	}, " ")),
+	"US-NY": setOf("", strings.Join([]string{
+		"ALBANY", "BUFFALO", "NEW_YORK_CITY", "ROCHESTER", "SYRACUSE", "YONKERS",
+	}, " ")),
+}
+
+// setOf returns the set of the space separated names in list, each prefixed with prefix and with
+// '_' standing in for a space.
+func setOf(prefix, list string) map[string]bool {
+	m := map[string]bool{}
+	for _, name := range strings.Fields(list) {
+		m[prefix+strings.ReplaceAll(name, "_", " ")] = true
+	}
+	return m
+}
+
+type defaultGeoCatalog struct{}
+
+// DefaultGeoCatalog returns the GeoCatalog compiled into this package. It knows the subdivisions
+// of the US and Canada and the cities of a few regions, and otherwise only checks that regions are
+// in "CC-SUB" form and cities are non-empty names without commas.
+func DefaultGeoCatalog() GeoCatalog {
+	return defaultGeoCatalog{}
+}
+
+// IsValidRegion implements GeoCatalog.
+func (defaultGeoCatalog) IsValidRegion(country, region string) bool {
+	sub, ok := strings.CutPrefix(region, country+"-")
+	if !ok || len(sub) == 0 || len(sub) > 3 || !isAlnum(sub) {
+		return false
+	}
+	if regions, ok := regionsByCountry[country]; ok {
+		return regions[region]
+	}
+	return true
+}
+
+// IsValidCity implements GeoCatalog.
+func (c defaultGeoCatalog) IsValidCity(country, region, city string) bool {
+	if !c.IsValidRegion(country, region) || strings.TrimSpace(city) == "" || strings.Contains(city, ",") {
+		return false
+	}
+	if cities, ok := citiesByRegion[region]; ok {
+		return cities[city]
+	}
+	return true
+}
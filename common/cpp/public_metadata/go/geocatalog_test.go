@@ -0,0 +1,79 @@
+package binarymetadata
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+)
+
+func TestDefaultGeoCatalog(t *testing.T) {
+	c := DefaultGeoCatalog()
+	regions := []struct {
+		country, region string
+		want            bool
+	}{
+		{"US", "US-CA", true},
+		{"US", "US-ZZ", false},
+		{"US", "CA-ON", false},
+		{"CA", "CA-ON", true},
+		{"GB", "GB-ENG", true},
+		{"GB", "GB", false},
+		{"GB", "GB-TOOLONG", false},
+	}
+	for _, tc := range regions {
+		if got := c.IsValidRegion(tc.country, tc.region); got != tc.want {
+			t.Errorf("IsValidRegion(%q, %q) = %v, want %v", tc.country, tc.region, got, tc.want)
+		}
+	}
+	cities := []struct {
+		country, region, city string
+		want                  bool
+	}{
+		{"US", "US-CA", "SUNNYVALE", true},
+		{"US", "US-CA", "PARIS", false},
+		{"US", "", "PARIS", false},
+		{"US", "US-NY", "NEW YORK CITY", true},
+		{"US", "US-TX", "PARIS", true},
+		{"GB", "GB-ENG", "LONDON", true},
+		{"GB", "GB-ENG", "", false},
+	}
+	for _, tc := range cities {
+		if got := c.IsValidCity(tc.country, tc.region, tc.city); got != tc.want {
+			t.Errorf("IsValidCity(%q, %q, %q) = %v, want %v", tc.country, tc.region, tc.city, got, tc.want)
+		}
+	}
+}
+
+func TestValidatorGeoCatalog(t *testing.T) {
+	v, err := NewValidator(ValidationConfig{GeoCatalog: DefaultGeoCatalog()})
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+	for _, tc := range []struct {
+		region, city string
+		wantField    string
+	}{
+		{region: "US-CA", city: "SUNNYVALE"},
+		{region: "US-CA", city: "PARIS", wantField: "city"},
+		{region: "US-ZZ", city: "PARIS", wantField: "region"},
+	} {
+		in := serializeForTest(t, &NewBinaryFields{
+			Version:     1,
+			Country:     "US",
+			Region:      tc.region,
+			City:        tc.city,
+			ServiceType: ServiceTypeChromeIPBlinding,
+			Expiration:  &tpb.Timestamp{Seconds: 900},
+		})
+		err := v.Validate(in, time.Unix(0, 0))
+		var fe *FieldError
+		switch {
+		case tc.wantField == "" && err != nil:
+			t.Errorf("Validate(%s, %s) returned error: %v", tc.region, tc.city, err)
+		case tc.wantField != "" && (!errors.As(err, &fe) || fe.Field != tc.wantField || !errors.Is(err, ErrInvalidGeoHint)):
+			t.Errorf("Validate(%s, %s) returned error: %v, want %s error wrapping %v", tc.region, tc.city, err, tc.wantField, ErrInvalidGeoHint)
+		}
+	}
+}
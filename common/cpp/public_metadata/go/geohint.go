@@ -0,0 +1,36 @@
+package binarymetadata
+
+import (
+	"strings"
+
+	"google3/privacy/net/boq/common/tokens/tokentypes"
+)
+
+// CanonicalizeGeoHint returns geo in the canonical form used on the wire, so that the same logical
+// location always serializes to identical bytes:
+//   - the country is trimmed, upper-cased and converted from alpha-3 to alpha-2 where possible,
+//   - the region is upper-cased into ISO 3166-2 "CC-SUB" form, prefixing the country when only the
+//     subdivision was given and accepting '_' or ' ' as the separator,
+//   - the city is upper-cased with surrounding whitespace trimmed and inner runs of whitespace
+//     collapsed to a single space.
+//
+// Values that cannot be interpreted are upper-cased and trimmed but otherwise left as is, for
+// validation to reject.
+func CanonicalizeGeoHint(geo tokentypes.GeoHint) tokentypes.GeoHint {
+	country := strings.ToUpper(strings.TrimSpace(geo.Country))
+	if c, err := NormalizeCountry(country); err == nil {
+		country = c
+	}
+	region := strings.ToUpper(strings.TrimSpace(geo.Region))
+	if region != "" {
+		region = strings.NewReplacer("_", "-", " ", "-").Replace(region)
+		if country != "" && !strings.Contains(region, "-") {
+			region = country + "-" + region
+		}
+	}
+	return tokentypes.GeoHint{
+		Country: country,
+		Region:  region,
+		City:    strings.ToUpper(strings.Join(strings.Fields(geo.City), " ")),
+	}
+}
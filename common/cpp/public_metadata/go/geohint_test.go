@@ -0,0 +1,56 @@
+package binarymetadata
+
+import (
+	"testing"
+
+	"google3/privacy/net/boq/common/tokens/tokentypes"
+	"google3/third_party/golang/cmp/cmp"
+)
+
+func TestCanonicalizeGeoHint(t *testing.T) {
+	tests := []struct {
+		name string
+		in   tokentypes.GeoHint
+		want tokentypes.GeoHint
+	}{
+		{
+			name: "already canonical",
+			in:   tokentypes.GeoHint{Country: "US", Region: "US-CA", City: "SUNNYVALE"},
+			want: tokentypes.GeoHint{Country: "US", Region: "US-CA", City: "SUNNYVALE"},
+		},
+		{
+			name: "mixed case and whitespace",
+			in:   tokentypes.GeoHint{Country: " us", Region: "us-ca ", City: "  New   York City "},
+			want: tokentypes.GeoHint{Country: "US", Region: "US-CA", City: "NEW YORK CITY"},
+		},
+		{
+			name: "subdivision only",
+			in:   tokentypes.GeoHint{Country: "USA", Region: "ny"},
+			want: tokentypes.GeoHint{Country: "US", Region: "US-NY"},
+		},
+		{
+			name: "underscore separator",
+			in:   tokentypes.GeoHint{Country: "GB", Region: "gb_eng"},
+			want: tokentypes.GeoHint{Country: "GB", Region: "GB-ENG"},
+		},
+		{
+			name: "empty",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, CanonicalizeGeoHint(tc.in)); diff != "" {
+				t.Errorf("CanonicalizeGeoHint() returned unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewCanonicalGeoHint(t *testing.T) {
+	a := New(&NewBinaryFields{Country: "us", Region: "ca", City: "sunnyvale ", CanonicalGeoHint: true})
+	defer a.Free()
+	want := tokentypes.GeoHint{Country: "US", Region: "US-CA", City: "SUNNYVALE"}
+	if diff := cmp.Diff(&want, a.GetGeoHint()); diff != "" {
+		t.Errorf("GetGeoHint() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
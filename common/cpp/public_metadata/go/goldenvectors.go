@@ -0,0 +1,115 @@
+// Package goldenvectors generates serialized public metadata test vectors for cross-language
+// regression testing of the C++, Java and Go implementations.
+package goldenvectors
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"google3/privacy/net/common/cpp/public_metadata/go/binarymetadata"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	plpb "google3/privacy/net/common/proto/proxy_layer_go_proto"
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+)
+
+// Expiration is the expiration of every vector. It is fixed so that regenerating the vectors is
+// reproducible; consumers validate against a time shortly before it.
+var Expiration = time.Unix(1701110700, 0)
+
+// Vector is a single test vector.
+type Vector struct {
+	// Name identifies the vector, e.g. "v2_city_debug_all_proxy_b".
+	Name string `json:"name"`
+	// Fields are the inputs the vector was serialized from.
+	Fields *binarymetadata.NewBinaryFields `json:"fields"`
+	// Serialized is the canonical serialization of Fields, base64 encoded in JSON.
+	Serialized []byte `json:"serialized"`
+}
+
+var geos = []struct {
+	name                  string
+	country, region, city string
+}{
+	{"country", "US", "", ""},
+	{"region", "US", "US-CA", ""},
+	{"city", "US", "US-CA", "SUNNYVALE"},
+}
+
+var debugModes = []pmpb.PublicMetadata_DebugMode{
+	pmpb.PublicMetadata_UNSPECIFIED_DEBUG_MODE,
+	pmpb.PublicMetadata_DEBUG_ALL,
+}
+
+// proxyLayers lists the proxy layers per version. Versions before 2 do not carry one.
+var proxyLayers = map[int32][]plpb.ProxyLayer{
+	1: {plpb.ProxyLayer_PROXY_LAYER_UNSPECIFIED},
+	2: {plpb.ProxyLayer_PROXY_A, plpb.ProxyLayer_PROXY_B},
+	3: {plpb.ProxyLayer_PROXY_LAYER_UNSPECIFIED, plpb.ProxyLayer_PROXY_A, plpb.ProxyLayer_PROXY_B},
+}
+
+// Generate returns a vector for every combination of supported version, geo granularity, debug
+// mode and proxy layer, in a stable order.
+func Generate() ([]Vector, error) {
+	var vectors []Vector
+	for _, version := range binarymetadata.SupportedVersions() {
+		for _, geo := range geos {
+			for _, debug := range debugModes {
+				for _, layer := range proxyLayers[version] {
+					name := fmt.Sprintf("v%d_%s_%s", version, geo.name, debugName(debug))
+					if version >= 2 {
+						name += "_" + layerName(layer)
+					}
+					fields := &binarymetadata.NewBinaryFields{
+						Version:     version,
+						Country:     geo.country,
+						Region:      geo.region,
+						City:        geo.city,
+						ServiceType: "chromeipblinding",
+						Expiration:  &tpb.Timestamp{Seconds: Expiration.Unix()},
+						DebugMode:   debug,
+						ProxyLayer:  layer,
+					}
+					out, err := serialize(fields)
+					if err != nil {
+						return nil, fmt.Errorf("vector %s: %w", name, err)
+					}
+					vectors = append(vectors, Vector{Name: name, Fields: fields, Serialized: out})
+				}
+			}
+		}
+	}
+	return vectors, nil
+}
+
+func serialize(fields *binarymetadata.NewBinaryFields) ([]byte, error) {
+	bs := binarymetadata.New(fields)
+	defer bs.Free()
+	return binarymetadata.Serialize(bs)
+}
+
+func debugName(m pmpb.PublicMetadata_DebugMode) string {
+	if m == pmpb.PublicMetadata_DEBUG_ALL {
+		return "debug_all"
+	}
+	return "debug_off"
+}
+
+func layerName(l plpb.ProxyLayer) string {
+	switch l {
+	case plpb.ProxyLayer_PROXY_A:
+		return "proxy_a"
+	case plpb.ProxyLayer_PROXY_B:
+		return "proxy_b"
+	}
+	return "proxy_any"
+}
+
+// WriteJSON writes vectors to w as an indented JSON array.
+func WriteJSON(w io.Writer, vectors []Vector) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(vectors)
+}
@@ -0,0 +1,76 @@
+package goldenvectors
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"google3/privacy/net/common/cpp/public_metadata/go/binarymetadata"
+)
+
+func TestGenerate(t *testing.T) {
+	vectors, err := Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	// 3 geos x 2 debug modes, times 1 proxy layer for version 1, 2 for version 2 and 3 for
+	// version 3.
+	if len(vectors) != 36 {
+		t.Errorf("Generate returned %d vectors, want 36", len(vectors))
+	}
+	names := map[string]bool{}
+	for _, v := range vectors {
+		if names[v.Name] {
+			t.Errorf("duplicate vector name %q", v.Name)
+		}
+		names[v.Name] = true
+		if err := binarymetadata.ValidateMetadataCardinality(v.Serialized, Expiration.Add(-time.Hour)); err != nil {
+			t.Errorf("ValidateMetadataCardinality(%s) failed: %v", v.Name, err)
+		}
+	}
+}
+
+func TestWriteJSONIsReproducible(t *testing.T) {
+	var runs [2]bytes.Buffer
+	for i := range runs {
+		vectors, err := Generate()
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		if err := WriteJSON(&runs[i], vectors); err != nil {
+			t.Fatalf("WriteJSON failed: %v", err)
+		}
+	}
+	if !bytes.Equal(runs[0].Bytes(), runs[1].Bytes()) {
+		t.Error("WriteJSON output differs between runs")
+	}
+	var decoded []Vector
+	if err := json.Unmarshal(runs[0].Bytes(), &decoded); err != nil {
+		t.Fatalf("json.Unmarshal failed: %v", err)
+	}
+	if len(decoded) == 0 || decoded[0].Fields == nil || len(decoded[0].Serialized) == 0 {
+		t.Errorf("decoded vectors are incomplete: %+v", decoded)
+	}
+}
+
+// TestCheckedInVectors fails when the serialization of any vector changes. If the change is
+// intended, regenerate the file with "publicmetadatacli golden > testdata/golden_vectors.json".
+func TestCheckedInVectors(t *testing.T) {
+	want, err := os.ReadFile("testdata/golden_vectors.json")
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	vectors, err := Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	var got bytes.Buffer
+	if err := WriteJSON(&got, vectors); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	if !bytes.Equal(got.Bytes(), want) {
+		t.Errorf("generated vectors differ from testdata/golden_vectors.json:\n%s", got.String())
+	}
+}
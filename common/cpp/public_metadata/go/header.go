@@ -0,0 +1,95 @@
+package binarymetadata
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+)
+
+// HeaderName is the HTTP header that carries public metadata on CONNECT and MASQUE proxy
+// requests.
+const HeaderName = "Public-Metadata"
+
+// MaxHeaderValueLength caps the length of a header value ParseHeader accepts, and of one
+// FormatHeader produces. It leaves ample room for the metadata itself while keeping a hostile
+// client from making the server decode kilobytes of base64 per request.
+const MaxHeaderValueLength = 1024
+
+// FormatHeader serializes bs and encodes it as a HeaderName value, in unpadded URL-safe base64. It
+// fails with ErrMalformed if the value would exceed MaxHeaderValueLength.
+func FormatHeader(bs *BinaryStruct) (string, error) {
+	out, err := Serialize(bs)
+	if err != nil {
+		return "", err
+	}
+	if n := base64.RawURLEncoding.EncodedLen(len(out)); n > MaxHeaderValueLength {
+		return "", fmt.Errorf("%w: header value of %d bytes exceeds %d", ErrMalformed, n, MaxHeaderValueLength)
+	}
+	return base64.RawURLEncoding.EncodeToString(out), nil
+}
+
+// ParseHeader decodes and deserializes a value produced by FormatHeader. Values longer than
+// MaxHeaderValueLength are rejected before decoding. The caller must Free the result.
+func ParseHeader(value string) (*BinaryStruct, error) {
+	if len(value) > MaxHeaderValueLength {
+		return nil, fmt.Errorf("%w: header value of %d bytes exceeds %d", ErrMalformed, len(value), MaxHeaderValueLength)
+	}
+	in, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: header value is not base64url: %v", ErrMalformed, err)
+	}
+	return Deserialize(in)
+}
+
+// SetHeader sets the HeaderName header of h to the encoding of bs.
+func SetHeader(h http.Header, bs *BinaryStruct) error {
+	v, err := FormatHeader(bs)
+	if err != nil {
+		return err
+	}
+	h.Set(HeaderName, v)
+	return nil
+}
+
+// FromHeader parses the HeaderName header of h. It fails with ErrMissingField if the header is
+// absent and with ErrMalformed if it is repeated. The caller must Free the result.
+func FromHeader(h http.Header) (*BinaryStruct, error) {
+	values := h.Values(HeaderName)
+	switch len(values) {
+	case 0:
+		return nil, fmt.Errorf("%w: no %s header", ErrMissingField, HeaderName)
+	case 1:
+		return ParseHeader(values[0])
+	}
+	return nil, fmt.Errorf("%w: %d %s headers", ErrMalformed, len(values), HeaderName)
+}
+
+type contextKey struct{}
+
+// FromContext returns the metadata stored by RequireHeader in the context of a request. The
+// metadata is freed once the handler returns, so it must not be retained beyond that.
+func FromContext(ctx context.Context) (*BinaryStruct, bool) {
+	bs, ok := ctx.Value(contextKey{}).(*BinaryStruct)
+	return bs, ok
+}
+
+// RequireHeader returns a handler that parses the HeaderName header of each request and checks it
+// with v at v.Now() before calling next, which can read the metadata with FromContext. Requests without a
+// parseable header are answered with 400 Bad Request and those v rejects with 403 Forbidden; in
+// both cases next is not called.
+func RequireHeader(v *Validator, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bs, err := FromHeader(r.Header)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer bs.Free()
+		if err := v.ValidateStruct(bs, v.Now()); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, bs)))
+	})
+}
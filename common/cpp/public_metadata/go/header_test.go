@@ -0,0 +1,118 @@
+package binarymetadata
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+)
+
+func newHeaderTestStruct(t *testing.T, expiration time.Time) *BinaryStruct {
+	t.Helper()
+	bs := New(&NewBinaryFields{
+		Version:     1,
+		Country:     "US",
+		ServiceType: ServiceTypeChromeIPBlinding,
+		Expiration:  &tpb.Timestamp{Seconds: expiration.Truncate(15 * time.Minute).Unix()},
+	})
+	t.Cleanup(bs.Free)
+	return bs
+}
+
+func TestHeaderRoundTrip(t *testing.T) {
+	bs := newHeaderTestStruct(t, time.Now().Add(time.Hour))
+	h := http.Header{}
+	if err := SetHeader(h, bs); err != nil {
+		t.Fatalf("SetHeader failed: %v", err)
+	}
+	if strings.ContainsAny(h.Get(HeaderName), "+/=") {
+		t.Errorf("header value %q is not unpadded base64url", h.Get(HeaderName))
+	}
+	got, err := FromHeader(h)
+	if err != nil {
+		t.Fatalf("FromHeader failed: %v", err)
+	}
+	defer got.Free()
+	if !Equal(bs, got) {
+		t.Errorf("FromHeader = %v, want %v", got, bs)
+	}
+}
+
+func TestFromHeaderErrors(t *testing.T) {
+	value, err := FormatHeader(newHeaderTestStruct(t, time.Now().Add(time.Hour)))
+	if err != nil {
+		t.Fatalf("FormatHeader failed: %v", err)
+	}
+	tests := []struct {
+		name   string
+		values []string
+		want   error
+	}{
+		{name: "missing", want: ErrMissingField},
+		{name: "repeated", values: []string{value, value}, want: ErrMalformed},
+		{name: "padded", values: []string{value + "="}, want: ErrMalformed},
+		{name: "too long", values: []string{strings.Repeat("A", MaxHeaderValueLength+1)}, want: ErrMalformed},
+		{name: "not metadata", values: []string{"AAAA"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := http.Header{HeaderName: tc.values}
+			bs, err := FromHeader(h)
+			if err == nil {
+				bs.Free()
+			}
+			if err == nil || tc.want != nil && !errors.Is(err, tc.want) {
+				t.Errorf("FromHeader = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestRequireHeader(t *testing.T) {
+	v, err := NewValidator(ValidationConfig{})
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+	var country string
+	handler := RequireHeader(v, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bs, ok := FromContext(r.Context())
+		if !ok {
+			t.Error("FromContext found no metadata")
+			return
+		}
+		country = bs.GetGeoHint().Country
+	}))
+	tests := []struct {
+		name       string
+		expiration time.Time
+		header     bool
+		want       int
+	}{
+		{name: "valid", expiration: time.Now().Add(time.Hour), header: true, want: http.StatusOK},
+		{name: "expired", expiration: time.Now().Add(-time.Hour), header: true, want: http.StatusForbidden},
+		{name: "missing", want: http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			country = ""
+			r := httptest.NewRequest(http.MethodConnect, "https://proxy.example:443", nil)
+			if tc.header {
+				if err := SetHeader(r.Header, newHeaderTestStruct(t, tc.expiration)); err != nil {
+					t.Fatalf("SetHeader failed: %v", err)
+				}
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tc.want {
+				t.Errorf("status = %d, want %d", w.Code, tc.want)
+			}
+			if reached := country != ""; reached != (tc.want == http.StatusOK) {
+				t.Errorf("handler reached = %v, want %v", reached, tc.want == http.StatusOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package binarymetadata
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// IssuanceRequest describes the client that metadata is minted for.
+type IssuanceRequest struct {
+	// ServiceType is the service the client asked tokens for. Empty means the DefaultPreset.
+	ServiceType string
+	// Country and Region locate the client, as for PresetOptions.
+	Country string
+	Region  string
+	// DebugModeCapability is set for clients allowed DEBUG_ALL metadata, e.g. test accounts.
+	DebugModeCapability *DebugModeCapability
+	// AuditTag is the NewBinaryFields.AuditTag of the result.
+	AuditTag string
+}
+
+// IssuanceMetadataProvider decides what metadata the token issuance servers mint for a request.
+// The result has passed ValidateStructForIssuance, and the caller must free it.
+type IssuanceMetadataProvider interface {
+	IssuanceMetadata(ctx context.Context, r *IssuanceRequest) (*BinaryStruct, error)
+}
+
+// IssuanceConfig configures NewPresetIssuanceProvider.
+type IssuanceConfig struct {
+	// Presets maps service types onto the name of the built-in or registered preset minted for
+	// them. Each preset must issue metadata of the service type it is mapped from.
+	Presets map[string]string
+	// DefaultPreset is minted for requests without a service type. Empty rejects them.
+	DefaultPreset string
+	// Lifetime is the PresetOptions.Lifetime of every preset.
+	Lifetime time.Duration
+	// Validator checks the metadata before it is returned, typically with a Policy. Its Clock is
+	// also the clock expirations are computed from. Nil uses NewValidator(ValidationConfig{}).
+	Validator *Validator
+}
+
+// PresetIssuanceProvider is the IssuanceMetadataProvider built from presets and a Validator. It is
+// safe for concurrent use.
+type PresetIssuanceProvider struct {
+	presets       map[string]string
+	defaultPreset string
+	lifetime      time.Duration
+	v             *Validator
+}
+
+// NewPresetIssuanceProvider returns a PresetIssuanceProvider for cfg, or an error if cfg names an
+// unknown preset or service type.
+func NewPresetIssuanceProvider(cfg IssuanceConfig) (*PresetIssuanceProvider, error) {
+	if cfg.Lifetime < 0 {
+		return nil, fmt.Errorf("negative Lifetime %v", cfg.Lifetime)
+	}
+	p := &PresetIssuanceProvider{presets: map[string]string{}, defaultPreset: cfg.DefaultPreset, lifetime: cfg.Lifetime, v: cfg.Validator}
+	if p.v == nil {
+		v, err := NewValidator(ValidationConfig{})
+		if err != nil {
+			return nil, err
+		}
+		p.v = v
+	}
+	presetsMu.RLock()
+	defer presetsMu.RUnlock()
+	for s, name := range cfg.Presets {
+		if err := checkServiceType(s); err != nil {
+			return nil, fmt.Errorf("Presets: %w", err)
+		}
+		if _, ok := presets[name]; !ok {
+			return nil, fmt.Errorf("Presets: unknown preset %q for %q", name, s)
+		}
+		p.presets[s] = name
+	}
+	if _, ok := presets[cfg.DefaultPreset]; cfg.DefaultPreset != "" && !ok {
+		return nil, fmt.Errorf("unknown DefaultPreset %q", cfg.DefaultPreset)
+	}
+	return p, nil
+}
+
+// IssuanceMetadata builds the preset of r.ServiceType for r and checks it with the Validator of p
+// at its current time. It fails with ctx.Err() if ctx is already done.
+func (p *PresetIssuanceProvider) IssuanceMetadata(ctx context.Context, r *IssuanceRequest) (*BinaryStruct, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	name := p.defaultPreset
+	if r.ServiceType != "" {
+		name = p.presets[r.ServiceType]
+	}
+	if name == "" {
+		return nil, &FieldError{Field: "service_type", Value: r.ServiceType, Err: ErrUnsupportedServiceType}
+	}
+	f, err := BuildPreset(name, PresetOptions{Country: r.Country, Region: r.Region, Lifetime: p.lifetime, Clock: p.v.cfg.Clock})
+	if err != nil {
+		return nil, err
+	}
+	if r.ServiceType != "" && f.ServiceType != r.ServiceType {
+		return nil, fmt.Errorf("preset %q issues service type %q, not %q", name, f.ServiceType, r.ServiceType)
+	}
+	f.DebugModeCapability = r.DebugModeCapability
+	f.AuditTag = r.AuditTag
+	bs, err := NewChecked(f)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.v.ValidateStructForIssuance(bs, p.v.Now()); err != nil {
+		bs.Free()
+		return nil, err
+	}
+	return bs, nil
+}
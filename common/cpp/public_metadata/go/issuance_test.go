@@ -0,0 +1,118 @@
+package binarymetadata
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+)
+
+func TestPresetIssuanceProvider(t *testing.T) {
+	t.Cleanup(func() {
+		presetsMu.Lock()
+		delete(presets, "test_debug_exit")
+		presetsMu.Unlock()
+	})
+	err := RegisterPreset("test_debug_exit", func(o PresetOptions) (*NewBinaryFields, error) {
+		f, err := RegionalExit(o)
+		if err != nil {
+			return nil, err
+		}
+		f.DebugMode = pmpb.PublicMetadata_DEBUG_ALL
+		return f, nil
+	})
+	if err != nil {
+		t.Fatalf("RegisterPreset failed: %v", err)
+	}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	now := time.Unix(1800000000, 0).Truncate(15 * time.Minute)
+	v, err := NewValidator(ValidationConfig{Clock: &fixedClock{now}, Policy: testPolicy(t)})
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+	newProvider := func(t *testing.T, preset string) *PresetIssuanceProvider {
+		t.Helper()
+		p, err := NewPresetIssuanceProvider(IssuanceConfig{
+			Presets:       map[string]string{ServiceTypeChromeIPBlinding: preset},
+			DefaultPreset: PresetDefaultPPNProd,
+			Lifetime:      2 * time.Hour,
+			Validator:     v,
+		})
+		if err != nil {
+			t.Fatalf("NewPresetIssuanceProvider failed: %v", err)
+		}
+		return p
+	}
+
+	for _, tc := range []struct {
+		name   string
+		preset string
+		req    IssuanceRequest
+	}{
+		{name: "default", preset: PresetRegionalExit, req: IssuanceRequest{Country: "US"}},
+		{name: "service_type", preset: PresetRegionalExit, req: IssuanceRequest{ServiceType: ServiceTypeChromeIPBlinding, Country: "US", Region: "US-CA"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			bs, err := newProvider(t, tc.preset).IssuanceMetadata(context.Background(), &tc.req)
+			if err != nil {
+				t.Fatalf("IssuanceMetadata failed: %v", err)
+			}
+			defer bs.Free()
+			if got, want := bs.GetExpiration().AsTime(), now.Add(2*time.Hour); !got.Equal(want) {
+				t.Errorf("expiration = %v, want %v", got, want)
+			}
+			if got := bs.GetGeoHint().Region; got != tc.req.Region {
+				t.Errorf("region = %q, want %q", got, tc.req.Region)
+			}
+		})
+	}
+
+	for _, tc := range []struct {
+		name   string
+		preset string
+		ctx    context.Context
+		req    IssuanceRequest
+		want   error
+	}{
+		{name: "unmapped_service_type", preset: PresetRegionalExit, req: IssuanceRequest{ServiceType: "cronet", Country: "US"}, want: ErrUnsupportedServiceType},
+		{name: "invalid_country", preset: PresetRegionalExit, req: IssuanceRequest{Country: "XX"}, want: ErrInvalidCountry},
+		{name: "missing_region", preset: PresetRegionalExit, req: IssuanceRequest{ServiceType: ServiceTypeChromeIPBlinding, Country: "US"}, want: ErrMissingField},
+		{name: "policy", preset: "test_debug_exit", req: IssuanceRequest{ServiceType: ServiceTypeChromeIPBlinding, Country: "US", Region: "US-CA"}, want: ErrPolicyViolation},
+		{name: "cancelled", preset: PresetRegionalExit, ctx: cancelled, req: IssuanceRequest{Country: "US"}, want: context.Canceled},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := tc.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			bs, err := newProvider(t, tc.preset).IssuanceMetadata(ctx, &tc.req)
+			if err == nil {
+				bs.Free()
+			}
+			if !errors.Is(err, tc.want) {
+				t.Errorf("IssuanceMetadata() = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewPresetIssuanceProviderErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		cfg  IssuanceConfig
+	}{
+		{name: "unknown_service_type", cfg: IssuanceConfig{Presets: map[string]string{"no_such_service": PresetDefaultPPNProd}}},
+		{name: "unknown_preset", cfg: IssuanceConfig{Presets: map[string]string{ServiceTypeChromeIPBlinding: "no_such_preset"}}},
+		{name: "unknown_default_preset", cfg: IssuanceConfig{DefaultPreset: "no_such_preset"}},
+		{name: "negative_lifetime", cfg: IssuanceConfig{Lifetime: -time.Hour}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewPresetIssuanceProvider(tc.cfg); err == nil {
+				t.Error("NewPresetIssuanceProvider succeeded, want error")
+			}
+		})
+	}
+}
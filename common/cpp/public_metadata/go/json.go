@@ -0,0 +1,212 @@
+package binarymetadata
+
+import (
+	"encoding/json"
+	"fmt"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	wrap "google3/privacy/net/common/cpp/public_metadata/go/wrap_public_metadata"
+	plpb "google3/privacy/net/common/proto/proxy_layer_go_proto"
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+)
+
+// jsonMetadata is the stable JSON representation of public metadata. Field names follow the C++
+// BinaryPublicMetadata struct and enums are encoded by name. Unset optionals are encoded as null.
+type jsonMetadata struct {
+	Version                int32   `json:"version"`
+	ServiceType            *string `json:"service_type"`
+	Country                *string `json:"country"`
+	Region                 *string `json:"region"`
+	City                   *string `json:"city"`
+	ExpirationEpochSeconds *int64  `json:"expiration_epoch_seconds"`
+	DebugMode              string  `json:"debug_mode"`
+	ProxyLayer             string  `json:"proxy_layer"`
+}
+
+// jsonMetadataOmitEmpty has the same layout as jsonMetadata, but unset and empty optionals are
+// left out of the encoding.
+type jsonMetadataOmitEmpty struct {
+	Version                int32   `json:"version"`
+	ServiceType            *string `json:"service_type,omitempty"`
+	Country                *string `json:"country,omitempty"`
+	Region                 *string `json:"region,omitempty"`
+	City                   *string `json:"city,omitempty"`
+	ExpirationEpochSeconds *int64  `json:"expiration_epoch_seconds,omitempty"`
+	DebugMode              string  `json:"debug_mode"`
+	ProxyLayer             string  `json:"proxy_layer"`
+}
+
+// JSONMarshalOptions configures the JSON encoding of public metadata.
+type JSONMarshalOptions struct {
+	// OmitEmpty leaves unset and empty optionals out of the output instead of encoding them as null
+	// or "".
+	OmitEmpty bool
+}
+
+// Marshal encodes bs as JSON. Metadata carrying extensions the C++ struct does not model, such as
+// the network type or the millisecond part of the expiration, is rejected with ErrUnrepresentable.
+func (o JSONMarshalOptions) Marshal(bs *BinaryStruct) ([]byte, error) {
+	j, err := bs.toJSON()
+	if err != nil {
+		return nil, err
+	}
+	return o.marshal(j)
+}
+
+// MarshalFields encodes fields as JSON.
+func (o JSONMarshalOptions) MarshalFields(fields *NewBinaryFields) ([]byte, error) {
+	return o.marshal(fields.toJSON())
+}
+
+func (o JSONMarshalOptions) marshal(j *jsonMetadata) ([]byte, error) {
+	if !o.OmitEmpty {
+		return json.Marshal(j)
+	}
+	for _, s := range []**string{&j.ServiceType, &j.Country, &j.Region, &j.City} {
+		if *s != nil && **s == "" {
+			*s = nil
+		}
+	}
+	return json.Marshal(jsonMetadataOmitEmpty(*j))
+}
+
+// MarshalJSON implements json.Marshaler.
+func (bs *BinaryStruct) MarshalJSON() ([]byte, error) {
+	return JSONMarshalOptions{}.Marshal(bs)
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Any C++ struct previously held by bs is freed.
+func (bs *BinaryStruct) UnmarshalJSON(b []byte) error {
+	err := bs.unmarshalJSON(b)
+	bs.audit(AuditMutate, "unmarshal_json", err)
+	return err
+}
+
+func (bs *BinaryStruct) unmarshalJSON(b []byte) error {
+	var fields NewBinaryFields
+	if err := fields.UnmarshalJSON(b); err != nil {
+		return err
+	}
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if bs.frozen {
+		return ErrFrozen
+	}
+	if bs.metadata != nil {
+		bs.free()
+	}
+	bs.metadata = newMetadata()
+	bs.trackAllocation()
+	setFields(bs.metadata, &fields)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (f *NewBinaryFields) MarshalJSON() ([]byte, error) {
+	return JSONMarshalOptions{}.MarshalFields(f)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (f *NewBinaryFields) UnmarshalJSON(b []byte) error {
+	var j jsonMetadata
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	fields, err := j.toFields()
+	if err != nil {
+		return err
+	}
+	*f = *fields
+	return nil
+}
+
+func (bs *BinaryStruct) toJSON() (*jsonMetadata, error) {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+	if err := bs.checkFreed(); err != nil {
+		return nil, err
+	}
+	if len(bs.extra) > 0 {
+		e := bs.extra[0]
+		field := extraField(e.Type)
+		if e.Type == ExtensionTypeExpirationMillis {
+			field = "expiration"
+		}
+		return nil, &FieldError{Field: field, Value: formatExtra(&e), Err: ErrUnrepresentable}
+	}
+	j := &jsonMetadata{
+		Version:     int32(bs.md().GetVersion()),
+		ServiceType: stringOptionalPtr(bs.md().GetService_type()),
+		Country:     stringOptionalPtr(bs.md().GetCountry()),
+		Region:      stringOptionalPtr(bs.md().GetRegion()),
+		City:        stringOptionalPtr(bs.md().GetCity()),
+		DebugMode:   bs.debugMode().String(),
+		ProxyLayer:  bs.proxyLayer().String(),
+	}
+	if exp := bs.expiration(); exp != nil {
+		s := exp.GetSeconds()
+		j.ExpirationEpochSeconds = &s
+	}
+	return j, nil
+}
+
+// stringOptionalPtr returns a copy of the optional's value, or nil if it is unset.
+func stringOptionalPtr(o wrap.StringOptional) *string {
+	if o == nil || !o.HasValue() {
+		return nil
+	}
+	s := o.Value()
+	return &s
+}
+
+func (f *NewBinaryFields) toJSON() *jsonMetadata {
+	j := &jsonMetadata{
+		Version:     f.Version,
+		ServiceType: &f.ServiceType,
+		Country:     &f.Country,
+		Region:      &f.Region,
+		City:        &f.City,
+		DebugMode:   f.DebugMode.String(),
+		ProxyLayer:  f.ProxyLayer.String(),
+	}
+	if f.Expiration != nil || !f.ExpirationTime.IsZero() {
+		s := f.expirationSeconds()
+		j.ExpirationEpochSeconds = &s
+	}
+	return j
+}
+
+func (j *jsonMetadata) toFields() (*NewBinaryFields, error) {
+	f := &NewBinaryFields{Version: j.Version}
+	for _, o := range []struct {
+		dst *string
+		src *string
+	}{
+		{&f.ServiceType, j.ServiceType},
+		{&f.Country, j.Country},
+		{&f.Region, j.Region},
+		{&f.City, j.City},
+	} {
+		if o.src != nil {
+			*o.dst = *o.src
+		}
+	}
+	if j.ExpirationEpochSeconds != nil {
+		f.Expiration = &tpb.Timestamp{Seconds: *j.ExpirationEpochSeconds}
+	}
+	if j.DebugMode != "" {
+		v, ok := pmpb.PublicMetadata_DebugMode_value[j.DebugMode]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown debug_mode %q", ErrInvalidDebugMode, j.DebugMode)
+		}
+		f.DebugMode = pmpb.PublicMetadata_DebugMode(v)
+	}
+	if j.ProxyLayer != "" {
+		v, ok := plpb.ProxyLayer_value[j.ProxyLayer]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown proxy_layer %q", ErrInvalidProxyLayer, j.ProxyLayer)
+		}
+		f.ProxyLayer = plpb.ProxyLayer(v)
+	}
+	return f, nil
+}
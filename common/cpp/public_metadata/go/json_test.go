@@ -0,0 +1,109 @@
+package binarymetadata
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	plpb "google3/privacy/net/common/proto/proxy_layer_go_proto"
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	fields := &NewBinaryFields{
+		Version:     2,
+		Country:     "US",
+		ServiceType: "chromeipblinding",
+		Expiration:  &tpb.Timestamp{Seconds: 3600},
+		DebugMode:   pmpb.PublicMetadata_DEBUG_ALL,
+		ProxyLayer:  plpb.ProxyLayer_PROXY_B,
+	}
+	tests := []struct {
+		name string
+		opts JSONMarshalOptions
+		want string
+	}{
+		{
+			name: "default",
+			want: `{"version":2,"service_type":"chromeipblinding","country":"US","region":"","city":"","expiration_epoch_seconds":3600,"debug_mode":"DEBUG_ALL","proxy_layer":"PROXY_B"}`,
+		},
+		{
+			name: "omit empty",
+			opts: JSONMarshalOptions{OmitEmpty: true},
+			want: `{"version":2,"service_type":"chromeipblinding","country":"US","expiration_epoch_seconds":3600,"debug_mode":"DEBUG_ALL","proxy_layer":"PROXY_B"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bs := New(fields)
+			defer bs.Free()
+			got, err := tc.opts.Marshal(bs)
+			if err != nil {
+				t.Fatalf("Marshal() failed: %v", err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("Marshal() = %s, want %s", got, tc.want)
+			}
+			got, err = tc.opts.MarshalFields(fields)
+			if err != nil {
+				t.Fatalf("MarshalFields() failed: %v", err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("MarshalFields() = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	bs := New(&NewBinaryFields{
+		Version:     2,
+		Country:     "US",
+		Region:      "US-CA",
+		City:        "SUNNYVALE",
+		ServiceType: "chromeipblinding",
+		Expiration:  &tpb.Timestamp{Seconds: 3600},
+		DebugMode:   pmpb.PublicMetadata_DEBUG_ALL,
+		ProxyLayer:  plpb.ProxyLayer_PROXY_A,
+	})
+	defer bs.Free()
+	b, err := json.Marshal(bs)
+	if err != nil {
+		t.Fatalf("json.Marshal() failed: %v", err)
+	}
+	var got BinaryStruct
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("json.Unmarshal(%s) failed: %v", b, err)
+	}
+	defer got.Free()
+	if got.String() != bs.String() {
+		t.Errorf("json round trip: got %v; want %v", got.String(), bs.String())
+	}
+}
+
+func TestUnmarshalJSONUnknownEnum(t *testing.T) {
+	var f NewBinaryFields
+	if err := json.Unmarshal([]byte(`{"debug_mode":"DEBUG_SOME"}`), &f); !errors.Is(err, ErrInvalidDebugMode) {
+		t.Errorf("json.Unmarshal() returned error: %v, want error: %v", err, ErrInvalidDebugMode)
+	}
+}
+
+func TestMarshalJSONRejectsExtensions(t *testing.T) {
+	bs := newTestStruct(t, 3)
+	if err := bs.SetNetworkType(NetworkTypeWiFi); err != nil {
+		t.Fatalf("SetNetworkType failed: %v", err)
+	}
+	_, err := json.Marshal(bs)
+	if !errors.Is(err, ErrUnrepresentable) {
+		t.Errorf("json.Marshal() returned error: %v, want error: %v", err, ErrUnrepresentable)
+	}
+	var fe *FieldError
+	if !errors.As(err, &fe) || fe.Field != "network_type" || fe.Value != "WIFI" {
+		t.Errorf("json.Marshal() returned error: %v, want a FieldError for network_type WIFI", err)
+	}
+	if _, err := SerializeCBOR(bs); !errors.Is(err, ErrUnrepresentable) {
+		t.Errorf("SerializeCBOR() returned error: %v, want error: %v", err, ErrUnrepresentable)
+	}
+}
@@ -0,0 +1,94 @@
+package binarymetadata
+
+import "fmt"
+
+// GetKeyEpoch returns the signing key epoch the metadata declares and whether it declares one.
+func (bs *BinaryStruct) GetKeyEpoch() (uint32, bool) {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+	return bs.keyEpoch()
+}
+
+func (bs *BinaryStruct) keyEpoch() (uint32, bool) {
+	e, ok := bs.findExtra(ExtensionTypeKeyEpoch)
+	if !ok {
+		return 0, false
+	}
+	k, err := KeyEpochExtensionFromExtension(e)
+	return k.Epoch, err == nil
+}
+
+// SetKeyEpoch declares the epoch of the key the metadata is signed with, so that redemption can
+// reject tokens presented with metadata minted for another key; see RedemptionVerifier.KeyEpoch.
+// It fails with ErrInvalidKeyEpoch for versions without the KeyEpoch capability.
+func (bs *BinaryStruct) SetKeyEpoch(epoch uint32) error {
+	err := bs.setKeyEpoch(epoch)
+	bs.audit(AuditMutate, "set_key_epoch", err)
+	return err
+}
+
+func (bs *BinaryStruct) setKeyEpoch(epoch uint32) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if err := bs.checkMutable(); err != nil {
+		return err
+	}
+	c, err := Capabilities(int32(bs.md().GetVersion()))
+	if err != nil {
+		return err
+	}
+	if !c.KeyEpoch {
+		return fmt.Errorf("%w: version %d does not support the key epoch extension", ErrInvalidKeyEpoch, c.Version)
+	}
+	e, err := KeyEpochExtension{Epoch: epoch}.AsExtension()
+	if err != nil {
+		return err
+	}
+	bs.putExtra(e)
+	return nil
+}
+
+// ClearKeyEpoch removes the key epoch.
+func (bs *BinaryStruct) ClearKeyEpoch() {
+	defer bs.audit(AuditMutate, "clear_key_epoch", nil)
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if !bs.frozen {
+		bs.removeExtra(ExtensionTypeKeyEpoch)
+	}
+}
+
+// checkKeyEpoch rejects a malformed key epoch and key epochs on versions that cannot carry one. The
+// caller holds the lock.
+func (bs *BinaryStruct) checkKeyEpoch(c VersionCapabilities) error {
+	e, ok := bs.findExtra(ExtensionTypeKeyEpoch)
+	if !ok {
+		return nil
+	}
+	if _, err := KeyEpochExtensionFromExtension(e); err != nil {
+		return err
+	}
+	if !c.KeyEpoch {
+		return fmt.Errorf("%w: version %d does not support the key epoch extension", ErrInvalidKeyEpoch, c.Version)
+	}
+	return nil
+}
+
+// CheckKeyEpoch returns a *MismatchError for the "key_epoch" field unless bs declares epoch, the
+// epoch of the key its token was actually signed with.
+func CheckKeyEpoch(bs *BinaryStruct, epoch uint32) error {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+	if err := bs.checkFreed(); err != nil {
+		return err
+	}
+	got, ok := bs.keyEpoch()
+	if ok && got == epoch {
+		return nil
+	}
+	e := &MismatchError{Field: "key_epoch", Want: fmt.Sprint(epoch)}
+	if ok {
+		e.Got = fmt.Sprint(got)
+	}
+	return e
+}
@@ -0,0 +1,105 @@
+package binarymetadata
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+	"time"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+)
+
+func TestKeyEpochRoundTrip(t *testing.T) {
+	bs := newTestStruct(t, 3)
+	if _, ok := bs.GetKeyEpoch(); ok {
+		t.Error("GetKeyEpoch() reported an epoch before SetKeyEpoch")
+	}
+	if err := bs.SetKeyEpoch(0x01020304); err != nil {
+		t.Fatalf("SetKeyEpoch failed: %v", err)
+	}
+	out, err := Serialize(bs)
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	got, err := DeserializeOptions{Strict: true, Canonical: true}.Deserialize(out)
+	if err != nil {
+		t.Fatalf("Deserialize failed: %v", err)
+	}
+	defer got.Free()
+	if got.GetVersion() != 3 {
+		t.Errorf("GetVersion() after round trip = %d, want 3", got.GetVersion())
+	}
+	if epoch, ok := got.GetKeyEpoch(); !ok || epoch != 0x01020304 {
+		t.Errorf("GetKeyEpoch() = %#x, %t, want 0x01020304, true", epoch, ok)
+	}
+	got.ClearKeyEpoch()
+	if _, ok := got.GetKeyEpoch(); ok {
+		t.Error("GetKeyEpoch() reported an epoch after ClearKeyEpoch")
+	}
+}
+
+func TestKeyEpochRejectsOlderVersions(t *testing.T) {
+	bs := New(&NewBinaryFields{Version: 2, Country: "US", ServiceType: "chromeipblinding", Expiration: tpb.New(time.Unix(900, 0))})
+	defer bs.Free()
+	if err := bs.SetKeyEpoch(7); !errors.Is(err, ErrInvalidKeyEpoch) {
+		t.Errorf("SetKeyEpoch() returned error: %v, want error: %v", err, ErrInvalidKeyEpoch)
+	}
+	if err := bs.SetExtension(ExtensionTypeKeyEpoch, []byte{0, 0, 0, 7}); err != nil {
+		t.Fatalf("SetExtension failed: %v", err)
+	}
+	out, err := Serialize(bs)
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	if _, err := (DeserializeOptions{Strict: true}).Deserialize(out); !errors.Is(err, ErrInvalidKeyEpoch) {
+		t.Errorf("Deserialize() returned error: %v, want error: %v", err, ErrInvalidKeyEpoch)
+	}
+	err = defaultValidator.Validate(out, time.Unix(0, 0))
+	var fe *FieldError
+	if !errors.As(err, &fe) || fe.Field != "key_epoch" || !errors.Is(err, ErrInvalidKeyEpoch) {
+		t.Errorf("Validate() returned error: %v, want key_epoch error wrapping %v", err, ErrInvalidKeyEpoch)
+	}
+}
+
+func TestRedemptionVerifierKeyEpoch(t *testing.T) {
+	bs := newTestStruct(t, 3)
+	if err := bs.SetKeyEpoch(41); err != nil {
+		t.Fatalf("SetKeyEpoch failed: %v", err)
+	}
+	metadata, err := Serialize(bs)
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	message := []byte("0123456789abcdef0123456789abcdef")
+	var key *rsa.PrivateKey
+	var sig []byte
+	for ok := false; !ok; {
+		if key, err = rsa.GenerateKey(rand.Reader, 1024); err != nil {
+			t.Fatalf("GenerateKey failed: %v", err)
+		}
+		sig, ok = signForTest(t, key, message, metadata)
+	}
+	tok := RedeemedToken{Message: message, Signature: sig}
+	right, wrong := uint32(41), uint32(42)
+	for _, tc := range []struct {
+		epoch   *uint32
+		wantErr bool
+	}{
+		{epoch: nil},
+		{epoch: &right},
+		{epoch: &wrong, wantErr: true},
+	} {
+		v := &RedemptionVerifier{PublicKey: &key.PublicKey, KeyEpoch: tc.epoch, Clock: &fixedClock{time.Unix(0, 0)}}
+		err := v.Verify(tok, metadata)
+		var me *MismatchError
+		if gotErr := errors.As(err, &me) && me.Field == "key_epoch"; gotErr != tc.wantErr || (!tc.wantErr && err != nil) {
+			t.Errorf("Verify(KeyEpoch %v) returned error: %v, want key epoch mismatch: %t", tc.epoch, err, tc.wantErr)
+		}
+	}
+
+	bs.ClearKeyEpoch()
+	if err := CheckKeyEpoch(bs, 41); !errors.Is(err, ErrMetadataMismatch) {
+		t.Errorf("CheckKeyEpoch() without an epoch returned error: %v, want error: %v", err, ErrMetadataMismatch)
+	}
+}
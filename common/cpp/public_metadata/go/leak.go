@@ -0,0 +1,71 @@
+package binarymetadata
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+)
+
+var leakDetection atomic.Bool
+
+func init() {
+	leakDetection.Store(detectLeaks)
+}
+
+// SetLeakDetection turns leak detection on or off and returns the previous setting. While it is
+// on, every BinaryStruct allocating C++ memory records its allocation stack, and those collected by
+// the garbage collector without a call to Free are reported by LeakedStacks. It is on by default in
+// builds with the binarymetadata_debug tag. Structs returned by Acquire are not tracked.
+func SetLeakDetection(on bool) bool {
+	return leakDetection.Swap(on)
+}
+
+// allocRecord is the allocation of a BinaryStruct tracked by leak detection.
+type allocRecord struct {
+	released atomic.Bool
+	stack    string
+}
+
+var (
+	leaksMu sync.Mutex
+	leaks   []string
+)
+
+// LeakedStacks returns the allocation stacks of the structs collected without Free since the
+// process started, oldest first. Collection happens at the discretion of the garbage collector, so
+// a leak shows up some time after the struct became unreachable.
+func LeakedStacks() []string {
+	leaksMu.Lock()
+	defer leaksMu.Unlock()
+	return append([]string(nil), leaks...)
+}
+
+// trackAllocation records the allocation of the C++ struct of bs if leak detection is on,
+// releasing any earlier record. The caller holds the lock or owns bs exclusively.
+func (bs *BinaryStruct) trackAllocation() {
+	bs.releaseAllocation()
+	if !leakDetection.Load() {
+		return
+	}
+	r := &allocRecord{stack: string(debug.Stack())}
+	bs.alloc = r
+	runtime.AddCleanup(bs, reportLeak, r)
+}
+
+// releaseAllocation marks the C++ struct of bs as freed for leak detection.
+func (bs *BinaryStruct) releaseAllocation() {
+	if bs.alloc != nil {
+		bs.alloc.released.Store(true)
+		bs.alloc = nil
+	}
+}
+
+func reportLeak(r *allocRecord) {
+	if r.released.Load() {
+		return
+	}
+	leaksMu.Lock()
+	defer leaksMu.Unlock()
+	leaks = append(leaks, r.stack)
+}
@@ -0,0 +1,75 @@
+package binarymetadata
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+)
+
+//go:noinline
+func leakForTest() {
+	New(&NewBinaryFields{Version: 1, Country: "US", ServiceType: "chromeipblinding", Expiration: &tpb.Timestamp{Seconds: 900}})
+}
+
+//go:noinline
+func freeAfterUseForTest() {
+	bs := New(&NewBinaryFields{Version: 1, Country: "US", ServiceType: "chromeipblinding", Expiration: &tpb.Timestamp{Seconds: 900}})
+	bs.Free()
+	NewManaged(&NewBinaryFields{Version: 1, Country: "US", ServiceType: "chromeipblinding", Expiration: &tpb.Timestamp{Seconds: 900}})
+}
+
+//go:noinline
+func releaseAfterUseForTest() {
+	bs := New(&NewBinaryFields{Version: 1, Country: "US", ServiceType: "chromeipblinding", Expiration: &tpb.Timestamp{Seconds: 900}})
+	out, err := Serialize(bs)
+	if err != nil {
+		panic(err)
+	}
+	Release(bs)
+	if bs, err = Deserialize(out); err != nil {
+		panic(err)
+	}
+	Release(bs)
+}
+
+// leaksSince collects garbage until at least want leaks were reported after the first before, or
+// it gives up.
+func leaksSince(before, want int) []string {
+	var leaks []string
+	for range 20 {
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+		if leaks = LeakedStacks()[before:]; len(leaks) >= want {
+			break
+		}
+	}
+	return leaks
+}
+
+func TestLeakDetection(t *testing.T) {
+	was := SetLeakDetection(true)
+	t.Cleanup(func() { SetLeakDetection(was) })
+
+	before := len(LeakedStacks())
+	freeAfterUseForTest()
+	leakForTest()
+	leaks := leaksSince(before, 1)
+	if len(leaks) != 1 || !strings.Contains(leaks[0], "leakForTest") {
+		t.Errorf("LeakedStacks() = %q, want the stack of leakForTest only", leaks)
+	}
+}
+
+func TestLeakDetectionRelease(t *testing.T) {
+	was := SetLeakDetection(true)
+	t.Cleanup(func() { SetLeakDetection(was) })
+
+	before := len(LeakedStacks())
+	releaseAfterUseForTest()
+	// The pool drops its values after two collections, which leaksSince runs many more of.
+	if leaks := leaksSince(before, 1); len(leaks) != 0 {
+		t.Errorf("LeakedStacks() = %q after Release, want none", leaks)
+	}
+}
@@ -0,0 +1,38 @@
+package binarymetadata
+
+import "fmt"
+
+// MaxSerializedSize is the size of the largest well-formed extensions list: the uint16 length
+// prefix followed by as many bytes as it can declare. Deserialize always rejects longer input.
+const MaxSerializedSize = 2 + 0xffff
+
+// checkBounds rejects input exceeding the limits of o before any of it crosses into C++. Limits
+// on the extensions are only checked for well-formed lists; malformed ones are left to the parser
+// to report.
+func (o DeserializeOptions) checkBounds(in []byte) error {
+	maxSize := MaxSerializedSize
+	if o.MaxInputSize > 0 && o.MaxInputSize < maxSize {
+		maxSize = o.MaxInputSize
+	}
+	if len(in) > maxSize {
+		return fmt.Errorf("%w: input of %d bytes exceeds %d", ErrTooLarge, len(in), maxSize)
+	}
+	if o.MaxExtensions <= 0 && o.MaxExtensionLength <= 0 {
+		return nil
+	}
+	exts, err := DecodeExtensions(in)
+	if err != nil {
+		return nil
+	}
+	if o.MaxExtensions > 0 && len(exts) > o.MaxExtensions {
+		return fmt.Errorf("%w: %d extensions exceed %d", ErrTooLarge, len(exts), o.MaxExtensions)
+	}
+	if o.MaxExtensionLength > 0 {
+		for _, e := range exts {
+			if len(e.Value) > o.MaxExtensionLength {
+				return fmt.Errorf("%w: extension %#04x of %d bytes exceeds %d", ErrTooLarge, e.Type, len(e.Value), o.MaxExtensionLength)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package binarymetadata
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+)
+
+func TestDeserializeLimits(t *testing.T) {
+	bs := New(&NewBinaryFields{Version: 1, Country: "US", ServiceType: "chromeipblinding", Expiration: &tpb.Timestamp{Seconds: 900}})
+	defer bs.Free()
+	blob, err := Serialize(bs)
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	exts, err := DecodeExtensions(blob)
+	if err != nil {
+		t.Fatalf("DecodeExtensions failed: %v", err)
+	}
+	longest := 0
+	for _, e := range exts {
+		longest = max(longest, len(e.Value))
+	}
+	for _, tc := range []struct {
+		name    string
+		opts    DeserializeOptions
+		in      []byte
+		wantErr error
+	}{
+		{name: "defaults", in: blob},
+		{name: "at limits", opts: DeserializeOptions{MaxInputSize: len(blob), MaxExtensions: len(exts), MaxExtensionLength: longest}, in: blob},
+		{name: "input size", opts: DeserializeOptions{MaxInputSize: len(blob) - 1}, in: blob, wantErr: ErrTooLarge},
+		{name: "beyond the wire format", opts: DeserializeOptions{MaxInputSize: 1 << 30}, in: append(bytes.Clone(blob), make([]byte, MaxSerializedSize)...), wantErr: ErrTooLarge},
+		{name: "extension count", opts: DeserializeOptions{MaxExtensions: len(exts) - 1}, in: blob, wantErr: ErrTooLarge},
+		{name: "extension length", opts: DeserializeOptions{MaxExtensionLength: longest - 1}, in: blob, wantErr: ErrTooLarge},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.opts.Deserialize(tc.in)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("Deserialize() returned error: %v, want error: %v", err, tc.wantErr)
+			}
+			if err == nil {
+				got.Free()
+			}
+		})
+	}
+
+	// Malformed lists are left to the parser, which rejects them for what they are.
+	_, err = DeserializeOptions{MaxExtensions: 1}.Deserialize(blob[:len(blob)-1])
+	if err == nil || errors.Is(err, ErrTooLarge) {
+		t.Errorf("Deserialize(truncated) returned error: %v, want a parse error", err)
+	}
+}
@@ -0,0 +1,46 @@
+package binarymetadata
+
+import (
+	"expvar"
+	"sync/atomic"
+
+	wrap "google3/privacy/net/common/cpp/public_metadata/go/wrap_public_metadata"
+)
+
+// approxMetadataBytes estimates the C++ heap used by one BinaryPublicMetadata: the struct with its
+// optional fields, and geo strings short enough to fit the small string buffer.
+const approxMetadataBytes = 200
+
+var liveMetadata atomic.Int64
+
+// LiveStats counts the wrapped C++ structs that have been allocated and not deleted yet.
+type LiveStats struct {
+	// Objects is the number of live structs. A value that grows with the uptime of a process points
+	// at a caller that forgets to Free.
+	Objects int64
+	// ApproxBytes estimates the C++ heap held by the live structs.
+	ApproxBytes int64
+}
+
+// GetLiveStats returns a snapshot of the process-wide LiveStats. It is also published through
+// expvar as "binarymetadata_live".
+func GetLiveStats() LiveStats {
+	n := liveMetadata.Load()
+	return LiveStats{Objects: n, ApproxBytes: n * approxMetadataBytes}
+}
+
+func init() {
+	expvar.Publish("binarymetadata_live", expvar.Func(func() any { return GetLiveStats() }))
+}
+
+// newMetadata allocates a C++ struct, which must be released with deleteMetadata.
+func newMetadata() wrap.BinaryPublicMetadata {
+	liveMetadata.Add(1)
+	return wrap.NewBinaryPublicMetadata()
+}
+
+// deleteMetadata releases a C++ struct allocated by newMetadata.
+func deleteMetadata(m wrap.BinaryPublicMetadata) {
+	liveMetadata.Add(-1)
+	wrap.DeleteBinaryPublicMetadata(m)
+}
@@ -0,0 +1,43 @@
+package binarymetadata
+
+import (
+	"encoding/json"
+	"expvar"
+	"runtime"
+	"testing"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+)
+
+func TestLiveStats(t *testing.T) {
+	// Let pending finalizers of other tests run first, so that only this test moves the counts.
+	runtime.GC()
+	runtime.GC()
+	before := GetLiveStats()
+	var structs []*BinaryStruct
+	for range 3 {
+		structs = append(structs, New(&NewBinaryFields{Version: 1, Country: "US", ServiceType: "chromeipblinding", Expiration: &tpb.Timestamp{Seconds: 900}}))
+	}
+	if _, err := Deserialize([]byte{0}); err == nil {
+		t.Error("Deserialize() succeeded, want error")
+	}
+	got := GetLiveStats()
+	if got.Objects != before.Objects+3 || got.ApproxBytes != before.ApproxBytes+3*approxMetadataBytes {
+		t.Errorf("GetLiveStats() = %+v after allocating 3 structs, want 3 more than %+v", got, before)
+	}
+	for _, bs := range structs {
+		bs.Free()
+	}
+	if got := GetLiveStats(); got != before {
+		t.Errorf("GetLiveStats() = %+v after freeing, want %+v", got, before)
+	}
+
+	v := expvar.Get("binarymetadata_live")
+	if v == nil {
+		t.Fatal("binarymetadata_live is not published")
+	}
+	var published LiveStats
+	if err := json.Unmarshal([]byte(v.String()), &published); err != nil {
+		t.Errorf("json.Unmarshal(%s) failed: %v", v.String(), err)
+	}
+}
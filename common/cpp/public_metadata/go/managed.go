@@ -0,0 +1,74 @@
+package binarymetadata
+
+import (
+	"runtime"
+	"sync/atomic"
+)
+
+var (
+	managedFreed     atomic.Uint64
+	managedFinalized atomic.Uint64
+)
+
+// ManagedStats counts how managed BinaryStructs released their C++ memory.
+type ManagedStats struct {
+	// Freed is the number of managed structs released by an explicit call to Free.
+	Freed uint64
+	// Finalized is the number of managed structs reclaimed by the garbage collector because Free was
+	// never called. A growing value points at a caller that forgets to Free.
+	Finalized uint64
+}
+
+// GetManagedStats returns a snapshot of the process-wide ManagedStats.
+func GetManagedStats() ManagedStats {
+	return ManagedStats{
+		Freed:     managedFreed.Load(),
+		Finalized: managedFinalized.Load(),
+	}
+}
+
+// NewManaged returns a new BinaryStruct whose C++ memory is released by a finalizer if the caller
+// never calls Free. Calling Free is still preferred since finalizers run at the garbage collector's
+// discretion.
+func NewManaged(fields *NewBinaryFields) *BinaryStruct {
+	return Manage(New(fields))
+}
+
+// Manage registers a finalizer on bs, e.g. one returned by Deserialize, that frees the wrapped C++
+// struct once bs becomes unreachable. It returns bs for chaining.
+func Manage(bs *BinaryStruct) *BinaryStruct {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if bs.managed {
+		return bs
+	}
+	bs.managed = true
+	runtime.SetFinalizer(bs, finalizeBinaryStruct)
+	return bs
+}
+
+// Unmanage removes the finalizer registered by NewManaged or Manage. The caller becomes responsible
+// for calling Free again.
+func (bs *BinaryStruct) Unmanage() {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	bs.unmanage()
+}
+
+func (bs *BinaryStruct) unmanage() {
+	if !bs.managed {
+		return
+	}
+	bs.managed = false
+	runtime.SetFinalizer(bs, nil)
+}
+
+func finalizeBinaryStruct(bs *BinaryStruct) {
+	if bs.metadata == nil {
+		return
+	}
+	managedFinalized.Add(1)
+	bs.releaseMetadata(bs.metadata)
+	bs.releaseAllocation()
+	bs.metadata = nil
+}
@@ -0,0 +1,38 @@
+package binarymetadata
+
+import (
+	"testing"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+)
+
+func TestManagedFree(t *testing.T) {
+	before := GetManagedStats()
+	bs := NewManaged(&NewBinaryFields{
+		Version:     1,
+		Country:     "US",
+		ServiceType: "chromeipblinding",
+		Expiration:  &tpb.Timestamp{Seconds: 3600},
+	})
+	if _, err := Serialize(bs); err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	bs.Free()
+	after := GetManagedStats()
+	if got := after.Freed - before.Freed; got != 1 {
+		t.Errorf("ManagedStats.Freed increased by %d, want 1", got)
+	}
+	if bs.managed {
+		t.Error("Free() left the finalizer registered")
+	}
+}
+
+func TestUnmanage(t *testing.T) {
+	before := GetManagedStats()
+	bs := NewManaged(&NewBinaryFields{Country: "US"})
+	bs.Unmanage()
+	bs.Free()
+	if got := GetManagedStats().Freed - before.Freed; got != 0 {
+		t.Errorf("ManagedStats.Freed increased by %d after Unmanage, want 0", got)
+	}
+}
@@ -0,0 +1,101 @@
+// Package metadataservice implements the PublicMetadataService gRPC service over the
+// binarymetadata package, so that callers that cannot link the C++ wrapper can validate blobs
+// centrally.
+package metadataservice
+
+import (
+	"context"
+	"time"
+
+	"google3/privacy/net/common/cpp/public_metadata/go/binarymetadata"
+	"google3/third_party/golang/grpc/codes"
+	"google3/third_party/golang/grpc/status"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	grpcpb "google3/privacy/net/common/proto/public_metadata_service_go_grpc"
+	pb "google3/privacy/net/common/proto/public_metadata_service_go_proto"
+)
+
+// Server implements PublicMetadataService.
+type Server struct {
+	grpcpb.UnimplementedPublicMetadataServiceServer
+	// now returns the time Validate checks against when the request does not set one.
+	now func() time.Time
+}
+
+// New returns a Server that validates against the current time by default.
+func New() *Server {
+	return NewWithClock(binarymetadata.SystemClock)
+}
+
+// NewWithClock returns a Server that validates against the time of c by default.
+func NewWithClock(c binarymetadata.Clock) *Server {
+	return &Server{now: c.Now}
+}
+
+func toFields(bs *binarymetadata.BinaryStruct) *pb.BinaryPublicMetadataFields {
+	geo := bs.GetGeoHint()
+	f := &pb.BinaryPublicMetadataFields{
+		Version:     bs.GetVersion(),
+		ServiceType: bs.GetServiceType(),
+		Country:     geo.Country,
+		Region:      geo.Region,
+		City:        geo.City,
+		DebugMode:   bs.GetDebugMode(),
+		ProxyLayer:  bs.GetProxyLayer(),
+	}
+	if exp := bs.GetExpiration(); exp != nil {
+		f.Expiration = &tpb.Timestamp{Seconds: exp.GetSeconds()}
+	}
+	return f
+}
+
+// Deserialize implements PublicMetadataService.
+func (s *Server) Deserialize(ctx context.Context, req *pb.DeserializeMetadataRequest) (*pb.DeserializeMetadataResponse, error) {
+	bs, err := binarymetadata.DeserializeContext(ctx, req.GetSerializedMetadata())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "deserialize failed: %v", err)
+	}
+	defer bs.Free()
+	return &pb.DeserializeMetadataResponse{Metadata: toFields(bs)}, nil
+}
+
+// Validate implements PublicMetadataService.
+func (s *Server) Validate(ctx context.Context, req *pb.ValidateMetadataRequest) (*pb.ValidateMetadataResponse, error) {
+	t := s.now()
+	if req.GetValidationTime() != nil {
+		t = req.GetValidationTime().AsTime()
+	}
+	err := binarymetadata.ValidateMetadataCardinalityContext(ctx, req.GetSerializedMetadata(), t)
+	if err == nil {
+		return &pb.ValidateMetadataResponse{Valid: true}, nil
+	}
+	if ctx.Err() != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+	resp := &pb.ValidateMetadataResponse{ErrorMessage: err.Error()}
+	if kind := binarymetadata.ErrorKind(err); kind != nil {
+		resp.ErrorKind = kind.Error()
+	}
+	return resp, nil
+}
+
+// Describe implements PublicMetadataService.
+func (s *Server) Describe(ctx context.Context, req *pb.DescribeMetadataRequest) (*pb.DescribeMetadataResponse, error) {
+	exts, err := binarymetadata.DecodeExtensions(req.GetSerializedMetadata())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "decode failed: %v", err)
+	}
+	resp := &pb.DescribeMetadataResponse{}
+	for _, e := range exts {
+		resp.Extensions = append(resp.Extensions, &pb.DescribeMetadataResponse_Extension{Type: uint32(e.Type), Value: e.Value})
+	}
+	bs, err := binarymetadata.DeserializeContext(ctx, req.GetSerializedMetadata())
+	if err != nil {
+		resp.DeserializeError = err.Error()
+		return resp, nil
+	}
+	defer bs.Free()
+	resp.Metadata = toFields(bs)
+	return resp, nil
+}
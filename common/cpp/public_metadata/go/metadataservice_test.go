@@ -0,0 +1,97 @@
+package metadataservice
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google3/privacy/net/common/cpp/public_metadata/go/binarymetadata"
+	"google3/privacy/net/common/cpp/public_metadata/go/binarymetadatatest"
+	"google3/third_party/golang/cmp/cmp"
+	"google3/third_party/golang/grpc/codes"
+	"google3/third_party/golang/grpc/status"
+	"google3/third_party/golang/protobuf/v2/proto/proto"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	plpb "google3/privacy/net/common/proto/proxy_layer_go_proto"
+	pb "google3/privacy/net/common/proto/public_metadata_service_go_proto"
+)
+
+func TestDeserialize(t *testing.T) {
+	s := New()
+	resp, err := s.Deserialize(context.Background(), &pb.DeserializeMetadataRequest{SerializedMetadata: binarymetadatatest.GoldenV2})
+	if err != nil {
+		t.Fatalf("Deserialize failed: %v", err)
+	}
+	want := &pb.BinaryPublicMetadataFields{
+		Version:     2,
+		ServiceType: "chromeipblinding",
+		Country:     "US",
+		Region:      "US-NY",
+		City:        "NEW YORK CITY",
+		Expiration:  &tpb.Timestamp{Seconds: binarymetadatatest.GoldenExpiration.Unix()},
+		ProxyLayer:  plpb.ProxyLayer_PROXY_A,
+	}
+	if !proto.Equal(resp.GetMetadata(), want) {
+		t.Errorf("Deserialize() = %v, want %v", resp.GetMetadata(), want)
+	}
+
+	_, err = s.Deserialize(context.Background(), &pb.DeserializeMetadataRequest{SerializedMetadata: []byte("bad")})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Deserialize(bad) returned error: %v, want code %v", err, codes.InvalidArgument)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	s := New()
+	before := binarymetadatatest.GoldenExpiration.Add(-time.Hour)
+	tests := []struct {
+		name string
+		now  time.Time
+		req  *pb.ValidateMetadataRequest
+		want *pb.ValidateMetadataResponse
+	}{
+		{
+			name: "valid at request time",
+			now:  binarymetadatatest.GoldenExpiration.Add(time.Hour),
+			req:  &pb.ValidateMetadataRequest{SerializedMetadata: binarymetadatatest.GoldenV1, ValidationTime: &tpb.Timestamp{Seconds: before.Unix()}},
+			want: &pb.ValidateMetadataResponse{Valid: true},
+		},
+		{
+			name: "expired at server time",
+			now:  binarymetadatatest.GoldenExpiration.Add(time.Hour),
+			req:  &pb.ValidateMetadataRequest{SerializedMetadata: binarymetadatatest.GoldenV1},
+			want: &pb.ValidateMetadataResponse{ErrorKind: binarymetadata.ErrExpired.Error()},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s.now = func() time.Time { return tc.now }
+			got, err := s.Validate(context.Background(), tc.req)
+			if err != nil {
+				t.Fatalf("Validate failed: %v", err)
+			}
+			got.ErrorMessage = ""
+			if !proto.Equal(got, tc.want) {
+				t.Errorf("Validate() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	resp, err := New().Describe(context.Background(), &pb.DescribeMetadataRequest{SerializedMetadata: binarymetadatatest.GoldenV2})
+	if err != nil {
+		t.Fatalf("Describe failed: %v", err)
+	}
+	var types []uint32
+	for _, e := range resp.GetExtensions() {
+		types = append(types, e.GetType())
+	}
+	if diff := cmp.Diff([]uint32{0x0001, 0x0002, 0xF001, 0xF002, 0xF003}, types); diff != "" {
+		t.Errorf("Describe() extension types returned unexpected diff (-want +got):\n%s", diff)
+	}
+	if resp.GetMetadata().GetCity() != "NEW YORK CITY" || resp.GetDeserializeError() != "" {
+		t.Errorf("Describe() = %v, want city NEW YORK CITY and no error", resp)
+	}
+}
@@ -0,0 +1,59 @@
+package binarymetadata
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Operation names an instrumented entry point of this package.
+type Operation string
+
+// Instrumented operations.
+const (
+	OperationSerialize                   Operation = "serialize"
+	OperationDeserialize                 Operation = "deserialize"
+	OperationValidateMetadataCardinality Operation = "validate_metadata_cardinality"
+)
+
+// Metrics receives measurements for every Serialize, Deserialize and ValidateMetadataCardinality
+// call, including the Context variants. Implementations must be safe for concurrent use and
+// should return quickly, since they run inline with the call.
+type Metrics interface {
+	// CountCall is called once per call to op.
+	CountCall(op Operation)
+	// CountError is called when op fails. kind is the result of ErrorKind for the error and may
+	// be nil.
+	CountError(op Operation, kind error)
+	// ObserveLatency records how long op took, failed calls included.
+	ObserveLatency(op Operation, d time.Duration)
+}
+
+// metricsHolder wraps a Metrics so that it can be stored in an atomic.Pointer.
+type metricsHolder struct {
+	m Metrics
+}
+
+var registeredMetrics atomic.Pointer[metricsHolder]
+
+// SetMetrics registers m to receive measurements from this package, replacing any earlier
+// registration. It is meant to be called once during process start up; nil disables metrics.
+func SetMetrics(m Metrics) {
+	if m == nil {
+		registeredMetrics.Store(nil)
+		return
+	}
+	registeredMetrics.Store(&metricsHolder{m: m})
+}
+
+// record reports a call to op that started at start and returned err.
+func record(op Operation, start time.Time, err error) {
+	h := registeredMetrics.Load()
+	if h == nil {
+		return
+	}
+	h.m.CountCall(op)
+	if err != nil {
+		h.m.CountError(op, ErrorKind(err))
+	}
+	h.m.ObserveLatency(op, time.Since(start))
+}
@@ -0,0 +1,88 @@
+package binarymetadata
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"google3/third_party/golang/cmp/cmp"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+)
+
+type fakeMetrics struct {
+	mu        sync.Mutex
+	calls     map[Operation]int
+	errors    map[Operation][]error
+	latencies map[Operation]int
+}
+
+func newFakeMetrics() *fakeMetrics {
+	return &fakeMetrics{
+		calls:     map[Operation]int{},
+		errors:    map[Operation][]error{},
+		latencies: map[Operation]int{},
+	}
+}
+
+func (f *fakeMetrics) CountCall(op Operation) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls[op]++
+}
+
+func (f *fakeMetrics) CountError(op Operation, kind error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.errors[op] = append(f.errors[op], kind)
+}
+
+func (f *fakeMetrics) ObserveLatency(op Operation, d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.latencies[op]++
+}
+
+func TestMetrics(t *testing.T) {
+	m := newFakeMetrics()
+	SetMetrics(m)
+	defer SetMetrics(nil)
+
+	bs := New(&NewBinaryFields{
+		Version:     1,
+		Country:     "US",
+		ServiceType: "chromeipblinding",
+		Expiration:  &tpb.Timestamp{Seconds: 900},
+	})
+	defer bs.Free()
+	out, err := Serialize(bs)
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	parsed, err := Deserialize(out)
+	if err != nil {
+		t.Fatalf("Deserialize failed: %v", err)
+	}
+	parsed.Free()
+	if _, err := Deserialize([]byte("bad")); err == nil {
+		t.Fatal("Deserialize(bad) succeeded, want error")
+	}
+	if err := ValidateMetadataCardinality(out, time.Unix(0, 0)); err != nil {
+		t.Fatalf("ValidateMetadataCardinality failed: %v", err)
+	}
+
+	wantCalls := map[Operation]int{
+		OperationSerialize:                   1,
+		OperationDeserialize:                 2,
+		OperationValidateMetadataCardinality: 1,
+	}
+	if diff := cmp.Diff(wantCalls, m.calls); diff != "" {
+		t.Errorf("calls returned unexpected diff (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(wantCalls, m.latencies); diff != "" {
+		t.Errorf("latencies returned unexpected diff (-want +got):\n%s", diff)
+	}
+	if len(m.errors) != 1 || len(m.errors[OperationDeserialize]) != 1 {
+		t.Errorf("errors = %v, want one deserialize error", m.errors)
+	}
+}
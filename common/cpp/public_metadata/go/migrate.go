@@ -0,0 +1,104 @@
+package binarymetadata
+
+import (
+	"bytes"
+	"fmt"
+
+	plpb "google3/privacy/net/common/proto/proxy_layer_go_proto"
+)
+
+// migrationStep converts fields from one version to the adjacent one.
+type migrationStep func(f *NewBinaryFields)
+
+// upgrades maps a version onto the step converting it to the next version, and downgrades onto
+// the step converting it to the previous one. Adding a version only needs an entry in each.
+var (
+	upgrades = map[int32]migrationStep{
+		// Version 2 metadata without a proxy layer needs the version extension, which readers
+		// predating it reject, so upgrades pin tokens to the first proxy, which older tokens were
+		// implicitly usable at.
+		1: func(f *NewBinaryFields) {
+			if f.ProxyLayer == plpb.ProxyLayer_PROXY_LAYER_UNSPECIFIED {
+				f.ProxyLayer = plpb.ProxyLayer_PROXY_A
+			}
+		},
+		// Version 3 only adds extensions, which Migrate carries over.
+		2: func(*NewBinaryFields) {},
+	}
+	downgrades = map[int32]migrationStep{
+		2: func(f *NewBinaryFields) { f.ProxyLayer = plpb.ProxyLayer_PROXY_LAYER_UNSPECIFIED },
+		// Version 2 readers infer the version from the proxy layer, so metadata usable at any proxy
+		// is pinned to the first one like on upgrades from version 1.
+		3: func(f *NewBinaryFields) {
+			if f.ProxyLayer == plpb.ProxyLayer_PROXY_LAYER_UNSPECIFIED {
+				f.ProxyLayer = plpb.ProxyLayer_PROXY_A
+			}
+		},
+	}
+)
+
+// Migrate returns a copy of bs converted to targetVersion, applying the upgrade or downgrade rules
+// of every version in between; e.g. downgrading to version 1 drops the proxy layer. Extensions
+// added with SetExtension are carried over, except those the target version cannot represent. bs
+// is left unchanged and the caller must Free the result.
+func Migrate(bs *BinaryStruct, targetVersion int32) (*BinaryStruct, error) {
+	target, err := Capabilities(targetVersion)
+	if err != nil {
+		return nil, err
+	}
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+	if err := bs.checkFreed(); err != nil {
+		return nil, err
+	}
+	version := int32(bs.md().GetVersion())
+	if _, err := Capabilities(version); err != nil {
+		return nil, err
+	}
+	f := bs.fields()
+	for f.Version != targetVersion {
+		steps, next := upgrades, f.Version+1
+		if targetVersion < f.Version {
+			steps, next = downgrades, f.Version-1
+		}
+		step, ok := steps[f.Version]
+		if !ok {
+			return nil, fmt.Errorf("%w: no migration from version %d to %d", ErrUnknownVersion, f.Version, next)
+		}
+		step(f)
+		f.Version = next
+	}
+	out := New(f)
+	for _, e := range bs.extra {
+		if e.Type == ExtensionTypeExpirationMillis && !target.ExpirationMillis {
+			continue
+		}
+		if d, ok := coarseExtensions[e.Type]; ok && !d.allowed(target) {
+			continue
+		}
+		if e.Type == ExtensionTypeKeyEpoch && !target.KeyEpoch {
+			continue
+		}
+		if e.Type == ExtensionTypeNonce && !target.Nonce {
+			continue
+		}
+		out.extra = append(out.extra, Extension{Type: e.Type, Value: bytes.Clone(e.Value)})
+	}
+	return out, nil
+}
+
+// fields returns the fields of the C++ struct. The caller must hold bs.mu.
+func (bs *BinaryStruct) fields() *NewBinaryFields {
+	geo := bs.geoHint()
+	return &NewBinaryFields{
+		Version:     int32(bs.md().GetVersion()),
+		ServiceType: bs.serviceType(),
+		Expiration:  bs.expiration(),
+		DebugMode:   bs.debugMode(),
+		Country:     geo.Country,
+		Region:      geo.Region,
+		City:        geo.City,
+		ProxyLayer:  bs.proxyLayer(),
+		AuditTag:    bs.auditTag,
+	}
+}
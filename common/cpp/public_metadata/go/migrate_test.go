@@ -0,0 +1,97 @@
+package binarymetadata
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	plpb "google3/privacy/net/common/proto/proxy_layer_go_proto"
+)
+
+func TestMigrate(t *testing.T) {
+	tests := []struct {
+		name      string
+		version   int32
+		layer     plpb.ProxyLayer
+		target    int32
+		wantLayer plpb.ProxyLayer
+	}{
+		{name: "upgrade defaults proxy layer", version: 1, target: 2, wantLayer: plpb.ProxyLayer_PROXY_A},
+		{name: "downgrade drops proxy layer", version: 2, layer: plpb.ProxyLayer_PROXY_B, target: 1, wantLayer: plpb.ProxyLayer_PROXY_LAYER_UNSPECIFIED},
+		{name: "same version", version: 2, layer: plpb.ProxyLayer_PROXY_B, target: 2, wantLayer: plpb.ProxyLayer_PROXY_B},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bs := New(&NewBinaryFields{
+				Version:     tc.version,
+				Country:     "US",
+				Region:      "US-CA",
+				City:        "SUNNYVALE",
+				ServiceType: "chromeipblinding",
+				Expiration:  &tpb.Timestamp{Seconds: 900},
+				ProxyLayer:  tc.layer,
+			})
+			defer bs.Free()
+			if err := bs.SetExtension(0xF0FF, []byte("opaque")); err != nil {
+				t.Fatalf("SetExtension failed: %v", err)
+			}
+			got, err := Migrate(bs, tc.target)
+			if err != nil {
+				t.Fatalf("Migrate failed: %v", err)
+			}
+			defer got.Free()
+			if got.GetVersion() != tc.target {
+				t.Errorf("GetVersion() = %d, want %d", got.GetVersion(), tc.target)
+			}
+			if got.GetProxyLayer() != tc.wantLayer {
+				t.Errorf("GetProxyLayer() = %v, want %v", got.GetProxyLayer(), tc.wantLayer)
+			}
+			if got.GetGeoHint().City != "SUNNYVALE" || got.GetExpiration().GetSeconds() != 900 {
+				t.Errorf("Migrate() = %s, want the other fields unchanged", got.DebugString())
+			}
+			if v, ok := got.GetExtension(0xF0FF); !ok || string(v) != "opaque" {
+				t.Errorf("GetExtension(0xF0FF) = %q, %v, want opaque", v, ok)
+			}
+			if bs.GetVersion() != tc.version {
+				t.Errorf("Migrate() changed the input version to %d", bs.GetVersion())
+			}
+			out, err := Serialize(got)
+			if err != nil {
+				t.Fatalf("Serialize failed: %v", err)
+			}
+			back, err := Deserialize(out)
+			if err != nil {
+				t.Fatalf("Deserialize failed: %v", err)
+			}
+			defer back.Free()
+			if back.GetVersion() != tc.target {
+				t.Errorf("GetVersion() after round trip = %d, want %d", back.GetVersion(), tc.target)
+			}
+		})
+	}
+}
+
+func TestMigrateDropsExpirationMillis(t *testing.T) {
+	bs := New(&NewBinaryFields{Version: 3, Country: "US", ServiceType: "chromeipblinding"})
+	defer bs.Free()
+	if err := bs.SetExpirationTime(time.Unix(900, 500*int64(time.Millisecond))); err != nil {
+		t.Fatalf("SetExpirationTime failed: %v", err)
+	}
+	got, err := Migrate(bs, 1)
+	if err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	defer got.Free()
+	if want := time.Unix(900, 0); !got.GetExpirationTime().Equal(want) {
+		t.Errorf("GetExpirationTime() = %v, want %v", got.GetExpirationTime(), want)
+	}
+}
+
+func TestMigrateUnknownVersion(t *testing.T) {
+	bs := New(&NewBinaryFields{Version: 2})
+	defer bs.Free()
+	if _, err := Migrate(bs, 7); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("Migrate(7) returned error: %v, want error: %v", err, ErrUnknownVersion)
+	}
+}
@@ -0,0 +1,34 @@
+package binarymetadata
+
+// NetworkType is the coarse kind of network a client connects from, as carried by the network
+// type extension. It is deliberately limited to a few values so that egress policies can tell
+// Wi-Fi from cellular clients without learning more about the network.
+type NetworkType uint8
+
+// Network types, equal to their wire values.
+const (
+	NetworkTypeUnknown  NetworkType = 0x00
+	NetworkTypeWiFi     NetworkType = 0x01
+	NetworkTypeCellular NetworkType = 0x02
+)
+
+func (t NetworkType) String() string {
+	return coarseName(ExtensionTypeNetworkType, uint8(t))
+}
+
+// GetNetworkType returns the network type and whether it is set to a valid value.
+func (bs *BinaryStruct) GetNetworkType() (NetworkType, bool) {
+	v, ok := bs.getCoarse(ExtensionTypeNetworkType)
+	return NetworkType(v), ok
+}
+
+// SetNetworkType sets the network type. It fails with ErrInvalidNetworkType for values other than
+// the NetworkType constants and for versions without the NetworkType capability.
+func (bs *BinaryStruct) SetNetworkType(t NetworkType) error {
+	return bs.setCoarse(ExtensionTypeNetworkType, uint8(t))
+}
+
+// ClearNetworkType removes the network type.
+func (bs *BinaryStruct) ClearNetworkType() {
+	bs.clearCoarse(ExtensionTypeNetworkType)
+}
@@ -0,0 +1,145 @@
+package binarymetadata
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSetNetworkTypeRejectsOlderVersions(t *testing.T) {
+	for _, version := range []int32{1, 2} {
+		bs := New(&NewBinaryFields{Version: version, Country: "US", ServiceType: "chromeipblinding"})
+		defer bs.Free()
+		if err := bs.SetNetworkType(NetworkTypeWiFi); !errors.Is(err, ErrInvalidNetworkType) {
+			t.Errorf("version %d: SetNetworkType() returned error: %v, want error: %v", version, err, ErrInvalidNetworkType)
+		}
+		if nt, ok := bs.GetNetworkType(); ok {
+			t.Errorf("version %d: GetNetworkType() = %v, true, want false", version, nt)
+		}
+	}
+}
+
+func TestSetNetworkTypeRejectsFineValues(t *testing.T) {
+	bs := New(&NewBinaryFields{Version: 3, Country: "US", ServiceType: "chromeipblinding"})
+	defer bs.Free()
+	if err := bs.SetNetworkType(NetworkType(3)); !errors.Is(err, ErrInvalidNetworkType) {
+		t.Errorf("SetNetworkType(3) returned error: %v, want error: %v", err, ErrInvalidNetworkType)
+	}
+	bs.Free()
+	if err := bs.SetNetworkType(NetworkTypeWiFi); !errors.Is(err, ErrFreed) {
+		t.Errorf("SetNetworkType() after Free returned error: %v, want error: %v", err, ErrFreed)
+	}
+}
+
+func TestNetworkTypeRoundTrip(t *testing.T) {
+	for _, want := range []NetworkType{NetworkTypeUnknown, NetworkTypeWiFi, NetworkTypeCellular} {
+		bs := newTestStruct(t, 3)
+		if err := bs.SetNetworkType(want); err != nil {
+			t.Fatalf("SetNetworkType(%v) failed: %v", want, err)
+		}
+		out, err := Serialize(bs)
+		if err != nil {
+			t.Fatalf("Serialize failed: %v", err)
+		}
+		if !IsCanonical(out) {
+			t.Errorf("IsCanonical(Serialize()) = false for %v, want true", want)
+		}
+		got, err := DeserializeOptions{Strict: true}.Deserialize(out)
+		if err != nil {
+			t.Fatalf("Deserialize failed: %v", err)
+		}
+		defer got.Free()
+		if got.GetVersion() != 3 {
+			t.Errorf("GetVersion() after round trip = %d, want 3", got.GetVersion())
+		}
+		if nt, ok := got.GetNetworkType(); !ok || nt != want {
+			t.Errorf("GetNetworkType() = %v, %t, want %v, true", nt, ok, want)
+		}
+		if err := defaultValidator.ValidateStruct(got, time.Unix(0, 0)); err != nil {
+			t.Errorf("ValidateStruct(%v) failed: %v", want, err)
+		}
+		if err := ValidateMetadataCardinality(out, time.Unix(0, 0)); err != nil {
+			t.Errorf("ValidateMetadataCardinality(%v) failed: %v", want, err)
+		}
+		got.ClearNetworkType()
+		if nt, ok := got.GetNetworkType(); ok {
+			t.Errorf("GetNetworkType() after ClearNetworkType = %v, true, want false", nt)
+		}
+	}
+}
+
+func TestNetworkTypeCardinality(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		enable  bool
+		value   []byte
+		rule    string
+		wantErr error
+	}{
+		{name: "unsupported version", value: []byte{0x01}, rule: "supported_by_version", wantErr: ErrInvalidNetworkType},
+		{name: "fine value", enable: true, value: []byte{0x07}, rule: "coarse_value", wantErr: ErrInvalidNetworkType},
+		{name: "too long", enable: true, value: []byte{0x01, 0x00}, rule: "coarse_value", wantErr: ErrMalformed},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			version := int32(2)
+			if tc.enable {
+				version = 3
+			}
+			bs := newTestStruct(t, version)
+			if err := bs.SetExtension(ExtensionTypeNetworkType, tc.value); err != nil {
+				t.Fatalf("SetExtension failed: %v", err)
+			}
+			out, err := Serialize(bs)
+			if err != nil {
+				t.Fatalf("Serialize failed: %v", err)
+			}
+			if _, err := (DeserializeOptions{Strict: true}).Deserialize(out); !errors.Is(err, tc.wantErr) {
+				t.Errorf("Deserialize() returned error: %v, want error: %v", err, tc.wantErr)
+			}
+			err = defaultValidator.ValidateStruct(bs, time.Unix(0, 0))
+			var fe *FieldError
+			if !errors.As(err, &fe) || fe.Field != "network_type" || !errors.Is(err, tc.wantErr) {
+				t.Errorf("ValidateStruct() returned error: %v, want network_type error wrapping %v", err, tc.wantErr)
+			}
+			found := false
+			for _, f := range defaultValidator.findings(bs, time.Unix(0, 0)) {
+				found = found || f.Field == "network_type" && f.Rule == tc.rule
+			}
+			if !found {
+				t.Errorf("findings() lacks network_type rule %q", tc.rule)
+			}
+		})
+	}
+}
+
+func TestMigrateDropsNetworkType(t *testing.T) {
+	bs := newTestStruct(t, 3)
+	if err := bs.SetNetworkType(NetworkTypeCellular); err != nil {
+		t.Fatalf("SetNetworkType failed: %v", err)
+	}
+	got, err := Migrate(bs, 1)
+	if err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	defer got.Free()
+	if nt, ok := got.GetNetworkType(); ok {
+		t.Errorf("GetNetworkType() after Migrate(1) = %v, true, want false", nt)
+	}
+}
+
+func TestNetworkTypeExtension(t *testing.T) {
+	e, err := NetworkTypeExtension{Type: NetworkTypeCellular}.AsExtension()
+	if err != nil {
+		t.Fatalf("AsExtension failed: %v", err)
+	}
+	got, err := NetworkTypeExtensionFromExtension(e)
+	if err != nil || got.Type != NetworkTypeCellular {
+		t.Errorf("NetworkTypeExtensionFromExtension() = %v, %v, want %v", got, err, NetworkTypeCellular)
+	}
+	if _, err := (NetworkTypeExtension{Type: 3}).AsExtension(); !errors.Is(err, ErrInvalidNetworkType) {
+		t.Errorf("AsExtension(3) returned error: %v, want error: %v", err, ErrInvalidNetworkType)
+	}
+	if s := NetworkType(9).String(); s != "network_type(9)" {
+		t.Errorf("NetworkType(9).String() = %q, want network_type(9)", s)
+	}
+}
@@ -0,0 +1,90 @@
+package binarymetadata
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// GetNonce returns the nonce the metadata carries and whether it carries one.
+func (bs *BinaryStruct) GetNonce() ([NonceSize]byte, bool) {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+	return bs.nonce()
+}
+
+func (bs *BinaryStruct) nonce() ([NonceSize]byte, bool) {
+	e, ok := bs.findExtra(ExtensionTypeNonce)
+	if !ok {
+		return [NonceSize]byte{}, false
+	}
+	n, err := NonceExtensionFromExtension(e)
+	return n.Nonce, err == nil
+}
+
+// SetNonce sets the nonce of the metadata, so that no two blobs minted from the same fields are
+// equal; see RedemptionVerifier.ReplayCache. It fails with ErrInvalidNonce for versions without the
+// Nonce capability.
+func (bs *BinaryStruct) SetNonce(nonce [NonceSize]byte) error {
+	err := bs.setNonce(nonce)
+	bs.audit(AuditMutate, "set_nonce", err)
+	return err
+}
+
+// SetRandomNonce is like SetNonce with a nonce read from crypto/rand. If reading fails the nonce is
+// left unchanged.
+func (bs *BinaryStruct) SetRandomNonce() error {
+	var nonce [NonceSize]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		err = fmt.Errorf("reading a random nonce: %w", err)
+		bs.audit(AuditMutate, "set_nonce", err)
+		return err
+	}
+	return bs.SetNonce(nonce)
+}
+
+func (bs *BinaryStruct) setNonce(nonce [NonceSize]byte) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if err := bs.checkMutable(); err != nil {
+		return err
+	}
+	c, err := Capabilities(int32(bs.md().GetVersion()))
+	if err != nil {
+		return err
+	}
+	if !c.Nonce {
+		return fmt.Errorf("%w: version %d does not support the nonce extension", ErrInvalidNonce, c.Version)
+	}
+	e, err := NonceExtension{Nonce: nonce}.AsExtension()
+	if err != nil {
+		return err
+	}
+	bs.putExtra(e)
+	return nil
+}
+
+// ClearNonce removes the nonce.
+func (bs *BinaryStruct) ClearNonce() {
+	defer bs.audit(AuditMutate, "clear_nonce", nil)
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if !bs.frozen {
+		bs.removeExtra(ExtensionTypeNonce)
+	}
+}
+
+// checkNonce rejects a malformed nonce and nonces on versions that cannot carry one. The caller
+// holds the lock.
+func (bs *BinaryStruct) checkNonce(c VersionCapabilities) error {
+	e, ok := bs.findExtra(ExtensionTypeNonce)
+	if !ok {
+		return nil
+	}
+	if _, err := NonceExtensionFromExtension(e); err != nil {
+		return err
+	}
+	if !c.Nonce {
+		return fmt.Errorf("%w: version %d does not support the nonce extension", ErrInvalidNonce, c.Version)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package binarymetadata
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+)
+
+func TestNonceRoundTrip(t *testing.T) {
+	bs := newTestStruct(t, 3)
+	if _, ok := bs.GetNonce(); ok {
+		t.Error("GetNonce() reported a nonce before SetNonce")
+	}
+	want := [NonceSize]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	if err := bs.SetNonce(want); err != nil {
+		t.Fatalf("SetNonce failed: %v", err)
+	}
+	out, err := Serialize(bs)
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	got, err := DeserializeOptions{Strict: true, Canonical: true}.Deserialize(out)
+	if err != nil {
+		t.Fatalf("Deserialize failed: %v", err)
+	}
+	defer got.Free()
+	if got.GetVersion() != 3 {
+		t.Errorf("GetVersion() after round trip = %d, want 3", got.GetVersion())
+	}
+	if nonce, ok := got.GetNonce(); !ok || nonce != want {
+		t.Errorf("GetNonce() = %x, %t, want %x, true", nonce, ok, want)
+	}
+	if err := got.SetRandomNonce(); err != nil {
+		t.Fatalf("SetRandomNonce failed: %v", err)
+	}
+	if nonce, _ := got.GetNonce(); nonce == want {
+		t.Errorf("GetNonce() = %x after SetRandomNonce, want a new nonce", nonce)
+	}
+	got.ClearNonce()
+	if _, ok := got.GetNonce(); ok {
+		t.Error("GetNonce() reported a nonce after ClearNonce")
+	}
+}
+
+func TestNonceRejectsOlderVersions(t *testing.T) {
+	bs := New(&NewBinaryFields{Version: 2, Country: "US", ServiceType: "chromeipblinding", Expiration: tpb.New(time.Unix(900, 0))})
+	defer bs.Free()
+	if err := bs.SetRandomNonce(); !errors.Is(err, ErrInvalidNonce) {
+		t.Errorf("SetRandomNonce() returned error: %v, want error: %v", err, ErrInvalidNonce)
+	}
+	if err := bs.SetExtension(ExtensionTypeNonce, make([]byte, NonceSize)); err != nil {
+		t.Fatalf("SetExtension failed: %v", err)
+	}
+	out, err := Serialize(bs)
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	if _, err := (DeserializeOptions{Strict: true}).Deserialize(out); !errors.Is(err, ErrInvalidNonce) {
+		t.Errorf("Deserialize() returned error: %v, want error: %v", err, ErrInvalidNonce)
+	}
+	err = defaultValidator.Validate(out, time.Unix(0, 0))
+	var fe *FieldError
+	if !errors.As(err, &fe) || fe.Field != "nonce" || !errors.Is(err, ErrInvalidNonce) {
+		t.Errorf("Validate() returned error: %v, want nonce error wrapping %v", err, ErrInvalidNonce)
+	}
+}
+
+func TestNonceExtensionFromExtension(t *testing.T) {
+	if _, err := NonceExtensionFromExtension(Extension{Type: ExtensionTypeNonce, Value: make([]byte, NonceSize-1)}); !errors.Is(err, ErrMalformed) {
+		t.Errorf("NonceExtensionFromExtension(%d bytes) returned error: %v, want error: %v", NonceSize-1, err, ErrMalformed)
+	}
+}
@@ -0,0 +1,91 @@
+package binarymetadata
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ParallelConfig sizes a ParallelValidator.
+type ParallelConfig struct {
+	// Workers is the number of goroutines validating a batch. Zero means GOMAXPROCS.
+	Workers int
+	// MaxConcurrentCalls caps the validations in flight across all batches of the
+	// ParallelValidator, each of which calls into C++. Zero means Workers.
+	MaxConcurrentCalls int
+}
+
+// ParallelValidator validates batches of blobs on a bounded pool of workers, instead of one
+// goroutine per blob, which thrashes the scheduler and the threads backing calls into C++ under
+// load. It is safe for concurrent use.
+type ParallelValidator struct {
+	validate func([]byte, time.Time) error
+	workers  int
+	calls    chan struct{}
+}
+
+// NewParallelValidator returns a ParallelValidator running validate, typically the Validate,
+// ValidateForIssuance or ValidateForRedemption method of a Validator.
+func NewParallelValidator(validate func([]byte, time.Time) error, cfg ParallelConfig) (*ParallelValidator, error) {
+	if validate == nil {
+		return nil, fmt.Errorf("nil validate func")
+	}
+	if cfg.Workers < 0 || cfg.MaxConcurrentCalls < 0 {
+		return nil, fmt.Errorf("negative Workers %d or MaxConcurrentCalls %d", cfg.Workers, cfg.MaxConcurrentCalls)
+	}
+	if cfg.Workers == 0 {
+		cfg.Workers = runtime.GOMAXPROCS(0)
+	}
+	if cfg.MaxConcurrentCalls == 0 {
+		cfg.MaxConcurrentCalls = cfg.Workers
+	}
+	return &ParallelValidator{validate: validate, workers: cfg.Workers, calls: make(chan struct{}, cfg.MaxConcurrentCalls)}, nil
+}
+
+// BatchResult is the outcome of ValidateAll.
+type BatchResult struct {
+	// Errs holds the error of every blob, nil for the valid ones, in the order of the batch.
+	Errs []error
+	// Invalid is the number of non-nil errors in Errs.
+	Invalid int
+}
+
+// ValidateAll validates every blob of in at time t. Blobs not validated before ctx is done fail
+// with ctx.Err().
+func (p *ParallelValidator) ValidateAll(ctx context.Context, in [][]byte, t time.Time) BatchResult {
+	errs := make([]error, len(in))
+	var (
+		next    atomic.Int64
+		invalid atomic.Int64
+		wg      sync.WaitGroup
+	)
+	for range min(p.workers, len(in)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := int(next.Add(1) - 1); i < len(in); i = int(next.Add(1) - 1) {
+				if errs[i] = p.validateOne(ctx, in[i], t); errs[i] != nil {
+					invalid.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return BatchResult{Errs: errs, Invalid: int(invalid.Load())}
+}
+
+func (p *ParallelValidator) validateOne(ctx context.Context, in []byte, t time.Time) error {
+	select {
+	case p.calls <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-p.calls }()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return p.validate(in, t)
+}
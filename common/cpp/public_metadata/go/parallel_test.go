@@ -0,0 +1,74 @@
+package binarymetadata
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+)
+
+func TestParallelValidator(t *testing.T) {
+	exp := time.Unix(1701110700, 0)
+	valid := serializeForTest(t, &NewBinaryFields{Version: 1, Country: "US", ServiceType: "chromeipblinding", Expiration: tpb.New(exp)})
+	in := [][]byte{valid, {0}, valid, nil, valid}
+	p, err := NewParallelValidator(defaultValidator.Validate, ParallelConfig{Workers: 2})
+	if err != nil {
+		t.Fatalf("NewParallelValidator failed: %v", err)
+	}
+	res := p.ValidateAll(context.Background(), in, exp.Add(-time.Hour))
+	if len(res.Errs) != len(in) || res.Invalid != 2 {
+		t.Fatalf("ValidateAll() = %+v, want %d errors of which 2 non-nil", res, len(in))
+	}
+	for i, err := range res.Errs {
+		if wantErr := in[i] == nil || len(in[i]) == 1; (err != nil) != wantErr {
+			t.Errorf("ValidateAll() error %d = %v, want error: %t", i, err, wantErr)
+		}
+	}
+}
+
+func TestParallelValidatorCapsConcurrentCalls(t *testing.T) {
+	var inFlight, peak atomic.Int64
+	validate := func([]byte, time.Time) error {
+		n := inFlight.Add(1)
+		for {
+			old := peak.Load()
+			if n <= old || peak.CompareAndSwap(old, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		inFlight.Add(-1)
+		return nil
+	}
+	p, err := NewParallelValidator(validate, ParallelConfig{Workers: 8, MaxConcurrentCalls: 2})
+	if err != nil {
+		t.Fatalf("NewParallelValidator failed: %v", err)
+	}
+	if res := p.ValidateAll(context.Background(), make([][]byte, 32), time.Now()); res.Invalid != 0 {
+		t.Errorf("ValidateAll() = %+v, want no errors", res)
+	}
+	if got := peak.Load(); got > 2 {
+		t.Errorf("%d validations ran concurrently, want at most 2", got)
+	}
+}
+
+func TestParallelValidatorCanceled(t *testing.T) {
+	p, err := NewParallelValidator(func([]byte, time.Time) error { return nil }, ParallelConfig{})
+	if err != nil {
+		t.Fatalf("NewParallelValidator failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res := p.ValidateAll(ctx, make([][]byte, 4), time.Now())
+	for i, err := range res.Errs {
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("ValidateAll() error %d = %v, want %v", i, err, context.Canceled)
+		}
+	}
+	if _, err := NewParallelValidator(nil, ParallelConfig{}); err == nil {
+		t.Error("NewParallelValidator(nil) succeeded, want error")
+	}
+}
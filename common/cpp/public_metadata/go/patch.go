@@ -0,0 +1,69 @@
+package binarymetadata
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// patchFields maps the field names a patch mask may hold, the names Diff reports plus "version",
+// onto the function copying that field from the patch.
+var patchFields = map[string]func(dst, patch *NewBinaryFields){
+	"version":      func(dst, patch *NewBinaryFields) { dst.Version = patch.Version },
+	"service_type": func(dst, patch *NewBinaryFields) { dst.ServiceType = patch.ServiceType },
+	"expiration": func(dst, patch *NewBinaryFields) {
+		dst.Expiration, dst.ExpirationTime = patch.Expiration, patch.ExpirationTime
+	},
+	"debug_mode":  func(dst, patch *NewBinaryFields) { dst.DebugMode = patch.DebugMode },
+	"country":     func(dst, patch *NewBinaryFields) { dst.Country = patch.Country },
+	"region":      func(dst, patch *NewBinaryFields) { dst.Region = patch.Region },
+	"city":        func(dst, patch *NewBinaryFields) { dst.City = patch.City },
+	"proxy_layer": func(dst, patch *NewBinaryFields) { dst.ProxyLayer = patch.ProxyLayer },
+}
+
+// ApplyPatch returns a copy of base with the fields named in mask replaced by those of patch,
+// e.g. a mask of "expiration" keeps everything but the expiration of a template. Fields are named
+// as in Diff, plus "version"; "*" names every field. Fields outside the mask are taken from base
+// even if set in patch, and fields in the mask are taken from patch even if unset there, so a
+// mask can clear a field. patch.DebugModeCapability authorizes the result as for NewChecked.
+//
+// The result is built with NewChecked and carries over the extensions of base added with
+// SetExtension, except the expiration milliseconds when the expiration is patched. base is left
+// unchanged and the caller must Free the result.
+func ApplyPatch(base *BinaryStruct, patch *NewBinaryFields, mask []string) (*BinaryStruct, error) {
+	for _, name := range mask {
+		if _, ok := patchFields[name]; !ok && name != "*" {
+			return nil, fmt.Errorf("unknown field %q in patch mask", name)
+		}
+	}
+	base.mu.RLock()
+	defer base.mu.RUnlock()
+	if err := base.checkFreed(); err != nil {
+		return nil, err
+	}
+	f := base.fields()
+	patched := map[string]bool{}
+	for _, name := range mask {
+		if name == "*" {
+			for n := range patchFields {
+				patched[n] = true
+			}
+			continue
+		}
+		patched[name] = true
+	}
+	for name := range patched {
+		patchFields[name](f, patch)
+	}
+	f.DebugModeCapability = patch.DebugModeCapability
+	out, err := NewChecked(f)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range base.extra {
+		if e.Type == ExtensionTypeExpirationMillis && patched["expiration"] {
+			continue
+		}
+		out.extra = append(out.extra, Extension{Type: e.Type, Value: bytes.Clone(e.Value)})
+	}
+	return out, nil
+}
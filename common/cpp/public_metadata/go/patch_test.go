@@ -0,0 +1,99 @@
+package binarymetadata
+
+import (
+	"testing"
+	"time"
+
+	"google3/third_party/golang/cmp/cmp"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	plpb "google3/privacy/net/common/proto/proxy_layer_go_proto"
+)
+
+func TestApplyPatch(t *testing.T) {
+	base := New(&NewBinaryFields{
+		Version:     2,
+		Country:     "US",
+		Region:      "US-CA",
+		City:        "SUNNYVALE",
+		ServiceType: ServiceTypeChromeIPBlinding,
+		Expiration:  &tpb.Timestamp{Seconds: 3600},
+		ProxyLayer:  plpb.ProxyLayer_PROXY_A,
+	})
+	defer base.Free()
+	patch := &NewBinaryFields{
+		Version:     1,
+		Country:     "CA",
+		ServiceType: ServiceTypeChromeIPBlinding,
+		Expiration:  &tpb.Timestamp{Seconds: 4500},
+		ProxyLayer:  plpb.ProxyLayer_PROXY_B,
+	}
+	tests := []struct {
+		name string
+		mask []string
+		want []FieldDelta
+	}{
+		{name: "empty mask"},
+		{
+			name: "expiration",
+			mask: []string{"expiration"},
+			want: []FieldDelta{{Field: "expiration", Old: "1970-01-01T01:00:00Z", New: "1970-01-01T01:15:00Z"}},
+		},
+		{
+			name: "clears unset fields",
+			mask: []string{"region", "city"},
+			want: []FieldDelta{{Field: "region", Old: "US-CA"}, {Field: "city", Old: "SUNNYVALE"}},
+		},
+		{
+			name: "everything",
+			mask: []string{"*"},
+			want: []FieldDelta{
+				{Field: "expiration", Old: "1970-01-01T01:00:00Z", New: "1970-01-01T01:15:00Z"},
+				{Field: "country", Old: "US", New: "CA"},
+				{Field: "region", Old: "US-CA"},
+				{Field: "city", Old: "SUNNYVALE"},
+				{Field: "proxy_layer", Old: "PROXY_A", New: "PROXY_LAYER_UNSPECIFIED"},
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := ApplyPatch(base, patch, tc.mask)
+			if err != nil {
+				t.Fatalf("ApplyPatch failed: %v", err)
+			}
+			defer out.Free()
+			if diff := cmp.Diff(tc.want, Diff(base, out)); diff != "" {
+				t.Errorf("ApplyPatch returned unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestApplyPatchErrors(t *testing.T) {
+	base := New(&NewBinaryFields{Version: 1, Country: "US", ServiceType: ServiceTypeChromeIPBlinding, Expiration: &tpb.Timestamp{Seconds: 3600}})
+	defer base.Free()
+	if _, err := ApplyPatch(base, &NewBinaryFields{}, []string{"colour"}); err == nil {
+		t.Error("ApplyPatch with an unknown mask field succeeded, want error")
+	}
+	patch := &NewBinaryFields{ExpirationTime: time.Unix(4500, 5e8)}
+	if _, err := ApplyPatch(base, patch, []string{"expiration"}); err == nil {
+		t.Error("ApplyPatch with a sub-second expiration succeeded, want error")
+	}
+}
+
+func TestApplyPatchKeepsExtensions(t *testing.T) {
+	base := New(&NewBinaryFields{Version: 1, Country: "US", ServiceType: ServiceTypeChromeIPBlinding, Expiration: &tpb.Timestamp{Seconds: 3600}})
+	defer base.Free()
+	if err := base.SetExtension(0xF0AA, []byte("opaque")); err != nil {
+		t.Fatalf("SetExtension failed: %v", err)
+	}
+	out, err := ApplyPatch(base, &NewBinaryFields{Country: "CA"}, []string{"country"})
+	if err != nil {
+		t.Fatalf("ApplyPatch failed: %v", err)
+	}
+	defer out.Free()
+	if v, ok := out.GetExtension(0xF0AA); !ok || string(v) != "opaque" {
+		t.Errorf("GetExtension(0xF0AA) = %q, %v, want opaque, true", v, ok)
+	}
+}
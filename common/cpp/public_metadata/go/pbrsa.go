@@ -0,0 +1,63 @@
+package binarymetadata
+
+import (
+	"crypto/hkdf"
+	"crypto/rsa"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// publicMetadataHKDFInfo is the HKDF info string of the partially blind RSA exponent derivation.
+const publicMetadataHKDFInfo = "PBRSA"
+
+// PublicMetadataExponent derives the exponent that the partially blind RSA scheme binds
+// publicMetadata, usually the output of Serialize, into for a key with modulus n. It matches
+// ComputeExponentWithPublicMetadata in the C++ anonymous tokens library: HKDF-SHA384 over
+// "key" || publicMetadata || 0x00, salted with the big-endian modulus and expanded to half the
+// modulus length plus 16 bytes, truncated to half the modulus length, made odd and reduced below
+// 2^(prime bits - 2) so that it is coprime with the safe primes of the key. Like the C++ library,
+// it takes the prime length as half the modulus length in whole bytes.
+func PublicMetadataExponent(n *big.Int, publicMetadata []byte) (*big.Int, error) {
+	if n.Sign() <= 0 || n.BitLen()%2 == 1 {
+		return nil, errors.New("strong RSA modulus should be even bits")
+	}
+	modulus := n.Bytes()
+	outLen := len(modulus) / 2
+	input := make([]byte, 0, len("key")+len(publicMetadata)+1)
+	input = append(input, "key"...)
+	input = append(input, publicMetadata...)
+	input = append(input, 0x00)
+	// Expanding 16 bytes past the output keeps the truncated bytes indistinguishable from random.
+	out, err := hkdf.Key(sha512.New384, input, modulus, publicMetadataHKDFInfo, outLen+16)
+	if err != nil {
+		return nil, fmt.Errorf("deriving the public metadata exponent: %w", err)
+	}
+	e := new(big.Int).SetBytes(out[:outLen])
+	e.SetBit(e, 0, 1)
+	for i := outLen*8 - 2; i < e.BitLen(); i++ {
+		e.SetBit(e, i, 0)
+	}
+	return e, nil
+}
+
+// DerivedPublicKey is an RSA public key whose exponent may exceed the range of rsa.PublicKey.
+type DerivedPublicKey struct {
+	N *big.Int
+	E *big.Int
+}
+
+// DerivePublicKey returns the public key that verifies partially blind RSA signatures made by pk
+// over publicMetadata. With useRSAPublicExponent the derived exponent is multiplied by pk.E, as
+// the C++ library does for keys created with use_rsa_public_exponent; otherwise it replaces it.
+func DerivePublicKey(pk *rsa.PublicKey, publicMetadata []byte, useRSAPublicExponent bool) (*DerivedPublicKey, error) {
+	e, err := PublicMetadataExponent(pk.N, publicMetadata)
+	if err != nil {
+		return nil, err
+	}
+	if useRSAPublicExponent {
+		e.Mul(e, big.NewInt(int64(pk.E)))
+	}
+	return &DerivedPublicKey{N: new(big.Int).Set(pk.N), E: e}, nil
+}
@@ -0,0 +1,101 @@
+package binarymetadata
+
+import (
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha512"
+	"math/big"
+	"testing"
+)
+
+func TestPublicMetadataExponent(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	n := key.N
+	e, err := PublicMetadataExponent(n, []byte("metadata"))
+	if err != nil {
+		t.Fatalf("PublicMetadataExponent failed: %v", err)
+	}
+	if e.Bit(0) != 1 {
+		t.Errorf("exponent %v is even", e)
+	}
+	if max := len(n.Bytes())/2*8 - 2; e.BitLen() > max {
+		t.Errorf("exponent has %d bits, want at most %d", e.BitLen(), max)
+	}
+	again, err := PublicMetadataExponent(n, []byte("metadata"))
+	if err != nil {
+		t.Fatalf("PublicMetadataExponent failed: %v", err)
+	}
+	if e.Cmp(again) != 0 {
+		t.Error("PublicMetadataExponent is not deterministic")
+	}
+	other, err := PublicMetadataExponent(n, []byte("other metadata"))
+	if err != nil {
+		t.Fatalf("PublicMetadataExponent failed: %v", err)
+	}
+	if e.Cmp(other) == 0 {
+		t.Error("PublicMetadataExponent does not depend on the metadata")
+	}
+	otherModulus, err := PublicMetadataExponent(new(big.Int).Add(n, big.NewInt(2)), []byte("metadata"))
+	if err != nil {
+		t.Fatalf("PublicMetadataExponent failed: %v", err)
+	}
+	if e.Cmp(otherModulus) == 0 {
+		t.Error("PublicMetadataExponent does not depend on the modulus")
+	}
+	if _, err := PublicMetadataExponent(new(big.Int).Rsh(n, 1), []byte("metadata")); err == nil {
+		t.Error("PublicMetadataExponent accepted a modulus with an odd number of bits")
+	}
+}
+
+func TestPublicMetadataExponentPrimeBytes(t *testing.T) {
+	// A 2040 bit modulus has 255 bytes, so the C++ library masks the exponent to 127*8-2 bits
+	// rather than 2040/2-2.
+	n := new(big.Int).Lsh(big.NewInt(1), 2039)
+	n.Add(n, big.NewInt(1))
+	metadata := []byte("metadata")
+	e, err := PublicMetadataExponent(n, metadata)
+	if err != nil {
+		t.Fatalf("PublicMetadataExponent failed: %v", err)
+	}
+	input := append(append([]byte("key"), metadata...), 0x00)
+	out, err := hkdf.Key(sha512.New384, input, n.Bytes(), publicMetadataHKDFInfo, 127+16)
+	if err != nil {
+		t.Fatalf("hkdf.Key failed: %v", err)
+	}
+	want := new(big.Int).SetBytes(out[:127])
+	want.SetBit(want, 0, 1)
+	mask := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 127*8-2), big.NewInt(1))
+	want.And(want, mask)
+	if e.Cmp(want) != 0 {
+		t.Errorf("PublicMetadataExponent() = %x, want %x", e, want)
+	}
+}
+
+func TestDerivePublicKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	e, err := PublicMetadataExponent(key.N, nil)
+	if err != nil {
+		t.Fatalf("PublicMetadataExponent failed: %v", err)
+	}
+	pk, err := DerivePublicKey(&key.PublicKey, nil, false)
+	if err != nil {
+		t.Fatalf("DerivePublicKey failed: %v", err)
+	}
+	if pk.N.Cmp(key.N) != 0 || pk.E.Cmp(e) != 0 {
+		t.Errorf("DerivePublicKey = (%v, %v), want (%v, %v)", pk.N, pk.E, key.N, e)
+	}
+	pk, err = DerivePublicKey(&key.PublicKey, nil, true)
+	if err != nil {
+		t.Fatalf("DerivePublicKey failed: %v", err)
+	}
+	if want := new(big.Int).Mul(e, big.NewInt(int64(key.E))); pk.E.Cmp(want) != 0 {
+		t.Errorf("DerivePublicKey with the RSA exponent = %v, want %v", pk.E, want)
+	}
+}
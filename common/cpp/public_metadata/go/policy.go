@@ -0,0 +1,171 @@
+package binarymetadata
+
+import (
+	"fmt"
+
+	plpb "google3/privacy/net/common/proto/proxy_layer_go_proto"
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+	polpb "google3/privacy/net/common/proto/public_metadata_policy_go_proto"
+)
+
+// Policy holds the cross-field rules of a PublicMetadataPolicy config, such as "city level geo
+// hints only for service type X" or "no debug mode on PROXY_B". Set ValidationConfig.Policy to
+// evaluate it in Validate. It is immutable and safe for concurrent use.
+type Policy struct {
+	rules []policyRule
+}
+
+type policyRule struct {
+	name string
+	when policyMatch
+	// require is nil for rules rejecting all metadata matching when.
+	require *policyMatch
+}
+
+// policyMatch is a compiled PublicMetadataPolicy.Match. Nil sets match any value.
+type policyMatch struct {
+	serviceTypes map[string]bool
+	proxyLayers  map[plpb.ProxyLayer]bool
+	debugModes   map[pmpb.PublicMetadata_DebugMode]bool
+	countries    map[string]bool
+	geoLevels    map[GeoGranularity]bool
+}
+
+// geoLevels maps the GeoLevel enum onto GeoGranularity.
+var geoLevels = map[polpb.PublicMetadataPolicy_GeoLevel]GeoGranularity{
+	polpb.PublicMetadataPolicy_GEO_LEVEL_COUNTRY: GeoCountry,
+	polpb.PublicMetadataPolicy_GEO_LEVEL_REGION:  GeoRegion,
+	polpb.PublicMetadataPolicy_GEO_LEVEL_CITY:    GeoCity,
+}
+
+// NewPolicy compiles p. It fails if a rule has no name or the name of an earlier rule, or lists a
+// service type, country or enum value that no metadata can carry.
+func NewPolicy(p *polpb.PublicMetadataPolicy) (*Policy, error) {
+	out := &Policy{}
+	names := map[string]bool{}
+	for i, r := range p.GetRules() {
+		if r.GetName() == "" {
+			return nil, fmt.Errorf("policy rule %d has no name", i)
+		}
+		if names[r.GetName()] {
+			return nil, fmt.Errorf("duplicate policy rule %q", r.GetName())
+		}
+		names[r.GetName()] = true
+		when, err := compileMatch(r.GetWhen())
+		if err != nil {
+			return nil, fmt.Errorf("policy rule %q: when: %w", r.GetName(), err)
+		}
+		rule := policyRule{name: r.GetName(), when: when}
+		if r.GetRequire() != nil {
+			require, err := compileMatch(r.GetRequire())
+			if err != nil {
+				return nil, fmt.Errorf("policy rule %q: require: %w", r.GetName(), err)
+			}
+			rule.require = &require
+		}
+		out.rules = append(out.rules, rule)
+	}
+	return out, nil
+}
+
+func compileMatch(m *polpb.PublicMetadataPolicy_Match) (policyMatch, error) {
+	var out policyMatch
+	for _, s := range m.GetServiceTypes() {
+		if err := checkServiceType(s); err != nil {
+			return policyMatch{}, err
+		}
+		out.serviceTypes = addTo(out.serviceTypes, s)
+	}
+	for _, l := range m.GetProxyLayers() {
+		if _, ok := plpb.ProxyLayer_name[int32(l)]; !ok {
+			return policyMatch{}, fmt.Errorf("%w: %d", ErrInvalidProxyLayer, l)
+		}
+		out.proxyLayers = addTo(out.proxyLayers, l)
+	}
+	for _, d := range m.GetDebugModes() {
+		if _, ok := pmpb.PublicMetadata_DebugMode_name[int32(d)]; !ok {
+			return policyMatch{}, fmt.Errorf("%w: %d", ErrInvalidDebugMode, d)
+		}
+		out.debugModes = addTo(out.debugModes, d)
+	}
+	for _, c := range m.GetCountries() {
+		if !IsValidCountry(c) {
+			return policyMatch{}, fmt.Errorf("%w: %q", ErrInvalidCountry, c)
+		}
+		out.countries = addTo(out.countries, c)
+	}
+	for _, l := range m.GetGeoLevels() {
+		g, ok := geoLevels[l]
+		if !ok {
+			return policyMatch{}, fmt.Errorf("%w: geo level %v", ErrInvalidGeoHint, l)
+		}
+		out.geoLevels = addTo(out.geoLevels, g)
+	}
+	return out, nil
+}
+
+func addTo[K comparable](set map[K]bool, k K) map[K]bool {
+	if set == nil {
+		set = map[K]bool{}
+	}
+	set[k] = true
+	return set
+}
+
+// policySubject holds the fields of metadata a policy matches on.
+type policySubject struct {
+	serviceType string
+	proxyLayer  plpb.ProxyLayer
+	debugMode   pmpb.PublicMetadata_DebugMode
+	country     string
+	geoLevel    GeoGranularity
+}
+
+func (m *policyMatch) matches(s policySubject) bool {
+	return (m.serviceTypes == nil || m.serviceTypes[s.serviceType]) &&
+		(m.proxyLayers == nil || m.proxyLayers[s.proxyLayer]) &&
+		(m.debugModes == nil || m.debugModes[s.debugMode]) &&
+		(m.countries == nil || m.countries[s.country]) &&
+		(m.geoLevels == nil || m.geoLevels[s.geoLevel])
+}
+
+// Check returns the first rule of p that bs breaks as a *FieldError for the "policy" field, or nil
+// if bs satisfies every rule.
+func (p *Policy) Check(bs *BinaryStruct) error {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+	if err := bs.checkFreed(); err != nil {
+		return err
+	}
+	if fs := p.findings(bs); len(fs) > 0 {
+		return fs[0].fieldError()
+	}
+	return nil
+}
+
+// findings returns a finding for every rule of p that bs breaks, in rule order. The caller holds
+// the lock.
+func (p *Policy) findings(bs *BinaryStruct) []Finding {
+	geo := bs.geoHint()
+	s := policySubject{
+		serviceType: bs.serviceType(),
+		proxyLayer:  bs.proxyLayer(),
+		debugMode:   bs.debugMode(),
+		country:     geo.Country,
+		geoLevel:    GeoCountry,
+	}
+	switch {
+	case geo.City != "":
+		s.geoLevel = GeoCity
+	case geo.Region != "":
+		s.geoLevel = GeoRegion
+	}
+	var fs []Finding
+	for _, r := range p.rules {
+		if !r.when.matches(s) || r.require != nil && r.require.matches(s) {
+			continue
+		}
+		fs = append(fs, Finding{Field: "policy", Rule: r.name, Severity: SeverityError, Err: fmt.Errorf("%w: %s", ErrPolicyViolation, r.name)})
+	}
+	return fs
+}
@@ -0,0 +1,95 @@
+package binarymetadata
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	plpb "google3/privacy/net/common/proto/proxy_layer_go_proto"
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+	polpb "google3/privacy/net/common/proto/public_metadata_policy_go_proto"
+)
+
+func testPolicy(t *testing.T) *Policy {
+	t.Helper()
+	p, err := NewPolicy(&polpb.PublicMetadataPolicy{Rules: []*polpb.PublicMetadataPolicy_Rule{
+		{
+			Name:    "city_only_for_chrome",
+			When:    &polpb.PublicMetadataPolicy_Match{GeoLevels: []polpb.PublicMetadataPolicy_GeoLevel{polpb.PublicMetadataPolicy_GEO_LEVEL_CITY}},
+			Require: &polpb.PublicMetadataPolicy_Match{ServiceTypes: []string{ServiceTypeChromeIPBlinding}},
+		},
+		{
+			Name: "no_debug_on_proxy_b",
+			When: &polpb.PublicMetadataPolicy_Match{
+				DebugModes:  []pmpb.PublicMetadata_DebugMode{pmpb.PublicMetadata_DEBUG_ALL},
+				ProxyLayers: []plpb.ProxyLayer{plpb.ProxyLayer_PROXY_B},
+			},
+		},
+	}})
+	if err != nil {
+		t.Fatalf("NewPolicy failed: %v", err)
+	}
+	return p
+}
+
+func TestPolicy(t *testing.T) {
+	v, err := NewValidator(ValidationConfig{Policy: testPolicy(t)})
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+	for _, tc := range []struct {
+		name     string
+		fields   NewBinaryFields
+		wantRule string
+	}{
+		{name: "chrome city", fields: NewBinaryFields{ServiceType: ServiceTypeChromeIPBlinding, City: "MOUNTAIN VIEW", ProxyLayer: plpb.ProxyLayer_PROXY_B}},
+		{name: "cronet region", fields: NewBinaryFields{ServiceType: ServiceTypeCronet, ProxyLayer: plpb.ProxyLayer_PROXY_A}},
+		{name: "cronet city", fields: NewBinaryFields{ServiceType: ServiceTypeCronet, City: "MOUNTAIN VIEW", ProxyLayer: plpb.ProxyLayer_PROXY_A}, wantRule: "city_only_for_chrome"},
+		{name: "debug on proxy a", fields: NewBinaryFields{ServiceType: ServiceTypeChromeIPBlinding, DebugMode: pmpb.PublicMetadata_DEBUG_ALL, ProxyLayer: plpb.ProxyLayer_PROXY_A}},
+		{name: "debug on proxy b", fields: NewBinaryFields{ServiceType: ServiceTypeChromeIPBlinding, DebugMode: pmpb.PublicMetadata_DEBUG_ALL, ProxyLayer: plpb.ProxyLayer_PROXY_B}, wantRule: "no_debug_on_proxy_b"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := tc.fields
+			f.Version, f.Country, f.Region, f.Expiration = 2, "US", "US-CA", &tpb.Timestamp{Seconds: 900}
+			bs := New(&f)
+			defer bs.Free()
+			err := v.ValidateStruct(bs, time.Unix(0, 0))
+			if tc.wantRule == "" {
+				if err != nil {
+					t.Errorf("ValidateStruct() failed: %v", err)
+				}
+				return
+			}
+			var fe *FieldError
+			if !errors.As(err, &fe) || fe.Field != "policy" || !errors.Is(err, ErrPolicyViolation) {
+				t.Fatalf("ValidateStruct() returned error: %v, want policy violation", err)
+			}
+			found := false
+			for _, f := range v.findings(bs, time.Unix(0, 0)) {
+				found = found || f.Field == "policy" && f.Rule == tc.wantRule
+			}
+			if !found {
+				t.Errorf("findings() lacks policy rule %q", tc.wantRule)
+			}
+		})
+	}
+}
+
+func TestNewPolicyErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		rules []*polpb.PublicMetadataPolicy_Rule
+	}{
+		{name: "no name", rules: []*polpb.PublicMetadataPolicy_Rule{{}}},
+		{name: "duplicate name", rules: []*polpb.PublicMetadataPolicy_Rule{{Name: "a"}, {Name: "a"}}},
+		{name: "unknown service type", rules: []*polpb.PublicMetadataPolicy_Rule{{Name: "a", When: &polpb.PublicMetadataPolicy_Match{ServiceTypes: []string{"nosuchservice"}}}}},
+		{name: "invalid country", rules: []*polpb.PublicMetadataPolicy_Rule{{Name: "a", Require: &polpb.PublicMetadataPolicy_Match{Countries: []string{"usa"}}}}},
+		{name: "unspecified geo level", rules: []*polpb.PublicMetadataPolicy_Rule{{Name: "a", When: &polpb.PublicMetadataPolicy_Match{GeoLevels: []polpb.PublicMetadataPolicy_GeoLevel{polpb.PublicMetadataPolicy_GEO_LEVEL_UNSPECIFIED}}}}},
+		{name: "invalid debug mode", rules: []*polpb.PublicMetadataPolicy_Rule{{Name: "a", When: &polpb.PublicMetadataPolicy_Match{DebugModes: []pmpb.PublicMetadata_DebugMode{42}}}}},
+	} {
+		if _, err := NewPolicy(&polpb.PublicMetadataPolicy{Rules: tc.rules}); err == nil {
+			t.Errorf("%s: NewPolicy() succeeded, want error", tc.name)
+		}
+	}
+}
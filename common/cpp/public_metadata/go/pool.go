@@ -0,0 +1,108 @@
+package binarymetadata
+
+import (
+	"runtime"
+	"sync"
+
+	wrap "google3/privacy/net/common/cpp/public_metadata/go/wrap_public_metadata"
+)
+
+// pool holds reset BinaryStructs for reuse by Acquire. Values dropped by the pool during garbage
+// collection are freed by a finalizer, which unlike the NewManaged one is not counted in
+// ManagedStats.
+var pool = sync.Pool{
+	New: func() any {
+		bs := &BinaryStruct{metadata: newMetadata()}
+		bs.pooled = true
+		runtime.SetFinalizer(bs, freePooled)
+		return bs
+	},
+}
+
+func freePooled(bs *BinaryStruct) {
+	if bs.metadata != nil {
+		deleteMetadata(bs.metadata)
+		bs.metadata = nil
+	}
+}
+
+// Acquire returns a BinaryStruct populated from fields, reusing a previously released C++
+// allocation when one is available. Hand it back with Release once done; calling Free instead is
+// also safe but forgoes the reuse.
+func Acquire(fields *NewBinaryFields) *BinaryStruct {
+	bs := pool.Get().(*BinaryStruct)
+	setFields(bs.metadata, fields)
+	bs.auditTag = fields.AuditTag
+	bs.audit(AuditConstruct, "acquire", nil)
+	return bs
+}
+
+// Release resets bs and returns its allocation to the pool used by Acquire. bs must not be used
+// afterwards. Any BinaryStruct may be released, not only those returned by Acquire; frozen ones and
+// those allocated in an Arena are freed instead.
+func Release(bs *BinaryStruct) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if bs.metadata == nil {
+		return
+	}
+	if bs.frozen || bs.arena != nil {
+		bs.free()
+		return
+	}
+	bs.unmanage()
+	if !bs.pooled {
+		bs.pooled = true
+		runtime.SetFinalizer(bs, freePooled)
+	}
+	bs.reset()
+	bs.auditTag = ""
+	bs.releaseAllocation()
+	pool.Put(bs)
+}
+
+// Reset clears every field of the wrapped C++ struct, leaving all optionals unset, without
+// releasing its memory. Extensions added with SetExtension are dropped too. With Refill, it lets
+// hot loops reuse one allocation instead of pairing New with Free for every metadata.
+func (bs *BinaryStruct) Reset() {
+	defer bs.audit(AuditMutate, "reset", nil)
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if bs.metadata == nil || bs.frozen {
+		return
+	}
+	bs.reset()
+}
+
+// Refill resets bs and populates it from fields as New does, reusing the wrapped C++ struct. It
+// returns ErrFreed if bs has been freed.
+func (bs *BinaryStruct) Refill(fields *NewBinaryFields) error {
+	err := bs.refill(fields)
+	bs.audit(AuditMutate, "refill", err)
+	return err
+}
+
+func (bs *BinaryStruct) refill(fields *NewBinaryFields) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if err := bs.checkMutable(); err != nil {
+		return err
+	}
+	bs.reset()
+	setFields(bs.metadata, fields)
+	bs.auditTag = fields.AuditTag
+	return nil
+}
+
+func (bs *BinaryStruct) reset() {
+	bs.metadata.SetVersion(0)
+	bs.metadata.SetService_type(wrap.NewStringOptional())
+	bs.metadata.SetCountry(wrap.NewStringOptional())
+	bs.metadata.SetRegion(wrap.NewStringOptional())
+	bs.metadata.SetCity(wrap.NewStringOptional())
+	bs.metadata.SetExpiration_epoch_seconds(wrap.NewUint64Optional())
+	bs.metadata.SetDebug_mode(0)
+	bs.metadata.SetProxy_layer(wrap.NewUint32Optional())
+	bs.extra = nil
+	bs.newer = false
+}
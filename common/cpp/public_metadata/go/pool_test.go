@@ -0,0 +1,81 @@
+package binarymetadata
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	plpb "google3/privacy/net/common/proto/proxy_layer_go_proto"
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+)
+
+func TestReset(t *testing.T) {
+	bs := New(&NewBinaryFields{
+		Version:     2,
+		Country:     "US",
+		Region:      "US-CA",
+		City:        "SUNNYVALE",
+		ServiceType: "chromeipblinding",
+		Expiration:  &tpb.Timestamp{Seconds: 3600},
+		DebugMode:   pmpb.PublicMetadata_DEBUG_ALL,
+		ProxyLayer:  plpb.ProxyLayer_PROXY_B,
+	})
+	defer bs.Free()
+	bs.Reset()
+	if bs.metadata.GetCountry().HasValue() || bs.metadata.GetService_type().HasValue() || bs.GetExpiration() != nil {
+		t.Errorf("Reset() left optionals set: %v", bs)
+	}
+	if bs.GetDebugMode() != pmpb.PublicMetadata_UNSPECIFIED_DEBUG_MODE {
+		t.Errorf("Reset() left debug mode %v", bs.GetDebugMode())
+	}
+}
+
+func TestRefill(t *testing.T) {
+	bs := New(&NewBinaryFields{Version: 2, Country: "US", Region: "US-CA", ServiceType: "chromeipblinding", DebugMode: pmpb.PublicMetadata_DEBUG_ALL})
+	defer bs.Free()
+	if err := bs.SetExtension(0x7001, []byte{1}); err != nil {
+		t.Fatalf("SetExtension failed: %v", err)
+	}
+	for _, seconds := range []int64{3600, 4500} {
+		fields := &NewBinaryFields{Version: 1, Country: "CA", ServiceType: "chromeipblinding", Expiration: &tpb.Timestamp{Seconds: seconds}}
+		if err := bs.Refill(fields); err != nil {
+			t.Fatalf("Refill failed: %v", err)
+		}
+		got, err := Serialize(bs)
+		if err != nil {
+			t.Fatalf("Serialize(refilled) failed: %v", err)
+		}
+		want := New(fields)
+		defer want.Free()
+		wantOut, err := Serialize(want)
+		if err != nil {
+			t.Fatalf("Serialize(new) failed: %v", err)
+		}
+		if !bytes.Equal(got, wantOut) {
+			t.Errorf("Serialize(refilled) = %x, want %x", got, wantOut)
+		}
+	}
+	bs.Free()
+	if err := bs.Refill(&NewBinaryFields{Version: 1}); !errors.Is(err, ErrFreed) {
+		t.Errorf("Refill() after Free returned error: %v, want error: %v", err, ErrFreed)
+	}
+}
+
+func TestAcquireRelease(t *testing.T) {
+	fields := &NewBinaryFields{
+		Version:     1,
+		Country:     "US",
+		ServiceType: "chromeipblinding",
+		Expiration:  &tpb.Timestamp{Seconds: 3600},
+	}
+	want := New(fields)
+	defer want.Free()
+	for i := 0; i < 3; i++ {
+		bs := Acquire(fields)
+		if !Equal(bs, want) {
+			t.Errorf("Acquire() = %v, want %v", bs, want)
+		}
+		Release(bs)
+	}
+}
@@ -0,0 +1,48 @@
+package binarymetadata
+
+// The getters return "" or nil for optionals that are unset, which makes an unset value and an
+// empty one indistinguishable. The Has* methods below report whether each optional is set.
+
+// hasValue is implemented by the wrapped C++ optionals.
+type hasValue interface {
+	HasValue() bool
+}
+
+func isSet(o hasValue) bool {
+	return o != nil && o.HasValue()
+}
+
+// HasServiceType reports whether the service type is set.
+func (bs *BinaryStruct) HasServiceType() bool {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+	return isSet(bs.md().GetService_type())
+}
+
+// HasCountry reports whether the country is set.
+func (bs *BinaryStruct) HasCountry() bool {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+	return isSet(bs.md().GetCountry())
+}
+
+// HasRegion reports whether the region is set.
+func (bs *BinaryStruct) HasRegion() bool {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+	return isSet(bs.md().GetRegion())
+}
+
+// HasCity reports whether the city is set.
+func (bs *BinaryStruct) HasCity() bool {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+	return isSet(bs.md().GetCity())
+}
+
+// HasExpiration reports whether the expiration is set.
+func (bs *BinaryStruct) HasExpiration() bool {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+	return isSet(bs.md().GetExpiration_epoch_seconds())
+}
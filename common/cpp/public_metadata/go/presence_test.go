@@ -0,0 +1,27 @@
+package binarymetadata
+
+import (
+	"testing"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+)
+
+func TestPresence(t *testing.T) {
+	bs := New(&NewBinaryFields{
+		Version:    1,
+		Country:    "US",
+		Expiration: &tpb.Timestamp{Seconds: 900},
+	})
+	defer bs.Free()
+	// New sets every optional, so empty values are still present.
+	if !bs.HasServiceType() || !bs.HasCountry() || !bs.HasRegion() || !bs.HasCity() || !bs.HasExpiration() {
+		t.Error("New() left an optional unset")
+	}
+	bs.Reset()
+	if bs.HasServiceType() || bs.HasCountry() || bs.HasRegion() || bs.HasCity() || bs.HasExpiration() {
+		t.Error("Reset() left an optional set")
+	}
+	if got := bs.GetServiceType(); got != "" {
+		t.Errorf("GetServiceType() after Reset() = %q, want empty", got)
+	}
+}
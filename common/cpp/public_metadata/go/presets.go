@@ -0,0 +1,165 @@
+package binarymetadata
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	plpb "google3/privacy/net/common/proto/proxy_layer_go_proto"
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+)
+
+// PresetOptions are the per-issuance inputs of a Preset.
+type PresetOptions struct {
+	// Country is an ISO 3166-1 code, normalized with NormalizeCountry.
+	Country string
+	// Region is an ISO 3166-2 code. Presets that issue country level metadata ignore it.
+	Region string
+	// Lifetime is how far after Clock the expiration falls, rounded up to the next boundary. Zero
+	// means an hour.
+	Lifetime time.Duration
+	// Clock is the time the expiration is computed from. Nil uses SystemClock.
+	Clock Clock
+}
+
+// defaultPresetLifetime is the lifetime of preset metadata when PresetOptions.Lifetime is zero.
+const defaultPresetLifetime = time.Hour
+
+// Preset builds the fields of a common kind of metadata from o. The fields it returns pass
+// NewChecked, except that DEBUG_ALL presets need a DebugModeCapability when a DebugModeAllowlist
+// is registered.
+type Preset func(o PresetOptions) (*NewBinaryFields, error)
+
+// Names of the built-in presets.
+const (
+	PresetDefaultPPNProd   = "default_ppn_prod"
+	PresetDebugCountryOnly = "debug_country_only"
+	PresetRegionalExit     = "regional_exit"
+)
+
+var (
+	presetsMu sync.RWMutex
+	// presets holds the built-in presets and those added with RegisterPreset.
+	presets = map[string]Preset{
+		PresetDefaultPPNProd:   DefaultPPNProd,
+		PresetDebugCountryOnly: DebugCountryOnly,
+		PresetRegionalExit:     RegionalExit,
+	}
+)
+
+// DefaultPPNProd is production metadata for the first proxy of chromeipblinding: version 2,
+// country level and debugging off.
+func DefaultPPNProd(o PresetOptions) (*NewBinaryFields, error) {
+	return buildPreset(o, &NewBinaryFields{
+		Version:     2,
+		ServiceType: ServiceTypeChromeIPBlinding,
+		ProxyLayer:  plpb.ProxyLayer_PROXY_A,
+	})
+}
+
+// DebugCountryOnly is DefaultPPNProd with DEBUG_ALL, for test accounts.
+func DebugCountryOnly(o PresetOptions) (*NewBinaryFields, error) {
+	return buildPreset(o, &NewBinaryFields{
+		Version:     2,
+		ServiceType: ServiceTypeChromeIPBlinding,
+		ProxyLayer:  plpb.ProxyLayer_PROXY_A,
+		DebugMode:   pmpb.PublicMetadata_DEBUG_ALL,
+	})
+}
+
+// RegionalExit is production metadata for the exit proxy of chromeipblinding with a region level
+// geo hint. It fails if o.Region is empty.
+func RegionalExit(o PresetOptions) (*NewBinaryFields, error) {
+	if o.Region == "" {
+		return nil, &FieldError{Field: "region", Err: ErrMissingField}
+	}
+	return buildPreset(o, &NewBinaryFields{
+		Version:     2,
+		ServiceType: ServiceTypeChromeIPBlinding,
+		ProxyLayer:  plpb.ProxyLayer_PROXY_B,
+		Region:      o.Region,
+	})
+}
+
+// buildPreset fills in the geo hint and expiration of f from o and checks the result.
+func buildPreset(o PresetOptions, f *NewBinaryFields) (*NewBinaryFields, error) {
+	country, err := NormalizeCountry(o.Country)
+	if err != nil {
+		return nil, &FieldError{Field: "country", Value: o.Country, Err: err}
+	}
+	f.Country = country
+	if o.Clock == nil {
+		o.Clock = SystemClock
+	}
+	if o.Lifetime == 0 {
+		o.Lifetime = defaultPresetLifetime
+	}
+	exp, err := NextExpiration(o.Clock, o.Lifetime, f.Version)
+	if err != nil {
+		return nil, err
+	}
+	if f.Expiration, err = ExpirationTimestamp(exp); err != nil {
+		return nil, err
+	}
+	// Debug authorization is up to the caller, so it is the only rule not checked here.
+	check := *f
+	check.DebugMode = pmpb.PublicMetadata_UNSPECIFIED_DEBUG_MODE
+	bs, err := NewChecked(&check)
+	if err != nil {
+		return nil, err
+	}
+	bs.Free()
+	return f, nil
+}
+
+// RegisterPreset adds p under name, so that teams can share presets through BuildPreset. Names
+// must be non-empty lower case letters, digits and underscores. Registering a name twice is an
+// error. It is meant to be called from init functions.
+func RegisterPreset(name string, p Preset) error {
+	if name == "" || !isPresetName(name) {
+		return fmt.Errorf("preset name %q must be non-empty lower case letters, digits and underscores", name)
+	}
+	if p == nil {
+		return fmt.Errorf("nil preset %q", name)
+	}
+	presetsMu.Lock()
+	defer presetsMu.Unlock()
+	if _, ok := presets[name]; ok {
+		return fmt.Errorf("preset %q is already registered", name)
+	}
+	presets[name] = p
+	return nil
+}
+
+// BuildPreset builds the fields of the built-in or registered preset name from o.
+func BuildPreset(name string, o PresetOptions) (*NewBinaryFields, error) {
+	presetsMu.RLock()
+	p, ok := presets[name]
+	presetsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown preset %q", name)
+	}
+	return p(o)
+}
+
+// PresetNames returns the built-in and registered preset names in ascending order.
+func PresetNames() []string {
+	presetsMu.RLock()
+	defer presetsMu.RUnlock()
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func isPresetName(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '_' {
+			return false
+		}
+	}
+	return true
+}
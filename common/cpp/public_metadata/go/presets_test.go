@@ -0,0 +1,93 @@
+package binarymetadata
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+)
+
+func TestPresets(t *testing.T) {
+	now := time.Unix(1800000000, 0).Truncate(15 * time.Minute)
+	o := PresetOptions{Country: "usa", Region: "US-CA", Clock: &fixedClock{now}}
+	v, err := NewValidator(ValidationConfig{})
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+	for _, name := range []string{PresetDefaultPPNProd, PresetDebugCountryOnly, PresetRegionalExit} {
+		t.Run(name, func(t *testing.T) {
+			f, err := BuildPreset(name, o)
+			if err != nil {
+				t.Fatalf("BuildPreset failed: %v", err)
+			}
+			if f.Country != "US" {
+				t.Errorf("Country = %q, want US", f.Country)
+			}
+			if got, want := f.Expiration.AsTime(), now.Add(defaultPresetLifetime); !got.Equal(want) {
+				t.Errorf("Expiration = %v, want %v", got, want)
+			}
+			if got, want := f.Region != "", name == PresetRegionalExit; got != want {
+				t.Errorf("Region = %q, want set %v", f.Region, want)
+			}
+			if got, want := f.DebugMode == pmpb.PublicMetadata_DEBUG_ALL, name == PresetDebugCountryOnly; got != want {
+				t.Errorf("DebugMode = %v, want DEBUG_ALL %v", f.DebugMode, want)
+			}
+			bs := New(f)
+			defer bs.Free()
+			if err := v.ValidateStruct(bs, now); err != nil {
+				t.Errorf("ValidateStruct failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestPresetErrors(t *testing.T) {
+	if _, err := DefaultPPNProd(PresetOptions{Country: "XX"}); !errors.Is(err, ErrInvalidCountry) {
+		t.Errorf("DefaultPPNProd(XX) = %v, want ErrInvalidCountry", err)
+	}
+	if _, err := RegionalExit(PresetOptions{Country: "US"}); !errors.Is(err, ErrMissingField) {
+		t.Errorf("RegionalExit without a region = %v, want ErrMissingField", err)
+	}
+	if _, err := BuildPreset("no_such_preset", PresetOptions{Country: "US"}); err == nil {
+		t.Error("BuildPreset(no_such_preset) succeeded, want error")
+	}
+}
+
+func TestRegisterPreset(t *testing.T) {
+	t.Cleanup(func() {
+		presetsMu.Lock()
+		delete(presets, "test_preset")
+		presetsMu.Unlock()
+	})
+	preset := func(o PresetOptions) (*NewBinaryFields, error) {
+		f, err := DefaultPPNProd(o)
+		if err != nil {
+			return nil, err
+		}
+		f.Version = 1
+		return f, nil
+	}
+	if err := RegisterPreset("test_preset", preset); err != nil {
+		t.Fatalf("RegisterPreset failed: %v", err)
+	}
+	if !slices.Contains(PresetNames(), "test_preset") {
+		t.Errorf("PresetNames() = %v, want it to contain test_preset", PresetNames())
+	}
+	f, err := BuildPreset("test_preset", PresetOptions{Country: "US"})
+	if err != nil {
+		t.Fatalf("BuildPreset failed: %v", err)
+	}
+	if f.Version != 1 {
+		t.Errorf("Version = %d, want 1", f.Version)
+	}
+	for _, name := range []string{"test_preset", PresetDefaultPPNProd, "", "Upper", "with-dash"} {
+		if err := RegisterPreset(name, preset); err == nil {
+			t.Errorf("RegisterPreset(%q) succeeded, want error", name)
+		}
+	}
+	if err := RegisterPreset("nil_preset", nil); err == nil {
+		t.Error("RegisterPreset with a nil preset succeeded, want error")
+	}
+}
@@ -0,0 +1,66 @@
+package binarymetadata
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"google3/third_party/golang/protobuf/v2/encoding/protojson/protojson"
+
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+)
+
+// protoJSONMarshalOptions are the canonical protojson options of MarshalProtoJSON: proto field
+// names, enums by name and unpopulated fields omitted. Timestamps are always RFC 3339 in UTC.
+var protoJSONMarshalOptions = protojson.MarshalOptions{UseProtoNames: true}
+
+// ToProto converts bs into a PublicMetadata proto. The exit location is mapped by
+// GetExitLocation. The version and proxy layer have no proto field and are dropped.
+func (bs *BinaryStruct) ToProto() (*pmpb.PublicMetadata, error) {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+	if err := bs.checkFreed(); err != nil {
+		return nil, err
+	}
+	return &pmpb.PublicMetadata{
+		ExitLocation: bs.exitLocation(),
+		ServiceType:  bs.serviceType(),
+		Expiration:   bs.expiration(),
+		DebugMode:    bs.debugMode(),
+	}, nil
+}
+
+// MarshalProtoJSON returns the protojson encoding of bs.ToProto() in the canonical form of
+// MarshalPublicMetadataJSON.
+func MarshalProtoJSON(bs *BinaryStruct) ([]byte, error) {
+	md, err := bs.ToProto()
+	if err != nil {
+		return nil, err
+	}
+	return MarshalPublicMetadataJSON(md)
+}
+
+// MarshalPublicMetadataJSON encodes md with protojson in a canonical form: proto field names,
+// enums by name, unpopulated fields omitted and no insignificant whitespace. protojson output
+// is otherwise deliberately unstable, so services that compare or hash JSON must use this.
+func MarshalPublicMetadataJSON(md *pmpb.PublicMetadata) ([]byte, error) {
+	b, err := protoJSONMarshalOptions.Marshal(md)
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := json.Compact(&out, b); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// UnmarshalProtoJSON decodes protojson, as produced by MarshalProtoJSON or any other protojson
+// encoder, into the fields for version through NewBinaryFieldsFromProto. Unknown fields are
+// rejected.
+func UnmarshalProtoJSON(b []byte, version int32) (*NewBinaryFields, error) {
+	md := &pmpb.PublicMetadata{}
+	if err := (protojson.UnmarshalOptions{}).Unmarshal(b, md); err != nil {
+		return nil, err
+	}
+	return NewBinaryFieldsFromProto(md, version)
+}
@@ -0,0 +1,73 @@
+package binarymetadata
+
+import (
+	"errors"
+	"testing"
+
+	"google3/third_party/golang/cmp/cmp"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+)
+
+func TestMarshalProtoJSON(t *testing.T) {
+	fields := &NewBinaryFields{
+		Version:     1,
+		Country:     "US",
+		Region:      "US-CA",
+		City:        "SUNNYVALE",
+		ServiceType: ServiceTypeChromeIPBlinding,
+		Expiration:  &tpb.Timestamp{Seconds: 1701110700},
+		DebugMode:   pmpb.PublicMetadata_DEBUG_ALL,
+	}
+	bs := New(fields)
+	defer bs.Free()
+	got, err := MarshalProtoJSON(bs)
+	if err != nil {
+		t.Fatalf("MarshalProtoJSON failed: %v", err)
+	}
+	want := `{"exit_location":{"country":"US","city_geo_id":"US-CA,SUNNYVALE"},"service_type":"chromeipblinding","expiration":"2023-11-27T18:45:00Z","debug_mode":"DEBUG_ALL"}`
+	if string(got) != want {
+		t.Errorf("MarshalProtoJSON = %s, want %s", got, want)
+	}
+
+	decoded, err := UnmarshalProtoJSON(got, 1)
+	if err != nil {
+		t.Fatalf("UnmarshalProtoJSON failed: %v", err)
+	}
+	if diff := cmp.Diff(fields.toJSON(), decoded.toJSON()); diff != "" {
+		t.Errorf("UnmarshalProtoJSON returned diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestMarshalProtoJSONOmitsUnset(t *testing.T) {
+	bs := New(&NewBinaryFields{Version: 1, Country: "US", ServiceType: ServiceTypeChromeIPBlinding, Expiration: &tpb.Timestamp{Seconds: 900}})
+	defer bs.Free()
+	got, err := MarshalProtoJSON(bs)
+	if err != nil {
+		t.Fatalf("MarshalProtoJSON failed: %v", err)
+	}
+	want := `{"exit_location":{"country":"US"},"service_type":"chromeipblinding","expiration":"1970-01-01T00:15:00Z"}`
+	if string(got) != want {
+		t.Errorf("MarshalProtoJSON = %s, want %s", got, want)
+	}
+}
+
+func TestUnmarshalProtoJSONErrors(t *testing.T) {
+	for _, in := range []string{`{"colour":"red"}`, `{"debug_mode":"DEBUG_SOME"}`, `not json`} {
+		if _, err := UnmarshalProtoJSON([]byte(in), 1); err == nil {
+			t.Errorf("UnmarshalProtoJSON(%s) succeeded, want error", in)
+		}
+	}
+	if _, err := UnmarshalProtoJSON([]byte(`{"exit_location":{"country":"XX"}}`), 1); !errors.Is(err, ErrInvalidCountry) {
+		t.Errorf("UnmarshalProtoJSON with country XX = %v, want ErrInvalidCountry", err)
+	}
+}
+
+func TestToProtoFreed(t *testing.T) {
+	bs := New(&NewBinaryFields{Version: 1, Country: "US"})
+	bs.Free()
+	if _, err := bs.ToProto(); !errors.Is(err, ErrFreed) {
+		t.Errorf("ToProto after Free = %v, want ErrFreed", err)
+	}
+}
@@ -0,0 +1,81 @@
+package binarymetadata
+
+import (
+	"fmt"
+
+	wrap "google3/privacy/net/common/cpp/public_metadata/go/wrap_public_metadata"
+	plpb "google3/privacy/net/common/proto/proxy_layer_go_proto"
+)
+
+// Proxy layer wire values, as carried by the ProxyLayer extension. Values without an entry in
+// proxyLayerToWire are invalid.
+const (
+	ProxyLayerWireA uint8 = 0x00
+	ProxyLayerWireB uint8 = 0x01
+)
+
+// proxyLayerToWire maps ProxyLayer enum values onto the values carried by the C++ struct and the
+// ProxyLayer extension. A new layer, e.g. PROXY_C, only needs an entry here once the enum and the
+// C++ encoder support it. PROXY_LAYER_UNSPECIFIED has no wire value.
+var proxyLayerToWire = map[plpb.ProxyLayer]uint{
+	plpb.ProxyLayer_PROXY_A: uint(ProxyLayerWireA),
+	plpb.ProxyLayer_PROXY_B: uint(ProxyLayerWireB),
+}
+
+// proxyLayerFromWire is the inverse of proxyLayerToWire.
+var proxyLayerFromWire = func() map[uint]plpb.ProxyLayer {
+	m := make(map[uint]plpb.ProxyLayer, len(proxyLayerToWire))
+	for l, w := range proxyLayerToWire {
+		m[w] = l
+	}
+	return m
+}()
+
+// ProxyLayerToWire returns the wire value for l, or an error wrapping ErrInvalidProxyLayer if l
+// has none.
+func ProxyLayerToWire(l plpb.ProxyLayer) (uint, error) {
+	w, ok := proxyLayerToWire[l]
+	if !ok {
+		return 0, fmt.Errorf("%w: %v has no wire value", ErrInvalidProxyLayer, l)
+	}
+	return w, nil
+}
+
+// ProxyLayerFromWire returns the ProxyLayer for wire value w, or an error wrapping
+// ErrInvalidProxyLayer if w is not mapped.
+func ProxyLayerFromWire(w uint) (plpb.ProxyLayer, error) {
+	l, ok := proxyLayerFromWire[w]
+	if !ok {
+		return plpb.ProxyLayer_PROXY_LAYER_UNSPECIFIED, fmt.Errorf("%w: unmapped wire value %d", ErrInvalidProxyLayer, w)
+	}
+	return l, nil
+}
+
+// LookupProxyLayer is like GetProxyLayer, but returns an error wrapping ErrInvalidProxyLayer
+// instead of PROXY_LAYER_UNSPECIFIED when the wrapped struct holds an unmapped value.
+func (bs *BinaryStruct) LookupProxyLayer() (plpb.ProxyLayer, error) {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+	if err := bs.checkFreed(); err != nil {
+		return plpb.ProxyLayer_PROXY_LAYER_UNSPECIFIED, err
+	}
+	return bs.lookupProxyLayer()
+}
+
+func (bs *BinaryStruct) lookupProxyLayer() (plpb.ProxyLayer, error) {
+	w := bs.md().GetProxy_layer()
+	if w == nil || !w.HasValue() {
+		return plpb.ProxyLayer_PROXY_LAYER_UNSPECIFIED, nil
+	}
+	return ProxyLayerFromWire(w.Value())
+}
+
+// proxyLayerOptional returns the optional stored in the C++ struct for l. PROXY_LAYER_UNSPECIFIED
+// and layers without a wire value are unset.
+func proxyLayerOptional(l plpb.ProxyLayer) wrap.Uint32Optional {
+	w, err := ProxyLayerToWire(l)
+	if err != nil {
+		return wrap.NewUint32Optional()
+	}
+	return wrap.NewUint32Optional(w)
+}
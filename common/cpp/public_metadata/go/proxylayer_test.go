@@ -0,0 +1,89 @@
+package binarymetadata
+
+import (
+	"errors"
+	"testing"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	wrap "google3/privacy/net/common/cpp/public_metadata/go/wrap_public_metadata"
+	plpb "google3/privacy/net/common/proto/proxy_layer_go_proto"
+)
+
+func TestProxyLayerWireMapping(t *testing.T) {
+	for l := range plpb.ProxyLayer_name {
+		layer := plpb.ProxyLayer(l)
+		w, err := ProxyLayerToWire(layer)
+		if layer == plpb.ProxyLayer_PROXY_LAYER_UNSPECIFIED {
+			if !errors.Is(err, ErrInvalidProxyLayer) {
+				t.Errorf("ProxyLayerToWire(%v) returned error: %v, want error: %v", layer, err, ErrInvalidProxyLayer)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ProxyLayerToWire(%v) failed: %v", layer, err)
+			continue
+		}
+		got, err := ProxyLayerFromWire(w)
+		if err != nil || got != layer {
+			t.Errorf("ProxyLayerFromWire(%d) = %v, %v, want %v", w, got, err, layer)
+		}
+	}
+	if _, err := ProxyLayerFromWire(7); !errors.Is(err, ErrInvalidProxyLayer) {
+		t.Errorf("ProxyLayerFromWire(7) returned error: %v, want error: %v", err, ErrInvalidProxyLayer)
+	}
+}
+
+func TestNewCheckedProxyLayer(t *testing.T) {
+	if _, err := NewChecked(&NewBinaryFields{Version: 2, Expiration: &tpb.Timestamp{Seconds: 900}, ProxyLayer: plpb.ProxyLayer(42)}); !errors.Is(err, ErrInvalidProxyLayer) {
+		t.Errorf("NewChecked() returned error: %v, want error: %v", err, ErrInvalidProxyLayer)
+	}
+	bs := New(&NewBinaryFields{Version: 2, ProxyLayer: plpb.ProxyLayer_PROXY_B})
+	defer bs.Free()
+	bs.metadata.SetProxy_layer(wrap.NewUint32Optional(uint(9)))
+	if _, err := bs.LookupProxyLayer(); !errors.Is(err, ErrInvalidProxyLayer) {
+		t.Errorf("LookupProxyLayer() returned error: %v, want error: %v", err, ErrInvalidProxyLayer)
+	}
+	if got := bs.GetProxyLayer(); got != plpb.ProxyLayer_PROXY_LAYER_UNSPECIFIED {
+		t.Errorf("GetProxyLayer() = %v, want %v", got, plpb.ProxyLayer_PROXY_LAYER_UNSPECIFIED)
+	}
+}
+
+func TestProxyLayerRoundTrip(t *testing.T) {
+	for _, l := range []plpb.ProxyLayer{plpb.ProxyLayer_PROXY_LAYER_UNSPECIFIED, plpb.ProxyLayer_PROXY_A, plpb.ProxyLayer_PROXY_B} {
+		t.Run(l.String(), func(t *testing.T) {
+			bs := New(&NewBinaryFields{
+				Version:     2,
+				Country:     "US",
+				ServiceType: "chromeipblinding",
+				Expiration:  &tpb.Timestamp{Seconds: 900},
+				ProxyLayer:  l,
+			})
+			defer bs.Free()
+			if got := bs.GetProxyLayer(); got != l {
+				t.Errorf("GetProxyLayer() before Serialize = %v, want %v", got, l)
+			}
+			out, err := Serialize(bs)
+			if err != nil {
+				t.Fatalf("Serialize failed: %v", err)
+			}
+			got, err := Deserialize(out)
+			if err != nil {
+				t.Fatalf("Deserialize failed: %v", err)
+			}
+			defer got.Free()
+			if got.GetVersion() != 2 {
+				t.Errorf("GetVersion() after round trip = %d, want 2", got.GetVersion())
+			}
+			if got.GetProxyLayer() != l {
+				t.Errorf("GetProxyLayer() after round trip = %v, want %v", got.GetProxyLayer(), l)
+			}
+			fields, err := DeserializeGo(out)
+			if err != nil {
+				t.Fatalf("DeserializeGo failed: %v", err)
+			}
+			if fields.Version != 2 || fields.ProxyLayer != l {
+				t.Errorf("DeserializeGo() = version %d, proxy layer %v, want 2, %v", fields.Version, fields.ProxyLayer, l)
+			}
+		})
+	}
+}
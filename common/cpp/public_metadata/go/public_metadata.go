@@ -3,6 +3,10 @@ package binarymetadata
 
 import (
 	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
 	"time"
 
 	"google3/privacy/net/boq/common/tokens/tokentypes"
@@ -16,14 +20,57 @@ import (
 	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
 )
 
+// maxVersion is the newest metadata version understood by the C++ library.
+const maxVersion = 3
+
 // BinaryStruct is a wrapper type for a C++ BinaryPublicMetadata struct.
+//
+// A BinaryStruct is safe for concurrent use by multiple goroutines: getters and Serialize share a
+// read lock, while Free, Reset and other mutations take the write lock, so readers never observe
+// the wrapped C++ struct mid-update or after it has been deleted underneath them. A BinaryStruct
+// must not be copied after first use.
 type BinaryStruct struct {
+	mu       sync.RWMutex
 	metadata wrap.BinaryPublicMetadata
+	// managed is set when a finalizer has been registered to free metadata.
+	managed bool
+	// pooled is set while the struct is owned by the Acquire/Release pool.
+	pooled bool
+	// extra holds extensions of types not modeled by the C++ struct, sorted by type so that they
+	// can be merged into the known ones on serialization.
+	extra []Extension
+	// newer is set when the metadata was deserialized from a layout of a version newer than
+	// MaxKnownVersion.
+	newer bool
+	// frozen is set on the read-only copies returned by Freeze.
+	frozen bool
+	// auditTag is the AuditTag the metadata was created with.
+	auditTag string
+	// freedAt is the stack of the call to Free, recorded in builds with the binarymetadata_debug
+	// tag.
+	freedAt []byte
+	// alloc tracks metadata for leak detection, if it is on.
+	alloc *allocRecord
+	// arena is set when an Arena owns metadata.
+	arena *Arena
+}
+
+// GetVersion gets the metadata version
+func (bs *BinaryStruct) GetVersion() int32 {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+	return int32(bs.md().GetVersion())
 }
 
 // GetExpiration gets expiration timestamp
 func (bs *BinaryStruct) GetExpiration() *tpb.Timestamp {
-	epoch := bs.metadata.GetExpiration_epoch_seconds()
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+	return bs.expiration()
+}
+
+func (bs *BinaryStruct) expiration() *tpb.Timestamp {
+	epoch := bs.md().GetExpiration_epoch_seconds()
 	if epoch == nil || !epoch.HasValue() {
 		return nil
 	}
@@ -33,21 +80,55 @@ func (bs *BinaryStruct) GetExpiration() *tpb.Timestamp {
 
 // GetServiceType gets the service type
 func (bs *BinaryStruct) GetServiceType() string {
-	service := bs.metadata.GetService_type()
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+	return bs.serviceType()
+}
+
+func (bs *BinaryStruct) serviceType() string {
+	service := bs.md().GetService_type()
 	if service == nil || !service.HasValue() {
 		return ""
 	}
 	return service.Value()
 }
 
-// GetExitLocation converts the country, region, city into a Location struct
+// GetExitLocation converts the country, region, city into a Location struct. Returns nil if no
+// country is set. The region and city, when present, are joined with a comma into city_geo_id,
+// mirroring how PublicMetadataProtoToStruct maps city_geo_id onto the region.
 func (bs *BinaryStruct) GetExitLocation() *pmpb.PublicMetadata_Location {
-	return nil
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+	return bs.exitLocation()
+}
+
+func (bs *BinaryStruct) exitLocation() *pmpb.PublicMetadata_Location {
+	geo := bs.geoHint()
+	if geo.Country == "" {
+		return nil
+	}
+	var parts []string
+	if geo.Region != "" {
+		parts = append(parts, geo.Region)
+		if geo.City != "" {
+			parts = append(parts, geo.City)
+		}
+	}
+	return &pmpb.PublicMetadata_Location{
+		Country:   geo.Country,
+		CityGeoId: strings.Join(parts, ","),
+	}
 }
 
 // GetDebugMode gets the debug mode
 func (bs *BinaryStruct) GetDebugMode() pmpb.PublicMetadata_DebugMode {
-	value := int32(bs.metadata.GetDebug_mode())
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+	return bs.debugMode()
+}
+
+func (bs *BinaryStruct) debugMode() pmpb.PublicMetadata_DebugMode {
+	value := int32(bs.md().GetDebug_mode())
 	if _, ok := pmpb.PublicMetadata_DebugMode_name[value]; !ok {
 		return pmpb.PublicMetadata_UNSPECIFIED_DEBUG_MODE
 	}
@@ -56,34 +137,38 @@ func (bs *BinaryStruct) GetDebugMode() pmpb.PublicMetadata_DebugMode {
 
 // GetProxyLayer gets the proxy layer
 func (bs *BinaryStruct) GetProxyLayer() plpb.ProxyLayer {
-	value := bs.metadata.GetProxy_layer()
-	// TODO: b/306703210 - Shift the proxy values up to match the proto OR update binary struct to be
-	// an optional and then remove this kludge.
-	if bs.metadata.GetVersion() < 2 {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+	return bs.proxyLayer()
+}
+
+func (bs *BinaryStruct) proxyLayer() plpb.ProxyLayer {
+	l, err := bs.lookupProxyLayer()
+	if err != nil {
 		return plpb.ProxyLayer_PROXY_LAYER_UNSPECIFIED
 	}
-	if value == 0 {
-		return plpb.ProxyLayer_PROXY_A
-	}
-	if value == 1 {
-		return plpb.ProxyLayer_PROXY_B
-	}
-	return plpb.ProxyLayer_PROXY_LAYER_UNSPECIFIED
+	return l
 }
 
 // GetGeoHint gets the GeoHint (country, region, city) tuple.
 func (bs *BinaryStruct) GetGeoHint() *tokentypes.GeoHint {
-	country := bs.metadata.GetCountry()
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+	return bs.geoHint()
+}
+
+func (bs *BinaryStruct) geoHint() *tokentypes.GeoHint {
+	country := bs.md().GetCountry()
 	if country == nil || !country.HasValue() {
 		return &tokentypes.GeoHint{}
 	}
-	region := bs.metadata.GetRegion()
+	region := bs.md().GetRegion()
 	if region == nil || !region.HasValue() {
 		return &tokentypes.GeoHint{
 			Country: country.Value(),
 		}
 	}
-	city := bs.metadata.GetCity()
+	city := bs.md().GetCity()
 	if city == nil || !city.HasValue() {
 		return &tokentypes.GeoHint{
 			Country: country.Value(),
@@ -97,10 +182,31 @@ func (bs *BinaryStruct) GetGeoHint() *tokentypes.GeoHint {
 	}
 }
 
-// String produces a stringified version of the extensions for debugging purposes.
+// String produces a stringified version of the extensions for debugging purposes. Unless the debug
+// mode is DEBUG_ALL, the geo hint is truncated to the country, the expiration is bucketed and the
+// service tier is withheld so that the output is safe to log; see DebugString for the unredacted
+// form.
 func (bs *BinaryStruct) String() string {
-	return fmt.Sprintf("{Version: %d\n ServiceType: %s\n Expiration: %s\n DebugMode: %s\n ProxyLayer: %s\n GeoHint (country): %s\n GeoHint (region): %s\n GeoHint (city): %s}",
-		bs.metadata.GetVersion(), bs.GetServiceType(), bs.GetExpiration().String(), bs.GetDebugMode().String(), bs.GetProxyLayer().String(), bs.GetGeoHint().Country, bs.GetGeoHint().Region, bs.GetGeoHint().City)
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+	if bs.debugMode() == pmpb.PublicMetadata_DEBUG_ALL {
+		return bs.debugString()
+	}
+	return bs.redactedString()
+}
+
+// DebugString produces a stringified version of all extensions, including city level geo and the
+// exact expiration. It must not be used for logging.
+func (bs *BinaryStruct) DebugString() string {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+	return bs.debugString()
+}
+
+func (bs *BinaryStruct) debugString() string {
+	geo := bs.geoHint()
+	return fmt.Sprintf("{Version: %d\n ServiceType: %s\n Expiration: %s\n DebugMode: %s\n ProxyLayer: %s\n GeoHint (country): %s\n GeoHint (region): %s\n GeoHint (city): %s%s}",
+		bs.md().GetVersion(), bs.serviceType(), bs.expiration().String(), bs.debugMode().String(), bs.proxyLayer().String(), geo.Country, geo.Region, geo.City, bs.serviceTierString(false))
 }
 
 // NewBinaryFields contains all the data for creating a binary representation for public metadata.
@@ -113,30 +219,121 @@ type NewBinaryFields struct {
 	Region      string
 	City        string
 	ProxyLayer  plpb.ProxyLayer
+	// ExpirationTime is used when Expiration is nil. NewChecked rejects times before the unix
+	// epoch, times with sub-second precision, setting both fields and setting neither.
+	ExpirationTime time.Time
+	// CanonicalGeoHint makes New pass Country, Region and City through CanonicalizeGeoHint.
+	CanonicalGeoHint bool
+	// DebugModeCapability lets NewChecked create DEBUG_ALL metadata when a DebugModeAllowlist is
+	// registered with SetDebugModeAuthorizer. It is not serialized.
+	DebugModeCapability *DebugModeCapability
+	// AuditTag identifies the caller in the events recorded for the metadata by the registered
+	// AuditSink. It is not serialized.
+	AuditTag string
 }
 
-// New returns a new BinaryStruct.
+// New returns a new BinaryStruct. Proxy layers without a wire value are ignored; use NewChecked
+// to reject them instead.
 func New(fields *NewBinaryFields) *BinaryStruct {
-	metadata := wrap.NewBinaryPublicMetadata()
+	bs, _ := (*Arena)(nil).newStruct()
+	setFields(bs.metadata, fields)
+	bs.auditTag = fields.AuditTag
+	bs.audit(AuditConstruct, "new", nil)
+	return bs
+}
+
+// setFields copies fields into an allocated C++ struct.
+func setFields(metadata wrap.BinaryPublicMetadata, fields *NewBinaryFields) {
+	geo := tokentypes.GeoHint{Country: fields.Country, Region: fields.Region, City: fields.City}
+	if fields.CanonicalGeoHint {
+		geo = CanonicalizeGeoHint(geo)
+	}
 	metadata.SetVersion(uint(fields.Version))
-	metadata.SetCountry(wrap.NewStringOptional(fields.Country))
-	metadata.SetRegion(wrap.NewStringOptional(fields.Region))
-	metadata.SetCity(wrap.NewStringOptional(fields.City))
+	metadata.SetCountry(wrap.NewStringOptional(geo.Country))
+	metadata.SetRegion(wrap.NewStringOptional(geo.Region))
+	metadata.SetCity(wrap.NewStringOptional(geo.City))
 	metadata.SetService_type(wrap.NewStringOptional(fields.ServiceType))
-	metadata.SetExpiration_epoch_seconds(wrap.NewUint64Optional(uint64(fields.Expiration.GetSeconds())))
+	metadata.SetExpiration_epoch_seconds(wrap.NewUint64Optional(uint64(fields.expirationSeconds())))
 	metadata.SetDebug_mode(uint(fields.DebugMode.Number()))
-	if fields.ProxyLayer == plpb.ProxyLayer_PROXY_A {
-		metadata.SetProxy_layer(0)
-	} else if fields.ProxyLayer == plpb.ProxyLayer_PROXY_B {
-		metadata.SetProxy_layer(1)
+	if c, err := Capabilities(fields.Version); err == nil && c.ProxyLayer {
+		metadata.SetProxy_layer(proxyLayerOptional(fields.ProxyLayer))
+	}
+}
+
+// NewChecked is like New, but returns an error instead of silently dropping values that have no
+// representation in the wrapped C++ struct, such as an unmapped proxy layer, or missing values
+// that New writes as zero, such as an unset expiration. It also rejects string fields that are not
+// valid UTF-8, contain control characters or exceed the StringLimits.
+func NewChecked(fields *NewBinaryFields) (*BinaryStruct, error) {
+	if err := fields.checkExpirationTime(); err != nil {
+		return nil, err
+	}
+	if err := checkTextFields(fields.Country, fields.Region, fields.City, fields.ServiceType); err != nil {
+		return nil, err
 	}
-	return &BinaryStruct{metadata}
+	if err := authorizeDebugMode(fields); err != nil {
+		return nil, err
+	}
+	if fields.ProxyLayer != plpb.ProxyLayer_PROXY_LAYER_UNSPECIFIED {
+		if _, err := ProxyLayerToWire(fields.ProxyLayer); err != nil {
+			return nil, err
+		}
+	}
+	return New(fields), nil
 }
 
-// Free frees the memory of the wrapped C++ BinaryPublicMetadata struct.
+// Free frees the memory of the wrapped C++ BinaryPublicMetadata struct. Calling it again has no
+// effect. Afterwards getters return zero values, setters without an error result do nothing and
+// methods that return an error fail with ErrFreed. Builds with the binarymetadata_debug tag include the
+// stack of the Free call in those errors.
 func (bs *BinaryStruct) Free() {
-	wrap.DeleteBinaryPublicMetadata(bs.metadata)
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	bs.free()
+}
+
+func (bs *BinaryStruct) free() {
+	if bs.metadata == nil {
+		return
+	}
+	if recordFreeStacks {
+		bs.freedAt = debug.Stack()
+	}
+	if bs.managed {
+		bs.unmanage()
+		managedFreed.Add(1)
+	}
+	if bs.pooled {
+		bs.pooled = false
+		runtime.SetFinalizer(bs, nil)
+	}
+	bs.releaseMetadata(bs.metadata)
+	bs.releaseAllocation()
+	bs.arena = nil
 	bs.metadata = nil
+	bs.extra = nil
+	bs.newer = false
+}
+
+// wrappedStatus is the status of a call through the SWIG bridge, which passes the code and message
+// directly and only fills in the serialized StatusProto for statuses carrying payloads.
+type wrappedStatus interface {
+	GetStatus() []byte
+	GetStatus_code() int
+	GetStatus_message() string
+	GetStatus_has_payload() bool
+}
+
+func wrappedStatusToErr(st wrappedStatus) error {
+	code := st.GetStatus_code()
+	if code == 0 {
+		return nil
+	}
+	if st.GetStatus_has_payload() {
+		return unmarshalStatusToErr(st.GetStatus())
+	}
+	sp := &stpb.StatusProto{Code: proto.Int32(int32(code)), CanonicalCode: proto.Int32(int32(code)), Message: proto.String(st.GetStatus_message())}
+	return classifyStatus(status.FromProto(sp))
 }
 
 func unmarshalStatusToErr(serializedProto []byte) error {
@@ -145,48 +342,138 @@ func unmarshalStatusToErr(serializedProto []byte) error {
 	if err := proto.Unmarshal(serializedProto, &sp); err != nil {
 		return fmt.Errorf("proto.Unmarshal(%v): %w", serializedProto, err)
 	}
-	return status.FromProto(&sp).Err()
+	return classifyStatus(status.FromProto(&sp))
 }
 
 // Serialize the binary public metadata to bytes in a string. When this call returns, the caller
-// should ensure to call bs.Free()
+// should ensure to call bs.Free(). The output is in the canonical encoding; see IsCanonical.
 func Serialize(bs *BinaryStruct) ([]byte, error) {
+	start := time.Now()
+	out, err := appendSerialized(nil, bs)
+	record(OperationSerialize, start, err)
+	return out, err
+}
+
+// AppendSerialized is like Serialize but appends the encoding to dst and returns the extended
+// slice, so that callers can reuse a buffer across calls. On error dst is returned unchanged.
+func AppendSerialized(dst []byte, bs *BinaryStruct) ([]byte, error) {
+	start := time.Now()
+	out, err := appendSerialized(dst, bs)
+	record(OperationSerialize, start, err)
+	if err != nil {
+		return dst, err
+	}
+	return out, nil
+}
+
+func appendSerialized(dst []byte, bs *BinaryStruct) ([]byte, error) {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+	if err := bs.checkFreed(); err != nil {
+		return nil, err
+	}
+	if v := bs.md().GetVersion(); v > maxVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownVersion, v)
+	}
+	if exp := bs.md().GetExpiration_epoch_seconds(); isSet(exp) {
+		if err := checkExpirationSeconds(int64(exp.Value())); err != nil {
+			return nil, err
+		}
+	}
 	st := wrap.SerializeExtensionsWrapped(bs.metadata)
 	defer wrap.DeleteStatusOrExtensionsString(st)
-	if err := unmarshalStatusToErr(st.GetStatus()); err != nil {
+	if err := wrappedStatusToErr(st); err != nil {
 		return nil, err
 	}
-	return []byte(st.GetExtensions_str()), nil
+	return bs.appendExtra(dst, st.GetExtensions_str())
 }
 
-// Deserialize bytes to binary public metadata.
+// Deserialize bytes to binary public metadata. Extensions of types the C++ library does not know
+// are kept as is and re-emitted by Serialize, which writes every extension in type order.
 func Deserialize(in []byte) (*BinaryStruct, error) {
-	inStr := string(in)
-	st := wrap.DeserializeExtensionsWrapped(inStr)
-	defer wrap.DeleteStatusOrExtensions(st)
-	if err := unmarshalStatusToErr(st.GetStatus()); err != nil {
+	return DeserializeOptions{}.Deserialize(in)
+}
+
+// deserialize decodes in into a struct allocated in a, or on the C++ heap if a is nil.
+func deserialize(in []byte, a *Arena) (*BinaryStruct, error) {
+	known, extra := splitUnknownExtensions(in)
+	bs, err := a.newStruct()
+	if err != nil {
+		return nil, err
+	}
+	// The C++ library decodes straight into the struct we keep, so that a blob costs a single
+	// crossing rather than one getter and setter pair per field.
+	st := wrap.DeserializeExtensionsInto(string(known), bs.metadata)
+	defer wrap.DeleteWrappedStatus(st)
+	if err := wrappedStatusToErr(st); err != nil {
+		bs.Free()
 		return nil, err
 	}
-	// st.GetExtensions is allocated and should be deleted within this func, so we make a new copy below.
-	bs := &BinaryStruct{}
-	bs.metadata = wrap.NewBinaryPublicMetadata()
-	bs.metadata.SetVersion(st.GetExtensions().GetVersion())
-	bs.metadata.SetVersion(st.GetExtensions().GetVersion())
-	bs.metadata.SetService_type(st.GetExtensions().GetService_type())
-	bs.metadata.SetCountry(st.GetExtensions().GetCountry())
-	bs.metadata.SetRegion(st.GetExtensions().GetRegion())
-	bs.metadata.SetCity(st.GetExtensions().GetCity())
-	bs.metadata.SetDebug_mode(st.GetExtensions().GetDebug_mode())
-	if st.GetExtensions().GetExpiration_epoch_seconds().HasValue() {
-		bs.metadata.SetExpiration_epoch_seconds(wrap.NewUint64Optional(uint64(st.GetExtensions().GetExpiration_epoch_seconds().Value())))
-	}
-	bs.metadata.SetProxy_layer(st.GetExtensions().GetProxy_layer())
+	if exp := bs.metadata.GetExpiration_epoch_seconds(); exp.HasValue() {
+		if err := checkExpirationSeconds(int64(exp.Value())); err != nil {
+			bs.Free()
+			return nil, err
+		}
+	}
+	bs.extra = extra
 	return bs, nil
 }
 
 // ValidateMetadataCardinality checks that the input extensions meet client validation rules around
 // cardinality.
 func ValidateMetadataCardinality(in []byte, t time.Time) error {
-	inStr := string(in)
-	return unmarshalStatusToErr(wrap.ValidateBinaryPublicMetadataCardinality(inStr, t))
+	start := time.Now()
+	err := validateMetadataCardinality(in, t)
+	record(OperationValidateMetadataCardinality, start, err)
+	auditBlob(AuditValidate, "validate_metadata_cardinality", "", in, err)
+	return err
+}
+
+// ValidateMetadataCardinalityWithSkew is like ValidateMetadataCardinality, but tolerates an issuer
+// clock that is up to skew ahead of or behind t: in is accepted if it is valid at some time within
+// skew of t. It validates once, at the time within skew of t closest to accepting the expiration,
+// and the error is the one for that time.
+func ValidateMetadataCardinalityWithSkew(in []byte, t time.Time, skew time.Duration) error {
+	start := time.Now()
+	err := validateMetadataCardinality(in, skewedValidationTime(in, t, skew))
+	record(OperationValidateMetadataCardinality, start, err)
+	auditBlob(AuditValidate, "validate_metadata_cardinality", "", in, err)
+	return err
+}
+
+// validateMetadataCardinality is ValidateMetadataCardinality without the metrics and audit event.
+func validateMetadataCardinality(in []byte, t time.Time) error {
+	st := wrap.ValidateBinaryPublicMetadataCardinalityWrapped(string(in), t)
+	defer wrap.DeleteWrappedStatus(st)
+	return wrappedStatusToErr(st)
+}
+
+// skewedValidationTime returns the time within skew of t at which the expiration of in is closest
+// to the window of ValidateMetadataCardinality, from t to defaultMaxTimeToLive after it. It returns
+// t if the expiration is within the window at t or cannot be decoded.
+func skewedValidationTime(in []byte, t time.Time, skew time.Duration) time.Time {
+	if skew <= 0 {
+		return t
+	}
+	v, err := NewView(in)
+	if err != nil {
+		return t
+	}
+	ts := v.GetExpiration()
+	if ts == nil {
+		return t
+	}
+	switch exp := ts.AsTime(); {
+	case exp.Before(t):
+		if lo := t.Add(-skew); exp.Before(lo) {
+			return lo
+		}
+		return exp
+	case exp.After(t.Add(defaultMaxTimeToLive)):
+		if hi := t.Add(skew); exp.After(hi.Add(defaultMaxTimeToLive)) {
+			return hi
+		}
+		return exp.Add(-defaultMaxTimeToLive)
+	}
+	return t
 }
@@ -1,10 +1,13 @@
 package binarymetadata
 
 import (
+	"encoding/json"
 	"errors"
+	"sync"
 	"testing"
 	"time"
 
+	"google3/third_party/golang/protobuf/v2/proto/proto"
 	"google3/util/task/go/status"
 
 	tpb "google3/google/protobuf/timestamp_go_proto"
@@ -137,3 +140,85 @@ func TestRoundTripV2(t *testing.T) {
 		t.Errorf("proxy_layer: got %v; want %v", deserialized.GetProxyLayer(), bs.GetProxyLayer())
 	}
 }
+
+func TestGetExitLocation(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields *NewBinaryFields
+		want   *pmpb.PublicMetadata_Location
+	}{
+		{
+			name:   "no country",
+			fields: &NewBinaryFields{},
+			want:   nil,
+		},
+		{
+			name:   "country only",
+			fields: &NewBinaryFields{Country: "US"},
+			want:   &pmpb.PublicMetadata_Location{Country: "US"},
+		},
+		{
+			name:   "country and region",
+			fields: &NewBinaryFields{Country: "US", Region: "US-CA"},
+			want:   &pmpb.PublicMetadata_Location{Country: "US", CityGeoId: "US-CA"},
+		},
+		{
+			name:   "country region and city",
+			fields: &NewBinaryFields{Country: "US", Region: "US-CA", City: "SUNNYVALE"},
+			want:   &pmpb.PublicMetadata_Location{Country: "US", CityGeoId: "US-CA,SUNNYVALE"},
+		},
+		{
+			name:   "city without region",
+			fields: &NewBinaryFields{Country: "US", City: "SUNNYVALE"},
+			want:   &pmpb.PublicMetadata_Location{Country: "US"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bs := New(tc.fields)
+			defer bs.Free()
+			got := bs.GetExitLocation()
+			if !proto.Equal(got, tc.want) {
+				t.Errorf("GetExitLocation() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestConcurrentReaders(t *testing.T) {
+	bs := New(&NewBinaryFields{
+		Version:     2,
+		Country:     "US",
+		Region:      "US-CA",
+		City:        "SUNNYVALE",
+		ServiceType: "chromeipblinding",
+		Expiration:  &tpb.Timestamp{Seconds: 3600},
+		ProxyLayer:  plpb.ProxyLayer_PROXY_A,
+	})
+	defer bs.Free()
+	b, err := json.Marshal(bs)
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = bs.String()
+			if _, err := Serialize(bs); err != nil {
+				t.Errorf("Serialize failed: %v", err)
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		// Replaces the wrapped struct with an identical one while readers are running.
+		if err := json.Unmarshal(b, bs); err != nil {
+			t.Errorf("json.Unmarshal failed: %v", err)
+		}
+	}()
+	wg.Wait()
+}
@@ -0,0 +1,206 @@
+package binarymetadata
+
+import (
+	"crypto/rsa"
+	"fmt"
+	"math/big"
+	"time"
+
+	"google3/third_party/golang/protobuf/v2/proto/proto"
+
+	gidpb "google3/privacy/net/common/proto/get_initial_data_go_proto"
+	plpb "google3/privacy/net/common/proto/proxy_layer_go_proto"
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+	atpb "google3/third_party/anonymous_tokens/proto/anonymous_tokens_go_proto"
+)
+
+// PublicKeyRequest describes the GetInitialData call with which the Krypton client fetches the key
+// that signs tokens bound to public metadata, together with that metadata.
+type PublicKeyRequest struct {
+	ServiceType string
+	// Version is the metadata version the client validates, sent as validation_version.
+	Version int32
+	// Granularity is the geo hint precision the client asks for. GeoCountry and GeoCity are sent as
+	// COUNTRY and CITY_GEOS; zero sends UNKNOWN, which servers treat as country level.
+	Granularity GeoGranularity
+	// ProxyLayer is sent for service types with several proxy layers.
+	ProxyLayer plpb.ProxyLayer
+	// UseAttestation asks for an attestation nonce.
+	UseAttestation bool
+	// AllowDebugMode accepts metadata in a debug mode, as for clients with debug_mode_allowed.
+	AllowDebugMode bool
+}
+
+// locationGranularities maps GeoGranularity onto the values of the request. Region level hints
+// cannot be asked for.
+var locationGranularities = map[GeoGranularity]gidpb.GetInitialDataRequest_LocationGranularity{
+	0:          gidpb.GetInitialDataRequest_UNKNOWN,
+	GeoCountry: gidpb.GetInitialDataRequest_COUNTRY,
+	GeoCity:    gidpb.GetInitialDataRequest_CITY_GEOS,
+}
+
+// Proto returns the GetInitialDataRequest for r, or an error if r asks for a service type, version
+// or granularity that no metadata can carry.
+func (r PublicKeyRequest) Proto() (*gidpb.GetInitialDataRequest, error) {
+	if err := checkServiceType(r.ServiceType); err != nil {
+		return nil, &FieldError{Field: "service_type", Value: r.ServiceType, Err: err}
+	}
+	if _, err := Capabilities(r.Version); err != nil {
+		return nil, &FieldError{Field: "version", Value: fmt.Sprint(r.Version), Err: err}
+	}
+	g, ok := locationGranularities[r.Granularity]
+	if !ok {
+		return nil, fmt.Errorf("%w: granularity %d cannot be requested", ErrInvalidGeoHint, r.Granularity)
+	}
+	return &gidpb.GetInitialDataRequest{
+		UseAttestation:      r.UseAttestation,
+		ServiceType:         r.ServiceType,
+		LocationGranularity: g,
+		ValidationVersion:   int64(r.Version),
+		ProxyLayer:          r.ProxyLayer,
+	}, nil
+}
+
+// Marshal returns the proto body of the request for r.
+func (r PublicKeyRequest) Marshal() ([]byte, error) {
+	p, err := r.Proto()
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(p)
+}
+
+// PublicKey is the partially blind RSA key of a GetInitialDataResponse.
+type PublicKey struct {
+	UseCase string
+	// Version is the key_version that AuthAndSign requests name the key by.
+	Version int64
+	RSA     *rsa.PublicKey
+	// ValidFrom and ValidUntil bound the validity window of the key. ValidUntil is zero for keys
+	// without an expiration.
+	ValidFrom  time.Time
+	ValidUntil time.Time
+	// Proto is the key as received, with the signature parameters.
+	Proto *atpb.RSABlindSignaturePublicKey
+}
+
+// ValidAt reports whether t is within the validity window of k.
+func (k *PublicKey) ValidAt(t time.Time) bool {
+	return !t.Before(k.ValidFrom) && (k.ValidUntil.IsZero() || t.Before(k.ValidUntil))
+}
+
+// PublicKeyResponse is a parsed GetInitialDataResponse.
+type PublicKeyResponse struct {
+	Key *PublicKey
+	// Metadata is the public metadata that tokens signed with Key are bound to. The caller frees
+	// it.
+	Metadata *BinaryStruct
+	// TokenKeyID is the Privacy Pass token key ID, if the server sent one.
+	TokenKeyID []byte
+	// AttestationNonce is set if the request used attestation.
+	AttestationNonce []byte
+}
+
+// ParsePublicKeyResponse parses the proto body of the response to r. Like the Krypton client, it
+// rejects metadata for another service type, metadata down to the city when r did not ask for it,
+// debug modes unless r allows them, and expirations that are not rounded. The metadata is taken from
+// the Privacy Pass extensions if the server sent them, and from public_metadata_info otherwise.
+func (r PublicKeyRequest) ParsePublicKeyResponse(body []byte) (*PublicKeyResponse, error) {
+	var resp gidpb.GetInitialDataResponse
+	if err := proto.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("%w: GetInitialDataResponse: %v", ErrMalformed, err)
+	}
+	key, err := parsePublicKey(resp.GetAtPublicMetadataPublicKey())
+	if err != nil {
+		return nil, err
+	}
+	bs, err := r.responseMetadata(&resp)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.checkResponseMetadata(bs); err != nil {
+		bs.Free()
+		return nil, err
+	}
+	return &PublicKeyResponse{
+		Key:              key,
+		Metadata:         bs,
+		TokenKeyID:       resp.GetPrivacyPassData().GetTokenKeyId(),
+		AttestationNonce: resp.GetAttestation().GetAttestationNonce(),
+	}, nil
+}
+
+func parsePublicKey(p *atpb.RSABlindSignaturePublicKey) (*PublicKey, error) {
+	if p == nil {
+		return nil, fmt.Errorf("%w: no at_public_metadata_public_key", ErrMissingField)
+	}
+	var rp atpb.RSAPublicKey
+	if err := proto.Unmarshal(p.GetSerializedPublicKey(), &rp); err != nil {
+		return nil, fmt.Errorf("%w: serialized_public_key: %v", ErrMalformed, err)
+	}
+	n := new(big.Int).SetBytes(rp.GetN())
+	e := new(big.Int).SetBytes(rp.GetE())
+	if n.Sign() == 0 || e.Sign() == 0 || e.BitLen() > 31 {
+		return nil, fmt.Errorf("%w: serialized_public_key is not an RSA public key", ErrMalformed)
+	}
+	k := &PublicKey{
+		UseCase:   p.GetUseCase(),
+		Version:   p.GetKeyVersion(),
+		RSA:       &rsa.PublicKey{N: n, E: int(e.Int64())},
+		ValidFrom: p.GetKeyValidityStartTime().AsTime(),
+		Proto:     p,
+	}
+	if p.GetExpirationTime() != nil {
+		k.ValidUntil = p.GetExpirationTime().AsTime()
+		if !k.ValidUntil.After(k.ValidFrom) {
+			return nil, fmt.Errorf("%w: key expires at %v, before it is valid from %v", ErrInvalidExpiration, k.ValidUntil, k.ValidFrom)
+		}
+	}
+	return k, nil
+}
+
+func (r PublicKeyRequest) responseMetadata(resp *gidpb.GetInitialDataResponse) (*BinaryStruct, error) {
+	if exts := resp.GetPrivacyPassData().GetPublicMetadataExtensions(); len(exts) > 0 {
+		return DeserializeOptions{Strict: true}.Deserialize(exts)
+	}
+	info := resp.GetPublicMetadataInfo()
+	if info == nil {
+		return nil, fmt.Errorf("%w: no public metadata", ErrMissingField)
+	}
+	if v := info.GetValidationVersion(); v > r.Version {
+		return nil, fmt.Errorf("%w: metadata conforms to version %d, newer than %d", ErrUnknownVersion, v, r.Version)
+	}
+	f, err := NewBinaryFieldsFromProto(info.GetPublicMetadata(), r.Version)
+	if err != nil {
+		return nil, err
+	}
+	f.ProxyLayer = r.ProxyLayer
+	if f.DebugMode == pmpb.PublicMetadata_DEBUG_ALL && r.AllowDebugMode {
+		f.DebugModeCapability = GrantDebugMode("PublicKeyRequest.AllowDebugMode")
+	}
+	return NewChecked(f)
+}
+
+// checkResponseMetadata applies the checks of VerifyPublicMetadata in the Krypton client.
+func (r PublicKeyRequest) checkResponseMetadata(bs *BinaryStruct) error {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+	if s := bs.serviceType(); s != r.ServiceType {
+		return &FieldError{Field: "service_type", Value: s, Err: fmt.Errorf("%w: requested %q", ErrMetadataMismatch, r.ServiceType)}
+	}
+	if exp, ok := bs.expirationTime(); ok {
+		if d := bucketDelta(exp, expirationGranularity, 0); d != 0 {
+			return &FieldError{Field: "expiration", Value: exp.String(), Err: &BucketError{Bucket: expirationGranularity, Delta: d}}
+		}
+	}
+	if c := bs.geoHint().City; c != "" && r.Granularity != GeoCity {
+		return &FieldError{Field: "city", Value: c, Err: fmt.Errorf("%w: city level geo hint was not requested", ErrMetadataMismatch)}
+	}
+	if m := bs.debugMode(); m != pmpb.PublicMetadata_UNSPECIFIED_DEBUG_MODE && !r.AllowDebugMode {
+		return &FieldError{Field: "debug_mode", Value: m.String(), Err: fmt.Errorf("%w: debug mode is not allowed", ErrInvalidDebugMode)}
+	}
+	if l := bs.proxyLayer(); r.ProxyLayer != plpb.ProxyLayer_PROXY_LAYER_UNSPECIFIED && l != r.ProxyLayer {
+		return &FieldError{Field: "proxy_layer", Value: l.String(), Err: fmt.Errorf("%w: requested %v", ErrMetadataMismatch, r.ProxyLayer)}
+	}
+	return nil
+}
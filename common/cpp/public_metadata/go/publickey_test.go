@@ -0,0 +1,187 @@
+package binarymetadata
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"google3/third_party/golang/protobuf/v2/proto/proto"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	gidpb "google3/privacy/net/common/proto/get_initial_data_go_proto"
+	plpb "google3/privacy/net/common/proto/proxy_layer_go_proto"
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+	atpb "google3/third_party/anonymous_tokens/proto/anonymous_tokens_go_proto"
+)
+
+func TestPublicKeyRequestProto(t *testing.T) {
+	r := PublicKeyRequest{ServiceType: ServiceTypeChromeIPBlinding, Version: 2, Granularity: GeoCity, ProxyLayer: plpb.ProxyLayer_PROXY_B}
+	got, err := r.Proto()
+	if err != nil {
+		t.Fatalf("Proto failed: %v", err)
+	}
+	want := &gidpb.GetInitialDataRequest{
+		ServiceType:         ServiceTypeChromeIPBlinding,
+		LocationGranularity: gidpb.GetInitialDataRequest_CITY_GEOS,
+		ValidationVersion:   2,
+		ProxyLayer:          plpb.ProxyLayer_PROXY_B,
+	}
+	if !proto.Equal(got, want) {
+		t.Errorf("Proto() = %v, want %v", got, want)
+	}
+
+	for _, tc := range []struct {
+		name string
+		r    PublicKeyRequest
+		want error
+	}{
+		{name: "unknown_service_type", r: PublicKeyRequest{ServiceType: "no_such_service", Version: 2}, want: ErrUnsupportedServiceType},
+		{name: "unknown_version", r: PublicKeyRequest{ServiceType: ServiceTypeChromeIPBlinding, Version: 99}, want: ErrUnknownVersion},
+		{name: "region_granularity", r: PublicKeyRequest{ServiceType: ServiceTypeChromeIPBlinding, Version: 2, Granularity: GeoRegion}, want: ErrInvalidGeoHint},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := tc.r.Marshal(); !errors.Is(err, tc.want) {
+				t.Errorf("Marshal() = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+// publicKeyResponseForTest returns a response carrying a fresh key and country level
+// chromeipblinding metadata expiring at exp, with mutate applied.
+func publicKeyResponseForTest(t *testing.T, exp time.Time, mutate func(*gidpb.GetInitialDataResponse)) ([]byte, *rsa.PublicKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	pk, err := proto.Marshal(&atpb.RSAPublicKey{N: key.N.Bytes(), E: big.NewInt(int64(key.E)).Bytes()})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	resp := &gidpb.GetInitialDataResponse{
+		AtPublicMetadataPublicKey: &atpb.RSABlindSignaturePublicKey{
+			UseCase:              "TEST_USE_CASE",
+			KeyVersion:           3,
+			SerializedPublicKey:  pk,
+			KeyValidityStartTime: tpb.New(exp.Add(-24 * time.Hour)),
+			ExpirationTime:       tpb.New(exp.Add(24 * time.Hour)),
+		},
+		PublicMetadataInfo: &pmpb.PublicMetadataInfo{
+			PublicMetadata: &pmpb.PublicMetadata{
+				ExitLocation: &pmpb.PublicMetadata_Location{Country: "US"},
+				ServiceType:  ServiceTypeChromeIPBlinding,
+				Expiration:   tpb.New(exp),
+			},
+			ValidationVersion: 2,
+		},
+	}
+	if mutate != nil {
+		mutate(resp)
+	}
+	body, err := proto.Marshal(resp)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	return body, &key.PublicKey
+}
+
+func TestParsePublicKeyResponse(t *testing.T) {
+	exp := time.Unix(1800000000, 0).Truncate(15 * time.Minute)
+	r := PublicKeyRequest{ServiceType: ServiceTypeChromeIPBlinding, Version: 2, ProxyLayer: plpb.ProxyLayer_PROXY_A}
+	body, key := publicKeyResponseForTest(t, exp, nil)
+	resp, err := r.ParsePublicKeyResponse(body)
+	if err != nil {
+		t.Fatalf("ParsePublicKeyResponse failed: %v", err)
+	}
+	defer resp.Metadata.Free()
+	if !resp.Key.RSA.Equal(key) {
+		t.Error("Key.RSA differs from the key in the response")
+	}
+	if resp.Key.Version != 3 || resp.Key.UseCase != "TEST_USE_CASE" {
+		t.Errorf("Key = version %d, use case %q, want 3, TEST_USE_CASE", resp.Key.Version, resp.Key.UseCase)
+	}
+	for _, tc := range []struct {
+		at   time.Time
+		want bool
+	}{
+		{exp.Add(-25 * time.Hour), false},
+		{exp.Add(-24 * time.Hour), true},
+		{exp, true},
+		{exp.Add(24 * time.Hour), false},
+	} {
+		if got := resp.Key.ValidAt(tc.at); got != tc.want {
+			t.Errorf("ValidAt(%v) = %v, want %v", tc.at, got, tc.want)
+		}
+	}
+	if got := resp.Metadata.GetExpiration().AsTime(); !got.Equal(exp) {
+		t.Errorf("Metadata expiration = %v, want %v", got, exp)
+	}
+	if got := resp.Metadata.GetProxyLayer(); got != plpb.ProxyLayer_PROXY_A {
+		t.Errorf("Metadata proxy layer = %v, want PROXY_A", got)
+	}
+}
+
+func TestParsePublicKeyResponseExtensions(t *testing.T) {
+	exp := time.Unix(1800000000, 0).Truncate(15 * time.Minute)
+	exts := serializeForTest(t, &NewBinaryFields{Version: 2, ServiceType: ServiceTypeChromeIPBlinding, Country: "CA", ProxyLayer: plpb.ProxyLayer_PROXY_A, Expiration: tpb.New(exp)})
+	body, _ := publicKeyResponseForTest(t, exp, func(resp *gidpb.GetInitialDataResponse) {
+		resp.PrivacyPassData = &gidpb.GetInitialDataResponse_PrivacyPassData{TokenKeyId: []byte{1, 2}, PublicMetadataExtensions: exts}
+	})
+	resp, err := PublicKeyRequest{ServiceType: ServiceTypeChromeIPBlinding, Version: 2}.ParsePublicKeyResponse(body)
+	if err != nil {
+		t.Fatalf("ParsePublicKeyResponse failed: %v", err)
+	}
+	defer resp.Metadata.Free()
+	if got := resp.Metadata.GetGeoHint().Country; got != "CA" {
+		t.Errorf("Metadata country = %q, want the CA of the extensions", got)
+	}
+	if len(resp.TokenKeyID) != 2 {
+		t.Errorf("TokenKeyID = %x, want 0102", resp.TokenKeyID)
+	}
+}
+
+func TestParsePublicKeyResponseErrors(t *testing.T) {
+	exp := time.Unix(1800000000, 0).Truncate(15 * time.Minute)
+	r := PublicKeyRequest{ServiceType: ServiceTypeChromeIPBlinding, Version: 2}
+	for _, tc := range []struct {
+		name   string
+		r      PublicKeyRequest
+		mutate func(*gidpb.GetInitialDataResponse)
+		want   error
+	}{
+		{name: "no_key", r: r, mutate: func(resp *gidpb.GetInitialDataResponse) { resp.AtPublicMetadataPublicKey = nil }, want: ErrMissingField},
+		{name: "bad_key", r: r, mutate: func(resp *gidpb.GetInitialDataResponse) {
+			resp.AtPublicMetadataPublicKey.SerializedPublicKey = []byte{0xff}
+		}, want: ErrMalformed},
+		{name: "key_expires_before_valid", r: r, mutate: func(resp *gidpb.GetInitialDataResponse) {
+			resp.AtPublicMetadataPublicKey.ExpirationTime = resp.AtPublicMetadataPublicKey.KeyValidityStartTime
+		}, want: ErrInvalidExpiration},
+		{name: "no_metadata", r: r, mutate: func(resp *gidpb.GetInitialDataResponse) { resp.PublicMetadataInfo = nil }, want: ErrMissingField},
+		{name: "newer_version", r: PublicKeyRequest{ServiceType: ServiceTypeChromeIPBlinding, Version: 1}, want: ErrUnknownVersion},
+		{name: "service_type", r: PublicKeyRequest{ServiceType: ServiceTypeCronet, Version: 2}, want: ErrMetadataMismatch},
+		{name: "city_not_requested", r: r, mutate: func(resp *gidpb.GetInitialDataResponse) {
+			resp.PublicMetadataInfo.PublicMetadata.ExitLocation.CityGeoId = "US-CA,SUNNYVALE"
+		}, want: ErrMetadataMismatch},
+		{name: "debug_mode", r: r, mutate: func(resp *gidpb.GetInitialDataResponse) {
+			resp.PublicMetadataInfo.PublicMetadata.DebugMode = pmpb.PublicMetadata_DEBUG_ALL
+		}, want: ErrInvalidDebugMode},
+		{name: "unrounded_expiration", r: r, mutate: func(resp *gidpb.GetInitialDataResponse) {
+			resp.PublicMetadataInfo.PublicMetadata.Expiration = tpb.New(exp.Add(time.Minute))
+		}, want: ErrExpirationNotRounded},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			body, _ := publicKeyResponseForTest(t, exp, tc.mutate)
+			resp, err := tc.r.ParsePublicKeyResponse(body)
+			if err == nil {
+				resp.Metadata.Free()
+			}
+			if !errors.Is(err, tc.want) {
+				t.Errorf("ParsePublicKeyResponse() = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
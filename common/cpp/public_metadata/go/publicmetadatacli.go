@@ -4,7 +4,9 @@ package main
 import (
 	"context"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"os"
 	"time"
 
@@ -12,6 +14,8 @@ import (
 	"google3/base/go/google"
 	"google3/base/go/log"
 	"google3/privacy/net/common/cpp/public_metadata/go/binarymetadata"
+	"google3/privacy/net/common/cpp/public_metadata/go/conformance"
+	"google3/privacy/net/common/cpp/public_metadata/go/goldenvectors"
 	"google3/third_party/golang/subcommands/subcommands"
 )
 
@@ -36,7 +40,7 @@ func (p *parse) Execute(ctx context.Context, f *flag.FlagSet, args ...any) subco
 	}
 	defer s.Free()
 	println("Deserialized successfully")
-	println(s.String())
+	println(s.DebugString())
 	return subcommands.ExitSuccess
 }
 
@@ -111,9 +115,184 @@ func (p *validate) Synopsis() string {
 	return "Checks extensions using cardinality rules."
 }
 
+type golden struct{}
+
+// Execute implements subcommands.Command interface.
+func (p *golden) Execute(ctx context.Context, f *flag.FlagSet, args ...any) subcommands.ExitStatus {
+	vectors, err := goldenvectors.Generate()
+	if err != nil {
+		fmt.Printf("Generate failed %v\n", err)
+		return subcommands.ExitFailure
+	}
+	if err := goldenvectors.WriteJSON(os.Stdout, vectors); err != nil {
+		fmt.Printf("Write failed %v\n", err)
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
+
+// Name implements subcommands.Command interface.
+func (p *golden) Name() string {
+	return "golden"
+}
+
+// SetFlags implements subcommands.Command interface.
+func (p *golden) SetFlags(flags *flag.FlagSet) {}
+
+// Usage implements subcommands.Command interface.
+func (p *golden) Usage() string {
+	return `golden > vectors.json
+`
+}
+
+// Synopsis implements subcommands.Command interface.
+func (p *golden) Synopsis() string {
+	return "Writes golden test vectors as JSON to stdout."
+}
+
+type conform struct{}
+
+// Execute implements subcommands.Command interface.
+func (p *conform) Execute(ctx context.Context, f *flag.FlagSet, args ...any) subcommands.ExitStatus {
+	if err := conformance.Run(); err != nil {
+		fmt.Printf("Conformance failed:\n%v\n", err)
+		return subcommands.ExitFailure
+	}
+	fmt.Println("OK")
+	return subcommands.ExitSuccess
+}
+
+// Name implements subcommands.Command interface.
+func (p *conform) Name() string {
+	return "conformance"
+}
+
+// SetFlags implements subcommands.Command interface.
+func (p *conform) SetFlags(flags *flag.FlagSet) {}
+
+// Usage implements subcommands.Command interface.
+func (p *conform) Usage() string {
+	return `conformance
+`
+}
+
+// Synopsis implements subcommands.Command interface.
+func (p *conform) Synopsis() string {
+	return "Checks the encoder and decoder against the local regression vectors."
+}
+
+// extensionNames names the extension types known to binarymetadata.
+var extensionNames = map[uint16]string{
+	binarymetadata.ExtensionTypeExpirationTimestamp: "expiration",
+	binarymetadata.ExtensionTypeGeoHint:             "geo hint",
+	binarymetadata.ExtensionTypeServiceType:         "service type",
+	binarymetadata.ExtensionTypeDebugMode:           "debug mode",
+	binarymetadata.ExtensionTypeProxyLayer:          "proxy layer",
+	binarymetadata.ExtensionTypeExpirationMillis:    "expiration milliseconds",
+	binarymetadata.ExtensionTypeNetworkType:         "network type",
+	binarymetadata.ExtensionTypeClientPlatform:      "client platform",
+	binarymetadata.ExtensionTypeServiceTier:         "service tier",
+	binarymetadata.ExtensionTypeAttestationLevel:    "attestation level",
+	binarymetadata.ExtensionTypeKeyEpoch:            "key epoch",
+	binarymetadata.ExtensionTypeNonce:               "nonce",
+	binarymetadata.ExtensionTypeVersion:             "version",
+}
+
+// decodeBlob accepts the framing of binarymetadata.EncodeToString, hex and every base64 flavour,
+// padded or not.
+func decodeBlob(s string) ([]byte, error) {
+	if b, _, err := binarymetadata.DecodeString(s); err == nil {
+		return b, nil
+	}
+	if b, err := hex.DecodeString(s); err == nil {
+		return b, nil
+	}
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if b, err := enc.DecodeString(s); err == nil {
+			return b, nil
+		}
+	}
+	return nil, fmt.Errorf("%q is neither hex nor base64", s)
+}
+
+type inspect struct {
+	time int64
+}
+
+// Execute implements subcommands.Command interface.
+func (p *inspect) Execute(ctx context.Context, f *flag.FlagSet, args ...any) subcommands.ExitStatus {
+	if f.NArg() != 1 {
+		fmt.Printf("Expected one argument, got %v\n", f.NArg())
+		return subcommands.ExitUsageError
+	}
+	return p.run(os.Stdout, f.Arg(0))
+}
+
+// run prints the extensions list, the decoded fields and the validation verdict of arg to w.
+func (p *inspect) run(w io.Writer, arg string) subcommands.ExitStatus {
+	b, err := decodeBlob(arg)
+	if err != nil {
+		fmt.Fprintf(w, "Decode failed %v\n", err)
+		return subcommands.ExitUsageError
+	}
+	status := subcommands.ExitSuccess
+	if exts, err := binarymetadata.DecodeExtensions(b); err != nil {
+		fmt.Fprintf(w, "Extensions: decode failed %v\n", err)
+		status = subcommands.ExitFailure
+	} else {
+		fmt.Fprintf(w, "Extensions (%d):\n", len(exts))
+		for _, e := range exts {
+			name, ok := extensionNames[e.Type]
+			if !ok {
+				name = "unknown"
+			}
+			fmt.Fprintf(w, "  %#04x %-12s len=%-3d %x\n", e.Type, name, len(e.Value), e.Value)
+		}
+	}
+	if s, err := binarymetadata.Deserialize(b); err != nil {
+		fmt.Fprintf(w, "Fields: deserialize failed %v\n", err)
+		status = subcommands.ExitFailure
+	} else {
+		fmt.Fprintf(w, "Fields: %s\n", s.DebugString())
+		s.Free()
+	}
+	t := time.Unix(p.time, 0).UTC()
+	if err := binarymetadata.ValidateMetadataCardinality(b, t); err != nil {
+		fmt.Fprintf(w, "Validation at %s: FAILED %v\n", t.Format(time.RFC3339), err)
+		return subcommands.ExitFailure
+	}
+	fmt.Fprintf(w, "Validation at %s: OK\n", t.Format(time.RFC3339))
+	return status
+}
+
+// Name implements subcommands.Command interface.
+func (p *inspect) Name() string {
+	return "inspect"
+}
+
+// SetFlags implements subcommands.Command interface.
+func (p *inspect) SetFlags(flags *flag.FlagSet) {
+	flags.Int64Var(&p.time, "time", time.Now().Unix(), "Set a time in epoch seconds to validate the extensions against. Defaults to now")
+}
+
+// Usage implements subcommands.Command interface.
+func (p *inspect) Usage() string {
+	return `inspect [--time=<epoch seconds>] <base64 or hex of metadata>
+Example: inspect --time=1701100000 AD8AAQAQAAAAAAAAA4QAAAAAZWTjrAACABgAFlVTLFVTLU5ZLE5FVyBZT1JLIENJVFnwAQABAfACAAEA8AMAAQA=
+`
+}
+
+// Synopsis implements subcommands.Command interface.
+func (p *inspect) Synopsis() string {
+	return "Prints the extensions list, the fields and the validation verdict of metadata."
+}
+
 func init() {
 	subcommands.Register(&parse{}, "")
 	subcommands.Register(&validate{}, "")
+	subcommands.Register(&golden{}, "")
+	subcommands.Register(&conform{}, "")
+	subcommands.Register(&inspect{}, "")
 }
 
 func main() {
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"google3/third_party/golang/subcommands/subcommands"
+)
+
+// exampleBlob is the metadata of the usage examples: version 2 metadata for New York City, expiring
+// on 2023-11-27T18:45:00Z.
+const exampleBlob = "AD8AAQAQAAAAAAAAA4QAAAAAZWTjrAACABgAFlVTLFVTLU5ZLE5FVyBZT1JLIENJVFnwAQABAfACAAEA8AMAAQA="
+
+func TestDecodeBlob(t *testing.T) {
+	want, err := base64.StdEncoding.DecodeString(exampleBlob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, in := range []string{
+		exampleBlob,
+		strings.TrimRight(exampleBlob, "="),
+		base64.RawURLEncoding.EncodeToString(want),
+		hex.EncodeToString(want),
+	} {
+		if got, err := decodeBlob(in); err != nil || !bytes.Equal(got, want) {
+			t.Errorf("decodeBlob(%q) = %x, %v, want %x", in, got, err, want)
+		}
+	}
+	if _, err := decodeBlob("not a blob!"); err == nil {
+		t.Error("decodeBlob(not a blob!) succeeded, want error")
+	}
+}
+
+func TestInspect(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		arg        string
+		time       int64
+		want       subcommands.ExitStatus
+		wantOutput []string
+	}{
+		{
+			name:       "valid",
+			arg:        exampleBlob,
+			time:       1701100000,
+			want:       subcommands.ExitSuccess,
+			wantOutput: []string{"Extensions (5):", "0xf003 proxy layer", "Fields: ", "Validation at 2023-11-27T15:46:40Z: OK"},
+		},
+		{
+			name:       "expired",
+			arg:        exampleBlob,
+			time:       1701200000,
+			want:       subcommands.ExitFailure,
+			wantOutput: []string{"Extensions (5):", "Validation at 2023-11-28T19:33:20Z: FAILED"},
+		},
+		{
+			name:       "malformed",
+			arg:        "0001",
+			want:       subcommands.ExitFailure,
+			wantOutput: []string{"Extensions: decode failed", "Fields: deserialize failed", "FAILED"},
+		},
+		{
+			name:       "undecodable",
+			arg:        "not a blob!",
+			want:       subcommands.ExitUsageError,
+			wantOutput: []string{"Decode failed"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if got := (&inspect{time: tc.time}).run(&out, tc.arg); got != tc.want {
+				t.Errorf("run() = %v, want %v; output:\n%s", got, tc.want, out.String())
+			}
+			for _, want := range tc.wantOutput {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("run() output does not contain %q:\n%s", want, out.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+// Package main exports Serialize, Deserialize and ValidateMetadataCardinality with a C ABI, so
+// that services written in neither Go nor C++ can use this implementation of the format. Build it
+// with -buildmode=c-shared, which also writes the matching header.
+//
+// Metadata fields cross the ABI as the JSON encoding of binarymetadata.NewBinaryFields. Every
+// function returns NULL on success, or an error message otherwise. Messages and output buffers are
+// allocated with malloc and must be released with PublicMetadataFree.
+package main
+
+/*
+#include <stdint.h>
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"time"
+	"unsafe"
+
+	"google3/privacy/net/common/cpp/public_metadata/go/binarymetadata"
+)
+
+// goBytes copies the n bytes at p, refusing more than a serialized extensions list can hold.
+func goBytes(p unsafe.Pointer, n C.size_t) ([]byte, error) {
+	if n > binarymetadata.MaxSerializedSize {
+		return nil, fmt.Errorf("%w: %d bytes", binarymetadata.ErrTooLarge, n)
+	}
+	return C.GoBytes(p, C.int(n)), nil
+}
+
+func cError(err error) *C.char {
+	if err == nil {
+		return nil
+	}
+	return C.CString(err.Error())
+}
+
+// PublicMetadataSerialize serializes the fields encoded as JSON in the fieldsLen bytes at fields,
+// storing the result in *out and its length in *outLen.
+//
+//export PublicMetadataSerialize
+func PublicMetadataSerialize(fields *C.char, fieldsLen C.size_t, out **C.uint8_t, outLen *C.size_t) *C.char {
+	in, err := goBytes(unsafe.Pointer(fields), fieldsLen)
+	if err != nil {
+		return cError(err)
+	}
+	var f binarymetadata.NewBinaryFields
+	if err := f.UnmarshalJSON(in); err != nil {
+		return cError(err)
+	}
+	bs, err := binarymetadata.NewChecked(&f)
+	if err != nil {
+		return cError(err)
+	}
+	defer bs.Free()
+	b, err := binarymetadata.Serialize(bs)
+	if err != nil {
+		return cError(err)
+	}
+	*out = (*C.uint8_t)(C.CBytes(b))
+	*outLen = C.size_t(len(b))
+	return nil
+}
+
+// PublicMetadataDeserialize deserializes the inLen bytes at in, strictly unless strict is zero,
+// storing the fields encoded as NUL terminated JSON in *fields.
+//
+//export PublicMetadataDeserialize
+func PublicMetadataDeserialize(in *C.uint8_t, inLen C.size_t, strict C.int, fields **C.char) *C.char {
+	b, err := goBytes(unsafe.Pointer(in), inLen)
+	if err != nil {
+		return cError(err)
+	}
+	bs, err := binarymetadata.DeserializeOptions{Strict: strict != 0}.Deserialize(b)
+	if err != nil {
+		return cError(err)
+	}
+	defer bs.Free()
+	j, err := binarymetadata.JSONMarshalOptions{}.Marshal(bs)
+	if err != nil {
+		return cError(err)
+	}
+	*fields = C.CString(string(j))
+	return nil
+}
+
+// PublicMetadataValidate checks the inLen bytes at in with the cardinality rules at unixSeconds.
+//
+//export PublicMetadataValidate
+func PublicMetadataValidate(in *C.uint8_t, inLen C.size_t, unixSeconds C.int64_t) *C.char {
+	b, err := goBytes(unsafe.Pointer(in), inLen)
+	if err != nil {
+		return cError(err)
+	}
+	return cError(binarymetadata.ValidateMetadataCardinality(b, time.Unix(int64(unixSeconds), 0)))
+}
+
+// PublicMetadataFree releases a message or buffer returned by the other functions. NULL is
+// ignored.
+//
+//export PublicMetadataFree
+func PublicMetadataFree(p unsafe.Pointer) {
+	C.free(p)
+}
+
+func main() {}
@@ -0,0 +1,31 @@
+// Package main runs the PublicMetadataService gRPC server.
+package main
+
+import (
+	"fmt"
+	"net"
+
+	"google3/base/go/flag"
+	"google3/base/go/google"
+	"google3/base/go/log"
+	"google3/privacy/net/common/cpp/public_metadata/go/metadataservice"
+	"google3/third_party/golang/grpc/grpc"
+
+	grpcpb "google3/privacy/net/common/proto/public_metadata_service_go_grpc"
+)
+
+var port = flag.Int("port", 8080, "Port to serve PublicMetadataService on")
+
+func main() {
+	google.Init()
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", *port))
+	if err != nil {
+		log.Exitf("Failed to listen on port %d: %v", *port, err)
+	}
+	s := grpc.NewServer()
+	grpcpb.RegisterPublicMetadataServiceServer(s, metadataservice.New())
+	log.Infof("Serving PublicMetadataService on %v", lis.Addr())
+	if err := s.Serve(lis); err != nil {
+		log.Exitf("Serve failed: %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+package binarymetadata
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"google3/privacy/net/boq/common/tokens/tokentypes"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+)
+
+// SerializeGo is Serialize(New(fields)) implemented in Go, without crossing into C++. It is meant
+// to produce the same bytes and reject the same fields; CheckFieldsConsistency reports where it
+// does not.
+func SerializeGo(fields *NewBinaryFields) ([]byte, error) {
+	if fields.Version > maxVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownVersion, fields.Version)
+	}
+	geo := tokentypes.GeoHint{Country: fields.Country, Region: fields.Region, City: fields.City}
+	if fields.CanonicalGeoHint {
+		geo = CanonicalizeGeoHint(geo)
+	}
+	// The C++ library joins the parts as is, without rejecting commas the way GeoHintExtension
+	// does.
+	hint := asciiToUpper(geo.Country + "," + geo.Region + "," + geo.City)
+	if len(hint) > MaxGeoHintLength {
+		return nil, fmt.Errorf("%w: geo hint of %d bytes is too long", ErrInvalidGeoHint, len(hint))
+	}
+	st, err := ServiceTypeExtensionFromName(fields.ServiceType)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkExpirationSeconds(fields.expirationSeconds()); err != nil {
+		return nil, err
+	}
+	exp, _ := ExpirationExtension{TimestampPrecision: ExpirationTimestampPrecision, Timestamp: uint64(fields.expirationSeconds())}.AsExtension()
+	geoValue := binary.BigEndian.AppendUint16(make([]byte, 0, 2+len(hint)), uint16(len(hint)))
+	service, _ := st.AsExtension()
+	dm := DebugModeExtension{Mode: DebugModeWireProd}
+	if fields.DebugMode == pmpb.PublicMetadata_DEBUG_ALL {
+		dm.Mode = DebugModeWireDebug
+	}
+	debug, _ := dm.AsExtension()
+	exts := []Extension{exp, {Type: ExtensionTypeGeoHint, Value: append(geoValue, hint...)}, service, debug}
+	// New leaves the proxy layer unset for layers without a wire value.
+	w, err := ProxyLayerToWire(fields.ProxyLayer)
+	hasProxyLayer := err == nil && fields.Version >= 2
+	if hasProxyLayer {
+		pl, _ := ProxyLayerExtension{Layer: uint8(w)}.AsExtension()
+		exts = append(exts, pl)
+	}
+	if fields.Version > impliedVersion(hasProxyLayer) {
+		v, _ := VersionExtension{Version: uint8(fields.Version)}.AsExtension()
+		exts = append(exts, v)
+	}
+	return EncodeExtensions(exts)
+}
+
+// DeserializeGo is Deserialize implemented in Go, without crossing into C++. It is meant to accept
+// the same input and return the fields of the struct Deserialize would; CheckConsistency reports
+// where it does not. Extensions of types the C++ library does not know are skipped.
+func DeserializeGo(in []byte) (*NewBinaryFields, error) {
+	all, err := DecodeExtensions(in)
+	if err != nil {
+		return nil, err
+	}
+	var exts []Extension
+	for _, e := range all {
+		if isKnownExtensionType(e.Type) {
+			exts = append(exts, e)
+		}
+	}
+	// Like the C++ library, the layout is positional and the version is inferred from the number
+	// of extensions unless a version extension follows them.
+	if len(exts) < 4 || len(exts) > 6 {
+		return nil, fmt.Errorf("%w: %d extensions, want 4 to 6", ErrMalformed, len(exts))
+	}
+	exp, err := ExpirationExtensionFromExtension(exts[0])
+	if err != nil {
+		return nil, err
+	}
+	if exp.TimestampPrecision != ExpirationTimestampPrecision {
+		return nil, fmt.Errorf("%w: timestamp precision %d, want %d", ErrInvalidExpiration, exp.TimestampPrecision, ExpirationTimestampPrecision)
+	}
+	if err := checkExpirationSeconds(int64(exp.Timestamp)); err != nil {
+		return nil, err
+	}
+	geo, err := GeoHintExtensionFromExtension(exts[1])
+	if err != nil {
+		return nil, err
+	}
+	st, err := ServiceTypeExtensionFromExtension(exts[2])
+	if err != nil {
+		return nil, err
+	}
+	dm, err := DebugModeExtensionFromExtension(exts[3])
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTextFields(geo.CountryCode, geo.Region, geo.City, st.ServiceType); err != nil {
+		return nil, err
+	}
+	fields := &NewBinaryFields{
+		Version:     1,
+		ServiceType: st.ServiceType,
+		Expiration:  &tpb.Timestamp{Seconds: int64(exp.Timestamp)},
+		DebugMode:   pmpb.PublicMetadata_DebugMode(dm.Mode),
+		Country:     geo.CountryCode,
+		Region:      geo.Region,
+		City:        geo.City,
+	}
+	rest := exts[4:]
+	if len(rest) > 0 && rest[0].Type != ExtensionTypeVersion {
+		pl, err := ProxyLayerExtensionFromExtension(rest[0])
+		if err != nil {
+			return nil, err
+		}
+		fields.Version = 2
+		fields.ProxyLayer, _ = ProxyLayerFromWire(uint(pl.Layer))
+		rest = rest[1:]
+	}
+	if len(rest) > 0 {
+		v, err := VersionExtensionFromExtension(rest[0])
+		if err != nil {
+			return nil, err
+		}
+		if ver := int32(v.Version); ver <= fields.Version || ver > maxVersion {
+			return nil, fmt.Errorf("%w: version extension %d with %d extensions", ErrUnknownVersion, ver, len(exts))
+		}
+		fields.Version = int32(v.Version)
+		rest = rest[1:]
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%w: extension %#04x after the version extension", ErrMalformed, rest[0].Type)
+	}
+	return fields, nil
+}
+
+// asciiToUpper upper-cases the ASCII letters of s, like absl::AsciiStrToUpper.
+func asciiToUpper(s string) string {
+	return strings.Map(func(r rune) rune {
+		if 'a' <= r && r <= 'z' {
+			return r - 'a' + 'A'
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,61 @@
+package binarymetadata
+
+import (
+	"bytes"
+	"testing"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	plpb "google3/privacy/net/common/proto/proxy_layer_go_proto"
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+)
+
+func pureGoTestFields() []*NewBinaryFields {
+	exp := &tpb.Timestamp{Seconds: 1701111600}
+	return []*NewBinaryFields{
+		{Version: 1, ServiceType: "chromeipblinding", Expiration: exp, Country: "US"},
+		{Version: 1, ServiceType: "chromeipblinding", Expiration: exp, Country: "us", Region: "us-ca", City: "Sunnyvale", DebugMode: pmpb.PublicMetadata_DEBUG_ALL},
+		{Version: 2, ServiceType: "chromeipblinding", Expiration: exp, Country: "US", ProxyLayer: plpb.ProxyLayer_PROXY_B},
+		{Version: 2, ServiceType: "chromeipblinding", Expiration: exp, Country: "US"},
+		{Version: 2, ServiceType: "chromeipblinding", Expiration: exp, Country: "usa", Region: "ca", CanonicalGeoHint: true, ProxyLayer: plpb.ProxyLayer_PROXY_A},
+		{Version: 1, ServiceType: "chromeipblinding", Expiration: exp, Country: "US", City: "Zürich"},
+		{Version: 1, ServiceType: "chromeipblinding", Expiration: exp, Country: "US,CA"},
+		{Version: 1, ServiceType: "cronet", Expiration: exp, Country: "US"},
+		{Version: 3, ServiceType: "chromeipblinding", Expiration: exp, Country: "US"},
+		{Version: 3, ServiceType: "chromeipblinding", Expiration: exp, Country: "US", ProxyLayer: plpb.ProxyLayer_PROXY_A},
+		{Version: 4, ServiceType: "chromeipblinding", Expiration: exp, Country: "US"},
+	}
+}
+
+func TestSerializeGoMatchesSerialize(t *testing.T) {
+	for _, fields := range pureGoTestFields() {
+		bs := New(fields)
+		want, wantErr := Serialize(bs)
+		bs.Free()
+		got, err := SerializeGo(fields)
+		if (err == nil) != (wantErr == nil) || !bytes.Equal(got, want) {
+			t.Errorf("SerializeGo(%+v) = %x, %v, want %x, %v", fields, got, err, want, wantErr)
+		}
+	}
+}
+
+func TestDeserializeGoRoundTrip(t *testing.T) {
+	for _, fields := range pureGoTestFields() {
+		in, err := SerializeGo(fields)
+		if err != nil {
+			continue
+		}
+		got, err := DeserializeGo(in)
+		if err != nil {
+			// A comma in a geo hint part serializes, but does not deserialize.
+			if bs, cppErr := Deserialize(in); cppErr == nil {
+				bs.Free()
+				t.Errorf("DeserializeGo(%x) failed: %v, want it to succeed like Deserialize", in, err)
+			}
+			continue
+		}
+		out, err := SerializeGo(got)
+		if err != nil || !bytes.Equal(out, in) {
+			t.Errorf("SerializeGo(DeserializeGo(%x)) = %x, %v, want the input", in, out, err)
+		}
+	}
+}
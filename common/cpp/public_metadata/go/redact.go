@@ -0,0 +1,74 @@
+package binarymetadata
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+)
+
+// expirationLogBucket is the granularity expirations are coarsened to in redacted output.
+const expirationLogBucket = time.Hour
+
+// redacted replaces values that are set but withheld from redacted output.
+const redacted = "REDACTED"
+
+// bucketedExpiration returns the expiration truncated to expirationLogBucket, or nil if unset.
+func (bs *BinaryStruct) bucketedExpiration() *tpb.Timestamp {
+	exp := bs.expiration()
+	if exp == nil {
+		return nil
+	}
+	return tpb.New(exp.AsTime().Truncate(expirationLogBucket))
+}
+
+// redactIfSet returns redacted for non-empty values.
+func redactIfSet(s string) string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+func (bs *BinaryStruct) redactedString() string {
+	geo := bs.geoHint()
+	return fmt.Sprintf("{Version: %d\n ServiceType: %s\n Expiration (bucketed): %s\n DebugMode: %s\n ProxyLayer: %s\n GeoHint (country): %s\n GeoHint (region): %s\n GeoHint (city): %s%s}",
+		bs.md().GetVersion(), bs.serviceType(), bs.bucketedExpiration().String(), bs.debugMode().String(), bs.proxyLayer().String(), geo.Country, redactIfSet(geo.Region), redactIfSet(geo.City), bs.serviceTierString(true))
+}
+
+// LogValue implements slog.LogValuer, applying the same redaction rules as String.
+func (bs *BinaryStruct) LogValue() slog.Value {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+	geo := bs.geoHint()
+	debug := bs.debugMode() == pmpb.PublicMetadata_DEBUG_ALL
+	exp := bs.bucketedExpiration()
+	if debug {
+		exp = bs.expiration()
+	}
+	attrs := []slog.Attr{
+		slog.Int("version", int(bs.md().GetVersion())),
+		slog.String("service_type", bs.serviceType()),
+		slog.String("debug_mode", bs.debugMode().String()),
+		slog.String("proxy_layer", bs.proxyLayer().String()),
+		slog.String("country", geo.Country),
+	}
+	if exp != nil {
+		attrs = append(attrs, slog.Time("expiration", exp.AsTime()))
+	}
+	if debug {
+		attrs = append(attrs, slog.String("region", geo.Region), slog.String("city", geo.City))
+	} else {
+		attrs = append(attrs, slog.String("region", redactIfSet(geo.Region)), slog.String("city", redactIfSet(geo.City)))
+	}
+	if tier, ok := bs.coarse(ExtensionTypeServiceTier); ok {
+		s := redacted
+		if debug {
+			s = ServiceTier(tier).String()
+		}
+		attrs = append(attrs, slog.String("service_tier", s))
+	}
+	return slog.GroupValue(attrs...)
+}
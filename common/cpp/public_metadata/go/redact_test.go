@@ -0,0 +1,59 @@
+package binarymetadata
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+)
+
+func TestRedaction(t *testing.T) {
+	tests := []struct {
+		name      string
+		debugMode pmpb.PublicMetadata_DebugMode
+		wantCity  bool
+	}{
+		{
+			name:      "prod",
+			debugMode: pmpb.PublicMetadata_UNSPECIFIED_DEBUG_MODE,
+		},
+		{
+			name:      "debug",
+			debugMode: pmpb.PublicMetadata_DEBUG_ALL,
+			wantCity:  true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bs := New(&NewBinaryFields{
+				Version:     1,
+				Country:     "US",
+				Region:      "US-CA",
+				City:        "SUNNYVALE",
+				ServiceType: "chromeipblinding",
+				Expiration:  &tpb.Timestamp{Seconds: 4500},
+				DebugMode:   tc.debugMode,
+			})
+			defer bs.Free()
+			var buf bytes.Buffer
+			slog.New(slog.NewTextHandler(&buf, nil)).Info("metadata", "md", bs)
+			for name, out := range map[string]string{"String()": bs.String(), "LogValue()": buf.String()} {
+				if !strings.Contains(out, "US") {
+					t.Errorf("%s = %q, want it to contain the country", name, out)
+				}
+				if got := strings.Contains(out, "SUNNYVALE"); got != tc.wantCity {
+					t.Errorf("%s = %q, contains city: %v, want %v", name, out, got, tc.wantCity)
+				}
+			}
+			if !strings.Contains(bs.DebugString(), "SUNNYVALE") {
+				t.Errorf("DebugString() = %q, want it to contain the city", bs.DebugString())
+			}
+			if got := strings.Contains(bs.String(), "4500"); got != tc.wantCity {
+				t.Errorf("String() = %q, contains exact expiration: %v, want %v", bs.String(), got, tc.wantCity)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package binarymetadata
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// pssSaltLength is the RSA-PSS salt length of AT_PUBLIC_METADATA tokens, the SHA-384 output size.
+const pssSaltLength = sha512.Size384
+
+// RedeemedToken is an AT_PUBLIC_METADATA token presented for redemption.
+type RedeemedToken struct {
+	// Message is the token's random message, the nonce passed to BuildTokenInput.
+	Message []byte
+	// Signature is the unblinded partially blind RSA signature.
+	Signature []byte
+}
+
+// ExpectedMetadata lists what redeemed metadata must match. Zero fields are not checked.
+type ExpectedMetadata struct {
+	Version int32
+	// Expiration is the expiration bucket the metadata must carry.
+	Expiration time.Time
+	// Country, if set, requires the geo hint to be exactly Country, Region and City.
+	Country, Region, City string
+	ServiceType           string
+}
+
+// RedemptionVerifier checks that redeemed tokens were issued over the metadata presented
+// alongside them.
+type RedemptionVerifier struct {
+	// PublicKey is the issuer key the tokens were signed with.
+	PublicKey *rsa.PublicKey
+	// UseRSAPublicExponent is the option of the same name the key was created with; see
+	// DerivePublicKey.
+	UseRSAPublicExponent bool
+	// Expected is what the metadata must match.
+	Expected ExpectedMetadata
+	// KeyEpoch, if set, is the epoch of PublicKey, which the metadata must declare; see
+	// CheckKeyEpoch.
+	KeyEpoch *uint32
+	// ClockSkew is how long after its expiration metadata is still accepted, for issuers whose clock
+	// is behind. Metadata that expired at least ClockSkew ago fails with ErrExpired.
+	ClockSkew time.Duration
+	// Clock is the time expirations are checked against. Nil uses SystemClock.
+	Clock Clock
+	// ReplayCache, if set, rejects a token presented again with the same metadata with ErrReplayed.
+	// Tokens are remembered until their metadata expires, plus ClockSkew, so that they are
+	// remembered for as long as Verify accepts them.
+	ReplayCache ReplayCache
+}
+
+// MismatchError reports a field of redeemed metadata that differs from ExpectedMetadata.
+type MismatchError struct {
+	Field string
+	Want  string
+	Got   string
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("%v: %s is %q, want %q", ErrMetadataMismatch, e.Field, e.Got, e.Want)
+}
+
+func (e *MismatchError) Unwrap() error {
+	return ErrMetadataMismatch
+}
+
+// Verify checks that tok is signed over metadata, the serialized metadata presented with it, by
+// the key derived for metadata from v.PublicKey, and that metadata matches v.Expected and declares
+// v.KeyEpoch if set. A bad signature fails with ErrInvalidSignature. Mismatches are reported
+// together as joined *MismatchError values, in field order. Expired metadata fails with ErrExpired
+// before v.ReplayCache is consulted, so that the cache forgetting a token cannot let it be redeemed
+// again. Only tokens passing every check are recorded in v.ReplayCache.
+func (v *RedemptionVerifier) Verify(tok RedeemedToken, metadata []byte) error {
+	pk, err := DerivePublicKey(v.PublicKey, metadata, v.UseRSAPublicExponent)
+	if err != nil {
+		return err
+	}
+	input, err := EncodeMessagePublicMetadata(tok.Message, metadata)
+	if err != nil {
+		return err
+	}
+	digest := sha512.Sum384(input)
+	if err := verifyPSS(pk, digest[:], tok.Signature); err != nil {
+		return err
+	}
+	bs, err := Deserialize(metadata)
+	if err != nil {
+		return err
+	}
+	defer bs.Free()
+	errs := v.Expected.compare(bs)
+	if v.KeyEpoch != nil {
+		errs = errors.Join(errs, CheckKeyEpoch(bs, *v.KeyEpoch))
+	}
+	if errs != nil {
+		return errs
+	}
+	exp := bs.GetExpirationTime()
+	if exp.IsZero() {
+		return &FieldError{Field: "expiration", Err: ErrMissingField}
+	}
+	expiry := exp.Add(v.ClockSkew)
+	clock := v.Clock
+	if clock == nil {
+		clock = SystemClock
+	}
+	if !clock.Now().Before(expiry) {
+		return &FieldError{Field: "expiration", Value: exp.UTC().Format(time.RFC3339Nano), Err: ErrExpired}
+	}
+	if v.ReplayCache == nil {
+		return nil
+	}
+	return v.checkReplay(tok.Message, metadata, expiry)
+}
+
+func (v *RedemptionVerifier) checkReplay(message, metadata []byte, expiry time.Time) error {
+	seen, err := v.ReplayCache.CheckAndAdd(replayKey(message, metadata), expiry)
+	if err != nil {
+		return err
+	}
+	if seen {
+		return ErrReplayed
+	}
+	return nil
+}
+
+func (e ExpectedMetadata) compare(bs *BinaryStruct) error {
+	var errs []error
+	check := func(field, want, got string) {
+		if want != got {
+			errs = append(errs, &MismatchError{Field: field, Want: want, Got: got})
+		}
+	}
+	if e.Version != 0 {
+		check("version", fmt.Sprint(e.Version), fmt.Sprint(bs.GetVersion()))
+	}
+	if !e.Expiration.IsZero() {
+		got := ""
+		if exp := bs.GetExpiration(); exp != nil {
+			got = exp.AsTime().UTC().Format(time.RFC3339)
+		}
+		check("expiration", e.Expiration.UTC().Format(time.RFC3339), got)
+	}
+	if e.Country != "" {
+		geo := bs.GetGeoHint()
+		check("country", e.Country, geo.Country)
+		check("region", e.Region, geo.Region)
+		check("city", e.City, geo.City)
+	}
+	if e.ServiceType != "" {
+		check("service_type", e.ServiceType, bs.GetServiceType())
+	}
+	return errors.Join(errs...)
+}
+
+// verifyPSS is EMSA-PSS-VERIFY of RFC 8017 section 9.1.2 with SHA-384, MGF1-SHA-384 and a 48 byte
+// salt. It is implemented here because rsa.VerifyPSS cannot take derived exponents, which exceed
+// an int.
+func verifyPSS(pk *DerivedPublicKey, digest, sig []byte) error {
+	k := (pk.N.BitLen() + 7) / 8
+	if len(sig) != k {
+		return fmt.Errorf("%w: %d bytes, want %d", ErrInvalidSignature, len(sig), k)
+	}
+	s := new(big.Int).SetBytes(sig)
+	if s.Cmp(pk.N) >= 0 {
+		return fmt.Errorf("%w: signature is not below the modulus", ErrInvalidSignature)
+	}
+	emBits := pk.N.BitLen() - 1
+	emLen := (emBits + 7) / 8
+	m := new(big.Int).Exp(s, pk.E, pk.N)
+	if m.BitLen() > emLen*8 {
+		return ErrInvalidSignature
+	}
+	em := m.FillBytes(make([]byte, emLen))
+
+	hLen := sha512.Size384
+	if emLen < hLen+pssSaltLength+2 || em[emLen-1] != 0xbc {
+		return ErrInvalidSignature
+	}
+	maskedDB, h := em[:emLen-hLen-1], em[emLen-hLen-1:emLen-1]
+	topMask := byte(0xff >> (8*emLen - emBits))
+	if maskedDB[0]&^topMask != 0 {
+		return ErrInvalidSignature
+	}
+	db := mgf1SHA384(h, len(maskedDB))
+	for i := range db {
+		db[i] ^= maskedDB[i]
+	}
+	db[0] &= topMask
+	ps := emLen - hLen - pssSaltLength - 2
+	if !bytes.Equal(db[:ps], make([]byte, ps)) || db[ps] != 0x01 {
+		return ErrInvalidSignature
+	}
+	hash := sha512.New384()
+	hash.Write(make([]byte, 8))
+	hash.Write(digest)
+	hash.Write(db[len(db)-pssSaltLength:])
+	if subtle.ConstantTimeCompare(hash.Sum(nil), h) != 1 {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func mgf1SHA384(seed []byte, n int) []byte {
+	out := make([]byte, 0, n+sha512.Size384)
+	var counter [4]byte
+	for i := uint32(0); len(out) < n; i++ {
+		binary.BigEndian.PutUint32(counter[:], i)
+		h := sha512.New384()
+		h.Write(seed)
+		h.Write(counter[:])
+		out = h.Sum(out)
+	}
+	return out[:n]
+}
@@ -0,0 +1,121 @@
+package binarymetadata
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha512"
+	"errors"
+	"math/big"
+	"slices"
+	"testing"
+	"time"
+)
+
+// signForTest signs message and metadata as an issuer of AT_PUBLIC_METADATA tokens would after
+// unblinding. It returns false if the derived exponent has no inverse for key.
+func signForTest(t *testing.T, key *rsa.PrivateKey, message, metadata []byte) ([]byte, bool) {
+	t.Helper()
+	pk, err := DerivePublicKey(&key.PublicKey, metadata, false)
+	if err != nil {
+		t.Fatalf("DerivePublicKey failed: %v", err)
+	}
+	one := big.NewInt(1)
+	phi := new(big.Int).Mul(new(big.Int).Sub(key.Primes[0], one), new(big.Int).Sub(key.Primes[1], one))
+	d := new(big.Int).ModInverse(pk.E, phi)
+	if d == nil {
+		return nil, false
+	}
+	input, err := EncodeMessagePublicMetadata(message, metadata)
+	if err != nil {
+		t.Fatalf("EncodeMessagePublicMetadata failed: %v", err)
+	}
+	digest := sha512.Sum384(input)
+
+	emBits := key.N.BitLen() - 1
+	emLen := (emBits + 7) / 8
+	salt := make([]byte, pssSaltLength)
+	rand.Read(salt)
+	h := sha512.New384()
+	h.Write(make([]byte, 8))
+	h.Write(digest[:])
+	h.Write(salt)
+	mHash := h.Sum(nil)
+	db := make([]byte, emLen-len(mHash)-1)
+	db[len(db)-pssSaltLength-1] = 0x01
+	copy(db[len(db)-pssSaltLength:], salt)
+	mask := mgf1SHA384(mHash, len(db))
+	for i := range db {
+		db[i] ^= mask[i]
+	}
+	db[0] &= byte(0xff >> (8*emLen - emBits))
+	em := append(append(db, mHash...), 0xbc)
+	s := new(big.Int).Exp(new(big.Int).SetBytes(em), d, key.N)
+	return s.FillBytes(make([]byte, key.Size())), true
+}
+
+func TestRedemptionVerifier(t *testing.T) {
+	expiration := time.Unix(1800000000, 0).Truncate(15 * time.Minute)
+	bs := newHeaderTestStruct(t, expiration)
+	metadata, err := Serialize(bs)
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	message := []byte("0123456789abcdef0123456789abcdef")
+	var key *rsa.PrivateKey
+	var sig []byte
+	for ok := false; !ok; {
+		if key, err = rsa.GenerateKey(rand.Reader, 1024); err != nil {
+			t.Fatalf("GenerateKey failed: %v", err)
+		}
+		sig, ok = signForTest(t, key, message, metadata)
+	}
+	expected := ExpectedMetadata{
+		Version:     1,
+		Expiration:  expiration,
+		Country:     "US",
+		ServiceType: ServiceTypeChromeIPBlinding,
+	}
+	clock := &fixedClock{expiration.Add(-time.Hour)}
+	v := &RedemptionVerifier{PublicKey: &key.PublicKey, Expected: expected, Clock: clock}
+	if err := v.Verify(RedeemedToken{Message: message, Signature: sig}, metadata); err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	clock.t = expiration
+	if err := v.Verify(RedeemedToken{Message: message, Signature: sig}, metadata); !errors.Is(err, ErrExpired) {
+		t.Errorf("Verify at the expiration = %v, want ErrExpired", err)
+	}
+	clock.t = expiration.Add(-time.Hour)
+
+	other := New(&NewBinaryFields{Version: 1, Country: "CA", ServiceType: ServiceTypeChromeIPBlinding, Expiration: bs.GetExpiration()})
+	defer other.Free()
+	otherMetadata, err := Serialize(other)
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	if err := v.Verify(RedeemedToken{Message: message, Signature: sig}, otherMetadata); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify with other metadata = %v, want ErrInvalidSignature", err)
+	}
+	if err := v.Verify(RedeemedToken{Message: []byte("other"), Signature: sig}, metadata); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify with another message = %v, want ErrInvalidSignature", err)
+	}
+	if err := v.Verify(RedeemedToken{Message: message, Signature: sig[1:]}, metadata); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify with a short signature = %v, want ErrInvalidSignature", err)
+	}
+
+	v.Expected = ExpectedMetadata{Version: 2, Expiration: expiration.Add(15 * time.Minute), Country: "US", Region: "US-CA"}
+	err = v.Verify(RedeemedToken{Message: message, Signature: sig}, metadata)
+	if !errors.Is(err, ErrMetadataMismatch) {
+		t.Fatalf("Verify with other expectations = %v, want ErrMetadataMismatch", err)
+	}
+	var fields []string
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var me *MismatchError
+		if !errors.As(e, &me) {
+			t.Fatalf("Verify returned %v, want only MismatchErrors", e)
+		}
+		fields = append(fields, me.Field)
+	}
+	if want := []string{"version", "expiration", "region"}; !slices.Equal(fields, want) {
+		t.Errorf("Verify reported mismatches in %v, want %v", fields, want)
+	}
+}
@@ -0,0 +1,77 @@
+package binarymetadata
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+// ReplayCache remembers redeemed tokens until their metadata expires, so that RedemptionVerifier
+// can reject a token presented twice. Implementations backed by shared storage let every
+// redemption server of a fleet see the tokens redeemed by the others. Validator does not consult
+// it: it only sees the metadata, which every token of a batch shares.
+type ReplayCache interface {
+	// CheckAndAdd records key until expiry and reports whether it was already recorded and had not
+	// expired yet. It must be atomic: of two concurrent calls for the same key, one reports true.
+	CheckAndAdd(key [32]byte, expiry time.Time) (bool, error)
+}
+
+// replayKey identifies the pair of a token message and the serialized metadata it is bound to.
+func replayKey(message, metadata []byte) [32]byte {
+	h := sha256.New()
+	h.Write(binary.BigEndian.AppendUint32(nil, uint32(len(metadata))))
+	h.Write(metadata)
+	h.Write(message)
+	var key [32]byte
+	h.Sum(key[:0])
+	return key
+}
+
+// minReplaySweep is the number of entries below which MemoryReplayCache does not sweep.
+const minReplaySweep = 64
+
+// MemoryReplayCache is a ReplayCache for a single process. Expired entries are swept once the
+// cache has doubled in size since the last sweep. It is safe for concurrent use.
+type MemoryReplayCache struct {
+	mu        sync.Mutex
+	clock     Clock
+	entries   map[[32]byte]time.Time
+	nextSweep int
+}
+
+// NewMemoryReplayCache returns an empty MemoryReplayCache expiring entries by c. Nil uses
+// SystemClock.
+func NewMemoryReplayCache(c Clock) *MemoryReplayCache {
+	if c == nil {
+		c = SystemClock
+	}
+	return &MemoryReplayCache{clock: c, entries: map[[32]byte]time.Time{}, nextSweep: minReplaySweep}
+}
+
+// CheckAndAdd implements ReplayCache. It never fails.
+func (c *MemoryReplayCache) CheckAndAdd(key [32]byte, expiry time.Time) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.clock.Now()
+	if exp, ok := c.entries[key]; ok && now.Before(exp) {
+		return true, nil
+	}
+	c.entries[key] = expiry
+	if len(c.entries) >= c.nextSweep {
+		for k, exp := range c.entries {
+			if !now.Before(exp) {
+				delete(c.entries, k)
+			}
+		}
+		c.nextSweep = max(2*len(c.entries), minReplaySweep)
+	}
+	return false, nil
+}
+
+// Len returns the number of entries, including expired ones not swept yet.
+func (c *MemoryReplayCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
@@ -0,0 +1,110 @@
+package binarymetadata
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+)
+
+func TestMemoryReplayCache(t *testing.T) {
+	clock := &fixedClock{time.Unix(1800000000, 0)}
+	c := NewMemoryReplayCache(clock)
+	key := [32]byte{1}
+	expiry := clock.t.Add(time.Hour)
+	for i, want := range []bool{false, true, true} {
+		if seen, err := c.CheckAndAdd(key, expiry); err != nil || seen != want {
+			t.Errorf("CheckAndAdd() #%d = %t, %v, want %t, nil", i, seen, err, want)
+		}
+	}
+	clock.t = clock.t.Add(time.Hour)
+	if seen, _ := c.CheckAndAdd(key, clock.t.Add(time.Hour)); seen {
+		t.Error("CheckAndAdd() after the expiry reported the key as seen")
+	}
+}
+
+func TestMemoryReplayCacheSweep(t *testing.T) {
+	clock := &fixedClock{time.Unix(1800000000, 0)}
+	c := NewMemoryReplayCache(clock)
+	for i := range minReplaySweep - 1 {
+		c.CheckAndAdd([32]byte{byte(i), 1}, clock.t.Add(time.Minute))
+	}
+	clock.t = clock.t.Add(time.Minute)
+	c.CheckAndAdd([32]byte{0, 2}, clock.t.Add(time.Minute))
+	if got := c.Len(); got != 1 {
+		t.Errorf("Len() = %d after the sweep, want 1", got)
+	}
+}
+
+func TestMemoryReplayCacheConcurrent(t *testing.T) {
+	c := NewMemoryReplayCache(nil)
+	expiry := time.Now().Add(time.Hour)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	fresh := 0
+	for range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if seen, _ := c.CheckAndAdd([32]byte{7}, expiry); !seen {
+				mu.Lock()
+				fresh++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if fresh != 1 {
+		t.Errorf("%d concurrent CheckAndAdd calls reported the key as new, want 1", fresh)
+	}
+}
+
+func TestRedemptionVerifierReplayCache(t *testing.T) {
+	exp := time.Unix(1800000000, 0).Truncate(15 * time.Minute)
+	bs := New(&NewBinaryFields{Version: 3, Country: "US", ServiceType: "chromeipblinding", Expiration: tpb.New(exp)})
+	defer bs.Free()
+	if err := bs.SetRandomNonce(); err != nil {
+		t.Fatalf("SetRandomNonce failed: %v", err)
+	}
+	metadata, err := Serialize(bs)
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	message := []byte("0123456789abcdef0123456789abcdef")
+	var key *rsa.PrivateKey
+	var sig []byte
+	for ok := false; !ok; {
+		if key, err = rsa.GenerateKey(rand.Reader, 1024); err != nil {
+			t.Fatalf("GenerateKey failed: %v", err)
+		}
+		sig, ok = signForTest(t, key, message, metadata)
+	}
+	clock := &fixedClock{exp.Add(-time.Hour)}
+	v := &RedemptionVerifier{PublicKey: &key.PublicKey, ClockSkew: time.Minute, Clock: clock, ReplayCache: NewMemoryReplayCache(clock)}
+	tok := RedeemedToken{Message: message, Signature: sig}
+
+	if err := v.Verify(RedeemedToken{Message: message, Signature: make([]byte, len(sig))}, metadata); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("Verify(bad signature) returned error: %v, want error: %v", err, ErrInvalidSignature)
+	}
+	if err := v.Verify(tok, metadata); err != nil {
+		t.Fatalf("Verify failed after a bad signature, which must not be recorded: %v", err)
+	}
+	if err := v.Verify(tok, metadata); !errors.Is(err, ErrReplayed) {
+		t.Errorf("Verify(replayed) returned error: %v, want error: %v", err, ErrReplayed)
+	}
+	clock.t = clock.t.Add(time.Hour)
+	if err := v.Verify(tok, metadata); !errors.Is(err, ErrReplayed) {
+		t.Errorf("Verify(replayed within ClockSkew) returned error: %v, want error: %v", err, ErrReplayed)
+	}
+	clock.t = clock.t.Add(time.Minute)
+	if err := v.Verify(tok, metadata); !errors.Is(err, ErrExpired) {
+		t.Errorf("Verify(replayed after the cache forgot it) returned error: %v, want error: %v", err, ErrExpired)
+	}
+	if seen, _ := v.ReplayCache.CheckAndAdd(replayKey(message, metadata), clock.t.Add(time.Hour)); seen {
+		t.Error("the replay cache still remembers the token past ClockSkew, so the test does not cover its expiry")
+	}
+}
@@ -0,0 +1,134 @@
+package binarymetadata
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Severity ranks a Finding.
+type Severity int
+
+// Severities of a Finding.
+const (
+	// SeverityError findings make the metadata invalid.
+	SeverityError Severity = iota
+	// SeverityWarning findings are accepted but worth surfacing, e.g. debugging being enabled.
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "ERROR"
+	case SeverityWarning:
+		return "WARNING"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// Finding is a single result of validating one field.
+type Finding struct {
+	// Field is the name of the field, e.g. "expiration".
+	Field string
+	// Rule names the rule that was checked, e.g. "max_geo_granularity".
+	Rule string
+	// Value is the observed value, formatted for display.
+	Value string
+	// Severity says whether the finding makes the metadata invalid.
+	Severity Severity
+	// Err is one of the Err* sentinels, possibly wrapped with details. It is nil for warnings.
+	Err error
+}
+
+func (f Finding) fieldError() *FieldError {
+	return &FieldError{Field: f.Field, Value: f.Value, Err: f.Err}
+}
+
+func (f Finding) String() string {
+	s := fmt.Sprintf("%s %s (%s) %q", f.Severity, f.Field, f.Rule, f.Value)
+	if f.Err != nil {
+		s += ": " + f.Err.Error()
+	}
+	return s
+}
+
+// ValidationReport lists every finding for a blob.
+type ValidationReport struct {
+	Findings []Finding
+	// ClockSkew is the clock skew tolerance the expiration was checked with.
+	ClockSkew time.Duration
+}
+
+// Valid reports whether r has no SeverityError findings.
+func (r *ValidationReport) Valid() bool {
+	for _, f := range r.Findings {
+		if f.Severity == SeverityError {
+			return false
+		}
+	}
+	return true
+}
+
+// Err joins the SeverityError findings of r with errors.Join, each as a *FieldError, or returns
+// nil if r is valid.
+func (r *ValidationReport) Err() error {
+	var errs []error
+	for _, f := range r.Findings {
+		if f.Severity == SeverityError {
+			errs = append(errs, f.fieldError())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *ValidationReport) String() string {
+	var lines []string
+	for _, f := range r.Findings {
+		lines = append(lines, f.String())
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "valid")
+	}
+	if r.ClockSkew != 0 {
+		lines = append(lines, fmt.Sprintf("clock skew tolerance %v", r.ClockSkew))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Report deserializes in and returns every finding of v at time t. The error is non-nil only if
+// in does not deserialize.
+func (v *Validator) Report(in []byte, t time.Time) (*ValidationReport, error) {
+	bs, err := DeserializeOptions{WireFormat: v.cfg.WireFormat}.Deserialize(in)
+	if err != nil {
+		return nil, err
+	}
+	defer bs.Free()
+	return &ValidationReport{Findings: v.findings(bs, t), ClockSkew: v.cfg.ClockSkew}, nil
+}
+
+// defaultValidator enforces the rules of ValidateMetadataCardinality.
+var defaultValidator, _ = NewValidator(ValidationConfig{})
+
+// Validate is like ValidateMetadataCardinality but reports every finding rather than the first
+// error. The error is non-nil only if in does not deserialize.
+func Validate(in []byte, t time.Time) (*ValidationReport, error) {
+	return defaultValidator.Report(in, t)
+}
+
+// ValidateAll is like Validate on v but, rather than stopping at the first violation, returns all
+// of them joined into one error. errors.Is matches the sentinel of any violation.
+func (v *Validator) ValidateAll(in []byte, t time.Time) error {
+	r, err := v.Report(in, t)
+	if err != nil {
+		return err
+	}
+	return r.Err()
+}
+
+// ValidateAll checks in against the rules of ValidateMetadataCardinality and returns every
+// violation joined into one error, so producers can fix them all in one iteration.
+func ValidateAll(in []byte, t time.Time) error {
+	return defaultValidator.ValidateAll(in, t)
+}
@@ -0,0 +1,163 @@
+package binarymetadata
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+)
+
+func TestValidateReport(t *testing.T) {
+	exp := time.Unix(1701110700, 0)
+	in := serializeForTest(t, &NewBinaryFields{
+		Version:     1,
+		Country:     "US",
+		Region:      "US-CA",
+		ServiceType: "chromeipblinding",
+		Expiration:  tpb.New(exp),
+		DebugMode:   pmpb.PublicMetadata_DEBUG_ALL,
+	})
+
+	r, err := Validate(in, exp.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if !r.Valid() || len(r.Findings) != 1 || r.Findings[0].Severity != SeverityWarning {
+		t.Errorf("Validate() = %v, want valid with one warning", r)
+	}
+
+	r, err = Validate(in, exp.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if r.Valid() {
+		t.Fatalf("Validate() of expired metadata = %v, want invalid", r)
+	}
+	f := r.Findings[0]
+	if f.Field != "expiration" || f.Rule != "not_expired" || f.Value != "2023-11-27T18:45:00Z" || !errors.Is(f.Err, ErrExpired) {
+		t.Errorf("Findings[0] = %v, want expired expiration", f)
+	}
+
+	if _, err := Validate([]byte("bad"), exp); err == nil {
+		t.Error("Validate(bad) succeeded, want error")
+	}
+}
+
+func TestValidateAll(t *testing.T) {
+	now := time.Unix(1701100000, 0)
+	in := serializeForTest(t, &NewBinaryFields{
+		Version:     1,
+		Country:     "ZZ",
+		Region:      "ZZ-1",
+		City:        "NOWHERE",
+		ServiceType: "chromeipblinding",
+		Expiration:  &tpb.Timestamp{Seconds: 1701110701},
+	})
+	v, err := NewValidator(ValidationConfig{MaxGeoGranularity: GeoRegion})
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+	err = v.ValidateAll(in, now)
+	for _, want := range []error{ErrInvalidCountry, ErrExpirationNotRounded, ErrInvalidGeoHint} {
+		if !errors.Is(err, want) {
+			t.Errorf("ValidateAll() returned error: %v, want it to match %v", err, want)
+		}
+	}
+	if first := v.Validate(in, now); !errors.Is(first, ErrExpirationNotRounded) || errors.Is(first, ErrInvalidCountry) {
+		t.Errorf("Validate() returned error: %v, want only the first violation", first)
+	}
+	if err := ValidateAll(serializeForTest(t, &NewBinaryFields{
+		Version:     1,
+		Country:     "US",
+		ServiceType: "chromeipblinding",
+		Expiration:  &tpb.Timestamp{Seconds: 1701110700},
+	}), now); err != nil {
+		t.Errorf("ValidateAll() of valid metadata returned error: %v", err)
+	}
+}
+
+func TestValidatorClockSkew(t *testing.T) {
+	exp := time.Unix(1701110700, 0)
+	in := serializeForTest(t, &NewBinaryFields{
+		Version:     1,
+		Country:     "US",
+		ServiceType: "chromeipblinding",
+		Expiration:  tpb.New(exp),
+	})
+	v, err := NewValidator(ValidationConfig{ClockSkew: 5 * time.Minute})
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+	tests := []struct {
+		name        string
+		t           time.Time
+		wantErr     error
+		wantWarning bool
+	}{
+		{name: "before expiration", t: exp.Add(-time.Hour)},
+		{name: "expired within skew", t: exp.Add(2 * time.Minute), wantWarning: true},
+		{name: "expired beyond skew", t: exp.Add(10 * time.Minute), wantErr: ErrExpired},
+		{name: "too far within skew", t: exp.Add(-defaultMaxTimeToLive - 2*time.Minute), wantWarning: true},
+		{name: "too far beyond skew", t: exp.Add(-defaultMaxTimeToLive - 10*time.Minute), wantErr: ErrExpirationTooFar},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := v.Report(in, tc.t)
+			if err != nil {
+				t.Fatalf("Report failed: %v", err)
+			}
+			if r.ClockSkew != 5*time.Minute {
+				t.Errorf("ClockSkew = %v, want 5m", r.ClockSkew)
+			}
+			if err := r.Err(); !errors.Is(err, tc.wantErr) {
+				t.Errorf("Report(%v).Err() = %v, want %v", tc.t, err, tc.wantErr)
+			}
+			var warned bool
+			for _, f := range r.Findings {
+				warned = warned || f.Rule == "clock_skew" && f.Severity == SeverityWarning
+			}
+			if warned != tc.wantWarning {
+				t.Errorf("Report(%v) = %v, want clock_skew warning %v", tc.t, r, tc.wantWarning)
+			}
+		})
+	}
+	if _, err := NewValidator(ValidationConfig{ClockSkew: -time.Second}); err == nil {
+		t.Error("NewValidator with a negative ClockSkew succeeded, want error")
+	}
+}
+
+func TestValidateMetadataCardinalityWithSkew(t *testing.T) {
+	exp := time.Unix(1701110700, 0)
+	in := serializeForTest(t, &NewBinaryFields{
+		Version:     1,
+		Country:     "US",
+		ServiceType: "chromeipblinding",
+		Expiration:  tpb.New(exp),
+	})
+	if err := ValidateMetadataCardinality(in, exp.Add(2*time.Minute)); !errors.Is(err, ErrExpired) {
+		t.Fatalf("ValidateMetadataCardinality after expiration = %v, want ErrExpired", err)
+	}
+	if err := ValidateMetadataCardinalityWithSkew(in, exp.Add(2*time.Minute), 5*time.Minute); err != nil {
+		t.Errorf("ValidateMetadataCardinalityWithSkew within skew failed: %v", err)
+	}
+	if err := ValidateMetadataCardinalityWithSkew(in, exp.Add(10*time.Minute), 5*time.Minute); !errors.Is(err, ErrExpired) {
+		t.Errorf("ValidateMetadataCardinalityWithSkew beyond skew = %v, want ErrExpired", err)
+	}
+	early := exp.Add(-defaultMaxTimeToLive - 2*time.Minute)
+	if err := ValidateMetadataCardinalityWithSkew(in, early, 5*time.Minute); err != nil {
+		t.Errorf("ValidateMetadataCardinalityWithSkew too early within skew failed: %v", err)
+	}
+	if err := ValidateMetadataCardinalityWithSkew(in, early.Add(-10*time.Minute), 5*time.Minute); !errors.Is(err, ErrExpirationTooFar) {
+		t.Errorf("ValidateMetadataCardinalityWithSkew too early beyond skew = %v, want ErrExpirationTooFar", err)
+	}
+
+	sink := &fakeAuditSink{}
+	SetAuditSink(sink)
+	defer SetAuditSink(nil)
+	ValidateMetadataCardinalityWithSkew(in, exp.Add(10*time.Minute), 5*time.Minute)
+	if got := len(sink.operations()); got != 1 {
+		t.Errorf("ValidateMetadataCardinalityWithSkew recorded %d audit events, want 1", got)
+	}
+}
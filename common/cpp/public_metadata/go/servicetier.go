@@ -0,0 +1,46 @@
+package binarymetadata
+
+// ServiceTier is the entitlement level of a client, as carried by the service tier extension. It
+// is withheld from String and LogValue unless the debug mode is DEBUG_ALL, since together with the
+// geo hint it can narrow down a subscriber.
+type ServiceTier uint8
+
+// Service tiers, equal to their wire values.
+const (
+	ServiceTierFree ServiceTier = 0x00
+	ServiceTierPaid ServiceTier = 0x01
+)
+
+func (t ServiceTier) String() string {
+	return coarseName(ExtensionTypeServiceTier, uint8(t))
+}
+
+// GetServiceTier returns the service tier and whether it is set to a valid value.
+func (bs *BinaryStruct) GetServiceTier() (ServiceTier, bool) {
+	v, ok := bs.getCoarse(ExtensionTypeServiceTier)
+	return ServiceTier(v), ok
+}
+
+// SetServiceTier sets the service tier. It fails with ErrInvalidServiceTier for values other than
+// the ServiceTier constants and for versions without the ServiceTier capability.
+func (bs *BinaryStruct) SetServiceTier(t ServiceTier) error {
+	return bs.setCoarse(ExtensionTypeServiceTier, uint8(t))
+}
+
+// ClearServiceTier removes the service tier.
+func (bs *BinaryStruct) ClearServiceTier() {
+	bs.clearCoarse(ExtensionTypeServiceTier)
+}
+
+// serviceTierString returns the service tier line of String and DebugString, which is empty if the
+// tier is unset.
+func (bs *BinaryStruct) serviceTierString(redact bool) string {
+	v, ok := bs.coarse(ExtensionTypeServiceTier)
+	switch {
+	case !ok:
+		return ""
+	case redact:
+		return "\n ServiceTier: " + redacted
+	}
+	return "\n ServiceTier: " + ServiceTier(v).String()
+}
@@ -0,0 +1,109 @@
+package binarymetadata
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+)
+
+func TestServiceTierRoundTrip(t *testing.T) {
+	for _, want := range []ServiceTier{ServiceTierFree, ServiceTierPaid} {
+		bs := newTestStruct(t, 3)
+		if err := bs.SetServiceTier(want); err != nil {
+			t.Fatalf("SetServiceTier(%v) failed: %v", want, err)
+		}
+		out, err := Serialize(bs)
+		if err != nil {
+			t.Fatalf("Serialize failed: %v", err)
+		}
+		got, err := DeserializeOptions{Strict: true, Canonical: true}.Deserialize(out)
+		if err != nil {
+			t.Fatalf("Deserialize failed: %v", err)
+		}
+		defer got.Free()
+		if got.GetVersion() != 3 {
+			t.Errorf("GetVersion() after round trip = %d, want 3", got.GetVersion())
+		}
+		if tier, ok := got.GetServiceTier(); !ok || tier != want {
+			t.Errorf("GetServiceTier() = %v, %t, want %v, true", tier, ok, want)
+		}
+		if err := defaultValidator.ValidateStruct(got, time.Unix(0, 0)); err != nil {
+			t.Errorf("ValidateStruct(%v) failed: %v", want, err)
+		}
+	}
+}
+
+func TestServiceTierValidation(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		enable  bool
+		value   []byte
+		wantErr error
+	}{
+		{name: "unsupported version", value: []byte{byte(ServiceTierPaid)}, wantErr: ErrInvalidServiceTier},
+		{name: "unknown tier", enable: true, value: []byte{0x02}, wantErr: ErrInvalidServiceTier},
+		{name: "plan id", enable: true, value: []byte{0x00, 0x2a}, wantErr: ErrMalformed},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			version := int32(2)
+			if tc.enable {
+				version = 3
+			}
+			bs := newTestStruct(t, version)
+			if err := bs.SetExtension(ExtensionTypeServiceTier, tc.value); err != nil {
+				t.Fatalf("SetExtension failed: %v", err)
+			}
+			out, err := Serialize(bs)
+			if err != nil {
+				t.Fatalf("Serialize failed: %v", err)
+			}
+			if _, err := (DeserializeOptions{Strict: true}).Deserialize(out); !errors.Is(err, tc.wantErr) {
+				t.Errorf("Deserialize() returned error: %v, want error: %v", err, tc.wantErr)
+			}
+			err = defaultValidator.Validate(out, time.Unix(0, 0))
+			var fe *FieldError
+			if !errors.As(err, &fe) || fe.Field != "service_tier" || !errors.Is(err, tc.wantErr) {
+				t.Errorf("Validate() returned error: %v, want service_tier error wrapping %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestServiceTierRedaction(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		debugMode pmpb.PublicMetadata_DebugMode
+		want      string
+	}{
+		{name: "prod", debugMode: pmpb.PublicMetadata_UNSPECIFIED_DEBUG_MODE, want: redacted},
+		{name: "debug", debugMode: pmpb.PublicMetadata_DEBUG_ALL, want: "PAID"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			bs := New(&NewBinaryFields{Version: 3, Country: "US", ServiceType: "chromeipblinding", Expiration: tpb.New(time.Unix(900, 0)), DebugMode: tc.debugMode})
+			defer bs.Free()
+			if strings.Contains(bs.String(), "ServiceTier") {
+				t.Errorf("String() = %q, want no service tier before it is set", bs.String())
+			}
+			if err := bs.SetServiceTier(ServiceTierPaid); err != nil {
+				t.Fatalf("SetServiceTier failed: %v", err)
+			}
+			var buf bytes.Buffer
+			slog.New(slog.NewTextHandler(&buf, nil)).Info("metadata", "md", bs)
+			if got := bs.String(); !strings.Contains(got, "ServiceTier: "+tc.want) {
+				t.Errorf("String() = %q, want it to contain ServiceTier: %s", got, tc.want)
+			}
+			if got := buf.String(); !strings.Contains(got, "md.service_tier="+tc.want) {
+				t.Errorf("LogValue() = %q, want it to contain md.service_tier=%s", got, tc.want)
+			}
+			if got := bs.DebugString(); !strings.Contains(got, "ServiceTier: PAID") {
+				t.Errorf("DebugString() = %q, want it to contain ServiceTier: PAID", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package binarymetadata
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Known service types. Only ServiceTypeChromeIPBlinding has a wire encoding in the C++ library;
+// the others are accepted by validation but cannot be serialized yet.
+const (
+	ServiceTypeChromeIPBlinding  = "chromeipblinding"
+	ServiceTypeCronet            = "cronet"
+	ServiceTypeWebViewIPBlinding = "webviewipblinding"
+)
+
+var (
+	serviceTypesMu sync.RWMutex
+	// knownServiceTypes holds the built-in service types and those added with RegisterServiceType.
+	knownServiceTypes = map[string]bool{
+		ServiceTypeChromeIPBlinding:  true,
+		ServiceTypeCronet:            true,
+		ServiceTypeWebViewIPBlinding: true,
+	}
+)
+
+// RegisterServiceType adds name to the known service types. Names must be non-empty and consist of
+// lower case letters and digits. Registering a name twice is an error. It is meant to be called
+// from init functions.
+func RegisterServiceType(name string) error {
+	if name == "" || !isLowerAlnum(name) {
+		return fmt.Errorf("%w: %q must be non-empty lower case letters and digits", ErrUnsupportedServiceType, name)
+	}
+	serviceTypesMu.Lock()
+	defer serviceTypesMu.Unlock()
+	if knownServiceTypes[name] {
+		return fmt.Errorf("service type %q is already registered", name)
+	}
+	knownServiceTypes[name] = true
+	return nil
+}
+
+// IsKnownServiceType reports whether name is a built-in or registered service type.
+func IsKnownServiceType(name string) bool {
+	serviceTypesMu.RLock()
+	defer serviceTypesMu.RUnlock()
+	return knownServiceTypes[name]
+}
+
+// KnownServiceTypes returns the built-in and registered service types in ascending order.
+func KnownServiceTypes() []string {
+	serviceTypesMu.RLock()
+	defer serviceTypesMu.RUnlock()
+	names := make([]string, 0, len(knownServiceTypes))
+	for name := range knownServiceTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkServiceType returns an error wrapping ErrUnsupportedServiceType if name is not known.
+func checkServiceType(name string) error {
+	if !IsKnownServiceType(name) {
+		return fmt.Errorf("%w: %q is not a known service type", ErrUnsupportedServiceType, name)
+	}
+	return nil
+}
+
+func isLowerAlnum(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
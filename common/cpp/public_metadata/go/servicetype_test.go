@@ -0,0 +1,68 @@
+package binarymetadata
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+)
+
+func TestRegisterServiceType(t *testing.T) {
+	t.Cleanup(func() {
+		serviceTypesMu.Lock()
+		delete(knownServiceTypes, "testservice")
+		serviceTypesMu.Unlock()
+	})
+	if IsKnownServiceType("testservice") {
+		t.Fatal("IsKnownServiceType(testservice) before registration = true, want false")
+	}
+	if err := RegisterServiceType("testservice"); err != nil {
+		t.Fatalf("RegisterServiceType failed: %v", err)
+	}
+	if !IsKnownServiceType("testservice") {
+		t.Error("IsKnownServiceType(testservice) = false, want true")
+	}
+	if !slices.Contains(KnownServiceTypes(), "testservice") {
+		t.Errorf("KnownServiceTypes() = %v, want it to contain testservice", KnownServiceTypes())
+	}
+	for _, name := range []string{"testservice", ServiceTypeCronet, "", "Upper", "with,comma"} {
+		if err := RegisterServiceType(name); err == nil {
+			t.Errorf("RegisterServiceType(%q) succeeded, want error", name)
+		}
+	}
+}
+
+func TestValidatorRejectsUnknownServiceType(t *testing.T) {
+	bs := New(&NewBinaryFields{
+		Version:     2,
+		Country:     "US",
+		ServiceType: "other",
+		Expiration:  tpb.New(time.Unix(900, 0)),
+	})
+	defer bs.Free()
+	err := defaultValidator.ValidateStruct(bs, time.Unix(0, 0))
+	var fe *FieldError
+	if !errors.As(err, &fe) || fe.Field != "service_type" || !errors.Is(err, ErrUnsupportedServiceType) {
+		t.Errorf("ValidateStruct() returned error: %v, want service_type error wrapping %v", err, ErrUnsupportedServiceType)
+	}
+	v, err := NewValidator(ValidationConfig{AllowUnknownServiceTypes: true})
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+	if err := v.ValidateStruct(bs, time.Unix(0, 0)); err != nil {
+		t.Errorf("ValidateStruct() with AllowUnknownServiceTypes returned error: %v", err)
+	}
+}
+
+func TestBuilderAllowUnknownServiceTypes(t *testing.T) {
+	bs, err := NewBuilder().AllowUnknownServiceTypes().ServiceType("other").Expiration(time.Unix(900, 0)).Country("US").Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	defer bs.Free()
+	if got := bs.GetServiceType(); got != "other" {
+		t.Errorf("GetServiceType() = %q, want other", got)
+	}
+}
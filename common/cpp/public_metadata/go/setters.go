@@ -0,0 +1,112 @@
+package binarymetadata
+
+import (
+	"fmt"
+
+	"google3/privacy/net/boq/common/tokens/tokentypes"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	wrap "google3/privacy/net/common/cpp/public_metadata/go/wrap_public_metadata"
+	plpb "google3/privacy/net/common/proto/proxy_layer_go_proto"
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+)
+
+// The setters below change a single field in place, e.g. to extend the expiration of
+// deserialized metadata before serializing it again. They take the write lock and leave values
+// to be validated by Serialize, except where a value has no wire encoding at all. Metadata returned
+// by Freeze cannot be changed. Every call is reported to the registered AuditSink.
+
+// SetServiceType sets the service type.
+func (bs *BinaryStruct) SetServiceType(s string) {
+	defer bs.audit(AuditMutate, "set_service_type", nil)
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if bs.metadata == nil || bs.frozen {
+		return
+	}
+	bs.metadata.SetService_type(wrap.NewStringOptional(s))
+}
+
+// SetExpiration sets the expiration, or unsets it if exp is nil. Sub-second precision is
+// dropped. Serialize rejects expirations before the unix epoch or after
+// MaxExpirationEpochSeconds.
+func (bs *BinaryStruct) SetExpiration(exp *tpb.Timestamp) {
+	defer bs.audit(AuditMutate, "set_expiration", nil)
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if bs.metadata == nil || bs.frozen {
+		return
+	}
+	if exp == nil {
+		bs.metadata.SetExpiration_epoch_seconds(wrap.NewUint64Optional())
+		return
+	}
+	bs.metadata.SetExpiration_epoch_seconds(wrap.NewUint64Optional(uint64(exp.GetSeconds())))
+}
+
+// SetGeoHint sets the country, region and city, or unsets all three if geo is nil. Pass a geo
+// hint with an empty City to drop the city while keeping the rest.
+func (bs *BinaryStruct) SetGeoHint(geo *tokentypes.GeoHint) {
+	defer bs.audit(AuditMutate, "set_geo_hint", nil)
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if bs.metadata == nil || bs.frozen {
+		return
+	}
+	if geo == nil {
+		bs.metadata.SetCountry(wrap.NewStringOptional())
+		bs.metadata.SetRegion(wrap.NewStringOptional())
+		bs.metadata.SetCity(wrap.NewStringOptional())
+		return
+	}
+	bs.metadata.SetCountry(wrap.NewStringOptional(geo.Country))
+	bs.metadata.SetRegion(wrap.NewStringOptional(geo.Region))
+	bs.metadata.SetCity(wrap.NewStringOptional(geo.City))
+}
+
+// SetProxyLayer sets the proxy layer. It fails for layers with no wire value.
+func (bs *BinaryStruct) SetProxyLayer(l plpb.ProxyLayer) error {
+	err := bs.setProxyLayer(l)
+	bs.audit(AuditMutate, "set_proxy_layer", err)
+	return err
+}
+
+func (bs *BinaryStruct) setProxyLayer(l plpb.ProxyLayer) error {
+	w, err := ProxyLayerToWire(l)
+	if err != nil {
+		return err
+	}
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if err := bs.checkMutable(); err != nil {
+		return err
+	}
+	bs.metadata.SetProxy_layer(wrap.NewUint32Optional(w))
+	return nil
+}
+
+// SetDebugMode sets the debug mode. It fails for values outside the DebugMode enum and for
+// DEBUG_ALL if the registered DebugModeAuthorizer rejects the metadata.
+func (bs *BinaryStruct) SetDebugMode(m pmpb.PublicMetadata_DebugMode) error {
+	err := bs.setDebugMode(m)
+	bs.audit(AuditMutate, "set_debug_mode", err)
+	return err
+}
+
+func (bs *BinaryStruct) setDebugMode(m pmpb.PublicMetadata_DebugMode) error {
+	if _, ok := pmpb.PublicMetadata_DebugMode_name[int32(m)]; !ok {
+		return fmt.Errorf("%w: %d", ErrInvalidDebugMode, m)
+	}
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if err := bs.checkMutable(); err != nil {
+		return err
+	}
+	f := bs.fields()
+	f.DebugMode = m
+	if err := authorizeDebugMode(f); err != nil {
+		return err
+	}
+	bs.metadata.SetDebug_mode(uint(m.Number()))
+	return nil
+}
@@ -0,0 +1,78 @@
+package binarymetadata
+
+import (
+	"errors"
+	"testing"
+
+	"google3/privacy/net/boq/common/tokens/tokentypes"
+	"google3/third_party/golang/cmp/cmp"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	plpb "google3/privacy/net/common/proto/proxy_layer_go_proto"
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+)
+
+func TestSettersRoundTrip(t *testing.T) {
+	bs := New(&NewBinaryFields{
+		Version:     2,
+		Country:     "US",
+		Region:      "US-CA",
+		City:        "SUNNYVALE",
+		ServiceType: "chromeipblinding",
+		Expiration:  &tpb.Timestamp{Seconds: 900},
+		ProxyLayer:  plpb.ProxyLayer_PROXY_A,
+	})
+	defer bs.Free()
+	bs.SetExpiration(&tpb.Timestamp{Seconds: 1800})
+	bs.SetGeoHint(&tokentypes.GeoHint{Country: "US", Region: "US-CA"})
+	if err := bs.SetProxyLayer(plpb.ProxyLayer_PROXY_B); err != nil {
+		t.Fatalf("SetProxyLayer failed: %v", err)
+	}
+	if err := bs.SetDebugMode(pmpb.PublicMetadata_DEBUG_ALL); err != nil {
+		t.Fatalf("SetDebugMode failed: %v", err)
+	}
+
+	out, err := Serialize(bs)
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	got, err := Deserialize(out)
+	if err != nil {
+		t.Fatalf("Deserialize failed: %v", err)
+	}
+	defer got.Free()
+	if got.GetExpiration().GetSeconds() != 1800 {
+		t.Errorf("GetExpiration() = %v, want 1800s", got.GetExpiration())
+	}
+	if diff := cmp.Diff(&tokentypes.GeoHint{Country: "US", Region: "US-CA"}, got.GetGeoHint()); diff != "" {
+		t.Errorf("GetGeoHint() returned unexpected diff (-want +got):\n%s", diff)
+	}
+	if got.GetProxyLayer() != plpb.ProxyLayer_PROXY_B || got.GetDebugMode() != pmpb.PublicMetadata_DEBUG_ALL {
+		t.Errorf("got proxy layer %v and debug mode %v, want PROXY_B and DEBUG_ALL", got.GetProxyLayer(), got.GetDebugMode())
+	}
+}
+
+func TestSettersUnset(t *testing.T) {
+	bs := New(&NewBinaryFields{Version: 1, Country: "US", ServiceType: "chromeipblinding", Expiration: &tpb.Timestamp{Seconds: 900}})
+	defer bs.Free()
+	bs.SetExpiration(nil)
+	bs.SetGeoHint(nil)
+	if bs.HasExpiration() || bs.HasCountry() || bs.HasRegion() || bs.HasCity() {
+		t.Error("nil setters left an optional set")
+	}
+	bs.SetServiceType("other")
+	if got := bs.GetServiceType(); got != "other" {
+		t.Errorf("GetServiceType() = %q, want other", got)
+	}
+}
+
+func TestSettersRejectInvalid(t *testing.T) {
+	bs := New(&NewBinaryFields{Version: 2})
+	defer bs.Free()
+	if err := bs.SetProxyLayer(plpb.ProxyLayer_PROXY_LAYER_UNSPECIFIED); !errors.Is(err, ErrInvalidProxyLayer) {
+		t.Errorf("SetProxyLayer(UNSPECIFIED) returned error: %v, want error: %v", err, ErrInvalidProxyLayer)
+	}
+	if err := bs.SetDebugMode(pmpb.PublicMetadata_DebugMode(9)); !errors.Is(err, ErrInvalidDebugMode) {
+		t.Errorf("SetDebugMode(9) returned error: %v, want error: %v", err, ErrInvalidDebugMode)
+	}
+}
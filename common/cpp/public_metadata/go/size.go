@@ -0,0 +1,57 @@
+package binarymetadata
+
+import "fmt"
+
+// SerializedSize returns the exact length of Serialize(bs) without crossing into C++ or
+// allocating the encoding. It fails for the metadata that Serialize would reject for missing or
+// unencodable fields.
+func SerializedSize(bs *BinaryStruct) (int, error) {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+	if err := bs.checkFreed(); err != nil {
+		return 0, err
+	}
+	version := bs.md().GetVersion()
+	if version > maxVersion {
+		return 0, fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+	exp := bs.expiration()
+	if exp == nil {
+		return 0, fmt.Errorf("%w: missing expiration", ErrMissingField)
+	}
+	if err := checkExpirationSeconds(exp.GetSeconds()); err != nil {
+		return 0, err
+	}
+	geo := bs.geoHint()
+	if !isSet(bs.md().GetCountry()) {
+		return 0, fmt.Errorf("%w: missing country in geo information", ErrMissingField)
+	}
+	if !isSet(bs.md().GetService_type()) {
+		return 0, fmt.Errorf("%w: missing service type", ErrMissingField)
+	}
+	if _, ok := serviceTypeIDs[bs.serviceType()]; !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnsupportedServiceType, bs.serviceType())
+	}
+	// The list length, then each extension header and value: the expiration's precision and
+	// timestamp, the length-prefixed "COUNTRY,REGION,CITY" geo hint, and one byte each for the
+	// service type and the debug mode. Upper-casing is ASCII only and keeps the length.
+	size := 2 + (4 + 16) + (4 + 2 + len(geo.Country) + 1 + len(geo.Region) + 1 + len(geo.City)) + (4 + 1) + (4 + 1)
+	w := bs.md().GetProxy_layer()
+	hasProxyLayer := version >= 2 && isSet(w)
+	if hasProxyLayer {
+		if w.Value() > 1 {
+			return 0, fmt.Errorf("%w: unmapped wire value %d", ErrInvalidProxyLayer, w.Value())
+		}
+		size += 4 + 1
+	}
+	if int32(version) > impliedVersion(hasProxyLayer) {
+		size += 4 + 1
+	}
+	for _, e := range bs.extra {
+		size += 4 + len(e.Value)
+	}
+	if size-2 > 0xffff {
+		return 0, fmt.Errorf("%w: extensions list of %d bytes is too long", ErrMalformed, size-2)
+	}
+	return size, nil
+}
@@ -0,0 +1,71 @@
+package binarymetadata
+
+import (
+	"errors"
+	"testing"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	plpb "google3/privacy/net/common/proto/proxy_layer_go_proto"
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+)
+
+func TestSerializedSize(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields NewBinaryFields
+		extra  []byte
+	}{
+		{name: "v1 country", fields: NewBinaryFields{Version: 1, Country: "US"}},
+		{name: "v1 city", fields: NewBinaryFields{Version: 1, Country: "us", Region: "us-ca", City: "Sunnyvale", DebugMode: pmpb.PublicMetadata_DEBUG_ALL}},
+		{name: "v2 proxy layer", fields: NewBinaryFields{Version: 2, Country: "US", Region: "US-NY", City: "NEW YORK CITY", ProxyLayer: plpb.ProxyLayer_PROXY_B}},
+		{name: "v2 unset proxy layer", fields: NewBinaryFields{Version: 2, Country: "US"}},
+		{name: "extra extension", fields: NewBinaryFields{Version: 2, Country: "US", ProxyLayer: plpb.ProxyLayer_PROXY_A}, extra: []byte("opaque")},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.fields.ServiceType = ServiceTypeChromeIPBlinding
+			tc.fields.Expiration = &tpb.Timestamp{Seconds: 900}
+			bs := New(&tc.fields)
+			defer bs.Free()
+			if tc.extra != nil {
+				if err := bs.SetExtension(0xF0FF, tc.extra); err != nil {
+					t.Fatalf("SetExtension failed: %v", err)
+				}
+			}
+			out, err := Serialize(bs)
+			if err != nil {
+				t.Fatalf("Serialize failed: %v", err)
+			}
+			got, err := SerializedSize(bs)
+			if err != nil {
+				t.Fatalf("SerializedSize failed: %v", err)
+			}
+			if got != len(out) {
+				t.Errorf("SerializedSize() = %d, want %d", got, len(out))
+			}
+		})
+	}
+}
+
+func TestSerializedSizeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		fields  NewBinaryFields
+		wantErr error
+	}{
+		{name: "unknown service type", fields: NewBinaryFields{Version: 1, Country: "US", ServiceType: ServiceTypeCronet, Expiration: &tpb.Timestamp{Seconds: 900}}, wantErr: ErrUnsupportedServiceType},
+		{name: "unknown version", fields: NewBinaryFields{Version: 4, Country: "US", ServiceType: ServiceTypeChromeIPBlinding, Expiration: &tpb.Timestamp{Seconds: 900}}, wantErr: ErrUnknownVersion},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bs := New(&tc.fields)
+			defer bs.Free()
+			if _, err := SerializedSize(bs); !errors.Is(err, tc.wantErr) {
+				t.Errorf("SerializedSize() returned error: %v, want error: %v", err, tc.wantErr)
+			}
+			if _, err := Serialize(bs); err == nil {
+				t.Error("Serialize() succeeded, want it to fail like SerializedSize")
+			}
+		})
+	}
+}
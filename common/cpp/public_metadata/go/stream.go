@@ -0,0 +1,64 @@
+package binarymetadata
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// Serialized metadata starts with the uint16 length of the extensions that follow, so blobs are
+// self-delimiting and can be written back to back on a stream without further framing.
+
+// WriteTo writes the serialized metadata to w. It implements io.WriterTo.
+func (bs *BinaryStruct) WriteTo(w io.Writer) (int64, error) {
+	out, err := Serialize(bs)
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(out)
+	return int64(n), err
+}
+
+// readBlob reads one length-prefixed blob from r. It returns io.EOF only if r is exhausted before
+// the first byte, and io.ErrUnexpectedEOF if it ends inside the blob.
+func readBlob(r io.Reader) ([]byte, error) {
+	var prefix [2]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return nil, err
+	}
+	blob := make([]byte, 2+int(binary.BigEndian.Uint16(prefix[:])))
+	copy(blob, prefix[:])
+	if _, err := io.ReadFull(r, blob[2:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return blob, nil
+}
+
+// DecodeFrom reads one serialized blob from r and deserializes it. It reads exactly the bytes of
+// the blob, so further blobs can be decoded from the same reader. At the end of the stream it
+// returns io.EOF.
+func DecodeFrom(r io.Reader) (*BinaryStruct, error) {
+	blob, err := readBlob(r)
+	if err != nil {
+		return nil, err
+	}
+	return Deserialize(blob)
+}
+
+// ReadFrom reads one serialized blob from r and replaces the contents of bs with it. It
+// implements io.ReaderFrom, but unlike most implementations stops after a single blob rather than
+// reading r to the end. bs is left unchanged on error.
+func (bs *BinaryStruct) ReadFrom(r io.Reader) (int64, error) {
+	blob, err := readBlob(r)
+	if err != nil {
+		return 0, err
+	}
+	parsed, err := Deserialize(blob)
+	if err == nil {
+		err = bs.adopt(parsed)
+	}
+	bs.audit(AuditMutate, "read_from", err)
+	return int64(len(blob)), err
+}
@@ -0,0 +1,75 @@
+package binarymetadata
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	plpb "google3/privacy/net/common/proto/proxy_layer_go_proto"
+)
+
+func TestWriteToDecodeFrom(t *testing.T) {
+	var buf bytes.Buffer
+	var want []*BinaryStruct
+	for _, country := range []string{"US", "CA", "MX"} {
+		bs := New(&NewBinaryFields{
+			Version:     2,
+			Country:     country,
+			ServiceType: "chromeipblinding",
+			Expiration:  &tpb.Timestamp{Seconds: 900},
+			ProxyLayer:  plpb.ProxyLayer_PROXY_A,
+		})
+		defer bs.Free()
+		if _, err := bs.WriteTo(&buf); err != nil {
+			t.Fatalf("WriteTo failed: %v", err)
+		}
+		want = append(want, bs)
+	}
+	for i, w := range want {
+		got, err := DecodeFrom(&buf)
+		if err != nil {
+			t.Fatalf("DecodeFrom #%d failed: %v", i, err)
+		}
+		if !Equal(got, w) {
+			t.Errorf("DecodeFrom #%d differs: %v", i, Diff(got, w))
+		}
+		got.Free()
+	}
+	if _, err := DecodeFrom(&buf); err != io.EOF {
+		t.Errorf("DecodeFrom(exhausted) returned error: %v, want io.EOF", err)
+	}
+}
+
+func TestReadFrom(t *testing.T) {
+	src := New(&NewBinaryFields{
+		Version:     1,
+		Country:     "US",
+		ServiceType: "chromeipblinding",
+		Expiration:  &tpb.Timestamp{Seconds: 900},
+	})
+	defer src.Free()
+	var buf bytes.Buffer
+	n, err := src.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	blob := bytes.Clone(buf.Bytes())
+
+	dst := New(&NewBinaryFields{Version: 2, Country: "CA"})
+	defer dst.Free()
+	if m, err := dst.ReadFrom(&buf); err != nil || m != n {
+		t.Fatalf("ReadFrom() = %d, %v, want %d, nil", m, err, n)
+	}
+	if !Equal(src, dst) {
+		t.Errorf("ReadFrom() differs from source: %v", Diff(src, dst))
+	}
+
+	if _, err := dst.ReadFrom(bytes.NewReader(blob[:len(blob)-1])); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("ReadFrom(truncated) returned error: %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+	if got := dst.GetGeoHint().Country; got != "US" {
+		t.Errorf("ReadFrom(truncated) changed country to %q", got)
+	}
+}
@@ -0,0 +1,103 @@
+package binarymetadata
+
+import (
+	"fmt"
+	"sync/atomic"
+	"unicode"
+	"unicode/utf8"
+)
+
+// StringLimits bounds the length in bytes of the string fields that NewChecked and Deserialize
+// accept. A zero limit uses the default.
+type StringLimits struct {
+	// Country defaults to 3, the length of an ISO 3166-1 alpha-3 code.
+	Country int
+	// Region defaults to 6, the length of the longest ISO 3166-2 code, e.g. "GB-ENG".
+	Region int
+	// City defaults to 128.
+	City int
+	// ServiceType defaults to 64.
+	ServiceType int
+}
+
+var defaultStringLimits = StringLimits{Country: 3, Region: 6, City: 128, ServiceType: 64}
+
+var registeredStringLimits atomic.Pointer[StringLimits]
+
+// SetStringLimits replaces the limits checked by NewChecked and Deserialize. It is meant to be
+// called once during process start up.
+func SetStringLimits(l StringLimits) {
+	registeredStringLimits.Store(&l)
+}
+
+// stringLimits returns the registered limits with zero limits replaced by the defaults.
+func stringLimits() StringLimits {
+	l := defaultStringLimits
+	if r := registeredStringLimits.Load(); r != nil {
+		for _, p := range []struct{ dst, src *int }{
+			{&l.Country, &r.Country},
+			{&l.Region, &r.Region},
+			{&l.City, &r.City},
+			{&l.ServiceType, &r.ServiceType},
+		} {
+			if *p.src != 0 {
+				*p.dst = *p.src
+			}
+		}
+	}
+	return l
+}
+
+// maxDisplayedValue is how much of a rejected string FieldError.Value keeps, so that an oversized
+// value does not end up in logs in full.
+const maxDisplayedValue = 32
+
+// checkTextFields rejects string fields that are not valid UTF-8, contain control or formatting
+// characters, or are longer than the registered StringLimits. The error is a *FieldError wrapping
+// the sentinel of the field.
+func checkTextFields(country, region, city, serviceType string) error {
+	l := stringLimits()
+	for _, f := range []struct {
+		name, value string
+		max         int
+		kind        error
+	}{
+		{"service_type", serviceType, l.ServiceType, ErrUnsupportedServiceType},
+		{"country", country, l.Country, ErrInvalidCountry},
+		{"region", region, l.Region, ErrInvalidGeoHint},
+		{"city", city, l.City, ErrInvalidGeoHint},
+	} {
+		if err := checkText(f.value, f.max); err != nil {
+			return &FieldError{Field: f.name, Value: truncateForDisplay(f.value), Err: fmt.Errorf("%w: %v", f.kind, err)}
+		}
+	}
+	return nil
+}
+
+func checkText(s string, max int) error {
+	if len(s) > max {
+		return fmt.Errorf("%d bytes, want at most %d", len(s), max)
+	}
+	if !utf8.ValidString(s) {
+		return fmt.Errorf("not valid UTF-8")
+	}
+	for _, r := range s {
+		if unicode.IsControl(r) || unicode.Is(unicode.Cf, r) {
+			return fmt.Errorf("contains the control character %U", r)
+		}
+	}
+	return nil
+}
+
+func truncateForDisplay(s string) string {
+	if len(s) <= maxDisplayedValue {
+		return s
+	}
+	return s[:maxDisplayedValue] + "..."
+}
+
+// checkTextFields is checkTextFields for the fields of bs. The caller holds the lock.
+func (bs *BinaryStruct) checkTextFields() error {
+	geo := bs.geoHint()
+	return checkTextFields(geo.Country, geo.Region, geo.City, bs.serviceType())
+}
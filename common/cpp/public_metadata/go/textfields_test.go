@@ -0,0 +1,109 @@
+package binarymetadata
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+)
+
+func TestTextFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields NewBinaryFields
+		field  string
+		want   error
+	}{
+		{name: "valid", fields: NewBinaryFields{Country: "US", Region: "US-CA", City: "SÃO PAULO"}},
+		{name: "long country", fields: NewBinaryFields{Country: "USAA"}, field: "country", want: ErrInvalidCountry},
+		{name: "long region", fields: NewBinaryFields{Country: "US", Region: "US-CALI"}, field: "region", want: ErrInvalidGeoHint},
+		{name: "long city", fields: NewBinaryFields{Country: "US", City: strings.Repeat("A", 129)}, field: "city", want: ErrInvalidGeoHint},
+		{name: "newline in city", fields: NewBinaryFields{Country: "US", City: "SUNNYVALE\nFAKE LOG LINE"}, field: "city", want: ErrInvalidGeoHint},
+		{name: "escape in region", fields: NewBinaryFields{Country: "US", Region: "US\x1b[0m"}, field: "region", want: ErrInvalidGeoHint},
+		{name: "bidi override in city", fields: NewBinaryFields{Country: "US", City: "A‮B"}, field: "city", want: ErrInvalidGeoHint},
+		{name: "invalid UTF-8", fields: NewBinaryFields{Country: "US", City: "\xff\xfe"}, field: "city", want: ErrInvalidGeoHint},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.fields.Version = 1
+			tc.fields.ServiceType = ServiceTypeChromeIPBlinding
+			tc.fields.Expiration = &tpb.Timestamp{Seconds: 900}
+			checkField := func(op string, err error) {
+				t.Helper()
+				if tc.want == nil {
+					if err != nil {
+						t.Fatalf("%s failed: %v", op, err)
+					}
+					return
+				}
+				var fe *FieldError
+				if !errors.As(err, &fe) || fe.Field != tc.field || !errors.Is(err, tc.want) {
+					t.Fatalf("%s = %v, want a FieldError for %s wrapping %v", op, err, tc.field, tc.want)
+				}
+			}
+
+			bs, err := NewChecked(&tc.fields)
+			if err == nil {
+				bs.Free()
+			}
+			checkField("NewChecked", err)
+
+			unchecked := New(&tc.fields)
+			defer unchecked.Free()
+			in, err := Serialize(unchecked)
+			if err != nil {
+				t.Fatalf("Serialize failed: %v", err)
+			}
+			out, err := Deserialize(in)
+			if err == nil {
+				out.Free()
+			}
+			checkField("Deserialize", err)
+			out, err = DeserializeOptions{Strict: true}.Deserialize(in)
+			if err == nil {
+				out.Free()
+			}
+			checkField("strict Deserialize", err)
+			_, err = DeserializeGo(in)
+			checkField("DeserializeGo", err)
+		})
+	}
+}
+
+func TestSetStringLimits(t *testing.T) {
+	t.Cleanup(func() { SetStringLimits(StringLimits{}) })
+	fields := &NewBinaryFields{
+		Version:     1,
+		ServiceType: ServiceTypeChromeIPBlinding,
+		Expiration:  &tpb.Timestamp{Seconds: 900},
+		Country:     "US",
+		City:        "SUNNYVALE",
+	}
+	SetStringLimits(StringLimits{City: 4})
+	if _, err := NewChecked(fields); !errors.Is(err, ErrInvalidGeoHint) {
+		t.Errorf("NewChecked with a city limit of 4 = %v, want ErrInvalidGeoHint", err)
+	}
+	SetStringLimits(StringLimits{Country: 1})
+	if _, err := NewChecked(fields); !errors.Is(err, ErrInvalidCountry) {
+		t.Errorf("NewChecked with a country limit of 1 = %v, want ErrInvalidCountry", err)
+	}
+	SetStringLimits(StringLimits{})
+	bs, err := NewChecked(fields)
+	if err != nil {
+		t.Fatalf("NewChecked with the default limits failed: %v", err)
+	}
+	bs.Free()
+}
+
+func TestTextFieldErrorTruncatesValue(t *testing.T) {
+	long := strings.Repeat("A", 1000)
+	err := checkTextFields("US", "", long, ServiceTypeChromeIPBlinding)
+	var fe *FieldError
+	if !errors.As(err, &fe) {
+		t.Fatalf("checkTextFields = %v, want a FieldError", err)
+	}
+	if len(fe.Value) > maxDisplayedValue+len("...") {
+		t.Errorf("FieldError.Value has %d bytes, want at most %d", len(fe.Value), maxDisplayedValue+len("..."))
+	}
+}
@@ -0,0 +1,40 @@
+package binarymetadata
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// messagePrefix is the domain separator the partially blind RSA scheme prepends to messages
+// signed with public metadata.
+const messagePrefix = "msg"
+
+// EncodeMessagePublicMetadata returns the byte string a partially blind RSA signature with public
+// metadata covers: "msg", the length of publicMetadata as a big-endian uint32, publicMetadata and
+// message. It matches EncodeMessagePublicMetadata in the C++ anonymous tokens library.
+func EncodeMessagePublicMetadata(message, publicMetadata []byte) ([]byte, error) {
+	if uint64(len(publicMetadata)) > math.MaxUint32 {
+		return nil, fmt.Errorf("%w: public metadata of %d bytes is too long", ErrMalformed, len(publicMetadata))
+	}
+	out := make([]byte, 0, len(messagePrefix)+4+len(publicMetadata)+len(message))
+	out = append(out, messagePrefix...)
+	out = binary.BigEndian.AppendUint32(out, uint32(len(publicMetadata)))
+	out = append(out, publicMetadata...)
+	return append(out, message...), nil
+}
+
+// BuildTokenInput returns the byte string that is signed and verified for an AT_PUBLIC_METADATA
+// token over nonce, the token's random message: the serialized metadata bound to nonce by
+// EncodeMessagePublicMetadata.
+func BuildTokenInput(metadata *BinaryStruct, nonce []byte) ([]byte, error) {
+	if len(nonce) == 0 {
+		return nil, errors.New("empty nonce")
+	}
+	exts, err := Serialize(metadata)
+	if err != nil {
+		return nil, err
+	}
+	return EncodeMessagePublicMetadata(nonce, exts)
+}
@@ -0,0 +1,48 @@
+package binarymetadata
+
+import (
+	"bytes"
+	"testing"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+)
+
+func TestEncodeMessagePublicMetadata(t *testing.T) {
+	got, err := EncodeMessagePublicMetadata([]byte("hello"), []byte{0xaa, 0xbb})
+	if err != nil {
+		t.Fatalf("EncodeMessagePublicMetadata failed: %v", err)
+	}
+	want := []byte{'m', 's', 'g', 0x00, 0x00, 0x00, 0x02, 0xaa, 0xbb, 'h', 'e', 'l', 'l', 'o'}
+	if !bytes.Equal(got, want) {
+		t.Errorf("EncodeMessagePublicMetadata() = %x, want %x", got, want)
+	}
+}
+
+func TestBuildTokenInput(t *testing.T) {
+	bs := New(&NewBinaryFields{
+		Version:     1,
+		Country:     "US",
+		ServiceType: "chromeipblinding",
+		Expiration:  &tpb.Timestamp{Seconds: 900},
+	})
+	defer bs.Free()
+	exts, err := Serialize(bs)
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	nonce := bytes.Repeat([]byte{0x01}, 32)
+	got, err := BuildTokenInput(bs, nonce)
+	if err != nil {
+		t.Fatalf("BuildTokenInput failed: %v", err)
+	}
+	want, err := EncodeMessagePublicMetadata(nonce, exts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("BuildTokenInput() = %x, want %x", got, want)
+	}
+	if _, err := BuildTokenInput(bs, nil); err == nil {
+		t.Error("BuildTokenInput(nil nonce) succeeded, want error")
+	}
+}
@@ -0,0 +1,70 @@
+package binarymetadata
+
+import (
+	"fmt"
+	"slices"
+)
+
+// TokenType is a Privacy Pass token type, as carried in the first two bytes of a token.
+type TokenType uint16
+
+// Token types that metadata is issued for.
+const (
+	// TokenTypeBlindRSA is the publicly verifiable token type of RFC 9578. It carries no metadata.
+	TokenTypeBlindRSA TokenType = 0x0002
+	// TokenTypePublicMetadata is the RSA blind signature with public metadata token type that
+	// AT_PUBLIC_METADATA keys sign. The serialized metadata is bound into the signature.
+	TokenTypePublicMetadata TokenType = 0xDA7A
+)
+
+// tokenTypeVersions lists the metadata versions each token type accepts.
+var tokenTypeVersions = map[TokenType][]int32{
+	TokenTypeBlindRSA:       nil,
+	TokenTypePublicMetadata: {1, 2, 3},
+}
+
+func (t TokenType) String() string {
+	switch t {
+	case TokenTypeBlindRSA:
+		return "BLIND_RSA"
+	case TokenTypePublicMetadata:
+		return "AT_PUBLIC_METADATA"
+	}
+	return fmt.Sprintf("TokenType(%#04x)", uint16(t))
+}
+
+// MetadataVersions returns the metadata versions t accepts, in ascending order. It fails for
+// token types that carry no metadata.
+func MetadataVersions(t TokenType) ([]int32, error) {
+	versions, ok := tokenTypeVersions[t]
+	if !ok || len(versions) == 0 {
+		return nil, fmt.Errorf("%w: %v carries no public metadata", ErrUnsupportedTokenType, t)
+	}
+	return slices.Clone(versions), nil
+}
+
+// CheckTokenType checks that metadata of version can be carried by tokens of type t.
+func CheckTokenType(t TokenType, version int32) error {
+	versions, err := MetadataVersions(t)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(versions, version) {
+		return fmt.Errorf("%w: %v does not accept metadata version %d", ErrUnsupportedTokenType, t, version)
+	}
+	return nil
+}
+
+// TokenType returns the token type to issue for bs.
+func (bs *BinaryStruct) TokenType() (TokenType, error) {
+	bs.mu.RLock()
+	err := bs.checkFreed()
+	bs.mu.RUnlock()
+	if err != nil {
+		return 0, err
+	}
+	if err := CheckTokenType(TokenTypePublicMetadata, bs.GetVersion()); err != nil {
+		return 0, err
+	}
+	return TokenTypePublicMetadata, nil
+}
@@ -0,0 +1,40 @@
+package binarymetadata
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTokenType(t *testing.T) {
+	for _, v := range []int32{1, 2, 3} {
+		bs := New(&NewBinaryFields{Version: v})
+		got, err := bs.TokenType()
+		bs.Free()
+		if err != nil || got != TokenTypePublicMetadata {
+			t.Errorf("TokenType() of version %d = %v, %v, want %v", v, got, err, TokenTypePublicMetadata)
+		}
+	}
+	bs := New(&NewBinaryFields{Version: 4})
+	defer bs.Free()
+	if _, err := bs.TokenType(); !errors.Is(err, ErrUnsupportedTokenType) {
+		t.Errorf("TokenType() of version 4 returned error: %v, want error: %v", err, ErrUnsupportedTokenType)
+	}
+}
+
+func TestCheckTokenType(t *testing.T) {
+	tests := []struct {
+		tokenType TokenType
+		version   int32
+		wantErr   error
+	}{
+		{tokenType: TokenTypePublicMetadata, version: 2},
+		{tokenType: TokenTypePublicMetadata, version: 0, wantErr: ErrUnsupportedTokenType},
+		{tokenType: TokenTypeBlindRSA, version: 1, wantErr: ErrUnsupportedTokenType},
+		{tokenType: TokenType(0x1234), version: 1, wantErr: ErrUnsupportedTokenType},
+	}
+	for _, tc := range tests {
+		if err := CheckTokenType(tc.tokenType, tc.version); !errors.Is(err, tc.wantErr) {
+			t.Errorf("CheckTokenType(%v, %d) returned error: %v, want error: %v", tc.tokenType, tc.version, err, tc.wantErr)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package binarymetadata
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Tracer starts spans around the CGO calls made by SerializeContext, DeserializeContext and
+// ValidateMetadataCardinalityContext. It is shaped so that an OpenTelemetry trace.Tracer can be
+// adapted in a few lines: Start maps onto Tracer.Start with the operation as the span name, and
+// Span.End onto setting the attributes, recording the error and calling Span.End.
+type Tracer interface {
+	// Start begins a span for op as a child of any span in ctx.
+	Start(ctx context.Context, op Operation) Span
+}
+
+// Span is a single traced operation started by a Tracer.
+type Span interface {
+	// End finishes the span. err is the result of the call, nil on success.
+	End(attrs SpanAttributes, err error)
+}
+
+// SpanAttributes describe a traced call.
+type SpanAttributes struct {
+	// Version is the metadata version, or 0 if it is not known, e.g. because the input did not
+	// parse.
+	Version int32
+	// Size is the length in bytes of the serialized metadata that was read or written, or 0 if
+	// none was.
+	Size int
+	// ErrorKind is the result of ErrorKind for the error, nil on success or when unclassified.
+	ErrorKind error
+}
+
+// tracerHolder wraps a Tracer so that it can be stored in an atomic.Pointer.
+type tracerHolder struct {
+	t Tracer
+}
+
+var registeredTracer atomic.Pointer[tracerHolder]
+
+// SetTracer registers t to trace the Context variants of Serialize, Deserialize and
+// ValidateMetadataCardinality, replacing any earlier registration. nil disables tracing.
+func SetTracer(t Tracer) {
+	if t == nil {
+		registeredTracer.Store(nil)
+		return
+	}
+	registeredTracer.Store(&tracerHolder{t: t})
+}
+
+// startSpan starts a span for op, returning nil when no Tracer is registered.
+func startSpan(ctx context.Context, op Operation) Span {
+	h := registeredTracer.Load()
+	if h == nil {
+		return nil
+	}
+	return h.t.Start(ctx, op)
+}
+
+// endSpan ends span, if any, with the attributes of a call on bs and in that returned err.
+func endSpan(span Span, bs *BinaryStruct, in []byte, err error) {
+	if span == nil {
+		return
+	}
+	attrs := SpanAttributes{Size: len(in), ErrorKind: ErrorKind(err)}
+	if bs != nil {
+		bs.mu.RLock()
+		if bs.metadata != nil {
+			attrs.Version = int32(bs.md().GetVersion())
+		}
+		bs.mu.RUnlock()
+	}
+	span.End(attrs, err)
+}
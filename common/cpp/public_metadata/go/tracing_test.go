@@ -0,0 +1,85 @@
+package binarymetadata
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+)
+
+type fakeSpan struct {
+	op    Operation
+	attrs SpanAttributes
+	err   error
+}
+
+type fakeTracer struct {
+	mu    sync.Mutex
+	spans []*fakeSpan
+}
+
+type fakeSpanEnder struct {
+	t *fakeTracer
+	s *fakeSpan
+}
+
+func (f *fakeTracer) Start(ctx context.Context, op Operation) Span {
+	return fakeSpanEnder{t: f, s: &fakeSpan{op: op}}
+}
+
+func (e fakeSpanEnder) End(attrs SpanAttributes, err error) {
+	e.t.mu.Lock()
+	defer e.t.mu.Unlock()
+	e.s.attrs = attrs
+	e.s.err = err
+	e.t.spans = append(e.t.spans, e.s)
+}
+
+func TestTracer(t *testing.T) {
+	tr := &fakeTracer{}
+	SetTracer(tr)
+	defer SetTracer(nil)
+	ctx := context.Background()
+
+	bs := New(&NewBinaryFields{
+		Version:     1,
+		Country:     "US",
+		ServiceType: "chromeipblinding",
+		Expiration:  &tpb.Timestamp{Seconds: 900},
+	})
+	defer bs.Free()
+	out, err := SerializeContext(ctx, bs)
+	if err != nil {
+		t.Fatalf("SerializeContext failed: %v", err)
+	}
+	parsed, err := DeserializeContext(ctx, out)
+	if err != nil {
+		t.Fatalf("DeserializeContext failed: %v", err)
+	}
+	parsed.Free()
+	if err := ValidateMetadataCardinalityContext(ctx, out, time.Unix(0, 0)); err != nil {
+		t.Fatalf("ValidateMetadataCardinalityContext failed: %v", err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := SerializeContext(cancelled, bs); err == nil {
+		t.Fatal("SerializeContext(cancelled) succeeded, want error")
+	}
+
+	want := []fakeSpan{
+		{op: OperationSerialize, attrs: SpanAttributes{Version: 1, Size: len(out)}},
+		{op: OperationDeserialize, attrs: SpanAttributes{Version: 1, Size: len(out)}},
+		{op: OperationValidateMetadataCardinality, attrs: SpanAttributes{Size: len(out)}},
+	}
+	if len(tr.spans) != len(want) {
+		t.Fatalf("got %d spans, want %d", len(tr.spans), len(want))
+	}
+	for i, w := range want {
+		if got := *tr.spans[i]; got != w {
+			t.Errorf("span %d = %+v, want %+v", i, got, w)
+		}
+	}
+}
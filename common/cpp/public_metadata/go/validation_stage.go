@@ -0,0 +1,58 @@
+package binarymetadata
+
+import "time"
+
+// validationStage selects the rules of a Validator that apply.
+type validationStage int
+
+const (
+	// stageAny applies every rule, tolerating ClockSkew.
+	stageAny validationStage = iota
+	// stageIssuance applies every rule without tolerating any skew.
+	stageIssuance
+	// stageRedemption skips the rules that the issuer enforced and the signature binds.
+	stageRedemption
+)
+
+// ValidateForIssuance strictly deserializes in and checks it before it is signed at time t. Unlike
+// Validate it tolerates no ClockSkew, since t is the clock of the issuer itself: the expiration
+// must be after t and at most MaxTimeToLive after it.
+func (v *Validator) ValidateForIssuance(in []byte, t time.Time) error {
+	bs, err := DeserializeOptions{Strict: true, WireFormat: v.cfg.WireFormat}.Deserialize(in)
+	if err != nil {
+		return err
+	}
+	defer bs.Free()
+	return v.ValidateStructForIssuance(bs, t)
+}
+
+// ValidateStructForIssuance is like ValidateForIssuance for metadata that has already been
+// deserialized.
+func (v *Validator) ValidateStructForIssuance(bs *BinaryStruct, t time.Time) error {
+	err := v.validateStruct(bs, t, stageIssuance)
+	bs.auditTagged(AuditValidate, "validate_for_issuance", v.cfg.AuditTag, err)
+	return err
+}
+
+// ValidateForRedemption strictly deserializes in and checks it when a token bound to it is
+// redeemed at time t. The expiration is checked with ClockSkew and the version against
+// AcceptedVersions, but the expiration bucket and the Policy are not: the issuer enforced them, and
+// metadata issued under an earlier configuration is still redeemable. Replays are not checked
+// either: a batch of tokens shares one metadata, so only the token identifies a redemption, and
+// RedemptionVerifier.ReplayCache tracks them.
+func (v *Validator) ValidateForRedemption(in []byte, t time.Time) error {
+	bs, err := DeserializeOptions{Strict: true, WireFormat: v.cfg.WireFormat}.Deserialize(in)
+	if err != nil {
+		return err
+	}
+	defer bs.Free()
+	return v.ValidateStructForRedemption(bs, t)
+}
+
+// ValidateStructForRedemption is like ValidateForRedemption for metadata that has already been
+// deserialized.
+func (v *Validator) ValidateStructForRedemption(bs *BinaryStruct, t time.Time) error {
+	err := v.validateStruct(bs, t, stageRedemption)
+	bs.auditTagged(AuditValidate, "validate_for_redemption", v.cfg.AuditTag, err)
+	return err
+}
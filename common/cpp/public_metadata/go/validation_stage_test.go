@@ -0,0 +1,109 @@
+package binarymetadata
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	polpb "google3/privacy/net/common/proto/public_metadata_policy_go_proto"
+)
+
+func TestValidationStages(t *testing.T) {
+	// 18:45, a multiple of 15 minutes but not of an hour.
+	now := time.Unix(1701110700, 0)
+	p, err := NewPolicy(&polpb.PublicMetadataPolicy{Rules: []*polpb.PublicMetadataPolicy_Rule{{
+		Name:    "no_cities",
+		When:    &polpb.PublicMetadataPolicy_Match{GeoLevels: []polpb.PublicMetadataPolicy_GeoLevel{polpb.PublicMetadataPolicy_GEO_LEVEL_CITY}},
+		Require: &polpb.PublicMetadataPolicy_Match{ServiceTypes: []string{ServiceTypeCronet}},
+	}}})
+	if err != nil {
+		t.Fatalf("NewPolicy failed: %v", err)
+	}
+	tests := []struct {
+		name           string
+		cfg            ValidationConfig
+		fields         NewBinaryFields
+		t              time.Time
+		wantAny        error
+		wantIssuance   error
+		wantRedemption error
+	}{
+		{
+			name:   "valid",
+			fields: NewBinaryFields{Version: 1, Expiration: tpb.New(now.Add(time.Hour))},
+			t:      now,
+		},
+		{
+			name:         "expired within skew",
+			cfg:          ValidationConfig{ClockSkew: 5 * time.Minute},
+			fields:       NewBinaryFields{Version: 1, Expiration: tpb.New(now)},
+			t:            now.Add(time.Minute),
+			wantIssuance: ErrExpired,
+		},
+		{
+			name:         "expires at issuance",
+			fields:       NewBinaryFields{Version: 1, Expiration: tpb.New(now)},
+			t:            now,
+			wantIssuance: ErrExpired,
+		},
+		{
+			name:         "too far within skew",
+			cfg:          ValidationConfig{MaxTimeToLive: time.Hour, ClockSkew: 15 * time.Minute},
+			fields:       NewBinaryFields{Version: 1, Expiration: tpb.New(now.Add(75 * time.Minute))},
+			t:            now,
+			wantIssuance: ErrExpirationTooFar,
+		},
+		{
+			name:         "off bucket",
+			cfg:          ValidationConfig{ExpirationBucket: time.Hour},
+			fields:       NewBinaryFields{Version: 1, Expiration: tpb.New(now.Add(time.Hour))},
+			t:            now,
+			wantAny:      ErrExpirationNotRounded,
+			wantIssuance: ErrExpirationNotRounded,
+		},
+		{
+			name:         "policy",
+			cfg:          ValidationConfig{Policy: p},
+			fields:       NewBinaryFields{Version: 1, Region: "US-CA", City: "MOUNTAIN VIEW", Expiration: tpb.New(now.Add(time.Hour))},
+			t:            now,
+			wantAny:      ErrPolicyViolation,
+			wantIssuance: ErrPolicyViolation,
+		},
+		{
+			name:           "version not accepted",
+			cfg:            ValidationConfig{AcceptedVersions: []int32{2}},
+			fields:         NewBinaryFields{Version: 1, Expiration: tpb.New(now.Add(time.Hour))},
+			t:              now,
+			wantAny:        ErrUnknownVersion,
+			wantIssuance:   ErrUnknownVersion,
+			wantRedemption: ErrUnknownVersion,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := NewValidator(tc.cfg)
+			if err != nil {
+				t.Fatalf("NewValidator failed: %v", err)
+			}
+			f := tc.fields
+			f.Country, f.ServiceType = "US", ServiceTypeChromeIPBlinding
+			in := serializeForTest(t, &f)
+			if err := v.Validate(in, tc.t); !errors.Is(err, tc.wantAny) {
+				t.Errorf("Validate() returned error: %v, want error: %v", err, tc.wantAny)
+			}
+			if err := v.ValidateForIssuance(in, tc.t); !errors.Is(err, tc.wantIssuance) {
+				t.Errorf("ValidateForIssuance() returned error: %v, want error: %v", err, tc.wantIssuance)
+			}
+			if err := v.ValidateForRedemption(in, tc.t); !errors.Is(err, tc.wantRedemption) {
+				t.Errorf("ValidateForRedemption() returned error: %v, want error: %v", err, tc.wantRedemption)
+			}
+		})
+	}
+}
+
+func TestNewValidatorRejectsUnknownAcceptedVersions(t *testing.T) {
+	if _, err := NewValidator(ValidationConfig{AcceptedVersions: []int32{99}}); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("NewValidator() returned error: %v, want error: %v", err, ErrUnknownVersion)
+	}
+}
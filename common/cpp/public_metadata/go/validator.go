@@ -0,0 +1,312 @@
+package binarymetadata
+
+import (
+	"fmt"
+	"time"
+
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+)
+
+// GeoGranularity is how precise a geo hint is.
+type GeoGranularity int
+
+// Geo granularities from coarsest to finest.
+const (
+	GeoCountry GeoGranularity = iota + 1
+	GeoRegion
+	GeoCity
+)
+
+// DebugModePolicy says whether metadata may request debugging.
+type DebugModePolicy int
+
+// Debug mode policies.
+const (
+	// DebugModeAllowed accepts any debug mode.
+	DebugModeAllowed DebugModePolicy = iota
+	// DebugModeForbidden rejects DEBUG_ALL.
+	DebugModeForbidden
+)
+
+// defaultMaxTimeToLive is how far in the future ValidateMetadataCardinality accepts expirations.
+const defaultMaxTimeToLive = 7 * 24 * time.Hour
+
+// ValidationConfig is the rule set a Validator enforces. The zero value matches the rules of
+// ValidateMetadataCardinality.
+type ValidationConfig struct {
+	// MaxGeoGranularity is the finest geo hint accepted. Zero accepts city level hints.
+	MaxGeoGranularity GeoGranularity
+	// ExpirationBucket is the boundary expirations, including their millisecond part, must fall on.
+	// It must be a whole number of milliseconds. Zero uses the granularity of the metadata version,
+	// which is 15 minutes for version 3 too, so that millisecond expirations are only accepted by
+	// validators configured with a finer bucket, such as those of short lived debug tokens.
+	ExpirationBucket time.Duration
+	// ExpirationBucketOffset moves the bucket boundaries that far after the multiples of the
+	// bucket, e.g. to align them with a key rotation at five past the hour. It must be a whole
+	// number of milliseconds smaller than ExpirationBucket; if ExpirationBucket is zero it is taken modulo
+	// the granularity of the version. Expirations off a boundary are rejected with a *BucketError.
+	ExpirationBucketOffset time.Duration
+	// MaxTimeToLive is how far after the validation time expirations may be. Zero means 7 days.
+	MaxTimeToLive time.Duration
+	// MaxTimeToLiveByServiceType overrides MaxTimeToLive for the service types it lists, so that
+	// services rotating keys more often can keep their metadata short lived.
+	MaxTimeToLiveByServiceType map[string]time.Duration
+	// ClockSkew is how far the clock of the issuer may be off from the validation time. Metadata
+	// that expired less than ClockSkew ago, or expires less than ClockSkew beyond MaxTimeToLive, is
+	// accepted with a "clock_skew" warning. Zero tolerates no skew.
+	ClockSkew time.Duration
+	// AllowedServiceTypes lists the accepted service types. Empty accepts every service type with a
+	// wire encoding.
+	AllowedServiceTypes []string
+	// AllowUnknownServiceTypes accepts service types that are neither built in nor registered with
+	// RegisterServiceType.
+	AllowUnknownServiceTypes bool
+	// DebugMode says whether DEBUG_ALL is accepted.
+	DebugMode DebugModePolicy
+	// DebugModeUntil, if set, rejects DEBUG_ALL metadata validated after it, so that debug tokens
+	// cannot outlive a rollout by accident.
+	DebugModeUntil time.Time
+	// GeoCatalog, if set, rejects regions and cities it does not know. DefaultGeoCatalog returns
+	// the catalog built into this package.
+	GeoCatalog GeoCatalog
+	// Clock is the time source of Now. Nil uses SystemClock.
+	Clock Clock
+	// Policy, if set, rejects metadata breaking any of its rules.
+	Policy *Policy
+	// AcceptedVersions lists the metadata versions accepted. Empty accepts every version this
+	// package supports. Redemption servers narrow it to phase out a version once no unexpired
+	// metadata of it remains.
+	AcceptedVersions []int32
+	// WireFormat is the draft revision of the metadata passed to Validate, ValidateForIssuance,
+	// ValidateForRedemption and Report. It must be registered with RegisterWireFormat.
+	WireFormat WireFormat
+	// AuditTag, if set, replaces the tag of the metadata in the validation decisions reported to
+	// the registered AuditSink.
+	AuditTag string
+}
+
+// Validator checks serialized metadata against a ValidationConfig. It is safe for concurrent use.
+type Validator struct {
+	cfg          ValidationConfig
+	serviceTypes map[string]bool
+	versions     map[int32]bool
+}
+
+// NewValidator returns a Validator enforcing cfg, or an error if cfg is inconsistent.
+func NewValidator(cfg ValidationConfig) (*Validator, error) {
+	if cfg.MaxGeoGranularity == 0 {
+		cfg.MaxGeoGranularity = GeoCity
+	}
+	if cfg.MaxGeoGranularity < GeoCountry || cfg.MaxGeoGranularity > GeoCity {
+		return nil, fmt.Errorf("invalid MaxGeoGranularity %d", cfg.MaxGeoGranularity)
+	}
+	if cfg.ExpirationBucket < 0 || cfg.ExpirationBucket%time.Millisecond != 0 {
+		return nil, fmt.Errorf("ExpirationBucket %v is negative or not a whole number of milliseconds", cfg.ExpirationBucket)
+	}
+	if o := cfg.ExpirationBucketOffset; o < 0 || o%time.Millisecond != 0 || (cfg.ExpirationBucket > 0 && o >= cfg.ExpirationBucket) {
+		return nil, fmt.Errorf("ExpirationBucketOffset %v is negative, not a whole number of milliseconds or not smaller than ExpirationBucket", o)
+	}
+	if cfg.MaxTimeToLive == 0 {
+		cfg.MaxTimeToLive = defaultMaxTimeToLive
+	}
+	if cfg.MaxTimeToLive < 0 {
+		return nil, fmt.Errorf("negative MaxTimeToLive %v", cfg.MaxTimeToLive)
+	}
+	for s, ttl := range cfg.MaxTimeToLiveByServiceType {
+		if ttl <= 0 {
+			return nil, fmt.Errorf("MaxTimeToLiveByServiceType %v of %q is not positive", ttl, s)
+		}
+	}
+	if cfg.ClockSkew < 0 {
+		return nil, fmt.Errorf("negative ClockSkew %v", cfg.ClockSkew)
+	}
+	if _, err := wireTranscoder(cfg.WireFormat); err != nil {
+		return nil, err
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = SystemClock
+	}
+	v := &Validator{cfg: cfg}
+	if len(cfg.AllowedServiceTypes) > 0 {
+		v.serviceTypes = map[string]bool{}
+		for _, s := range cfg.AllowedServiceTypes {
+			if _, ok := serviceTypeIDs[s]; !ok {
+				return nil, fmt.Errorf("%w: %q", ErrUnsupportedServiceType, s)
+			}
+			v.serviceTypes[s] = true
+		}
+	}
+	if len(cfg.AcceptedVersions) > 0 {
+		v.versions = map[int32]bool{}
+		for _, ver := range cfg.AcceptedVersions {
+			if _, err := Capabilities(ver); err != nil {
+				return nil, fmt.Errorf("AcceptedVersions: %w", err)
+			}
+			v.versions[ver] = true
+		}
+	}
+	return v, nil
+}
+
+// Now returns the current time of the Clock v was configured with, for callers that validate
+// against the present rather than a time carried by the request.
+func (v *Validator) Now() time.Time {
+	return v.cfg.Clock.Now()
+}
+
+// Validate deserializes in and checks it against the rules of v at time t. It returns the first
+// violation as a *FieldError. Issuers and redemption servers should use ValidateForIssuance and
+// ValidateForRedemption, which apply the rules of their side only.
+func (v *Validator) Validate(in []byte, t time.Time) error {
+	bs, err := DeserializeOptions{WireFormat: v.cfg.WireFormat}.Deserialize(in)
+	if err != nil {
+		return err
+	}
+	defer bs.Free()
+	return v.ValidateStruct(bs, t)
+}
+
+// ValidateStruct is like Validate for metadata that has already been deserialized.
+func (v *Validator) ValidateStruct(bs *BinaryStruct, t time.Time) error {
+	err := v.validateStruct(bs, t, stageAny)
+	bs.auditTagged(AuditValidate, "validate", v.cfg.AuditTag, err)
+	return err
+}
+
+func (v *Validator) validateStruct(bs *BinaryStruct, t time.Time, stage validationStage) error {
+	for _, f := range v.stageFindings(bs, t, stage) {
+		if f.Severity == SeverityError {
+			return f.fieldError()
+		}
+	}
+	return nil
+}
+
+// maxTimeToLive returns how far after the validation time expirations of service may be.
+func (v *Validator) maxTimeToLive(service string) time.Duration {
+	if ttl, ok := v.cfg.MaxTimeToLiveByServiceType[service]; ok {
+		return ttl
+	}
+	return v.cfg.MaxTimeToLive
+}
+
+// findings returns every rule of v that bs breaks at t, in field order.
+func (v *Validator) findings(bs *BinaryStruct, t time.Time) []Finding {
+	return v.stageFindings(bs, t, stageAny)
+}
+
+// stageFindings is like findings with only the rules of stage.
+func (v *Validator) stageFindings(bs *BinaryStruct, t time.Time, stage validationStage) []Finding {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+	var fs []Finding
+	add := func(field, rule, value string, err error) {
+		fs = append(fs, Finding{Field: field, Rule: rule, Value: value, Severity: SeverityError, Err: err})
+	}
+	if err := bs.checkFreed(); err != nil {
+		add("metadata", "not_freed", "", err)
+		return fs
+	}
+
+	c, err := Capabilities(int32(bs.md().GetVersion()))
+	if err != nil {
+		add("version", "supported_version", fmt.Sprint(bs.md().GetVersion()), err)
+	} else {
+		if ver := int32(bs.md().GetVersion()); v.versions != nil && !v.versions[ver] {
+			add("version", "accepted_version", fmt.Sprint(ver), fmt.Errorf("%w: version %d is not accepted", ErrUnknownVersion, ver))
+		}
+		if err := bs.checkExpirationMillis(c); err != nil {
+			add("expiration", "expiration_precision", fmt.Sprint(bs.expirationMillis()), err)
+		}
+		fs = append(fs, bs.coarseFindings(c)...)
+		if err := bs.checkKeyEpoch(c); err != nil {
+			e, _ := bs.findExtra(ExtensionTypeKeyEpoch)
+			add("key_epoch", "supported_by_version", fmt.Sprintf("%x", e.Value), err)
+		}
+		if err := bs.checkNonce(c); err != nil {
+			e, _ := bs.findExtra(ExtensionTypeNonce)
+			add("nonce", "supported_by_version", fmt.Sprintf("%x", e.Value), err)
+		}
+	}
+
+	service := bs.serviceType()
+	if l, ok := bs.coarse(ExtensionTypeAttestationLevel); ok {
+		if err := checkAttestationLevel(service, AttestationLevel(l)); err != nil {
+			add("attestation_level", "allowed_for_service_type", AttestationLevel(l).String(), err)
+		}
+	}
+	switch {
+	case service == "":
+		add("service_type", "required", "", ErrMissingField)
+	case !v.cfg.AllowUnknownServiceTypes && !IsKnownServiceType(service):
+		add("service_type", "known_service_type", service, checkServiceType(service))
+	case v.serviceTypes != nil && !v.serviceTypes[service]:
+		add("service_type", "allowed_service_type", service, fmt.Errorf("%w: %q is not allowed", ErrUnsupportedServiceType, service))
+	}
+
+	if e, ok := bs.expirationTime(); !ok {
+		add("expiration", "required", "", ErrMissingField)
+	} else {
+		value := e.UTC().Format(time.RFC3339Nano)
+		bucket := v.cfg.ExpirationBucket
+		if bucket == 0 {
+			bucket, _ = ExpirationGranularity(int32(bs.md().GetVersion()))
+		}
+		if bucket > 0 && stage != stageRedemption {
+			offset := v.cfg.ExpirationBucketOffset % bucket
+			if d := bucketDelta(e, bucket, offset); d != 0 {
+				add("expiration", "expiration_bucket", value, &BucketError{Bucket: bucket, Offset: offset, Delta: d})
+			}
+		}
+		skew := v.cfg.ClockSkew
+		if stage == stageIssuance {
+			skew = 0
+		}
+		maxTTL := v.maxTimeToLive(service)
+		switch ttl := e.Sub(t); {
+		case ttl < -skew, stage == stageIssuance && ttl == 0:
+			add("expiration", "not_expired", value, ErrExpired)
+		case ttl > maxTTL+skew:
+			add("expiration", "max_time_to_live", value, fmt.Errorf("%w: %v more than %v after %v", ErrExpirationTooFar, ttl-maxTTL, maxTTL, t.UTC().Format(time.RFC3339)))
+		case ttl < 0 || ttl > maxTTL:
+			fs = append(fs, Finding{Field: "expiration", Rule: "clock_skew", Value: value, Severity: SeverityWarning})
+		}
+	}
+
+	geo := bs.geoHint()
+	if geo.Country == "" {
+		add("country", "required", "", ErrMissingField)
+	} else if !IsValidCountry(geo.Country) {
+		add("country", "iso_3166_alpha2", geo.Country, ErrInvalidCountry)
+	}
+	if geo.Region != "" && v.cfg.MaxGeoGranularity < GeoRegion {
+		add("region", "max_geo_granularity", geo.Region, fmt.Errorf("%w: finer than country level", ErrInvalidGeoHint))
+	}
+	if geo.City != "" && v.cfg.MaxGeoGranularity < GeoCity {
+		add("city", "max_geo_granularity", geo.City, fmt.Errorf("%w: finer than region level", ErrInvalidGeoHint))
+	}
+	if c := v.cfg.GeoCatalog; c != nil && geo.Country != "" {
+		switch {
+		case geo.Region != "" && !c.IsValidRegion(geo.Country, geo.Region):
+			add("region", "geo_catalog", geo.Region, fmt.Errorf("%w: unknown region of %s", ErrInvalidGeoHint, geo.Country))
+		case geo.City != "" && !c.IsValidCity(geo.Country, geo.Region, geo.City):
+			add("city", "geo_catalog", geo.City, fmt.Errorf("%w: unknown city in %s", ErrInvalidGeoHint, geo.Region))
+		}
+	}
+
+	if bs.debugMode() == pmpb.PublicMetadata_DEBUG_ALL {
+		switch {
+		case v.cfg.DebugMode == DebugModeForbidden:
+			add("debug_mode", "debug_mode_policy", bs.debugMode().String(), fmt.Errorf("%w: debugging is not allowed", ErrInvalidDebugMode))
+		case !v.cfg.DebugModeUntil.IsZero() && t.After(v.cfg.DebugModeUntil):
+			add("debug_mode", "debug_mode_until", bs.debugMode().String(), fmt.Errorf("%w: debugging is not allowed after %v", ErrInvalidDebugMode, v.cfg.DebugModeUntil.UTC().Format(time.RFC3339)))
+		default:
+			fs = append(fs, Finding{Field: "debug_mode", Rule: "debug_mode_policy", Value: bs.debugMode().String(), Severity: SeverityWarning})
+		}
+	}
+
+	if v.cfg.Policy != nil && stage != stageRedemption {
+		fs = append(fs, v.cfg.Policy.findings(bs)...)
+	}
+	return fs
+}
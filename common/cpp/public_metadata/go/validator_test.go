@@ -0,0 +1,153 @@
+package binarymetadata
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+)
+
+func serializeForTest(t *testing.T, fields *NewBinaryFields) []byte {
+	t.Helper()
+	bs := New(fields)
+	defer bs.Free()
+	out, err := Serialize(bs)
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	return out
+}
+
+func TestValidator(t *testing.T) {
+	now := time.Unix(1701100000, 0)
+	exp := time.Unix(1701110700, 0)
+	city := &NewBinaryFields{
+		Version:     1,
+		Country:     "US",
+		Region:      "US-CA",
+		City:        "SUNNYVALE",
+		ServiceType: "chromeipblinding",
+		Expiration:  tpb.New(exp),
+		DebugMode:   pmpb.PublicMetadata_DEBUG_ALL,
+	}
+	tests := []struct {
+		name      string
+		cfg       ValidationConfig
+		t         time.Time
+		wantField string
+		wantErr   error
+	}{
+		{name: "default accepts", t: now},
+		{name: "expired", t: exp.Add(time.Minute), wantField: "expiration", wantErr: ErrExpired},
+		{name: "too far", t: exp.Add(-8 * 24 * time.Hour), wantField: "expiration", wantErr: ErrExpirationTooFar},
+		{name: "short max ttl", cfg: ValidationConfig{MaxTimeToLive: time.Hour}, t: now, wantField: "expiration", wantErr: ErrExpirationTooFar},
+		{name: "hour buckets", cfg: ValidationConfig{ExpirationBucket: time.Hour}, t: now, wantField: "expiration", wantErr: ErrExpirationNotRounded},
+		{name: "region level only", cfg: ValidationConfig{MaxGeoGranularity: GeoRegion}, t: now, wantField: "city", wantErr: ErrInvalidGeoHint},
+		{name: "country level only", cfg: ValidationConfig{MaxGeoGranularity: GeoCountry}, t: now, wantField: "region", wantErr: ErrInvalidGeoHint},
+		{name: "debug forbidden", cfg: ValidationConfig{DebugMode: DebugModeForbidden}, t: now, wantField: "debug_mode", wantErr: ErrInvalidDebugMode},
+	}
+	in := serializeForTest(t, city)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := NewValidator(tc.cfg)
+			if err != nil {
+				t.Fatalf("NewValidator failed: %v", err)
+			}
+			err = v.Validate(in, tc.t)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("Validate() returned error: %v, want error: %v", err, tc.wantErr)
+			}
+			var fe *FieldError
+			if err != nil && (!errors.As(err, &fe) || fe.Field != tc.wantField) {
+				t.Errorf("Validate() returned error: %v, want a FieldError for %s", err, tc.wantField)
+			}
+		})
+	}
+}
+
+func TestNewValidatorRejectsBadConfig(t *testing.T) {
+	for _, cfg := range []ValidationConfig{
+		{MaxGeoGranularity: 7},
+		{ExpirationBucket: 1500 * time.Microsecond},
+		{MaxTimeToLive: -time.Hour},
+		{MaxTimeToLiveByServiceType: map[string]time.Duration{"chromeipblinding": 0}},
+		{AllowedServiceTypes: []string{"cronet"}},
+	} {
+		if _, err := NewValidator(cfg); err == nil {
+			t.Errorf("NewValidator(%+v) succeeded, want error", cfg)
+		}
+	}
+}
+
+func TestValidatorExpirationBucketOffset(t *testing.T) {
+	// 18:45, five minutes after a multiple of 20 minutes.
+	now := time.Unix(1701110700, 0)
+	tests := []struct {
+		name      string
+		exp       time.Time
+		wantDelta time.Duration
+	}{
+		{name: "on boundary", exp: now.Add(20 * time.Minute)},
+		{name: "before boundary", exp: now.Add(16 * time.Minute), wantDelta: -4 * time.Minute},
+		{name: "after boundary", exp: now.Add(30 * time.Minute), wantDelta: 10 * time.Minute},
+	}
+	v, err := NewValidator(ValidationConfig{ExpirationBucket: 20 * time.Minute, ExpirationBucketOffset: 5 * time.Minute})
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			in := serializeForTest(t, &NewBinaryFields{Version: 1, Country: "US", ServiceType: "chromeipblinding", Expiration: tpb.New(tc.exp)})
+			err := v.Validate(in, now)
+			if tc.wantDelta == 0 {
+				if err != nil {
+					t.Errorf("Validate() returned error: %v, want nil", err)
+				}
+				return
+			}
+			var be *BucketError
+			if !errors.As(err, &be) || be.Delta != tc.wantDelta || !errors.Is(err, ErrExpirationNotRounded) {
+				t.Errorf("Validate() returned error: %v, want a BucketError with delta %v", err, tc.wantDelta)
+			}
+		})
+	}
+
+	for _, cfg := range []ValidationConfig{
+		{ExpirationBucket: time.Hour, ExpirationBucketOffset: time.Hour},
+		{ExpirationBucketOffset: 1500 * time.Microsecond},
+		{ExpirationBucketOffset: -time.Minute},
+	} {
+		if _, err := NewValidator(cfg); err == nil {
+			t.Errorf("NewValidator(%+v) succeeded, want error", cfg)
+		}
+	}
+}
+
+func TestValidatorMaxTimeToLiveByServiceType(t *testing.T) {
+	now := time.Unix(1701110700, 0)
+	in := serializeForTest(t, &NewBinaryFields{Version: 1, Country: "US", ServiceType: "chromeipblinding", Expiration: tpb.New(now.Add(2 * time.Hour))})
+	tests := []struct {
+		name    string
+		cfg     ValidationConfig
+		wantErr error
+	}{
+		{name: "default", cfg: ValidationConfig{}},
+		{name: "short default", cfg: ValidationConfig{MaxTimeToLive: time.Hour}, wantErr: ErrExpirationTooFar},
+		{name: "override shorter", cfg: ValidationConfig{MaxTimeToLiveByServiceType: map[string]time.Duration{"chromeipblinding": time.Hour}}, wantErr: ErrExpirationTooFar},
+		{name: "override longer", cfg: ValidationConfig{MaxTimeToLive: time.Hour, MaxTimeToLiveByServiceType: map[string]time.Duration{"chromeipblinding": 3 * time.Hour}}},
+		{name: "other service", cfg: ValidationConfig{MaxTimeToLiveByServiceType: map[string]time.Duration{"cronet": time.Hour}}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := NewValidator(tc.cfg)
+			if err != nil {
+				t.Fatalf("NewValidator failed: %v", err)
+			}
+			if err := v.Validate(in, now); !errors.Is(err, tc.wantErr) {
+				t.Errorf("Validate() returned error: %v, want error: %v", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package binarymetadata
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// VersionCapabilities describes which fields and granularities a metadata version allows.
+type VersionCapabilities struct {
+	Version int32
+	// ExpirationGranularity is the boundary expirations must be rounded to. It applies to the
+	// millisecond part too: versions with ExpirationMillis keep the granularity of their
+	// predecessors, and a Validator needs a finer ExpirationBucket to accept a millisecond part.
+	ExpirationGranularity time.Duration
+	// ProxyLayer reports whether the version carries the proxy layer extension.
+	ProxyLayer bool
+	// ExpirationMillis reports whether the version carries the expiration with millisecond
+	// precision.
+	ExpirationMillis bool
+	// NetworkType reports whether the version carries the network type extension.
+	NetworkType bool
+	// ClientPlatform reports whether the version carries the client platform extension.
+	ClientPlatform bool
+	// ServiceTier reports whether the version carries the service tier extension.
+	ServiceTier bool
+	// AttestationLevel reports whether the version carries the attestation level extension.
+	AttestationLevel bool
+	// KeyEpoch reports whether the version carries the key epoch extension.
+	KeyEpoch bool
+	// Nonce reports whether the version carries the nonce extension.
+	Nonce bool
+}
+
+// versionCapabilities lists every version this package can produce and consume. Version 3 is
+// always carried by the version extension, so that none of its extensions is required.
+var versionCapabilities = map[int32]VersionCapabilities{
+	1: {Version: 1, ExpirationGranularity: expirationGranularity},
+	2: {Version: 2, ExpirationGranularity: expirationGranularity, ProxyLayer: true},
+	3: {
+		Version:               3,
+		ExpirationGranularity: expirationGranularity,
+		ProxyLayer:            true,
+		ExpirationMillis:      true,
+		NetworkType:           true,
+		ClientPlatform:        true,
+		ServiceTier:           true,
+		AttestationLevel:      true,
+		KeyEpoch:              true,
+		Nonce:                 true,
+	},
+}
+
+// Capabilities returns the capabilities of version.
+func Capabilities(version int32) (VersionCapabilities, error) {
+	c, ok := versionCapabilities[version]
+	if !ok {
+		return VersionCapabilities{}, fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+	return c, nil
+}
+
+// SupportedVersions returns the versions this package supports in ascending order.
+func SupportedVersions() []int32 {
+	versions := make([]int32, 0, len(versionCapabilities))
+	for v := range versionCapabilities {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	return versions
+}
+
+// MaxKnownVersion returns the newest version this package supports. Callers receiving metadata
+// from newer producers can compare against it to pick their own policy, e.g. deserializing with
+// DeserializeOptions.AllowNewerVersions.
+func MaxKnownVersion() int32 {
+	return maxVersion
+}
+
+// ChooseVersion returns the newest version that is at most clientMax, is listed in
+// serverSupported, and is supported by this package. It returns an error wrapping
+// ErrUnknownVersion if there is no such version.
+func ChooseVersion(clientMax int32, serverSupported []int32) (int32, error) {
+	best := int32(-1)
+	for _, v := range serverSupported {
+		if _, ok := versionCapabilities[v]; ok && v <= clientMax && v > best {
+			best = v
+		}
+	}
+	if best < 0 {
+		return 0, fmt.Errorf("%w: no common version for client max %d and server versions %v", ErrUnknownVersion, clientMax, serverSupported)
+	}
+	return best, nil
+}
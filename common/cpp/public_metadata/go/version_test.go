@@ -0,0 +1,86 @@
+package binarymetadata
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"google3/third_party/golang/cmp/cmp"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+)
+
+func TestChooseVersion(t *testing.T) {
+	tests := []struct {
+		name            string
+		clientMax       int32
+		serverSupported []int32
+		want            int32
+		wantErr         error
+	}{
+		{
+			name:            "newest common",
+			clientMax:       2,
+			serverSupported: []int32{1, 2},
+			want:            2,
+		},
+		{
+			name:            "client is older",
+			clientMax:       1,
+			serverSupported: []int32{2, 1},
+			want:            1,
+		},
+		{
+			name:            "server knows versions this package does not",
+			clientMax:       9,
+			serverSupported: []int32{1, 2, 9},
+			want:            2,
+		},
+		{
+			name:            "no overlap",
+			clientMax:       1,
+			serverSupported: []int32{2},
+			wantErr:         ErrUnknownVersion,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ChooseVersion(tc.clientMax, tc.serverSupported)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("ChooseVersion() returned error: %v, want error: %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("ChooseVersion() = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSupportedVersions(t *testing.T) {
+	if diff := cmp.Diff([]int32{1, 2, 3}, SupportedVersions()); diff != "" {
+		t.Errorf("SupportedVersions() returned unexpected diff (-want +got):\n%s", diff)
+	}
+	c, err := Capabilities(2)
+	if err != nil {
+		t.Fatalf("Capabilities(2) failed: %v", err)
+	}
+	if !c.ProxyLayer {
+		t.Error("Capabilities(2).ProxyLayer = false, want true")
+	}
+}
+
+func TestMaxKnownVersion(t *testing.T) {
+	versions := SupportedVersions()
+	if got, want := MaxKnownVersion(), versions[len(versions)-1]; got != want {
+		t.Errorf("MaxKnownVersion() = %d, want %d", got, want)
+	}
+}
+
+// newTestStruct returns metadata of version with the fields the extension tests share, freed when
+// t finishes. Version 3 is the first one with every optional extension.
+func newTestStruct(t *testing.T, version int32) *BinaryStruct {
+	t.Helper()
+	bs := New(&NewBinaryFields{Version: version, Country: "US", ServiceType: "chromeipblinding", Expiration: tpb.New(time.Unix(900, 0))})
+	t.Cleanup(bs.Free)
+	return bs
+}
@@ -0,0 +1,128 @@
+package binarymetadata
+
+import (
+	"google3/privacy/net/boq/common/tokens/tokentypes"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	plpb "google3/privacy/net/common/proto/proxy_layer_go_proto"
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+)
+
+// View is a read-only view of serialized metadata. Unlike Deserialize it does not allocate a C++
+// struct: NewView only splits the extensions list, and each getter decodes its own extension when
+// called. A View aliases the bytes it was created from, which must not be modified while it is in
+// use, and needs no Free.
+//
+// A View does not check the number or order of extensions, nor their values beyond what is needed
+// to decode them; getters return the zero value for missing or undecodable extensions. Use
+// Deserialize or ValidateMetadataCardinality where that matters.
+type View struct {
+	exts []Extension
+}
+
+// NewView returns a View of in. It fails only if in is not a well-formed extensions list.
+func NewView(in []byte) (View, error) {
+	exts, err := DecodeExtensions(in)
+	if err != nil {
+		return View{}, err
+	}
+	return View{exts: exts}, nil
+}
+
+func (v View) lookup(typeID uint16) (Extension, bool) {
+	for _, e := range v.exts {
+		if e.Type == typeID {
+			return e, true
+		}
+	}
+	return Extension{}, false
+}
+
+// GetVersion gets the metadata version: the one carried by the version extension if there is one
+// and, like the C++ library, the one inferred from the presence of the proxy layer extension
+// otherwise.
+func (v View) GetVersion() int32 {
+	if e, ok := v.lookup(ExtensionTypeVersion); ok {
+		if ver, err := VersionExtensionFromExtension(e); err == nil {
+			return int32(ver.Version)
+		}
+	}
+	_, ok := v.lookup(ExtensionTypeProxyLayer)
+	return impliedVersion(ok)
+}
+
+// GetExpiration gets expiration timestamp
+func (v View) GetExpiration() *tpb.Timestamp {
+	e, ok := v.lookup(ExtensionTypeExpirationTimestamp)
+	if !ok {
+		return nil
+	}
+	exp, err := ExpirationExtensionFromExtension(e)
+	if err != nil || checkExpirationSeconds(int64(exp.Timestamp)) != nil {
+		return nil
+	}
+	return &tpb.Timestamp{Seconds: int64(exp.Timestamp)}
+}
+
+// GetServiceType gets the service type
+func (v View) GetServiceType() string {
+	e, ok := v.lookup(ExtensionTypeServiceType)
+	if !ok {
+		return ""
+	}
+	st, err := ServiceTypeExtensionFromExtension(e)
+	if err != nil {
+		return ""
+	}
+	return st.ServiceType
+}
+
+// GetDebugMode gets the debug mode
+func (v View) GetDebugMode() pmpb.PublicMetadata_DebugMode {
+	e, ok := v.lookup(ExtensionTypeDebugMode)
+	if !ok {
+		return pmpb.PublicMetadata_UNSPECIFIED_DEBUG_MODE
+	}
+	dm, err := DebugModeExtensionFromExtension(e)
+	if err != nil {
+		return pmpb.PublicMetadata_UNSPECIFIED_DEBUG_MODE
+	}
+	return pmpb.PublicMetadata_DebugMode(dm.Mode)
+}
+
+// GetProxyLayer gets the proxy layer
+func (v View) GetProxyLayer() plpb.ProxyLayer {
+	e, ok := v.lookup(ExtensionTypeProxyLayer)
+	if !ok {
+		return plpb.ProxyLayer_PROXY_LAYER_UNSPECIFIED
+	}
+	pl, err := ProxyLayerExtensionFromExtension(e)
+	if err != nil {
+		return plpb.ProxyLayer_PROXY_LAYER_UNSPECIFIED
+	}
+	l, err := ProxyLayerFromWire(uint(pl.Layer))
+	if err != nil {
+		return plpb.ProxyLayer_PROXY_LAYER_UNSPECIFIED
+	}
+	return l
+}
+
+// GetGeoHint gets the GeoHint (country, region, city) tuple.
+func (v View) GetGeoHint() *tokentypes.GeoHint {
+	e, ok := v.lookup(ExtensionTypeGeoHint)
+	if !ok {
+		return &tokentypes.GeoHint{}
+	}
+	geo, err := GeoHintExtensionFromExtension(e)
+	if err != nil {
+		return &tokentypes.GeoHint{}
+	}
+	return &tokentypes.GeoHint{Country: geo.CountryCode, Region: geo.Region, City: geo.City}
+}
+
+// GetExtension returns the raw value of the extension with type typeID and whether it is present.
+// The returned slice aliases the viewed bytes.
+func (v View) GetExtension(typeID uint16) ([]byte, bool) {
+	e, ok := v.lookup(typeID)
+	return e.Value, ok
+}
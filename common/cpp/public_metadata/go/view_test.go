@@ -0,0 +1,78 @@
+package binarymetadata
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"google3/third_party/golang/cmp/cmp"
+	"google3/third_party/golang/protobuf/v2/proto/proto"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+	plpb "google3/privacy/net/common/proto/proxy_layer_go_proto"
+	pmpb "google3/privacy/net/common/proto/public_metadata_go_proto"
+)
+
+func TestViewMatchesDeserialize(t *testing.T) {
+	v2, err := base64.StdEncoding.DecodeString(exampleExtensions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs := New(&NewBinaryFields{
+		Version:     1,
+		Country:     "US",
+		Region:      "US-CA",
+		ServiceType: "chromeipblinding",
+		Expiration:  &tpb.Timestamp{Seconds: 900},
+		DebugMode:   pmpb.PublicMetadata_DEBUG_ALL,
+	})
+	v1, err := Serialize(bs)
+	bs.Free()
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	for name, in := range map[string][]byte{"v1": v1, "v2": v2} {
+		t.Run(name, func(t *testing.T) {
+			want, err := Deserialize(in)
+			if err != nil {
+				t.Fatalf("Deserialize failed: %v", err)
+			}
+			defer want.Free()
+			got, err := NewView(in)
+			if err != nil {
+				t.Fatalf("NewView failed: %v", err)
+			}
+			if got.GetVersion() != want.GetVersion() {
+				t.Errorf("GetVersion() = %d, want %d", got.GetVersion(), want.GetVersion())
+			}
+			if !proto.Equal(got.GetExpiration(), want.GetExpiration()) {
+				t.Errorf("GetExpiration() = %v, want %v", got.GetExpiration(), want.GetExpiration())
+			}
+			if got.GetServiceType() != want.GetServiceType() {
+				t.Errorf("GetServiceType() = %q, want %q", got.GetServiceType(), want.GetServiceType())
+			}
+			if got.GetDebugMode() != want.GetDebugMode() {
+				t.Errorf("GetDebugMode() = %v, want %v", got.GetDebugMode(), want.GetDebugMode())
+			}
+			if got.GetProxyLayer() != want.GetProxyLayer() {
+				t.Errorf("GetProxyLayer() = %v, want %v", got.GetProxyLayer(), want.GetProxyLayer())
+			}
+			if diff := cmp.Diff(want.GetGeoHint(), got.GetGeoHint()); diff != "" {
+				t.Errorf("GetGeoHint() returned unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestViewMissingExtensions(t *testing.T) {
+	v, err := NewView([]byte{0x00, 0x00})
+	if err != nil {
+		t.Fatalf("NewView failed: %v", err)
+	}
+	if v.GetExpiration() != nil || v.GetServiceType() != "" || v.GetProxyLayer() != plpb.ProxyLayer_PROXY_LAYER_UNSPECIFIED || v.GetGeoHint().Country != "" {
+		t.Errorf("View of an empty list returned non-zero fields")
+	}
+	if _, err := NewView([]byte{0x01}); !errors.Is(err, ErrMalformed) {
+		t.Errorf("NewView(truncated) returned error: %v, want error: %v", err, ErrMalformed)
+	}
+}
@@ -0,0 +1,90 @@
+package binarymetadata
+
+import (
+	"fmt"
+	"sync"
+)
+
+// WireFormat names a draft revision of the extensions format. The zero value is the revision this
+// package and the C++ library implement.
+//
+// This package registers no other revision, so only WireFormatCurrent is selectable until one is
+// registered with RegisterWireFormat, together with vectors of its encoding.
+type WireFormat string
+
+// WireFormatCurrent is the revision implemented by the C++ library.
+const WireFormatCurrent WireFormat = ""
+
+// WireTranscoder converts between a draft revision and WireFormatCurrent, so that servers can roll
+// forward to a new revision while still accepting blobs from clients pinned to an older one.
+type WireTranscoder interface {
+	// Decode converts in from the revision into WireFormatCurrent.
+	Decode(in []byte) ([]byte, error)
+	// Encode converts out from WireFormatCurrent into the revision.
+	Encode(out []byte) ([]byte, error)
+}
+
+var (
+	wireFormatsMu sync.RWMutex
+	wireFormats   = map[WireFormat]WireTranscoder{}
+)
+
+// RegisterWireFormat makes f selectable through DeserializeOptions.WireFormat,
+// ValidationConfig.WireFormat and SerializeWireFormat. Registering WireFormatCurrent or a format
+// twice is an error. It is meant to be called from init functions.
+func RegisterWireFormat(f WireFormat, t WireTranscoder) error {
+	if f == WireFormatCurrent || t == nil {
+		return fmt.Errorf("%w: cannot register %q", ErrUnknownWireFormat, f)
+	}
+	wireFormatsMu.Lock()
+	defer wireFormatsMu.Unlock()
+	if _, ok := wireFormats[f]; ok {
+		return fmt.Errorf("wire format %q is already registered", f)
+	}
+	wireFormats[f] = t
+	return nil
+}
+
+// wireTranscoder returns the transcoder of f, which is nil for WireFormatCurrent, or an error
+// wrapping ErrUnknownWireFormat if f is not registered.
+func wireTranscoder(f WireFormat) (WireTranscoder, error) {
+	if f == WireFormatCurrent {
+		return nil, nil
+	}
+	wireFormatsMu.RLock()
+	defer wireFormatsMu.RUnlock()
+	t, ok := wireFormats[f]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownWireFormat, f)
+	}
+	return t, nil
+}
+
+// decodeWireFormat converts in from f into WireFormatCurrent.
+func decodeWireFormat(in []byte, f WireFormat) ([]byte, error) {
+	t, err := wireTranscoder(f)
+	if err != nil || t == nil {
+		return in, err
+	}
+	out, err := t.Decode(in)
+	if err != nil {
+		return nil, fmt.Errorf("%w: wire format %q: %v", ErrMalformed, f, err)
+	}
+	return out, nil
+}
+
+// SerializeWireFormat is like Serialize, but encodes bs in the draft revision f.
+func SerializeWireFormat(bs *BinaryStruct, f WireFormat) ([]byte, error) {
+	t, err := wireTranscoder(f)
+	if err != nil {
+		return nil, err
+	}
+	out, err := Serialize(bs)
+	if err != nil || t == nil {
+		return out, err
+	}
+	if out, err = t.Encode(out); err != nil {
+		return nil, fmt.Errorf("wire format %q: %w", f, err)
+	}
+	return out, nil
+}
@@ -0,0 +1,131 @@
+package binarymetadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	tpb "google3/google/protobuf/timestamp_go_proto"
+)
+
+// unprefixed is a wire format that omits the length of the extensions list.
+type unprefixed struct{}
+
+func (unprefixed) Decode(in []byte) ([]byte, error) {
+	if len(in) > 0xffff {
+		return nil, fmt.Errorf("%d bytes", len(in))
+	}
+	return append(binary.BigEndian.AppendUint16(nil, uint16(len(in))), in...), nil
+}
+
+func (unprefixed) Encode(out []byte) ([]byte, error) {
+	return out[2:], nil
+}
+
+func registerWireFormatForTest(t *testing.T, f WireFormat, tr WireTranscoder) {
+	t.Helper()
+	if err := RegisterWireFormat(f, tr); err != nil {
+		t.Fatalf("RegisterWireFormat failed: %v", err)
+	}
+	t.Cleanup(func() {
+		wireFormatsMu.Lock()
+		defer wireFormatsMu.Unlock()
+		delete(wireFormats, f)
+	})
+}
+
+func TestWireFormat(t *testing.T) {
+	const old WireFormat = "unprefixed"
+	registerWireFormatForTest(t, old, unprefixed{})
+	exp := time.Unix(1701110700, 0)
+	bs := New(&NewBinaryFields{Version: 1, Country: "US", ServiceType: "chromeipblinding", Expiration: tpb.New(exp)})
+	defer bs.Free()
+	current, err := Serialize(bs)
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	out, err := SerializeWireFormat(bs, old)
+	if err != nil {
+		t.Fatalf("SerializeWireFormat failed: %v", err)
+	}
+	if !bytes.Equal(out, current[2:]) {
+		t.Errorf("SerializeWireFormat() = %x, want %x", out, current[2:])
+	}
+
+	got, err := DeserializeOptions{WireFormat: old, Strict: true}.Deserialize(out)
+	if err != nil {
+		t.Fatalf("Deserialize failed: %v", err)
+	}
+	defer got.Free()
+	if diff := Diff(bs, got); len(diff) > 0 {
+		t.Errorf("Deserialize() differs from the serialized struct: %v", diff)
+	}
+
+	v, err := NewValidator(ValidationConfig{WireFormat: old})
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+	if err := v.ValidateForRedemption(out, exp.Add(-time.Hour)); err != nil {
+		t.Errorf("ValidateForRedemption() failed: %v", err)
+	}
+	if err := v.ValidateForRedemption(current, exp.Add(-time.Hour)); err == nil {
+		t.Error("ValidateForRedemption(current format) succeeded, want error")
+	}
+}
+
+func TestUnknownWireFormat(t *testing.T) {
+	const unknown WireFormat = "unknown"
+	if _, err := (DeserializeOptions{WireFormat: unknown}).Deserialize([]byte{0, 0}); !errors.Is(err, ErrUnknownWireFormat) {
+		t.Errorf("Deserialize() returned error: %v, want error: %v", err, ErrUnknownWireFormat)
+	}
+	if _, err := NewValidator(ValidationConfig{WireFormat: unknown}); !errors.Is(err, ErrUnknownWireFormat) {
+		t.Errorf("NewValidator() returned error: %v, want error: %v", err, ErrUnknownWireFormat)
+	}
+	if err := RegisterWireFormat(WireFormatCurrent, unprefixed{}); !errors.Is(err, ErrUnknownWireFormat) {
+		t.Errorf("RegisterWireFormat(WireFormatCurrent) returned error: %v, want error: %v", err, ErrUnknownWireFormat)
+	}
+}
+
+// expanding is a wire format whose Decode appends an extension of pad zero bytes, counting calls.
+type expanding struct {
+	pad   int
+	calls *int
+}
+
+func (e expanding) Decode(in []byte) ([]byte, error) {
+	*e.calls++
+	out := append(binary.BigEndian.AppendUint16(nil, uint16(len(in)+2+e.pad)), in[2:]...)
+	out = binary.BigEndian.AppendUint16(out, 0xF0FF)
+	out = binary.BigEndian.AppendUint16(out, uint16(e.pad))
+	return append(out, make([]byte, e.pad)...), nil
+}
+
+func (expanding) Encode(out []byte) ([]byte, error) {
+	return out, nil
+}
+
+func TestWireFormatBounds(t *testing.T) {
+	var calls int
+	const f WireFormat = "expanding"
+	registerWireFormatForTest(t, f, expanding{pad: 64, calls: &calls})
+	in := serializeForTest(t, &NewBinaryFields{Version: 1, Country: "US", ServiceType: "chromeipblinding", Expiration: &tpb.Timestamp{Seconds: 900}})
+
+	_, err := DeserializeOptions{WireFormat: f, MaxInputSize: len(in) - 1}.Deserialize(in)
+	if !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Deserialize(raw input too large) returned error: %v, want error: %v", err, ErrTooLarge)
+	}
+	if calls != 0 {
+		t.Errorf("Deserialize(raw input too large) transcoded the input %d times, want 0", calls)
+	}
+
+	_, err = DeserializeOptions{WireFormat: f, MaxInputSize: len(in) + 32}.Deserialize(in)
+	if !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Deserialize(transcoded input too large) returned error: %v, want error: %v", err, ErrTooLarge)
+	}
+	if calls != 1 {
+		t.Errorf("Deserialize(transcoded input too large) transcoded the input %d times, want 1", calls)
+	}
+}
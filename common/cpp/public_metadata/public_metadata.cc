@@ -1,5 +1,7 @@
 #include "privacy/net/common/cpp/public_metadata/public_metadata.h"
 
+#include <cstddef>
+#include <cstdint>
 #include <string>
 
 #include "google/protobuf/timestamp.proto.h"
@@ -16,11 +18,42 @@ namespace privacy::ppn {
 
 using private_membership::anonymous_tokens::DebugMode;
 using private_membership::anonymous_tokens::ExpirationTimestamp;
+using private_membership::anonymous_tokens::Extension;
 using private_membership::anonymous_tokens::Extensions;
 using private_membership::anonymous_tokens::GeoHint;
 using private_membership::anonymous_tokens::ProxyLayer;
 using private_membership::anonymous_tokens::ServiceType;
 
+namespace {
+
+// The version extension carries the version whenever it is newer than the one
+// implied by the number of extensions, e.g. for version 2 metadata without a
+// proxy layer. Its value is the version as a single byte.
+constexpr uint16_t kVersionExtensionType = 0xF00B;
+
+// The newest version Deserialize accepts in the version extension.
+constexpr uint32_t kMaxVersion = 3;
+
+// Version 3 adds the extension types from 0xF004 to 0xF00A: the millisecond
+// part of the expiration, the network type, the client platform, the service
+// tier, the attestation level, the key epoch and the nonce. The struct does not
+// model them; Deserialize skips them and the Go wrapper decodes them.
+constexpr uint16_t kFirstVersion3ExtensionType = 0xF004;
+constexpr uint16_t kLastVersion3ExtensionType = 0xF00A;
+
+bool IsVersion3ExtensionType(uint16_t extension_type) {
+  return extension_type >= kFirstVersion3ExtensionType &&
+         extension_type <= kLastVersion3ExtensionType;
+}
+
+// Returns the version implied by the extensions Serialize writes without the
+// version extension.
+uint32_t ImpliedVersion(bool has_proxy_layer) {
+  return has_proxy_layer ? 2 : 1;
+}
+
+}  // namespace
+
 BinaryPublicMetadata PublicMetadataProtoToStruct(
     const PublicMetadata& metadata) {
   BinaryPublicMetadata binary_struct;
@@ -56,8 +89,28 @@ absl::Status ValidateBinaryPublicMetadataCardinality(
   if (!extensions.ok()) {
     return extensions.status();
   }
+  // The version extension and those added by version 3 are not registered with
+  // the anonymous tokens library, and their values do not depend on now.
+  Extensions registered;
+  uint32_t version = 1;
+  bool has_version3_extensions = false;
+  for (const Extension& extension : extensions->extensions) {
+    if (extension.extension_type == kVersionExtensionType) {
+      if (extension.extension_value.size() != 1) {
+        return absl::InvalidArgumentError("Invalid version extension");
+      }
+      version = static_cast<uint8_t>(extension.extension_value[0]);
+    } else if (IsVersion3ExtensionType(extension.extension_type)) {
+      has_version3_extensions = true;
+    } else {
+      registered.extensions.push_back(extension);
+    }
+  }
+  if (has_version3_extensions && version < 3) {
+    return absl::InvalidArgumentError("Extension not supported by the version");
+  }
   return private_membership::anonymous_tokens::ValidateExtensionsValues(
-      *extensions, now);
+      registered, now);
 }
 
 absl::StatusOr<std::string> Serialize(
@@ -116,12 +169,16 @@ absl::StatusOr<std::string> Serialize(
   }
   extensions.extensions.push_back(debug_mode_ext.value());
 
-  if (metadata.version == 2) {
+  const bool has_proxy_layer =
+      metadata.version >= 2 && metadata.proxy_layer.has_value();
+  if (has_proxy_layer) {
     ProxyLayer proxy_layer;
-    if (metadata.proxy_layer == 0) {
+    if (metadata.proxy_layer.value() == 0) {
       proxy_layer.layer = ProxyLayer::kProxyA;
-    } else if (metadata.proxy_layer == 1) {
+    } else if (metadata.proxy_layer.value() == 1) {
       proxy_layer.layer = ProxyLayer::kProxyB;
+    } else {
+      return absl::InvalidArgumentError("invalid proxy layer");
     }
     auto proxy_layer_ext = proxy_layer.AsExtension();
     if (!proxy_layer_ext.ok()) {
@@ -130,6 +187,17 @@ absl::StatusOr<std::string> Serialize(
     extensions.extensions.push_back(proxy_layer_ext.value());
   }
 
+  if (metadata.version > ImpliedVersion(has_proxy_layer)) {
+    if (metadata.version > 0xff) {
+      return absl::InvalidArgumentError("version does not fit the extension");
+    }
+    Extension version_ext;
+    version_ext.extension_type = kVersionExtensionType;
+    version_ext.extension_value =
+        std::string(1, static_cast<char>(metadata.version));
+    extensions.extensions.push_back(version_ext);
+  }
+
   return private_membership::anonymous_tokens::EncodeExtensions(extensions);
 }
 
@@ -142,9 +210,7 @@ absl::StatusOr<privacy::ppn::BinaryPublicMetadata> Deserialize(
   if (!extensions.ok()) {
     return extensions.status();
   }
-  // TODO: b/306703210 - propagate version information
-  if (extensions->extensions.size() != 4 &&
-      extensions->extensions.size() != 5) {
+  if (extensions->extensions.size() < 4) {
     return absl::InvalidArgumentError("Wrong number of extensions");
   }
   auto expiration =
@@ -172,15 +238,43 @@ absl::StatusOr<privacy::ppn::BinaryPublicMetadata> Deserialize(
   }
 
   BinaryPublicMetadata metadata;
-  if (extensions->extensions.size() == 5) {
-    auto proxy_layer = ProxyLayer::FromExtension(extensions->extensions[4]);
+  size_t next = 4;
+  if (next < extensions->extensions.size() &&
+      extensions->extensions[next].extension_type != kVersionExtensionType &&
+      !IsVersion3ExtensionType(extensions->extensions[next].extension_type)) {
+    auto proxy_layer = ProxyLayer::FromExtension(extensions->extensions[next]);
     if (!proxy_layer.ok()) {
       return proxy_layer.status();
     }
-    metadata.version = 2;
     metadata.proxy_layer = proxy_layer->layer;
-  } else {
-    metadata.version = 1;
+    ++next;
+  }
+  bool has_version3_extensions = false;
+  while (next < extensions->extensions.size() &&
+         IsVersion3ExtensionType(extensions->extensions[next].extension_type)) {
+    has_version3_extensions = true;
+    ++next;
+  }
+  metadata.version = ImpliedVersion(metadata.proxy_layer.has_value());
+  if (next < extensions->extensions.size()) {
+    const Extension& version_ext = extensions->extensions[next];
+    if (version_ext.extension_type != kVersionExtensionType ||
+        version_ext.extension_value.size() != 1) {
+      return absl::InvalidArgumentError("Invalid version extension");
+    }
+    const uint32_t version =
+        static_cast<uint8_t>(version_ext.extension_value[0]);
+    if (version == metadata.version || version < 2 || version > kMaxVersion) {
+      return absl::InvalidArgumentError("Unsupported version");
+    }
+    metadata.version = version;
+    ++next;
+  }
+  if (next != extensions->extensions.size()) {
+    return absl::InvalidArgumentError("Wrong number of extensions");
+  }
+  if (has_version3_extensions && metadata.version < 3) {
+    return absl::InvalidArgumentError("Extension not supported by the version");
   }
 
   metadata.expiration_epoch_seconds = expiration.value().timestamp;
@@ -41,8 +41,9 @@ struct BinaryPublicMetadata {
   uint32_t debug_mode;
 
   // Indicates whether the token is usable for only a specific proxy layer.
-  // 0 is proxy A and 1 is proxy B.
-  uint32_t proxy_layer;
+  // 0 is proxy A and 1 is proxy B. Unset means any proxy layer. Only
+  // serialized for version 2 and newer.
+  std::optional<uint32_t> proxy_layer;
 };
 
 BinaryPublicMetadata PublicMetadataProtoToStruct(
@@ -52,12 +53,16 @@ absl::Status ValidateBinaryPublicMetadataCardinality(
     absl::string_view encoded_extensions, absl::Time now);
 
 // Serialize a BinaryPublicMetadata struct into
-// draft-wood-privacypass-extensible-token format.
+// draft-wood-privacypass-extensible-token format. The version is implied by the
+// presence of the proxy layer extension, and carried by a trailing version
+// extension (0xF00B) when it is not, e.g. for version 2 without a proxy layer.
 // TODO: document extensions in more detail
 absl::StatusOr<std::string> Serialize(
     const privacy::ppn::BinaryPublicMetadata& metadata);
 
 // Deserialize a draft-wood-privacypass-extensible-token format into a struct.
+// The extensions added by version 3 that the struct does not model, types
+// 0xF004 to 0xF00A, are skipped.
 absl::StatusOr<privacy::ppn::BinaryPublicMetadata> Deserialize(
     absl::string_view encoded_extensions);
 
@@ -1,11 +1,14 @@
 #include "privacy/net/common/cpp/public_metadata/public_metadata.h"
 
 #include <cstdint>
+#include <optional>
+#include <string>
 
 #include "testing/base/public/gmock.h"
 #include "testing/base/public/gunit.h"
 #include "third_party/absl/time/clock.h"
 #include "third_party/absl/time/time.h"
+#include "third_party/anonymous_tokens/cpp/privacy_pass/token_encodings.h"
 
 namespace privacy::ppn {
 namespace {
@@ -64,5 +67,116 @@ TEST(BinaryPublicMetadataSerialize, RoundtripV2) {
             decoded.value().expiration_epoch_seconds);
 }
 
+TEST(BinaryPublicMetadataSerialize, RoundtripV2UnsetProxyLayer) {
+  BinaryPublicMetadata metadata;
+  metadata.version = 2;
+  metadata.service_type = "chromeipblinding";
+  metadata.country = "US";
+  metadata.region = "US-CA";
+  metadata.city = "SUNNYVALE";
+  metadata.debug_mode = 0;
+  uint64_t seconds = absl::ToUnixSeconds(absl::Now() + absl::Minutes(15));
+  seconds -= (seconds % 900);
+  metadata.expiration_epoch_seconds = seconds;
+  const auto encoded = Serialize(metadata);
+  ASSERT_TRUE(encoded.ok()) << encoded.status();
+  const auto decoded = Deserialize(encoded.value());
+  ASSERT_TRUE(decoded.ok()) << decoded.status();
+  EXPECT_EQ(metadata.version, decoded.value().version);
+  EXPECT_FALSE(decoded.value().proxy_layer.has_value());
+}
+
+TEST(BinaryPublicMetadataSerialize, RejectsRedundantVersionExtension) {
+  BinaryPublicMetadata metadata;
+  metadata.version = 1;
+  metadata.service_type = "chromeipblinding";
+  metadata.country = "US";
+  metadata.region = "";
+  metadata.city = "";
+  metadata.debug_mode = 0;
+  metadata.expiration_epoch_seconds = 900;
+  const auto encoded = Serialize(metadata);
+  ASSERT_TRUE(encoded.ok()) << encoded.status();
+  auto extensions =
+      private_membership::anonymous_tokens::DecodeExtensions(encoded.value());
+  ASSERT_TRUE(extensions.ok()) << extensions.status();
+  EXPECT_EQ(extensions->extensions.size(), 4);
+  private_membership::anonymous_tokens::Extension version_ext;
+  version_ext.extension_type = 0xF00B;
+  version_ext.extension_value = std::string(1, '\x01');
+  extensions->extensions.push_back(version_ext);
+  const auto reencoded =
+      private_membership::anonymous_tokens::EncodeExtensions(*extensions);
+  ASSERT_TRUE(reencoded.ok()) << reencoded.status();
+  EXPECT_FALSE(Deserialize(reencoded.value()).ok());
+}
+
+TEST(BinaryPublicMetadataSerialize, RoundtripV3) {
+  BinaryPublicMetadata metadata;
+  metadata.version = 3;
+  metadata.service_type = "chromeipblinding";
+  metadata.country = "US";
+  metadata.region = "US-CA";
+  metadata.city = "";
+  metadata.debug_mode = 0;
+  metadata.expiration_epoch_seconds = 900;
+  for (const auto& proxy_layer :
+       {std::optional<uint32_t>(), std::optional<uint32_t>(1)}) {
+    metadata.proxy_layer = proxy_layer;
+    const auto encoded = Serialize(metadata);
+    ASSERT_TRUE(encoded.ok()) << encoded.status();
+    const auto decoded = Deserialize(encoded.value());
+    ASSERT_TRUE(decoded.ok()) << decoded.status();
+    EXPECT_EQ(metadata.version, decoded.value().version);
+    EXPECT_EQ(metadata.proxy_layer, decoded.value().proxy_layer);
+  }
+}
+
+TEST(BinaryPublicMetadataSerialize, Version3ExtensionsNeedVersion3) {
+  BinaryPublicMetadata metadata;
+  metadata.service_type = "chromeipblinding";
+  metadata.country = "US";
+  metadata.region = "";
+  metadata.city = "";
+  metadata.debug_mode = 0;
+  metadata.expiration_epoch_seconds = 900;
+  for (const uint32_t version : {1, 3}) {
+    metadata.version = version;
+    const auto encoded = Serialize(metadata);
+    ASSERT_TRUE(encoded.ok()) << encoded.status();
+    auto extensions =
+        private_membership::anonymous_tokens::DecodeExtensions(encoded.value());
+    ASSERT_TRUE(extensions.ok()) << extensions.status();
+    // A network type extension, which the struct does not model.
+    private_membership::anonymous_tokens::Extension network_type;
+    network_type.extension_type = 0xF005;
+    network_type.extension_value = std::string(1, '\x01');
+    extensions->extensions.insert(extensions->extensions.begin() + 4,
+                                  network_type);
+    const auto reencoded =
+        private_membership::anonymous_tokens::EncodeExtensions(*extensions);
+    ASSERT_TRUE(reencoded.ok()) << reencoded.status();
+    const auto decoded = Deserialize(reencoded.value());
+    EXPECT_EQ(decoded.ok(), version == 3) << decoded.status();
+    EXPECT_EQ(ValidateBinaryPublicMetadataCardinality(reencoded.value(),
+                                                      absl::FromUnixSeconds(0))
+                  .ok(),
+              version == 3);
+  }
+}
+
+TEST(BinaryPublicMetadataSerialize, InvalidProxyLayer) {
+  BinaryPublicMetadata metadata;
+  metadata.version = 2;
+  metadata.service_type = "chromeipblinding";
+  metadata.country = "US";
+  metadata.region = "";
+  metadata.city = "";
+  metadata.debug_mode = 0;
+  metadata.proxy_layer = 9;
+  metadata.expiration_epoch_seconds = 900;
+  EXPECT_FALSE(Serialize(metadata).ok());
+}
+
 }  // namespace
 }  // namespace privacy::ppn